@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"toni/internal/backup"
+)
+
+func runBackup(config *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: toni backup list|create|restore <id>|prune")
+	}
+
+	fs := flag.NewFlagSet("backup "+args[0], flag.ContinueOnError)
+	policy := backup.DefaultPolicy()
+	fs.IntVar(&policy.KeepDaily, "keep-daily", policy.KeepDaily, "Number of daily backups to keep")
+	fs.IntVar(&policy.KeepWeekly, "keep-weekly", policy.KeepWeekly, "Number of weekly backups to keep")
+	reason := fs.String("reason", backup.ReasonManual, "Label recorded with the backup")
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	manager := backupManager(config, nil)
+	manager.Policy = policy
+
+	switch args[0] {
+	case "list", "ls":
+		backups, err := manager.List()
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backups in %s\n", config.BackupDir)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tCREATED\tREASON\tSIZE\tENCRYPTED")
+		for _, b := range backups {
+			encrypted := "no"
+			if b.Encrypted {
+				encrypted = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", b.ID, b.CreatedAt.Local().Format("2006-01-02 15:04"), b.Reason, formatSize(b.Size), encrypted)
+		}
+		return w.Flush()
+
+	case "create":
+		store, err := OpenStore(config)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		manager = backupManager(config, store)
+		manager.Policy = policy
+		b, err := manager.Create(store.DB, *reason)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created backup %s (%s, verified)\n", b.ID, formatSize(b.Size))
+		return nil
+
+	case "restore":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: toni backup restore <id>")
+		}
+		id := rest[0]
+		target, err := manager.Find(id)
+		if err != nil {
+			return err
+		}
+
+		// The current database is opened up front: an encrypted one supplies
+		// the key for sealed backups, and either way it is backed up below
+		// before being overwritten.
+		dbPath := config.DBPath
+		var store *Store
+		if config.Encrypted {
+			dbPath = config.EncryptedDBPath()
+		}
+		if fileExists(dbPath) {
+			if store, err = OpenStore(config); err != nil {
+				return fmt.Errorf("failed to open current database for safety backup: %w", err)
+			}
+			defer store.Close()
+			manager = backupManager(config, store)
+		} else if target.Encrypted {
+			if manager.Key, err = unlock(config, target.Path); err != nil {
+				return err
+			}
+			dbPath = config.EncryptedDBPath()
+		}
+
+		if err := manager.Verify(target); err != nil {
+			return err
+		}
+		if !*yes {
+			if !stdinIsTerminal() {
+				return fmt.Errorf("refusing to restore without confirmation; pass --yes")
+			}
+			question := fmt.Sprintf("Replace %s with backup %s from %s?", dbPath, id, target.CreatedAt.Local().Format("2006-01-02 15:04"))
+			if !confirm(os.Stdin, os.Stderr, question) {
+				return fmt.Errorf("restore cancelled")
+			}
+		}
+
+		// Keep a copy of what is about to be overwritten.
+		if store != nil {
+			safety, err := manager.Create(store.DB, backup.ReasonPreRestore)
+			if err != nil {
+				return fmt.Errorf("failed to back up current database: %w", err)
+			}
+			fmt.Printf("Saved current database as backup %s\n", safety.ID)
+
+			// Closing an encrypted store seals its working copy, so it must
+			// happen before the restored file is written.
+			manager.WorkDir = ""
+			if err := store.Close(); err != nil {
+				return err
+			}
+		}
+
+		if err := manager.Restore(id, dbPath); err != nil {
+			return err
+		}
+		fmt.Printf("Restored backup %s\n", id)
+		return nil
+
+	case "prune":
+		removed, err := manager.Prune()
+		if err != nil {
+			return err
+		}
+		for _, b := range removed {
+			fmt.Printf("Removed %s (%s)\n", b.ID, b.Reason)
+		}
+		fmt.Printf("%d backup(s) removed\n", len(removed))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown backup command %q", args[0])
+	}
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
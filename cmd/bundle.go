@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"toni/internal/backup"
+	"toni/internal/bundle"
+	"toni/internal/config"
+	"toni/internal/db"
+	"toni/internal/secure"
+)
+
+// bundleKeyPath is where the key bundles are signed with is kept.
+func bundleKeyPath(cfg *Config) string {
+	return filepath.Join(cfg.ConfigDir, "bundle.key")
+}
+
+// runBundle exports restaurants to a signed bundle for a friend, or imports
+// one into the want to visit list.
+func runBundle(cfg *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: toni bundle export|import|key")
+	}
+	switch args[0] {
+	case "export":
+		return runBundleExport(cfg, args[1:])
+	case "import":
+		return runBundleImport(cfg, args[1:])
+	case "key":
+		key, err := bundle.LoadOrCreateKey(bundleKeyPath(cfg))
+		if err != nil {
+			return err
+		}
+		fmt.Println(bundle.Signer{PublicKey: key.Public().(ed25519.PublicKey)}.Fingerprint())
+		return nil
+	default:
+		return fmt.Errorf("unknown bundle command %q (want export, import or key)", args[0])
+	}
+}
+
+func runBundleExport(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("bundle export", flag.ContinueOnError)
+	out := fs.String("o", "", "File to write the bundle to")
+	name := fs.String("name", "", "Name of the list (defaults to the query)")
+	from := fs.String("from", "", "Your name, as the recipient will see it")
+	limit := fs.Int("limit", 0, "Keep only the best rated n restaurants (0 for all)")
+	ratings := fs.Bool("ratings", false, "Include your ratings and would-return answers")
+	notes := fs.Bool("notes", false, "Include your latest visit notes, redacted")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("usage: toni bundle export -o file [--name n] [--from you] [--limit n] [--ratings] [--notes] [query]")
+	}
+	q := strings.Join(rest, " ")
+	where, params, err := db.RestaurantQuerySchema.Compile(q)
+	if err != nil {
+		return queryError(err)
+	}
+	path, err := config.ExpandHome(*out)
+	if err != nil {
+		return err
+	}
+	if *name == "" {
+		*name = q
+		if *name == "" {
+			*name = "Restaurants"
+		}
+	}
+
+	key, err := bundle.LoadOrCreateKey(bundleKeyPath(cfg))
+	if err != nil {
+		return err
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	restaurants, err := bundle.Collect(store.DB, where, params, bundle.ExportOptions{
+		Limit:   *limit,
+		Ratings: *ratings,
+		Notes:   *notes,
+	})
+	if err != nil {
+		return err
+	}
+	if len(restaurants) == 0 {
+		return fmt.Errorf("no restaurants match")
+	}
+
+	var buf bytes.Buffer
+	b := bundle.Bundle{Name: *name, From: *from, Created: time.Now().UTC(), Restaurants: restaurants}
+	if err := bundle.Write(&buf, b, key); err != nil {
+		return err
+	}
+	if err := secure.WriteFileAtomic(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	signer := bundle.Signer{PublicKey: key.Public().(ed25519.PublicKey)}
+	fmt.Printf("Wrote %d restaurants to %s, signed with key %s\n", len(restaurants), path, signer.Fingerprint())
+	return nil
+}
+
+func runBundleImport(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("bundle import", flag.ContinueOnError)
+	priority := fs.Int("priority", 0, "Priority (1-5) of the new wishlist entries")
+	dryRun := fs.Bool("dry-run", false, "Show what would be imported without changing anything")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: toni bundle import file [--priority n] [--dry-run]")
+	}
+	opts := bundle.ImportOptions{DryRun: *dryRun}
+	if *priority != 0 {
+		if *priority < 1 || *priority > 5 {
+			return fmt.Errorf("priority must be 1-5")
+		}
+		opts.Priority = priority
+	}
+	path, err := config.ExpandHome(rest[0])
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open bundle: %w", err)
+	}
+	b, signer, err := bundle.Read(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if !opts.DryRun {
+		safety, err := backupManager(cfg, store).Create(store.DB, backup.ReasonPreImport)
+		if err != nil {
+			return fmt.Errorf("failed to back up database before import: %w", err)
+		}
+		fmt.Printf("Saved current database as backup %s\n", safety.ID)
+	}
+
+	results, err := bundle.Import(store.DB, b, signer, opts)
+	if err != nil {
+		return err
+	}
+
+	from := b.From
+	if from == "" {
+		from = "an unnamed sender"
+	}
+	fmt.Printf("%q from %s, signed with key %s\n", b.Name, from, signer.Fingerprint())
+	counts := make(map[bundle.Status]int)
+	for _, r := range results {
+		counts[r.Status]++
+		fmt.Printf("  %-32s %s\n", r.Name, r.Status)
+	}
+	verb := "Added"
+	if opts.DryRun {
+		verb = "Would add"
+	}
+	fmt.Printf("%s %d to the wishlist (%d new restaurants); %d already there\n",
+		verb, counts[bundle.StatusNew]+counts[bundle.StatusAdded], counts[bundle.StatusNew], counts[bundle.StatusListed])
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"toni/internal/backup"
+)
+
+// RunCommand runs the subcommand selected on the command line.
+func RunCommand(config *Config) error {
+	switch config.Command {
+	case "backup":
+		return runBackup(config, config.Args)
+	case "bundle":
+		return runBundle(config, config.Args)
+	case "compare":
+		return runCompare(config, config.Args)
+	case "config":
+		return runConfig(config, config.Args)
+	case "credentials":
+		return runCredentials(config, config.Args)
+	case "export":
+		return runExport(config, config.Args)
+	case "import":
+		return runImport(config, config.Args)
+	case "keys":
+		return runKeys(config, config.Args)
+	case "list":
+		return runList(config, config.Args)
+	case "pick":
+		return runPick(config, config.Args)
+	case "plan":
+		return runPlan(config, config.Args)
+	case "rekey":
+		return runRekey(config, config.Args)
+	case "help":
+		usage()
+		return nil
+	default:
+		usage()
+		return fmt.Errorf("unknown command %q", config.Command)
+	}
+}
+
+// StartupBackup takes the automatic backup made each time toni starts and
+// applies the retention policy. Failures are reported but never fatal.
+func StartupBackup(config *Config, store *Store) {
+	manager := backupManager(config, store)
+	if _, err := manager.Create(store.DB, backup.ReasonStartup); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  Automatic backup failed: %v\n", err)
+		return
+	}
+	if _, err := manager.Prune(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠  Failed to prune old backups: %v\n", err)
+	}
+}
+
+// parseArgs parses fs from args, allowing flags to follow positional
+// arguments, and returns the positional arguments. Everything after "--" is
+// positional, so queries such as -return:no can follow it.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, rest = args[:i], args[i+1:]
+			break
+		}
+	}
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// backupManager returns the manager for the configured backup directory. When
+// store is encrypted, backups are sealed with its key.
+func backupManager(config *Config, store *Store) *backup.Manager {
+	manager := backup.NewManager(config.BackupDir, backup.DefaultPolicy())
+	if store != nil && store.enc != nil {
+		manager.Key = store.enc.Key()
+		manager.WorkDir = store.enc.WorkDir()
+	}
+	return manager
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage: toni [flags] [command]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	fmt.Fprintln(out, "  backup list                 List backups")
+	fmt.Fprintln(out, "  backup create [--reason r]  Back up the database now")
+	fmt.Fprintln(out, "  backup restore <id>         Replace the database with a backup")
+	fmt.Fprintln(out, "  backup prune                Apply the retention policy")
+	fmt.Fprintln(out, "  bundle export -o <file> [q]  Share matching restaurants as a signed bundle")
+	fmt.Fprintln(out, "  bundle import <file>        Add a bundle's restaurants to the wishlist")
+	fmt.Fprintln(out, "  bundle key                  Show the fingerprint bundles are signed with")
+	fmt.Fprintln(out, "  compare --with <file>       Compare ratings with a friend's database (only read)")
+	fmt.Fprintln(out, "  config list                 Show the config file")
+	fmt.Fprintln(out, "  config get <key>            Print a setting, e.g. location or profiles.work.db_path")
+	fmt.Fprintln(out, "  config set <key> <value>    Change a setting in the active (--profile) profile")
+	fmt.Fprintln(out, "  credentials set <name>      Store an API key in the encrypted vault")
+	fmt.Fprintln(out, "  credentials get <name>      Print an API key (--source shows where it came from)")
+	fmt.Fprintln(out, "  credentials rm <name>       Remove a stored API key")
+	fmt.Fprintln(out, "  credentials list            List stored API keys and their sources")
+	fmt.Fprintln(out, "  export geojson|kml [query]  Export restaurants with coordinates for map apps")
+	fmt.Fprintln(out, "  export ics [query]          Export visits and planned visits as a calendar")
+	fmt.Fprintln(out, "  import <source> <file>      Import from Google Takeout, Beli, Yelp or any CSV")
+	fmt.Fprintln(out, "  import mapping <source>     Print a source's CSV mapping to start your own from")
+	fmt.Fprintln(out, "  keys                        List key bindings and check the keymap file")
+	fmt.Fprintln(out, "  list <list> [query]         List visits, restaurants or wishlist entries matching a query")
+	fmt.Fprintln(out, "  pick [query]                Pick somewhere to eat from the wishlist and favourites")
+	fmt.Fprintln(out, "  plan add <name> --on <date> Plan a visit or note a reservation (--at, --party, --notes)")
+	fmt.Fprintln(out, "  plan list [--all]           List upcoming planned visits")
+	fmt.Fprintln(out, "  plan rm <id>                Remove a planned visit")
+	fmt.Fprintln(out, "  rekey                       Change the database passphrase (or encrypt it)")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Without a command toni starts the TUI.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"toni/internal/compare"
+	"toni/internal/config"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/util"
+)
+
+// runCompare compares the database with a friend's, which is only read.
+func runCompare(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	with := fs.String("with", "", "The other toni database")
+	limit := fs.Int("limit", 10, "Places to list in each section (0 for all)")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *with == "" && len(rest) == 1 {
+		*with = rest[0]
+	} else if *with == "" || len(rest) > 0 {
+		return fmt.Errorf("usage: toni compare --with friend.db [--limit n]")
+	}
+	path, err := config.ExpandHome(*with)
+	if err != nil {
+		return err
+	}
+
+	theirs, err := db.OpenReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer theirs.Close()
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := compare.Load(store.DB, theirs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Compared with %s: %d restaurants in common (you have %d, they have %d)\n",
+		path, report.Matched, report.MineCount, report.TheirsCount)
+	fmt.Println(report.Summary())
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printPairs := func(title string, pairs []compare.Pair) {
+		fmt.Fprintf(w, "\n%s (%d)\n", title, len(pairs))
+		if len(pairs) == 0 {
+			return
+		}
+		fmt.Fprintln(w, "YOU\tTHEM\tNAME\tCITY")
+		for _, p := range firstN(pairs, *limit) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				util.FormatRating(p.Mine.AvgRating), util.FormatRating(p.Theirs.AvgRating), p.Mine.Name, p.Mine.City)
+		}
+	}
+	printPairs("Agree", report.Agreements())
+	printPairs("Disagree", report.Disagreements())
+
+	fmt.Fprintf(w, "\nThey loved, you haven't been (%d)\n", len(report.TheyLoved))
+	if len(report.TheyLoved) > 0 {
+		fmt.Fprintln(w, "THEM\tNAME\tCITY\tCUISINE")
+		for _, l := range firstN(report.TheyLoved, *limit) {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				util.FormatRating(l.Theirs.AvgRating), l.Theirs.Name, l.Theirs.City, l.Theirs.Cuisine, lovedNote(l.Mine))
+		}
+	}
+	return w.Flush()
+}
+
+// lovedNote says what you already have of a place they loved.
+func lovedNote(mine *model.RestaurantStats) string {
+	switch {
+	case mine == nil:
+		return ""
+	case mine.Wishlisted:
+		return "on your wishlist"
+	default:
+		return "in your restaurants"
+	}
+}
+
+func firstN[T any](items []T, n int) []T {
+	if n > 0 && len(items) > n {
+		return items[:n]
+	}
+	return items
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"toni/internal/config"
+)
+
+func runConfig(cfg *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: toni config get <key> | set <key> <value> | list | path")
+	}
+
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "get":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: toni config get <key>")
+		}
+		value, err := cfg.File.Get(qualifyKey(cfg, rest[0]))
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+
+	case "set":
+		if len(rest) != 2 {
+			return fmt.Errorf("usage: toni config set <key> <value>")
+		}
+		key := qualifyKey(cfg, rest[0])
+		if err := cfg.File.Set(key, rest[1]); err != nil {
+			return err
+		}
+		if key == "default_profile" && cfg.File.Profile(rest[1]) == nil {
+			fmt.Fprintf(os.Stderr, "ℹ  Profile %q has no settings yet; it will use the defaults\n", rest[1])
+		}
+		if err := cfg.File.Save(cfg.FilePath); err != nil {
+			return err
+		}
+		fmt.Printf("%s = %s\n", key, rest[1])
+		return nil
+
+	case "list", "ls":
+		fmt.Printf("# %s (active profile: %s)\n", cfg.FilePath, cfg.Profile)
+		entries, err := cfg.File.Flatten()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t= %s\n", e[0], e[1])
+		}
+		return w.Flush()
+
+	case "path":
+		fmt.Println(cfg.FilePath)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
+
+// qualifyKey expands a key relative to the active profile, so "location"
+// means "profiles.<active>.location". Top-level keys and keys starting with
+// "profiles." are used as given.
+func qualifyKey(cfg *Config, key string) string {
+	if config.IsTopLevelKey(key) || strings.HasPrefix(key, "profiles.") {
+		return key
+	}
+	return "profiles." + cfg.Profile + "." + key
+}
@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"toni/internal/credentials"
+	"toni/internal/secure"
+
+	"golang.org/x/term"
+)
+
+// credentialCommandEnv names an external helper used to look up credentials,
+// e.g. "pass show toni/{name}".
+const credentialCommandEnv = "TONI_CREDENTIAL_COMMAND"
+
+func vaultPath(config *Config) string {
+	return filepath.Join(config.ConfigDir, "credentials.vault")
+}
+
+// openVault unlocks the credential vault, creating it with a new passphrase
+// when create is set and it does not exist yet.
+func openVault(config *Config, create bool) (*credentials.Vault, error) {
+	path := vaultPath(config)
+	var key *secure.Key
+	var err error
+	switch {
+	case fileExists(path):
+		key, err = unlock(config, path)
+	case create:
+		key, err = newKey(config, "Choose a passphrase for the credential vault")
+	default:
+		return nil, credentials.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return credentials.OpenVault(path, key)
+}
+
+// credentialChain returns the sources credentials are resolved from, in
+// priority order. The --yelp-key flag is handled by the caller before these.
+func credentialChain(config *Config) credentials.Chain {
+	chain := credentials.Chain{{Label: "env", Store: credentials.Env{}}}
+	if config.CredentialCommand != "" {
+		chain = append(chain, credentials.Source{Label: "command", Store: credentials.Command{Template: config.CredentialCommand}})
+	}
+	if fileExists(vaultPath(config)) {
+		chain = append(chain, credentials.Source{Label: "vault", Store: credentials.Lazy(func() (credentials.Store, error) {
+			return openVault(config, false)
+		})})
+	}
+	return append(chain, credentials.Source{Label: "file", Store: credentials.File{Dir: config.ConfigDir}})
+}
+
+// resolveCredential looks up a provider key and warns when it was read from
+// a file other users can read.
+func resolveCredential(config *Config, name string) (string, error) {
+	value, source, err := credentialChain(config).Lookup(name)
+	if errors.Is(err, credentials.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if source == "file" {
+		path := credentials.File{Dir: config.ConfigDir}.Path(name)
+		if credentials.WorldReadable(path) {
+			fmt.Fprintf(os.Stderr, "⚠  %s is readable by other users; run `chmod 600 %s` or move the key with `toni credentials set %s`\n", path, path, name)
+		}
+	}
+	return value, nil
+}
+
+// saveCredential stores a key entered during onboarding: in the vault when
+// the user chose a passphrase, otherwise in the legacy owner-only file.
+func saveCredential(config *Config, name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	if config.passphrase != nil {
+		vault, err := openVault(config, true)
+		if err != nil {
+			return err
+		}
+		return vault.Set(name, strings.TrimSpace(value))
+	}
+	return credentials.File{Dir: config.ConfigDir}.Set(name, value)
+}
+
+func runCredentials(config *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: toni credentials set|get|rm <name> | list")
+	}
+
+	fs := flag.NewFlagSet("credentials "+args[0], flag.ContinueOnError)
+	store := fs.String("store", "vault", "Where to store the credential: vault or file")
+	showSource := fs.Bool("source", false, "Print which source the credential came from")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "set":
+		if len(rest) < 1 || len(rest) > 2 {
+			return fmt.Errorf("usage: toni credentials set <name> [value]")
+		}
+		name := rest[0]
+		if err := credentials.ValidateName(name); err != nil {
+			return err
+		}
+		var value string
+		if len(rest) == 2 {
+			value = rest[1]
+			fmt.Fprintln(os.Stderr, "⚠  Values passed as arguments end up in your shell history; omit the value to be prompted.")
+		} else if value, err = readSecret(fmt.Sprintf("Value for %s: ", name)); err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("empty value")
+		}
+
+		switch *store {
+		case "vault":
+			vault, err := openVault(config, true)
+			if err != nil {
+				return err
+			}
+			if err := vault.Set(name, value); err != nil {
+				return err
+			}
+			// The vault supersedes a plaintext copy of the same key.
+			if err := (credentials.File{Dir: config.ConfigDir}).Delete(name); err == nil {
+				fmt.Printf("Removed plaintext copy of %s\n", name)
+			}
+			fmt.Printf("Stored %s in %s\n", name, vaultPath(config))
+		case "file":
+			if err := (credentials.File{Dir: config.ConfigDir}).Set(name, value); err != nil {
+				return err
+			}
+			fmt.Printf("Stored %s in %s\n", name, credentials.File{Dir: config.ConfigDir}.Path(name))
+		default:
+			return fmt.Errorf("unknown store %q (want vault or file)", *store)
+		}
+		return nil
+
+	case "get":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: toni credentials get <name>")
+		}
+		value, source, err := credentialChain(config).Lookup(rest[0])
+		if err != nil {
+			return err
+		}
+		if *showSource {
+			fmt.Fprintf(os.Stderr, "source: %s\n", source)
+		}
+		fmt.Println(value)
+		return nil
+
+	case "rm", "delete":
+		if len(rest) != 1 {
+			return fmt.Errorf("usage: toni credentials rm <name>")
+		}
+		name := rest[0]
+		removed := false
+		if fileExists(vaultPath(config)) {
+			vault, err := openVault(config, false)
+			if err != nil {
+				return err
+			}
+			if err := vault.Delete(name); err == nil {
+				fmt.Printf("Removed %s from the vault\n", name)
+				removed = true
+			} else if !errors.Is(err, credentials.ErrNotFound) {
+				return err
+			}
+		}
+		if err := (credentials.File{Dir: config.ConfigDir}).Delete(name); err == nil {
+			fmt.Printf("Removed %s\n", credentials.File{Dir: config.ConfigDir}.Path(name))
+			removed = true
+		} else if !errors.Is(err, credentials.ErrNotFound) {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("%w: %s", credentials.ErrNotFound, name)
+		}
+		if os.Getenv(credentials.EnvVar(name)) != "" {
+			fmt.Fprintf(os.Stderr, "ℹ  %s is still set in the environment\n", credentials.EnvVar(name))
+		}
+		return nil
+
+	case "list", "ls":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE")
+		chain := credentialChain(config)
+		for _, name := range knownCredentialNames(config) {
+			if _, source, err := chain.Lookup(name); err == nil {
+				fmt.Fprintf(w, "%s\t%s\n", name, source)
+			}
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown credentials command %q", args[0])
+	}
+}
+
+// knownCredentialNames returns the providers toni uses plus anything stored
+// in the vault or as a key file.
+func knownCredentialNames(config *Config) []string {
+	seen := map[string]bool{credentials.Yelp: true}
+	names := []string{credentials.Yelp}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if fileExists(vaultPath(config)) {
+		if vault, err := openVault(config, false); err == nil {
+			for _, name := range vault.Names() {
+				add(name)
+			}
+		}
+	}
+	matches, _ := filepath.Glob(filepath.Join(config.ConfigDir, "*_api_key"))
+	for _, m := range matches {
+		add(strings.TrimSuffix(filepath.Base(m), "_api_key"))
+	}
+	return names
+}
+
+// readSecret prompts for a value without echoing it, or reads it from stdin
+// when stdin is not a terminal.
+func readSecret(prompt string) (string, error) {
+	if !stdinIsTerminal() {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	value, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read value: %w", err)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
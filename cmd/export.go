@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"toni/internal/config"
+	"toni/internal/db"
+	"toni/internal/geo"
+	"toni/internal/ics"
+	"toni/internal/secure"
+)
+
+// runExport writes the restaurants matching a query as map features, or
+// visits as a calendar.
+func runExport(cfg *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: toni export geojson|kml|ics [-o file] [--name n] [query]")
+	}
+	format := args[0]
+	if format == "ics" {
+		return runExportCalendar(cfg, args[1:])
+	}
+	if format != "geojson" && format != "kml" {
+		return fmt.Errorf("unknown export format %q (want geojson, kml or ics)", format)
+	}
+	fs := flag.NewFlagSet("export "+format, flag.ContinueOnError)
+	out := fs.String("o", "", "File to write (default: standard output)")
+	name := fs.String("name", "", "Name of the KML document (defaults to the query)")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	q := strings.Join(rest, " ")
+	where, params, err := db.RestaurantQuerySchema.Compile(q)
+	if err != nil {
+		return queryError(err)
+	}
+	if *name == "" {
+		*name = "toni"
+		if q != "" {
+			*name += ": " + q
+		}
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	places, err := db.ListPickCandidatesWhere(store.DB, where, params)
+	if err != nil {
+		return err
+	}
+	located, missing := geo.Located(places)
+
+	var buf bytes.Buffer
+	if format == "kml" {
+		err = geo.WriteKML(&buf, *name, located)
+	} else {
+		err = geo.WriteGeoJSON(&buf, located)
+	}
+	if err != nil {
+		return err
+	}
+
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, "⚠  Left out %d restaurants without coordinates\n", missing)
+	}
+	return writeExport(*out, buf.Bytes(), fmt.Sprintf("%d restaurants", len(located)))
+}
+
+// runExportCalendar writes the visits matching a query, and the planned
+// visits, as an iCalendar file.
+func runExportCalendar(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("export ics", flag.ContinueOnError)
+	out := fs.String("o", "", "File to write (default: standard output)")
+	name := fs.String("name", "", "Name of the calendar (defaults to the query)")
+	plans := fs.Bool("plans", true, "Include planned visits")
+	visits := fs.Bool("visits", true, "Include visits")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	q := strings.Join(rest, " ")
+	where, params, err := db.VisitQuerySchema.Compile(q)
+	if err != nil {
+		return queryError(err)
+	}
+	if *name == "" {
+		*name = "toni"
+		if q != "" {
+			*name += ": " + q
+		}
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var events []ics.Event
+	visitCount, planCount, undated := 0, 0, 0
+	if *visits {
+		rows, err := db.ListVisitsWhere(store.DB, where, params)
+		if err != nil {
+			return err
+		}
+		for _, v := range rows {
+			e, ok := ics.VisitEvent(v)
+			if !ok {
+				undated++
+				continue
+			}
+			events = append(events, e)
+			visitCount++
+		}
+	}
+	if *plans {
+		rows, err := db.ListPlannedVisits(store.DB, "")
+		if err != nil {
+			return err
+		}
+		for _, p := range rows {
+			events = append(events, ics.PlanEvent(p, time.Local))
+			planCount++
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := ics.Write(&buf, *name, events, time.Now()); err != nil {
+		return err
+	}
+	if undated > 0 {
+		fmt.Fprintf(os.Stderr, "⚠  Left out %d visits without a date\n", undated)
+	}
+	return writeExport(*out, buf.Bytes(), fmt.Sprintf("%d visits and %d planned visits", visitCount, planCount))
+}
+
+// writeExport writes data to the file out, or to standard output when out is
+// empty. what describes the data for the message saying where it went.
+func writeExport(out string, data []byte, what string) error {
+	if out == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	path, err := config.ExpandHome(out)
+	if err != nil {
+		return err
+	}
+	if err := secure.WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s to %s\n", what, path)
+	return nil
+}
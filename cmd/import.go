@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"toni/internal/backup"
+	"toni/internal/config"
+	"toni/internal/importer"
+	"toni/internal/util"
+)
+
+// runImport imports restaurants, visits and want to visit entries from
+// another app's export.
+func runImport(cfg *Config, args []string) error {
+	if len(args) == 0 || args[0] == "sources" {
+		printImportSources()
+		if len(args) == 0 {
+			return fmt.Errorf("usage: toni import <source> <path> [--mapping file] [--dry-run] [--yes]")
+		}
+		return nil
+	}
+	if args[0] == "mapping" {
+		return runImportMapping(args[1:])
+	}
+
+	source, ok := importer.FindSource(args[0])
+	if !ok {
+		printImportSources()
+		return fmt.Errorf("unknown import source %q", args[0])
+	}
+	fs := flag.NewFlagSet("import "+source.Name, flag.ContinueOnError)
+	mappingPath := fs.String("mapping", "", "Mapping file describing the CSV's columns")
+	yes := fs.Bool("yes", false, "Import without asking after the preview")
+	dryRun := fs.Bool("dry-run", false, "Show the preview without importing")
+	rest, err := parseArgs(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: toni import %s <path> [--mapping file] [--dry-run] [--yes]", source.Name)
+	}
+	path, err := config.ExpandHome(rest[0])
+	if err != nil {
+		return err
+	}
+
+	var mapping *importer.Mapping
+	if *mappingPath != "" {
+		p, err := config.ExpandHome(*mappingPath)
+		if err != nil {
+			return err
+		}
+		if mapping, err = importer.LoadMapping(p); err != nil {
+			return err
+		}
+	}
+	records, problems, err := source.ReadExport(path, mapping)
+	if err != nil {
+		return err
+	}
+	return importRecords(cfg, records, problems, *dryRun, *yes)
+}
+
+// runImportMapping prints a source's mapping, as a starting point for a
+// mapping file.
+func runImportMapping(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: toni import mapping <source>")
+	}
+	source, ok := importer.FindSource(args[0])
+	if !ok {
+		return fmt.Errorf("unknown import source %q", args[0])
+	}
+	mapping := source.Mapping
+	if mapping == nil {
+		if source.Read != nil {
+			return fmt.Errorf("%s exports aren't CSV, so they have no mapping", source.Name)
+		}
+		mapping = &importer.Mapping{Columns: map[string]string{"name": "Name"}}
+	}
+	return mapping.Encode(os.Stdout)
+}
+
+func printImportSources() {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Sources:")
+	for _, s := range importer.Sources {
+		fmt.Fprintf(w, "  %s\t%s\n", s.Name, s.Help)
+	}
+	w.Flush()
+}
+
+// importRecords previews importing records, asks before going ahead unless
+// yes is set, backs up the database and imports them. Problems reading the
+// export are listed first.
+func importRecords(cfg *Config, records []importer.Record, problems []importer.Problem, dryRun, yes bool) error {
+	skipped := 0
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "⚠  %s\n", p)
+		if p.Line > 0 || p.Place != "" {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "%d rows skipped\n\n", skipped)
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("nothing to import")
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	preview, err := importer.Import(store.DB, records, importer.Options{DryRun: true})
+	if err != nil {
+		return err
+	}
+	if err := printImport(preview); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	if !yes {
+		if !stdinIsTerminal() {
+			return fmt.Errorf("refusing to import without confirmation; pass --yes")
+		}
+		if !confirm(os.Stdin, os.Stderr, "Import these?") {
+			return fmt.Errorf("import cancelled")
+		}
+	}
+
+	safety, err := backupManager(cfg, store).Create(store.DB, backup.ReasonPreImport)
+	if err != nil {
+		return fmt.Errorf("failed to back up database before import: %w", err)
+	}
+	fmt.Printf("Saved current database as backup %s\n", safety.ID)
+
+	results, err := importer.Import(store.DB, records, importer.Options{})
+	if err != nil {
+		return err
+	}
+	visits, wishlist, restaurants := importCounts(results)
+	fmt.Printf("Imported %d visits and %d want to visit entries (%d new restaurants)\n", visits, wishlist, restaurants)
+	return nil
+}
+
+// printImport prints what an import does with each record, then the totals.
+func printImport(results []importer.Result) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCITY\tIMPORT AS\tRATING\tRESTAURANT\tSTATUS")
+	for _, r := range results {
+		kind := "wishlist"
+		var rating *float64
+		if v := r.Record.Visit; v != nil {
+			kind = "visit " + v.VisitedOn
+			rating = v.Rating
+		}
+		restaurant := "existing"
+		if r.NewRestaurant {
+			restaurant = "new"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			util.TruncateString(r.Name, 32), r.Record.Restaurant.City, kind,
+			util.FormatRating(rating), restaurant, r.Status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	visits, wishlist, restaurants := importCounts(results)
+	fmt.Printf("\n%d visits and %d want to visit entries to import (%d new restaurants); %d already there\n",
+		visits, wishlist, restaurants, len(results)-visits-wishlist)
+	return nil
+}
+
+// importCounts counts the visits, want to visit entries and restaurants an
+// import added.
+func importCounts(results []importer.Result) (visits, wishlist, restaurants int) {
+	for _, r := range results {
+		if r.NewRestaurant {
+			restaurants++
+		}
+		if r.Status != importer.StatusAdded {
+			continue
+		}
+		if r.Record.Visit != nil {
+			visits++
+		} else {
+			wishlist++
+		}
+	}
+	return visits, wishlist, restaurants
+}
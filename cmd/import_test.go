@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"toni/internal/backup"
+	"toni/internal/db"
+)
+
+func TestImportCSVBacksUp(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{DBPath: filepath.Join(dir, "toni.db"), BackupDir: filepath.Join(dir, "backups")}
+	export := filepath.Join(dir, "beli.csv")
+	csv := "Name,City,Date Visited,Score,List\nLucali,Brooklyn,2025-03-14,9.1,Been\nDi Fara,Brooklyn,,,Want to Try\n"
+	if err := os.WriteFile(export, []byte(csv), 0600); err != nil {
+		t.Fatal(err)
+	}
+	preImport := func() int {
+		t.Helper()
+		backups, err := backup.NewManager(cfg.BackupDir, backup.DefaultPolicy()).List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, b := range backups {
+			if b.Reason == backup.ReasonPreImport {
+				n++
+			}
+		}
+		return n
+	}
+
+	if err := runImport(cfg, []string{"beli", export, "--dry-run"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := preImport(); n != 0 {
+		t.Errorf("dry run left %d pre-import backups, want 0", n)
+	}
+
+	if err := runImport(cfg, []string{"beli", export, "--yes"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := preImport(); n != 1 {
+		t.Errorf("import left %d pre-import backups, want 1", n)
+	}
+	database, err := db.Open(cfg.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	stats, err := db.ListRestaurantStats(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Errorf("import added %d restaurants, want 2", len(stats))
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"toni/internal/db"
+)
+
+// ReviewIntegrity checks the database on startup and reports problems on
+// stderr. Orphaned rows can be repaired after an interactive confirmation;
+// damage reported by SQLite itself is only surfaced, never touched.
+func ReviewIntegrity(database *sql.DB) error {
+	report, err := db.CheckIntegrity(database)
+	if err != nil {
+		return err
+	}
+	if report.OK() {
+		return nil
+	}
+
+	for _, problem := range report.Problems {
+		fmt.Fprintf(os.Stderr, "⚠  Database integrity: %s\n", problem)
+	}
+	if !report.HasOrphans() {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr,
+		"⚠  Found %d visit(s) and %d want-to-visit entr(ies) pointing at restaurants that no longer exist.\n",
+		len(report.OrphanedVisits), len(report.OrphanedWantToVisit),
+	)
+	if !stdinIsTerminal() {
+		fmt.Fprintln(os.Stderr, "   Run toni interactively to repair them.")
+		return nil
+	}
+	if !confirm(os.Stdin, os.Stderr, "   Delete these orphaned rows now?") {
+		fmt.Fprintln(os.Stderr, "   Leaving orphaned rows in place.")
+		return nil
+	}
+	if err := db.RepairOrphans(database, report); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "   Orphaned rows removed.")
+	return nil
+}
+
+// confirm asks a yes/no question and defaults to no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
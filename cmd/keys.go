@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"toni/internal/ui"
+)
+
+// runKeys prints the active key bindings, which also checks the keymap file
+// for mistakes and conflicts without starting the TUI.
+func runKeys(config *Config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: toni keys")
+	}
+	keys, err := ui.LoadKeyMap(config.KeymapPath)
+	if err != nil {
+		return err
+	}
+	if fileExists(config.KeymapPath) {
+		fmt.Fprintf(os.Stderr, "Keymap: %s\n", config.KeymapPath)
+	} else {
+		fmt.Fprintf(os.Stderr, "Keymap: defaults (%s does not exist)\n", config.KeymapPath)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTEXT\tACTION\tKEYS\tDESCRIPTION")
+	for _, b := range keys.Bindings() {
+		bound := "(unbound)"
+		if len(b.Keys) > 0 {
+			quoted := make([]string, len(b.Keys))
+			for i, k := range b.Keys {
+				quoted[i] = fmt.Sprintf("%q", k)
+			}
+			bound = strings.Join(quoted, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.Context, b.Action, bound, b.Help)
+	}
+	return w.Flush()
+}
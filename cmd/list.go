@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"toni/internal/db"
+	"toni/internal/query"
+	"toni/internal/util"
+)
+
+// runList prints the visits, restaurants or want to visit entries matching a
+// query. Everything after the list name is the query, so terms such as
+// -return:no are not mistaken for flags.
+func runList(config *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: toni list visits|restaurants|wishlist [query]")
+	}
+	q := strings.Join(args[1:], " ")
+
+	var schema query.Schema
+	switch args[0] {
+	case "visits":
+		schema = db.VisitQuerySchema
+	case "restaurants":
+		schema = db.RestaurantQuerySchema
+	case "wishlist", "want_to_visit":
+		schema = db.WantToVisitQuerySchema
+	default:
+		return fmt.Errorf("unknown list %q (want visits, restaurants or wishlist)", args[0])
+	}
+	where, params, err := schema.Compile(q)
+	if err != nil {
+		return queryError(err)
+	}
+
+	store, err := OpenStore(config)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	switch args[0] {
+	case "visits":
+		rows, err := db.ListVisitsWhere(store.DB, where, params)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "DATE\tNAME\tCITY\tPRICE\tRATING\tRETURN\tNOTES")
+		for _, r := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.VisitedOn, r.RestaurantName, r.City, r.PriceRange,
+				util.FormatRating(r.Rating), util.FormatWouldReturn(r.WouldReturn),
+				util.TruncateString(util.SingleLine(r.Notes), 40))
+		}
+	case "restaurants":
+		rows, err := db.ListRestaurantsWhere(store.DB, where, params)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "NAME\tCITY\tAREA\tCUISINE\tPRICE\tRATING\tVISITS\tLAST")
+		for _, r := range rows {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+				r.Name, r.City, r.Neighborhood, r.Cuisine, r.PriceRange,
+				util.FormatAvgRating(r.AvgRating), r.VisitCount, r.LastVisit)
+		}
+	default:
+		rows, err := db.ListWantToVisitWhere(store.DB, where, params)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "NAME\tCITY\tAREA\tCUISINE\tPRICE\tPRIORITY\tNOTES")
+		for _, r := range rows {
+			priority := "—"
+			if r.Priority != nil {
+				priority = fmt.Sprintf("%d", *r.Priority)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.RestaurantName, r.City, r.Neighborhood, r.Cuisine, r.PriceRange,
+				priority, util.TruncateString(util.SingleLine(r.Notes), 40))
+		}
+	}
+	return w.Flush()
+}
+
+// queryError adds the query with the bad part underlined to a query error.
+func queryError(err error) error {
+	var qe *query.Error
+	if !errors.As(err, &qe) {
+		return err
+	}
+	return fmt.Errorf("invalid query: %w\n\n  %s", err, strings.ReplaceAll(qe.Caret(), "\n", "\n  "))
+}
@@ -8,83 +8,50 @@ import (
 	"strings"
 	"time"
 
+	"toni/internal/credentials"
+	"toni/internal/ui"
+
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
 )
 
 type OnboardingSettings struct {
-	Completed   bool `json:"completed"`
-	YelpEnabled bool `json:"yelp_enabled"`
+	Completed       bool `json:"completed"`
+	YelpEnabled     bool `json:"yelp_enabled"`
+	EncryptDatabase bool `json:"encrypt_database"`
 }
 
 func onboardingPath(configDir string) string {
 	return filepath.Join(configDir, "onboarding.json")
 }
 
-func loadOnboardingSettings(configDir string) (OnboardingSettings, error) {
+// loadOnboardingSettings reads the onboarding.json written by versions of
+// toni that predate the config file.
+func loadOnboardingSettings(configDir string) (OnboardingSettings, bool, error) {
 	path := onboardingPath(configDir)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return OnboardingSettings{}, nil
+			return OnboardingSettings{}, false, nil
 		}
-		return OnboardingSettings{}, err
+		return OnboardingSettings{}, false, err
 	}
 
 	var settings OnboardingSettings
 	if err := json.Unmarshal(data, &settings); err != nil {
-		return OnboardingSettings{}, err
+		return OnboardingSettings{}, false, err
 	}
-	return settings, nil
+	return settings, true, nil
 }
 
-func saveOnboardingSettings(configDir string, settings OnboardingSettings) error {
-	if err := os.MkdirAll(configDir, 0700); err != nil {
-		return err
-	}
-	data, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(onboardingPath(configDir), data, 0644)
+func shouldRunOnboarding() bool {
+	return stdinIsTerminal()
 }
 
-func secureYelpKeyPath(configDir string) string {
-	return filepath.Join(configDir, "yelp_api_key")
-}
-
-func saveSecureYelpAPIKey(configDir, key string) error {
-	if strings.TrimSpace(key) == "" {
-		return nil
-	}
-	if err := os.MkdirAll(configDir, 0700); err != nil {
-		return err
-	}
-	// Owner read/write only.
-	return os.WriteFile(secureYelpKeyPath(configDir), []byte(strings.TrimSpace(key)+"\n"), 0600)
-}
-
-func loadSecureYelpAPIKey(configDir string) (string, error) {
-	data, err := os.ReadFile(secureYelpKeyPath(configDir))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
-		}
-		return "", err
-	}
-	return strings.TrimSpace(string(data)), nil
-}
-
-func shouldRunOnboarding(settings OnboardingSettings) bool {
-	if settings.Completed {
-		return false
-	}
-	fi, err := os.Stdin.Stat()
-	if err != nil {
-		return false
-	}
-	return (fi.Mode() & os.ModeCharDevice) != 0
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
 }
 
 type onboardingStep int
@@ -92,106 +59,133 @@ type onboardingStep int
 const (
 	stepEnable onboardingStep = iota
 	stepKey
+	stepEncrypt
+	stepPassphrase
+	stepConfirm
 	stepDone
 )
 
 type onboardingModel struct {
-	step        onboardingStep
-	enable      bool
-	existingKey string
-	keyInput    textinput.Model
-	settings    OnboardingSettings
-	capturedKey string
-	status      string
-	width       int
-	height      int
+	step             onboardingStep
+	enable           bool
+	encrypt          bool
+	alreadyEncrypted bool
+	existingKey      string
+	keyInput         textinput.Model
+	passInput        textinput.Model
+	confirmInput     textinput.Model
+	settings         OnboardingSettings
+	capturedKey      string
+	capturedPass     string
+	status           string
+	encStatus        string
+	passError        string
+	width            int
+	height           int
 }
 
+// Onboarding styles, built from the active theme by buildOnboardingStyles.
 var (
-	obColorSurface = lipgloss.Color("#2A332C")
-	obColorMuted   = lipgloss.Color("#7E8C80")
-	obColorText    = lipgloss.Color("#D6E0D3")
-	obColorAccent  = lipgloss.Color("#8FA082")
-	obColorDanger  = lipgloss.Color("#f38ba8")
+	obTitleStyle     lipgloss.Style
+	obHeaderStyle    lipgloss.Style
+	obTabsStyle      lipgloss.Style
+	obTabInactive    lipgloss.Style
+	obTabActive      lipgloss.Style
+	obPanelStyle     lipgloss.Style
+	obInputStyle     lipgloss.Style
+	obLabelStyle     lipgloss.Style
+	obMutedStyle     lipgloss.Style
+	obOptionStyle    lipgloss.Style
+	obOptionSelected lipgloss.Style
+	obWarnStyle      lipgloss.Style
+	obFooterStyle    lipgloss.Style
+)
 
+// buildOnboardingStyles derives the onboarding styles from the active theme.
+func buildOnboardingStyles() {
 	obTitleStyle = lipgloss.NewStyle().
-			Foreground(obColorAccent).
-			Bold(true)
+		Foreground(ui.ColorAccent).
+		Bold(true)
 
 	obHeaderStyle = lipgloss.NewStyle().
-			Foreground(obColorAccent).
-			Bold(true).
-			Padding(0, 1).
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderBottom(true).
-			BorderForeground(obColorMuted)
+		Foreground(ui.ColorAccent).
+		Bold(true).
+		Padding(0, 1).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderBottom(true).
+		BorderForeground(ui.ColorMuted)
 
 	obTabsStyle = lipgloss.NewStyle().
-			Padding(0, 2).
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderBottom(true).
-			BorderForeground(obColorMuted)
+		Padding(0, 2).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderBottom(true).
+		BorderForeground(ui.ColorMuted)
 
 	obTabInactive = lipgloss.NewStyle().
-			Foreground(obColorMuted).
-			Padding(0, 2)
+		Foreground(ui.ColorMuted).
+		Padding(0, 2)
 
 	obTabActive = lipgloss.NewStyle().
-			Foreground(obColorText).
-			Bold(true).
-			Underline(true).
-			Padding(0, 2)
+		Foreground(ui.ColorText).
+		Bold(true).
+		Underline(true).
+		Padding(0, 2)
 
 	obPanelStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(obColorMuted).
-			Padding(1, 2)
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(ui.ColorMuted).
+		Padding(1, 2)
 
 	obInputStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(obColorAccent).
-			Padding(0, 1)
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(ui.ColorAccent).
+		Padding(0, 1)
 
 	obLabelStyle = lipgloss.NewStyle().
-			Foreground(obColorAccent).
-			Bold(true)
+		Foreground(ui.ColorAccent).
+		Bold(true)
 
 	obMutedStyle = lipgloss.NewStyle().
-			Foreground(obColorMuted)
+		Foreground(ui.ColorMuted)
 
 	obOptionStyle = lipgloss.NewStyle().
-			Foreground(obColorText)
+		Foreground(ui.ColorText)
 
 	obOptionSelected = lipgloss.NewStyle().
-				Foreground(obColorAccent).
-				Bold(true)
+		Foreground(ui.ColorAccent).
+		Bold(true)
 
 	obWarnStyle = lipgloss.NewStyle().
-			Foreground(obColorDanger)
+		Foreground(ui.ColorRed)
 
 	obFooterStyle = lipgloss.NewStyle().
-			Foreground(obColorMuted).
-			Padding(0, 1).
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderTop(true).
-			BorderForeground(obColorMuted)
-)
+		Foreground(ui.ColorMuted).
+		Padding(0, 1).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderTop(true).
+		BorderForeground(ui.ColorMuted)
+}
 
-func newOnboardingModel(existingKey string) onboardingModel {
-	in := textinput.New()
-	in.Placeholder = "Paste YELP API key here"
+func newOnboardingModel(existingKey string, alreadyEncrypted bool) onboardingModel {
+	in := newOnboardingInput("Paste YELP API key here", "api> ")
 	in.CharLimit = 300
-	in.Prompt = "api> "
-	in.TextStyle = lipgloss.NewStyle().Foreground(obColorText)
-	in.PlaceholderStyle = lipgloss.NewStyle().Foreground(obColorMuted)
-	in.Cursor.Style = lipgloss.NewStyle().Foreground(obColorText).Background(obColorAccent)
 	in.Focus()
 
+	pass := newOnboardingInput("At least 8 characters", "passphrase> ")
+	pass.EchoMode = textinput.EchoPassword
+	pass.EchoCharacter = '•'
+	confirm := newOnboardingInput("Type it again", "repeat> ")
+	confirm.EchoMode = textinput.EchoPassword
+	confirm.EchoCharacter = '•'
+
 	return onboardingModel{
-		step:        stepEnable,
-		enable:      true,
-		existingKey: strings.TrimSpace(existingKey),
-		keyInput:    in,
+		step:             stepEnable,
+		enable:           true,
+		alreadyEncrypted: alreadyEncrypted,
+		existingKey:      strings.TrimSpace(existingKey),
+		keyInput:         in,
+		passInput:        pass,
+		confirmInput:     confirm,
 		settings: OnboardingSettings{
 			Completed:   true,
 			YelpEnabled: true,
@@ -199,6 +193,16 @@ func newOnboardingModel(existingKey string) onboardingModel {
 	}
 }
 
+func newOnboardingInput(placeholder, prompt string) textinput.Model {
+	in := textinput.New()
+	in.Placeholder = placeholder
+	in.Prompt = prompt
+	in.TextStyle = lipgloss.NewStyle().Foreground(ui.ColorText)
+	in.PlaceholderStyle = lipgloss.NewStyle().Foreground(ui.ColorMuted)
+	in.Cursor.Style = lipgloss.NewStyle().Foreground(ui.ColorText).Background(ui.ColorAccent)
+	return in
+}
+
 func (m onboardingModel) Init() tea.Cmd { return nil }
 
 func (m onboardingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -254,13 +258,11 @@ func (m onboardingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.capturedKey = key
 					m.status = "YELP API key saved."
 				}
-				m.step = stepDone
-				return m, tea.Quit
+				return m.encryptionStep()
 			case "esc":
 				m.settings.YelpEnabled = false
 				m.status = "Skipped key setup. YELP autocomplete disabled."
-				m.step = stepDone
-				return m, tea.Quit
+				return m.encryptionStep()
 			case "ctrl+c", "q":
 				m.settings.YelpEnabled = false
 				m.status = "Setup canceled. YELP autocomplete disabled."
@@ -270,6 +272,50 @@ func (m onboardingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			var cmd tea.Cmd
 			m.keyInput, cmd = m.keyInput.Update(msg)
 			return m, cmd
+		case stepEncrypt:
+			switch msg.String() {
+			case "y", "Y":
+				m.encrypt = true
+				return m.commitEncryption()
+			case "n", "N":
+				m.encrypt = false
+				return m.commitEncryption()
+			case "up", "k", "left", "h":
+				m.encrypt = true
+				return m, nil
+			case "down", "j", "right", "l":
+				m.encrypt = false
+				return m, nil
+			case "enter":
+				return m.commitEncryption()
+			case "ctrl+c", "q":
+				m.encStatus = "Database stored unencrypted."
+				m.step = stepDone
+				return m, tea.Quit
+			default:
+				return m, nil
+			}
+		case stepPassphrase, stepConfirm:
+			// Passphrases may contain any printable key, so only ctrl+c and
+			// esc leave these steps.
+			switch msg.String() {
+			case "ctrl+c", "esc":
+				m.passInput.Reset()
+				m.confirmInput.Reset()
+				m.settings.EncryptDatabase = false
+				m.encStatus = "Skipped passphrase. Database stored unencrypted."
+				m.step = stepDone
+				return m, tea.Quit
+			case "enter":
+				return m.commitPassphrase()
+			}
+			var cmd tea.Cmd
+			if m.step == stepPassphrase {
+				m.passInput, cmd = m.passInput.Update(msg)
+			} else {
+				m.confirmInput, cmd = m.confirmInput.Update(msg)
+			}
+			return m, cmd
 		}
 	}
 	return m, nil
@@ -279,20 +325,70 @@ func (m onboardingModel) nextStep() (tea.Model, tea.Cmd) {
 	if !m.enable {
 		m.settings.YelpEnabled = false
 		m.status = "YELP autocomplete disabled."
-		m.step = stepDone
-		return m, tea.Quit
+		return m.encryptionStep()
 	}
 	if m.existingKey != "" {
 		m.settings.YelpEnabled = true
 		m.capturedKey = m.existingKey
 		m.status = "Using existing YELP_API_KEY from environment/flags."
+		return m.encryptionStep()
+	}
+	m.step = stepKey
+	return m, nil
+}
+
+// encryptionStep moves on from the Yelp steps to the encryption choice,
+// skipping it when the database is already encrypted.
+func (m onboardingModel) encryptionStep() (tea.Model, tea.Cmd) {
+	if m.alreadyEncrypted {
+		m.settings.EncryptDatabase = true
+		m.encStatus = "Database is already encrypted."
 		m.step = stepDone
 		return m, tea.Quit
 	}
-	m.step = stepKey
+	m.keyInput.Blur()
+	m.step = stepEncrypt
 	return m, nil
 }
 
+func (m onboardingModel) commitEncryption() (tea.Model, tea.Cmd) {
+	if !m.encrypt {
+		m.settings.EncryptDatabase = false
+		m.encStatus = "Database stored unencrypted."
+		m.step = stepDone
+		return m, tea.Quit
+	}
+	m.step = stepPassphrase
+	return m, m.passInput.Focus()
+}
+
+func (m onboardingModel) commitPassphrase() (tea.Model, tea.Cmd) {
+	if m.step == stepPassphrase {
+		if len(m.passInput.Value()) < minPassphraseLength {
+			m.passError = fmt.Sprintf("Passphrase must be at least %d characters.", minPassphraseLength)
+			return m, nil
+		}
+		m.passError = ""
+		m.passInput.Blur()
+		m.step = stepConfirm
+		return m, m.confirmInput.Focus()
+	}
+
+	if err := validatePassphrase(m.passInput.Value(), m.confirmInput.Value()); err != nil {
+		m.passError = "Passphrases do not match. Try again."
+		m.passInput.Reset()
+		m.confirmInput.Reset()
+		m.confirmInput.Blur()
+		m.step = stepPassphrase
+		return m, m.passInput.Focus()
+	}
+	m.capturedPass = m.passInput.Value()
+	m.settings.EncryptDatabase = true
+	m.encStatus = "Database will be encrypted with your passphrase."
+	m.step = stepDone
+	return m, tea.Quit
+}
+
 func (m onboardingModel) View() string {
 	width := m.width
 	height := m.height
@@ -312,13 +408,13 @@ func (m onboardingModel) View() string {
 		contentHeight = 8
 	}
 	content := m.renderContent(width, contentHeight)
-	ui := lipgloss.JoinVertical(lipgloss.Left, header, tabs, content, footer)
+	screen := lipgloss.JoinVertical(lipgloss.Left, header, tabs, content, footer)
 
 	return lipgloss.NewStyle().
-		Foreground(obColorText).
+		Foreground(ui.ColorText).
 		Width(width).
 		Height(height).
-		Render(ui)
+		Render(screen)
 }
 
 func (m onboardingModel) renderHeader(width int) string {
@@ -334,13 +430,16 @@ func (m onboardingModel) renderHeader(width int) string {
 func (m onboardingModel) renderTabs(width int) string {
 	enableTab := obTabInactive.Render("Enable API")
 	keyTab := obTabInactive.Render("YELP API Key")
-	if m.step == stepEnable {
+	encryptTab := obTabInactive.Render("Encryption")
+	switch m.step {
+	case stepEnable:
 		enableTab = obTabActive.Render("Enable API")
-	}
-	if m.step == stepKey {
+	case stepKey:
 		keyTab = obTabActive.Render("YELP API Key")
+	case stepEncrypt, stepPassphrase, stepConfirm:
+		encryptTab = obTabActive.Render("Encryption")
 	}
-	return obTabsStyle.Width(width).Render(lipgloss.JoinHorizontal(lipgloss.Left, "  ", enableTab, keyTab))
+	return obTabsStyle.Width(width).Render(lipgloss.JoinHorizontal(lipgloss.Left, "  ", enableTab, keyTab, encryptTab))
 }
 
 func (m onboardingModel) renderFooter(width int) string {
@@ -349,6 +448,10 @@ func (m onboardingModel) renderFooter(width int) string {
 		return obFooterStyle.Width(width).Render("↑↓/jk to navigate  y/n enter to confirm  q cancel")
 	case stepKey:
 		return obFooterStyle.Width(width).Render("enter save  esc skip  q cancel")
+	case stepEncrypt:
+		return obFooterStyle.Width(width).Render("↑↓/jk to navigate  y/n enter to confirm  q skip")
+	case stepPassphrase, stepConfirm:
+		return obFooterStyle.Width(width).Render("enter continue  esc skip encryption")
 	default:
 		return obFooterStyle.Width(width).Render("Setup complete")
 	}
@@ -384,7 +487,7 @@ func (m onboardingModel) renderContent(width, height int) string {
 			offDisplay,
 			"",
 			obMutedStyle.Render("Use arrow keys or j/k to navigate, y/n or Enter to confirm"),
-			obMutedStyle.Render("You can change this later in ~/.toni/onboarding.json"),
+			obMutedStyle.Render("You can change this later with `toni config set providers.yelp.enabled`"),
 		)
 	case stepKey:
 		input := obInputStyle.Width(max(30, cardWidth-14)).Render(m.keyInput.View())
@@ -404,12 +507,59 @@ func (m onboardingModel) renderContent(width, height int) string {
 			"",
 			obMutedStyle.Render("Press Enter to save, Esc to skip."),
 		)
+	case stepEncrypt:
+		question := obLabelStyle.Render("Encrypt the database with a passphrase?")
+		on := "Encrypt my notes and visits"
+		off := "Keep the database unencrypted"
+
+		var onDisplay, offDisplay string
+		if m.encrypt {
+			onDisplay = "  " + obOptionSelected.Render("→ "+on)
+			offDisplay = "    " + obOptionStyle.Render(off)
+		} else {
+			onDisplay = "    " + obOptionStyle.Render(on)
+			offDisplay = "  " + obOptionSelected.Render("→ "+off)
+		}
+
+		body = lipgloss.JoinVertical(
+			lipgloss.Left,
+			question,
+			"",
+			onDisplay,
+			offDisplay,
+			"",
+			obMutedStyle.Render("The passphrase is asked for each time toni starts."),
+			obMutedStyle.Render("You can encrypt later or change the passphrase with `toni rekey`."),
+		)
+	case stepPassphrase, stepConfirm:
+		inputWidth := max(30, cardWidth-14)
+		lines := []string{
+			obLabelStyle.Render("Database passphrase"),
+			"",
+			obWarnStyle.Render("There is no way to recover your data if you forget it."),
+			"",
+			obInputStyle.Width(inputWidth).Render(m.passInput.View()),
+		}
+		if m.step == stepConfirm {
+			lines = append(lines, obInputStyle.Width(inputWidth).Render(m.confirmInput.View()))
+		}
+		if m.passError != "" {
+			lines = append(lines, "", obWarnStyle.Render(m.passError))
+		}
+		body = lipgloss.JoinVertical(lipgloss.Left, lines...)
 	default:
-		msg := obMutedStyle.Render(m.status)
-		if strings.Contains(strings.ToLower(m.status), "disabled") {
-			msg = obWarnStyle.Render(m.status)
+		lines := []string{obLabelStyle.Render("Onboarding Complete"), ""}
+		for _, status := range []string{m.status, m.encStatus} {
+			if status == "" {
+				continue
+			}
+			msg := obMutedStyle.Render(status)
+			if strings.Contains(strings.ToLower(status), "disabled") || strings.Contains(strings.ToLower(status), "unencrypted") {
+				msg = obWarnStyle.Render(status)
+			}
+			lines = append(lines, msg)
 		}
-		body = lipgloss.JoinVertical(lipgloss.Left, obLabelStyle.Render("Onboarding Complete"), "", msg)
+		body = lipgloss.JoinVertical(lipgloss.Left, lines...)
 	}
 
 	card := obPanelStyle.Width(cardWidth).Render(body)
@@ -430,8 +580,12 @@ func min(a, b int) int {
 	return b
 }
 
-func runOnboarding(configDir string, existingKey string) (OnboardingSettings, error) {
-	model := newOnboardingModel(existingKey)
+// runOnboarding runs the setup TUI and saves the Yelp key. When
+// the user chose to encrypt, the passphrase is kept on config so it is not
+// asked for again on this run, and the key goes into the encrypted vault.
+func runOnboarding(config *Config, existingKey string, alreadyEncrypted bool) (OnboardingSettings, error) {
+	buildOnboardingStyles()
+	model := newOnboardingModel(existingKey, alreadyEncrypted)
 	prog := tea.NewProgram(model, tea.WithAltScreen())
 	finalModel, err := prog.Run()
 	if err != nil {
@@ -441,13 +595,14 @@ func runOnboarding(configDir string, existingKey string) (OnboardingSettings, er
 	if !ok {
 		return OnboardingSettings{}, fmt.Errorf("unexpected onboarding model type")
 	}
-	if strings.TrimSpace(m.capturedKey) != "" {
-		if err := saveSecureYelpAPIKey(configDir, m.capturedKey); err != nil {
+	if m.capturedPass != "" {
+		config.passphrase = []byte(m.capturedPass)
+	}
+	// A key from the environment or flags is already stored somewhere.
+	if key := strings.TrimSpace(m.capturedKey); key != "" && key != m.existingKey {
+		if err := saveCredential(config, config.ActiveProfile().CredentialName(credentials.Yelp), key); err != nil {
 			return OnboardingSettings{}, err
 		}
 	}
-	if err := saveOnboardingSettings(configDir, m.settings); err != nil {
-		return OnboardingSettings{}, err
-	}
 	return m.settings, nil
 }
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"toni/internal/pick"
+	"toni/internal/util"
+)
+
+// runPick draws somewhere to eat from the wishlist and favourites. As with
+// list, every argument is part of the query.
+func runPick(config *Config, args []string) error {
+	q := strings.Join(args, " ")
+	criteria, err := pick.Parse(q)
+	if err != nil {
+		return queryError(err)
+	}
+
+	store, err := OpenStore(config)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	pool, err := pick.Pool(store.DB, criteria, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(pool) == 0 {
+		return fmt.Errorf("nothing on the wishlist or among favourites matches")
+	}
+
+	seed := uint64(time.Now().UnixNano())
+	e := pick.Draw(pool, rand.New(rand.NewPCG(seed, seed>>32)))
+	c := e.Candidate
+	fmt.Println(c.Name)
+	for _, part := range []struct{ label, value string }{
+		{"where", strings.Trim(strings.Join([]string{c.Neighborhood, c.City}, ", "), ", ")},
+		{"address", c.Address},
+		{"cuisine", c.Cuisine},
+		{"price", c.PriceRange},
+	} {
+		if part.value != "" {
+			fmt.Printf("  %-8s %s\n", part.label, part.value)
+		}
+	}
+	if e.DistanceKm != nil {
+		fmt.Printf("  %-8s %.1f km\n", "distance", *e.DistanceKm)
+	}
+	last := "never"
+	if c.LastVisit != "" {
+		last = util.FormatDateHuman(c.LastVisit)
+	}
+	fmt.Printf("  %-8s %s\n", "last", last)
+	fmt.Printf("  %-8s %s (1 of %d)\n", "why", e.Reason(), len(pool))
+	return nil
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/util"
+)
+
+// runPlan adds, lists and removes planned visits.
+func runPlan(cfg *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: toni plan add|list|rm")
+	}
+	switch args[0] {
+	case "add":
+		return runPlanAdd(cfg, args[1:])
+	case "list":
+		return runPlanList(cfg, args[1:])
+	case "rm":
+		return runPlanRemove(cfg, args[1:])
+	default:
+		return fmt.Errorf("unknown plan command %q (want add, list or rm)", args[0])
+	}
+}
+
+func runPlanAdd(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("plan add", flag.ContinueOnError)
+	on := fs.String("on", "", "Day of the visit, e.g. friday, oct 24 or 2026-10-24")
+	at := fs.String("at", "", "Time of the reservation, e.g. 19:30 or 7:30pm")
+	party := fs.Int("party", 0, "Party size")
+	notes := fs.String("notes", "", "Notes, such as the confirmation number")
+	city := fs.String("city", "", "City of the restaurant, when several share its name")
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 || *on == "" {
+		return fmt.Errorf("usage: toni plan add <restaurant> --on <date> [--at time] [--party n] [--notes text]")
+	}
+
+	date, err := util.ParseUpcomingDate(*on, time.Now(), cfg.DateOrder)
+	if err != nil {
+		return err
+	}
+	plan := model.NewPlannedVisit{PlannedOn: date.Format("2006-01-02"), Notes: *notes}
+	if *at != "" {
+		if plan.PlannedAt, err = util.ParseTimeOfDay(*at); err != nil {
+			return err
+		}
+	}
+	if *party < 0 {
+		return fmt.Errorf("party size must be positive")
+	}
+	if *party > 0 {
+		plan.PartySize = party
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	restaurant, err := findRestaurant(store.DB, strings.Join(rest, " "), *city)
+	if err != nil {
+		return err
+	}
+	plan.RestaurantID = restaurant.ID
+	id, err := db.InsertPlannedVisit(store.DB, plan)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Planned %s for %s (#%d)\n", restaurant.Name, formatPlanTime(plan.PlannedOn, plan.PlannedAt), id)
+	return nil
+}
+
+func runPlanList(cfg *Config, args []string) error {
+	fs := flag.NewFlagSet("plan list", flag.ContinueOnError)
+	all := fs.Bool("all", false, "Include plans for days that have passed")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	from := util.TodayISO()
+	if *all {
+		from = ""
+	}
+	plans, err := db.ListPlannedVisits(store.DB, from)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tWHEN\tNAME\tCITY\tPARTY\tNOTES")
+	for _, p := range plans {
+		party := "—"
+		if p.PartySize != nil {
+			party = strconv.Itoa(*p.PartySize)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			p.ID, formatPlanTime(p.PlannedOn, p.PlannedAt), p.RestaurantName, p.City, party,
+			util.TruncateString(util.SingleLine(p.Notes), 40))
+	}
+	return w.Flush()
+}
+
+func runPlanRemove(cfg *Config, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: toni plan rm <id>")
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(args[0], "#"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid plan id %q", args[0])
+	}
+
+	store, err := OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if _, err := db.GetPlannedVisit(store.DB, id); err != nil {
+		return fmt.Errorf("no planned visit #%d", id)
+	}
+	if err := db.DeletePlannedVisit(store.DB, id); err != nil {
+		return err
+	}
+	fmt.Printf("Removed planned visit #%d\n", id)
+	return nil
+}
+
+// findRestaurant returns the restaurant called name, in city when it is set.
+// A name that isn't an exact match is accepted when it matches only one
+// restaurant.
+func findRestaurant(database *sql.DB, name, city string) (model.Restaurant, error) {
+	found, err := db.SearchRestaurants(database, name)
+	if err != nil {
+		return model.Restaurant{}, err
+	}
+	var matches, exact []model.Restaurant
+	for _, r := range found {
+		if city != "" && !strings.EqualFold(r.City, city) {
+			continue
+		}
+		matches = append(matches, r)
+		if strings.EqualFold(r.Name, name) {
+			exact = append(exact, r)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+	if len(exact) > 1 {
+		matches = exact
+	}
+	switch len(matches) {
+	case 0:
+		return model.Restaurant{}, fmt.Errorf("no restaurant called %q; add it in the TUI first", name)
+	case 1:
+		return matches[0], nil
+	}
+	var names []string
+	for _, r := range matches {
+		names = append(names, r.Name+" ("+r.City+")")
+	}
+	return model.Restaurant{}, fmt.Errorf("%q matches %s; be more specific or pass --city", name, strings.Join(names, ", "))
+}
+
+// formatPlanTime formats the day and time of a plan, e.g. "2026-10-24 19:30".
+func formatPlanTime(date, at string) string {
+	if at == "" {
+		return date
+	}
+	return date + " " + at
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"toni/internal/credentials"
+	"toni/internal/secure"
+)
+
+// runRekey changes the passphrase of an encrypted database and re-seals its
+// backups. A plaintext database is encrypted instead.
+func runRekey(config *Config, args []string) error {
+	fs := flag.NewFlagSet("rekey", flag.ContinueOnError)
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	encPath := config.EncryptedDBPath()
+	if !fileExists(encPath) {
+		if !fileExists(config.DBPath) {
+			return fmt.Errorf("no database at %s", config.DBPath)
+		}
+		key, err := newKey(config, "Choose a passphrase to encrypt the database")
+		if err != nil {
+			return err
+		}
+		if err := encryptPlaintext(config, key); err != nil {
+			return err
+		}
+		fmt.Printf("Encrypted database written to %s\n", encPath)
+		return nil
+	}
+
+	config.Encrypted = true
+	store, err := OpenStore(config)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	pass, err := readNewPassphrase("Choose a new passphrase", newPassphraseEnv)
+	if err != nil {
+		return err
+	}
+	key, err := secure.NewKey(pass)
+	if err != nil {
+		return err
+	}
+
+	oldKey := store.enc.Key()
+	oldPass := config.passphrase
+	if err := store.enc.Rekey(key); err != nil {
+		return err
+	}
+	config.passphrase = pass
+	fmt.Println("Passphrase changed.")
+
+	// Keep the credential vault on the same passphrase if it shared the old
+	// one.
+	if fileExists(vaultPath(config)) {
+		if vaultKey, err := secure.UnlockFile(vaultPath(config), oldPass); err == nil {
+			vault, err := credentials.OpenVault(vaultPath(config), vaultKey)
+			if err != nil {
+				return err
+			}
+			if err := vault.Rekey(key); err != nil {
+				return fmt.Errorf("failed to re-encrypt credential vault: %w", err)
+			}
+			fmt.Println("Re-encrypted the credential vault with the new passphrase.")
+		}
+	}
+
+	manager := backupManager(config, store)
+	n, err := manager.Reseal(oldKey)
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt backups: %w", err)
+	}
+	if n > 0 {
+		fmt.Printf("Re-encrypted %d backup(s) with the new passphrase.\n", n)
+	}
+	return nil
+}
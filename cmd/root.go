@@ -7,27 +7,88 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"toni/internal/config"
+	"toni/internal/credentials"
+	"toni/internal/ui"
+	"toni/internal/util"
 )
 
 // Config holds CLI configuration.
 type Config struct {
-	DBPath      string
+	DBPath string
+	// ConfigDir is the directory holding the database and its companion
+	// files: the credential vault and legacy key files.
+	ConfigDir  string
+	BackupDir  string
+	PrefsPath  string
+	KeymapPath string
+	// HistoryPath keeps command line history between sessions.
+	HistoryPath string
+	// Theme names the colour theme; see ui.LoadTheme.
+	Theme string
+	// DateOrder is how numeric dates typed in forms are read.
+	DateOrder   util.DateOrder
 	YelpAPIKey  string
 	YelpEnabled bool
+	// Location biases restaurant search towards a place.
+	Location string
+	// CredentialCommand is an external helper that prints a credential,
+	// e.g. "pass show toni/{name}".
+	CredentialCommand string
+	// Encrypted selects the passphrase-encrypted database at
+	// EncryptedDBPath instead of the plaintext file at DBPath.
+	Encrypted bool
+
+	// Profile is the name of the active profile.
+	Profile string
+	// File is the loaded config file, stored at FilePath.
+	File     *config.Config
+	FilePath string
+
+	// Command is the subcommand to run instead of the TUI, e.g. "backup".
+	Command string
+	// Args holds the arguments following Command.
+	Args []string
+
+	// passphrase caches the database passphrase once it has been entered so
+	// the user is asked at most once per run.
+	passphrase []byte
+}
+
+// EncryptedDBPath returns where the encrypted database is kept.
+func (c *Config) EncryptedDBPath() string {
+	return c.DBPath + ".enc"
 }
 
-// ParseFlags parses command-line flags and returns configuration.
+// ActiveProfile returns the settings of the active profile. A profile that
+// is not in the config file yet reads as empty settings.
+func (c *Config) ActiveProfile() *config.Profile {
+	if p := c.File.Profile(c.Profile); p != nil {
+		return p
+	}
+	return &config.Profile{}
+}
+
+// ParseFlags parses command-line flags and the config file and returns
+// configuration. Flags override environment variables, which override the
+// active profile.
 func ParseFlags(version string) (*Config, error) {
-	config := &Config{}
+	cfg := &Config{}
 
 	// Load .env files first so env-based defaults work with existing flag parsing.
 	loadDotEnv(".env")
 	loadDotEnv(".env.local")
 
 	var showVersion bool
+	var configPath string
 	flag.BoolVar(&showVersion, "version", false, "Show version and exit")
-	flag.StringVar(&config.DBPath, "db", "", "Path to SQLite database file (default: ~/.toni/toni.db)")
-	flag.StringVar(&config.YelpAPIKey, "yelp-key", "", "Yelp Fusion API key (or set YELP_API_KEY env var)")
+	flag.StringVar(&cfg.DBPath, "db", "", "Path to SQLite database file (default: ~/.toni/toni.db)")
+	flag.StringVar(&cfg.YelpAPIKey, "yelp-key", "", "Yelp Fusion API key (or set YELP_API_KEY env var)")
+	flag.StringVar(&cfg.Profile, "profile", "", "Config profile to use (or set TONI_PROFILE env var)")
+	flag.StringVar(&configPath, "config", "", "Path to config file (default: ~/.config/toni/config.toml)")
+	flag.StringVar(&cfg.Theme, "theme", "", "Colour theme: auto, dark, light, high-contrast, none or a theme file (or set TONI_THEME env var)")
+	flag.Usage = usage
 	flag.Parse()
 
 	if showVersion {
@@ -35,54 +96,236 @@ func ParseFlags(version string) (*Config, error) {
 		os.Exit(0)
 	}
 
-	// Get Yelp API key from env if not provided via flag
-	if config.YelpAPIKey == "" {
-		config.YelpAPIKey = os.Getenv("YELP_API_KEY")
+	if flag.NArg() > 0 {
+		cfg.Command = flag.Arg(0)
+		cfg.Args = flag.Args()[1:]
+	}
+
+	if err := loadConfigFile(cfg, configPath); err != nil {
+		return nil, err
+	}
+
+	explicitProfile := cfg.Profile != "" || os.Getenv("TONI_PROFILE") != ""
+	if cfg.Profile == "" {
+		cfg.Profile = os.Getenv("TONI_PROFILE")
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = cfg.File.DefaultProfile
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = config.DefaultProfileName
+	}
+	if explicitProfile && cfg.File.Profile(cfg.Profile) == nil && cfg.Command != "config" {
+		return nil, fmt.Errorf("unknown profile %q (configured: %s)", cfg.Profile, strings.Join(cfg.File.ProfileNames(), ", "))
+	}
+	profile := cfg.ActiveProfile()
+
+	if err := resolvePaths(cfg, profile); err != nil {
+		return nil, err
+	}
+	cfg.Location = profile.Location
+	cfg.CredentialCommand = profile.CredentialCommand
+	if cmd := os.Getenv(credentialCommandEnv); cmd != "" {
+		cfg.CredentialCommand = cmd
+	}
+	dateOrder, err := util.ParseDateOrder(profile.DateOrder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date_order in profile %q: %w", cfg.Profile, err)
+	}
+	cfg.DateOrder = dateOrder
+
+	if cfg.Command == "" {
+		if err := applyTheme(cfg, profile); err != nil {
+			return nil, err
+		}
 	}
 
-	// Set default DB path if not specified
-	var configDir string
-	if config.DBPath == "" {
-		home, err := os.UserHomeDir()
+	yelpCredential := profile.CredentialName(credentials.Yelp)
+	if !cfg.File.Onboarded && cfg.Command == "" && shouldRunOnboarding() {
+		existingKey := cfg.YelpAPIKey
+		if existingKey == "" {
+			existingKey, _ = credentials.Env{}.Get(yelpCredential)
+		}
+		settings, err := runOnboarding(cfg, existingKey, fileExists(cfg.EncryptedDBPath()))
 		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
+			return nil, fmt.Errorf("failed to run onboarding: %w", err)
+		}
+		if profile, err = applyOnboarding(cfg, settings); err != nil {
+			return nil, err
+		}
+	}
+	cfg.Encrypted = profile.EncryptDatabase || fileExists(cfg.EncryptedDBPath())
+
+	cfg.YelpEnabled = profile.ProviderEnabled(credentials.Yelp)
+	if cfg.YelpAPIKey == "" && cfg.Command == "" {
+		// The environment always counts, as it did before provider settings
+		// existed; stored keys are only used when the provider is enabled.
+		key, _ := credentials.Env{}.Get(yelpCredential)
+		if key == "" && cfg.YelpEnabled {
+			var err error
+			if key, err = resolveCredential(cfg, yelpCredential); err != nil {
+				return nil, fmt.Errorf("failed to load Yelp API key: %w", err)
+			}
 		}
+		cfg.YelpAPIKey = key
+	}
+	if cfg.YelpAPIKey != "" {
+		cfg.YelpEnabled = true
+	}
 
-		configDir = filepath.Join(home, ".toni")
-		if err := os.MkdirAll(configDir, 0700); err != nil {
-			return nil, fmt.Errorf("failed to create config directory: %w", err)
+	return cfg, nil
+}
+
+// loadConfigFile reads the config file, importing settings from the legacy
+// onboarding.json the first time.
+func loadConfigFile(cfg *Config, path string) error {
+	var err error
+	if path == "" {
+		if path, err = config.Path(); err != nil {
+			return err
 		}
+	} else if path, err = config.ExpandHome(path); err != nil {
+		return err
+	}
+	cfg.FilePath = path
 
-		config.DBPath = filepath.Join(configDir, "toni.db")
-	} else {
-		configDir = filepath.Dir(config.DBPath)
+	file, exists, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	cfg.File = file
+	if exists {
+		return nil
+	}
+
+	legacyDir, err := defaultDataDir()
+	if err != nil {
+		return err
+	}
+	if cfg.DBPath != "" {
+		legacyDir = filepath.Dir(cfg.DBPath)
+	}
+	settings, found, err := loadOnboardingSettings(legacyDir)
+	if err != nil {
+		return fmt.Errorf("failed to load onboarding settings: %w", err)
+	}
+	if !found {
+		return nil
 	}
 
-	settings, err := loadOnboardingSettings(configDir)
+	profile, err := file.EnsureProfile(config.DefaultProfileName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load onboarding settings: %w", err)
+		return err
+	}
+	file.Onboarded = settings.Completed
+	profile.Provider(credentials.Yelp).Enabled = settings.YelpEnabled
+	profile.EncryptDatabase = settings.EncryptDatabase
+	if err := file.Save(path); err != nil {
+		return err
 	}
+	fmt.Fprintf(os.Stderr, "ℹ  Moved settings from %s to %s\n", onboardingPath(legacyDir), path)
+	return nil
+}
 
-	if shouldRunOnboarding(settings) {
-		settings, err = runOnboarding(configDir, config.YelpAPIKey)
+// resolvePaths works out the database, backup, preference and keymap
+// locations for the active profile. Databases other than toni.db get their
+// own backup directory and preference file so profiles sharing a directory
+// stay apart.
+func resolvePaths(cfg *Config, profile *config.Profile) error {
+	var err error
+	if cfg.DBPath == "" && profile.DBPath != "" {
+		if cfg.DBPath, err = config.ExpandHome(profile.DBPath); err != nil {
+			return err
+		}
+	}
+	if cfg.DBPath == "" {
+		dir, err := defaultDataDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to run onboarding: %w", err)
+			return err
 		}
+		cfg.DBPath = filepath.Join(dir, "toni.db")
 	}
 
-	config.YelpEnabled = settings.YelpEnabled
-	if config.YelpAPIKey == "" && settings.YelpEnabled {
-		secureKey, err := loadSecureYelpAPIKey(configDir)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load secure Yelp API key: %w", err)
+	cfg.ConfigDir = filepath.Dir(cfg.DBPath)
+	if err := os.MkdirAll(cfg.ConfigDir, 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(cfg.DBPath), filepath.Ext(cfg.DBPath))
+	cfg.BackupDir = filepath.Join(cfg.ConfigDir, "backups")
+	cfg.PrefsPath = filepath.Join(cfg.ConfigDir, "ui_prefs.json")
+	cfg.HistoryPath = filepath.Join(cfg.ConfigDir, "command_history")
+	if stem != "toni" {
+		cfg.BackupDir = filepath.Join(cfg.ConfigDir, "backups-"+stem)
+		cfg.PrefsPath = filepath.Join(cfg.ConfigDir, stem+".ui_prefs.json")
+		cfg.HistoryPath = filepath.Join(cfg.ConfigDir, stem+".command_history")
+	}
+	if profile.BackupDir != "" {
+		if cfg.BackupDir, err = config.ExpandHome(profile.BackupDir); err != nil {
+			return err
+		}
+	}
+	if profile.PrefsPath != "" {
+		if cfg.PrefsPath, err = config.ExpandHome(profile.PrefsPath); err != nil {
+			return err
 		}
-		config.YelpAPIKey = strings.TrimSpace(secureKey)
 	}
-	if config.YelpAPIKey != "" {
-		config.YelpEnabled = true
+
+	cfg.KeymapPath = filepath.Join(filepath.Dir(cfg.FilePath), "keymap.toml")
+	if profile.KeymapPath != "" {
+		if cfg.KeymapPath, err = config.ExpandHome(profile.KeymapPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyOnboarding records the choices made during onboarding in the active
+// profile and saves the config file.
+func applyOnboarding(cfg *Config, settings OnboardingSettings) (*config.Profile, error) {
+	profile, err := cfg.File.EnsureProfile(cfg.Profile)
+	if err != nil {
+		return nil, err
+	}
+	cfg.File.Onboarded = settings.Completed
+	profile.Provider(credentials.Yelp).Enabled = settings.YelpEnabled
+	profile.EncryptDatabase = settings.EncryptDatabase
+	if err := cfg.File.Save(cfg.FilePath); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// applyTheme loads and activates the colour theme for the TUI. NO_COLOR
+// overrides any configured theme.
+func applyTheme(cfg *Config, profile *config.Profile) error {
+	if cfg.Theme == "" {
+		cfg.Theme = os.Getenv("TONI_THEME")
+	}
+	if cfg.Theme == "" {
+		cfg.Theme = profile.Theme
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		cfg.Theme = ui.ThemeNone
+	}
+	name, err := config.ExpandHome(cfg.Theme)
+	if err != nil {
+		return err
+	}
+	theme, err := ui.LoadTheme(name, filepath.Join(filepath.Dir(cfg.FilePath), "themes"))
+	if err != nil {
+		return err
 	}
+	ui.ApplyTheme(theme)
+	return nil
+}
 
-	return config, nil
+func defaultDataDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".toni"), nil
 }
 
 func loadDotEnv(path string) {
@@ -92,6 +335,14 @@ func loadDotEnv(path string) {
 	}
 	defer f.Close()
 
+	var secrets []string
+	defer func() {
+		if len(secrets) > 0 && credentials.WorldReadable(path) {
+			fmt.Fprintf(os.Stderr, "⚠  %s is readable by other users and sets %s; run `chmod 600 %s` or use `toni credentials set`\n",
+				path, strings.Join(secrets, ", "), path)
+		}
+	}()
+
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -113,6 +364,21 @@ func loadDotEnv(path string) {
 		value = strings.Trim(value, `"'`)
 		if os.Getenv(key) == "" {
 			_ = os.Setenv(key, value)
+			if isSecretName(key) {
+				secrets = append(secrets, key)
+			}
+		}
+	}
+}
+
+// isSecretName reports whether an environment variable looks like it holds a
+// credential.
+func isSecretName(key string) bool {
+	key = strings.ToUpper(key)
+	for _, suffix := range []string{"_KEY", "_TOKEN", "_SECRET", "PASSPHRASE", "PASSWORD"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
 		}
 	}
+	return false
 }
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"toni/internal/db"
+	"toni/internal/secure"
+
+	"golang.org/x/term"
+)
+
+const (
+	// passphraseEnv lets scripts supply the database passphrase.
+	passphraseEnv = "TONI_PASSPHRASE"
+	// newPassphraseEnv supplies the new passphrase for `toni rekey`.
+	newPassphraseEnv = "TONI_NEW_PASSPHRASE"
+
+	minPassphraseLength = 8
+	passphraseAttempts  = 3
+	autoSyncInterval    = time.Minute
+)
+
+// Store is an open toni database, plain or encrypted.
+type Store struct {
+	DB  *sql.DB
+	enc *db.EncryptedDB
+}
+
+// Encrypted reports whether the database is sealed at rest.
+func (s *Store) Encrypted() bool {
+	return s.enc != nil
+}
+
+// Close closes the database. For an encrypted store this seals the latest
+// changes and wipes the decrypted working copy.
+func (s *Store) Close() error {
+	if s.enc != nil {
+		return s.enc.Close()
+	}
+	return s.DB.Close()
+}
+
+// OpenStore opens the configured database, taking a backup first if the
+// schema is about to be migrated. When encryption is enabled it asks for the
+// passphrase, and a plaintext database left from before encryption was turned
+// on is encrypted and removed.
+func OpenStore(config *Config) (*Store, error) {
+	if !config.Encrypted {
+		database, err := db.OpenWithOptions(config.DBPath, db.Options{
+			BeforeMigrate: beforeMigrate(config, nil),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &Store{DB: database}, nil
+	}
+
+	encPath := config.EncryptedDBPath()
+	var key *secure.Key
+	var err error
+	if fileExists(encPath) {
+		key, err = unlock(config, encPath)
+	} else {
+		key, err = newKey(config, "Choose a passphrase for the database")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if fileExists(config.DBPath) {
+		if err := encryptPlaintext(config, key); err != nil {
+			return nil, err
+		}
+	}
+
+	enc, err := db.OpenEncrypted(encPath, key, db.Options{
+		BeforeMigrate: beforeMigrate(config, key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{DB: enc.DB, enc: enc}, nil
+}
+
+// StartAutoSync periodically seals an encrypted store while the TUI runs.
+func (s *Store) StartAutoSync() {
+	if s.enc == nil {
+		return
+	}
+	s.enc.StartAutoSync(autoSyncInterval, func(err error) {
+		fmt.Fprintf(os.Stderr, "⚠  Failed to save encrypted database: %v\n", err)
+	})
+}
+
+func beforeMigrate(config *Config, key *secure.Key) func(database *sql.DB, from, to int) error {
+	return func(database *sql.DB, from, to int) error {
+		manager := backupManager(config, nil)
+		manager.Key = key
+		_, err := manager.Create(database, fmt.Sprintf("pre-migration-v%d", to))
+		return err
+	}
+}
+
+// encryptPlaintext seals an existing plaintext database (and its backups)
+// with key and removes the plaintext copies.
+func encryptPlaintext(config *Config, key *secure.Key) error {
+	if fileExists(config.EncryptedDBPath()) {
+		return fmt.Errorf("both %s and %s exist; move one of them aside", config.DBPath, config.EncryptedDBPath())
+	}
+	fmt.Fprintf(os.Stderr, "Encrypting %s...\n", config.DBPath)
+	if err := db.EncryptFile(config.DBPath, config.EncryptedDBPath(), key); err != nil {
+		return err
+	}
+	if err := db.RemovePlaintext(config.DBPath); err != nil {
+		return err
+	}
+
+	manager := backupManager(config, nil)
+	manager.Key = key
+	n, err := manager.Reseal(nil)
+	if err != nil {
+		return fmt.Errorf("database encrypted, but failed to encrypt backups: %w", err)
+	}
+	if n > 0 {
+		fmt.Fprintf(os.Stderr, "Encrypted %d existing backup(s).\n", n)
+	}
+	fmt.Fprintln(os.Stderr, "⚠  Deleted plaintext files may still be recoverable from disk.")
+	return nil
+}
+
+// unlock asks for the passphrase of the sealed file at path and derives its
+// key, retrying a few times on a wrong passphrase when interactive.
+//
+// A passphrase already entered this run, or one from the environment, is
+// tried first so that the database and credential vault share one prompt
+// when they share a passphrase.
+func unlock(config *Config, path string) (*secure.Key, error) {
+	var lastErr error
+	for _, pass := range [][]byte{config.passphrase, []byte(os.Getenv(passphraseEnv))} {
+		if len(pass) == 0 {
+			continue
+		}
+		key, err := secure.UnlockFile(path, pass)
+		if err == nil {
+			config.passphrase = pass
+			return key, nil
+		}
+		if !errors.Is(err, secure.ErrWrongPassphrase) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	if lastErr != nil && !stdinIsTerminal() {
+		return nil, lastErr
+	}
+
+	fmt.Fprintf(os.Stderr, "Unlocking %s\n", path)
+	for attempt := 1; ; attempt++ {
+		pass, err := readPassphrase("Passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		key, err := secure.UnlockFile(path, pass)
+		if errors.Is(err, secure.ErrWrongPassphrase) && attempt < passphraseAttempts {
+			fmt.Fprintln(os.Stderr, "Wrong passphrase, try again.")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		config.passphrase = pass
+		return key, nil
+	}
+}
+
+// newKey derives a key from a new passphrase, taken from onboarding, the
+// environment or an interactive prompt with confirmation.
+func newKey(config *Config, prompt string) (*secure.Key, error) {
+	pass := config.passphrase
+	if pass == nil {
+		var err error
+		if pass, err = readNewPassphrase(prompt, passphraseEnv); err != nil {
+			return nil, err
+		}
+	}
+	config.passphrase = pass
+	return secure.NewKey(pass)
+}
+
+func readNewPassphrase(prompt, env string) ([]byte, error) {
+	if pass := os.Getenv(env); pass != "" {
+		if err := validatePassphrase(pass, pass); err != nil {
+			return nil, err
+		}
+		return []byte(pass), nil
+	}
+	fmt.Fprintln(os.Stderr, prompt+".")
+	pass, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	again, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePassphrase(string(pass), string(again)); err != nil {
+		return nil, err
+	}
+	return pass, nil
+}
+
+// validatePassphrase checks a new passphrase and its confirmation.
+func validatePassphrase(pass, again string) error {
+	if len(pass) < minPassphraseLength {
+		return fmt.Errorf("passphrase must be at least %d characters", minPassphraseLength)
+	}
+	if pass != again {
+		return errors.New("passphrases do not match")
+	}
+	return nil
+}
+
+func readPassphrase(prompt string) ([]byte, error) {
+	if !stdinIsTerminal() {
+		return nil, fmt.Errorf("a passphrase is required; set %s or run toni interactively", passphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	pass = []byte(strings.TrimRight(string(pass), "\r\n"))
+	if len(pass) == 0 {
+		return nil, errors.New("no passphrase entered")
+	}
+	return pass, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
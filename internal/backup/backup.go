@@ -0,0 +1,503 @@
+// Package backup creates, verifies, prunes and restores copies of the toni
+// database using SQLite's online backup (VACUUM INTO).
+//
+// When the manager has a key, backups are sealed with it and only ever exist
+// in plaintext inside a scratch directory.
+package backup
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"toni/internal/secure"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	idLayout   = "20060102T150405Z"
+	filePrefix = "toni-"
+	fileSuffix = ".db"
+	// sealedSuffix marks backups encrypted with the manager's key.
+	sealedSuffix = ".db.enc"
+
+	// ReasonManual is the default label for backups made with
+	// `toni backup create`. Manual backups are never removed by retention.
+	ReasonManual = "manual"
+	// ReasonStartup marks the automatic backup taken when toni starts.
+	ReasonStartup = "startup"
+	// ReasonPreRestore marks the safety copy taken before a restore.
+	ReasonPreRestore = "pre-restore"
+	// ReasonPreImport marks the safety copy taken before an import.
+	ReasonPreImport = "pre-import"
+)
+
+var reasonSanitizer = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// ErrNotFound is returned when no backup matches an ID.
+var ErrNotFound = errors.New("backup not found")
+
+// Policy controls how many automatic backups are kept.
+type Policy struct {
+	// KeepDaily keeps the newest backup of each of the last N days that have
+	// a backup.
+	KeepDaily int
+	// KeepWeekly keeps the newest backup of each of the last M ISO weeks
+	// that have a backup.
+	KeepWeekly int
+}
+
+// DefaultPolicy returns the retention used when none is configured.
+func DefaultPolicy() Policy {
+	return Policy{KeepDaily: 7, KeepWeekly: 4}
+}
+
+// Backup describes one backup file.
+type Backup struct {
+	ID        string
+	Path      string
+	Reason    string
+	CreatedAt time.Time
+	Size      int64
+	// Encrypted is set for sealed backups.
+	Encrypted bool
+}
+
+// Manager owns a directory of backups.
+type Manager struct {
+	Dir    string
+	Policy Policy
+	// Key, when set, seals new backups and opens sealed ones.
+	Key *secure.Key
+	// WorkDir holds plaintext snapshots while they are verified and sealed.
+	// When empty a scratch directory is created per operation.
+	WorkDir string
+}
+
+// NewManager returns a manager storing backups in dir.
+func NewManager(dir string, policy Policy) *Manager {
+	return &Manager{Dir: dir, Policy: policy}
+}
+
+// Create writes a consistent copy of database into the backup directory and
+// verifies it before keeping it. A backup that fails verification is removed
+// and reported as an error.
+func (m *Manager) Create(database *sql.DB, reason string) (Backup, error) {
+	if err := os.MkdirAll(m.Dir, 0700); err != nil {
+		return Backup{}, fmt.Errorf("failed to create backup dir: %w", err)
+	}
+
+	reason = sanitizeReason(reason)
+	now := time.Now().UTC()
+	id := now.Format(idLayout)
+	for n := 2; m.exists(id); n++ {
+		id = fmt.Sprintf("%s-%d", now.Format(idLayout), n)
+	}
+	if m.Key != nil {
+		return m.createSealed(database, id, reason)
+	}
+	path := filepath.Join(m.Dir, filePrefix+id+"_"+reason+fileSuffix)
+
+	// VACUUM INTO refuses to overwrite, and a partial file must never look
+	// like a finished backup, so write under a temporary name first.
+	tmp := path + ".partial"
+	_ = os.Remove(tmp)
+	if _, err := database.Exec("VACUUM INTO ?", tmp); err != nil {
+		_ = os.Remove(tmp)
+		return Backup{}, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := Verify(tmp); err != nil {
+		_ = os.Remove(tmp)
+		return Backup{}, err
+	}
+	if err := os.Chmod(tmp, 0600); err != nil {
+		_ = os.Remove(tmp)
+		return Backup{}, fmt.Errorf("failed to set backup permissions: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return Backup{}, fmt.Errorf("failed to finalize backup: %w", err)
+	}
+
+	return m.describe(path)
+}
+
+// createSealed snapshots database into the work directory, verifies the
+// snapshot and writes it sealed into the backup directory.
+func (m *Manager) createSealed(database *sql.DB, id, reason string) (Backup, error) {
+	workDir, cleanup, err := m.workDir()
+	if err != nil {
+		return Backup{}, err
+	}
+	defer cleanup()
+
+	snapshot := filepath.Join(workDir, "backup-"+id+fileSuffix)
+	_ = os.Remove(snapshot)
+	defer os.Remove(snapshot)
+	if _, err := database.Exec("VACUUM INTO ?", snapshot); err != nil {
+		return Backup{}, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := Verify(snapshot); err != nil {
+		return Backup{}, err
+	}
+	plaintext, err := os.ReadFile(snapshot)
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to read backup snapshot: %w", err)
+	}
+	sealed, err := m.Key.Seal(plaintext)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	path := filepath.Join(m.Dir, filePrefix+id+"_"+reason+sealedSuffix)
+	if err := secure.WriteFileAtomic(path, sealed); err != nil {
+		return Backup{}, fmt.Errorf("failed to finalize backup: %w", err)
+	}
+	return m.describe(path)
+}
+
+// List returns all backups, newest first.
+func (m *Manager) List() ([]Backup, error) {
+	entries, err := os.ReadDir(m.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read backup dir: %w", err)
+	}
+
+	var backups []Backup
+	for _, e := range entries {
+		if e.IsDir() || !isBackupName(e.Name()) {
+			continue
+		}
+		b, err := m.describe(filepath.Join(m.Dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ID > backups[j].ID
+	})
+	return backups, nil
+}
+
+// Find returns the backup with the given ID.
+func (m *Manager) Find(id string) (Backup, error) {
+	backups, err := m.List()
+	if err != nil {
+		return Backup{}, err
+	}
+	for _, b := range backups {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return Backup{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Prune removes automatic backups that fall outside the retention policy and
+// returns what was removed. Manual backups, whatever their label, are always
+// kept.
+func (m *Manager) Prune() ([]Backup, error) {
+	backups, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for _, b := range backups {
+		if !IsAutomatic(b.Reason) {
+			keep[b.ID] = true
+			continue
+		}
+		local := b.CreatedAt.Local()
+		day := local.Format("2006-01-02")
+		if !days[day] && len(days) < m.Policy.KeepDaily {
+			days[day] = true
+			keep[b.ID] = true
+		}
+		year, wk := local.ISOWeek()
+		week := fmt.Sprintf("%d-W%02d", year, wk)
+		if !weeks[week] && len(weeks) < m.Policy.KeepWeekly {
+			weeks[week] = true
+			keep[b.ID] = true
+		}
+	}
+
+	var removed []Backup
+	for _, b := range backups {
+		if keep[b.ID] {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", b.ID, err)
+		}
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
+
+// Restore replaces the database at dbPath with the backup identified by id.
+// The database must not be open. The backup is verified before anything is
+// touched, and the WAL/shared-memory files of the old database are removed
+// so SQLite cannot replay them over the restored copy.
+//
+// When the manager has a key, dbPath is the encrypted database file and the
+// restored copy is sealed with the key whatever form the backup is in.
+func (m *Manager) Restore(id, dbPath string) error {
+	b, err := m.Find(id)
+	if err != nil {
+		return err
+	}
+	if err := m.Verify(b); err != nil {
+		return err
+	}
+	if m.Key != nil {
+		plaintext, err := m.plaintext(b)
+		if err != nil {
+			return err
+		}
+		sealed, err := m.Key.Seal(plaintext)
+		if err != nil {
+			return err
+		}
+		if err := secure.WriteFileAtomic(dbPath, sealed); err != nil {
+			return fmt.Errorf("failed to replace database: %w", err)
+		}
+		return nil
+	}
+	if b.Encrypted {
+		return fmt.Errorf("backup %s is encrypted and no passphrase was given", b.ID)
+	}
+
+	tmp := dbPath + ".restore"
+	if err := copyFile(b.Path, tmp); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to copy backup: %w", err)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			_ = os.Remove(tmp)
+			return fmt.Errorf("failed to remove %s: %w", dbPath+suffix, err)
+		}
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+	return nil
+}
+
+// Reseal rewrites every backup sealed with the manager's key: plaintext
+// backups are encrypted and removed, and backups sealed with old (for example
+// before a passphrase change) are re-encrypted. It returns how many backups
+// were rewritten.
+func (m *Manager) Reseal(old *secure.Key) (int, error) {
+	if m.Key == nil {
+		return 0, errors.New("no encryption key configured")
+	}
+	backups, err := m.List()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, b := range backups {
+		data, err := os.ReadFile(b.Path)
+		if err != nil {
+			return count, fmt.Errorf("failed to read backup %s: %w", b.ID, err)
+		}
+		var plaintext []byte
+		if b.Encrypted {
+			if _, err := m.Key.Unseal(data); err == nil {
+				continue
+			}
+			if old == nil {
+				return count, fmt.Errorf("backup %s: %w", b.ID, secure.ErrWrongPassphrase)
+			}
+			if plaintext, err = old.Unseal(data); err != nil {
+				return count, fmt.Errorf("backup %s: %w", b.ID, err)
+			}
+		} else {
+			plaintext = data
+		}
+
+		sealed, err := m.Key.Seal(plaintext)
+		if err != nil {
+			return count, err
+		}
+		path := strings.TrimSuffix(b.Path, fileSuffix)
+		path = strings.TrimSuffix(path, sealedSuffix) + sealedSuffix
+		if err := secure.WriteFileAtomic(path, sealed); err != nil {
+			return count, fmt.Errorf("failed to rewrite backup %s: %w", b.ID, err)
+		}
+		if path != b.Path {
+			if err := os.Remove(b.Path); err != nil {
+				return count, fmt.Errorf("failed to remove plaintext backup %s: %w", b.ID, err)
+			}
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Verify checks a backup, decrypting sealed backups into the work directory
+// first.
+func (m *Manager) Verify(b Backup) error {
+	if !b.Encrypted {
+		return Verify(b.Path)
+	}
+	plaintext, err := m.plaintext(b)
+	if err != nil {
+		return err
+	}
+	workDir, cleanup, err := m.workDir()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	path := filepath.Join(workDir, "verify-"+b.ID+fileSuffix)
+	defer os.Remove(path)
+	if err := os.WriteFile(path, plaintext, 0600); err != nil {
+		return fmt.Errorf("failed to decrypt backup %s: %w", b.ID, err)
+	}
+	return Verify(path)
+}
+
+// plaintext returns the contents of b, decrypted if it is sealed.
+func (m *Manager) plaintext(b Backup) ([]byte, error) {
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup %s: %w", b.ID, err)
+	}
+	if !b.Encrypted {
+		return data, nil
+	}
+	if m.Key == nil {
+		return nil, fmt.Errorf("backup %s is encrypted and no passphrase was given", b.ID)
+	}
+	plaintext, err := m.Key.Unseal(data)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s: %w", b.ID, err)
+	}
+	return plaintext, nil
+}
+
+func (m *Manager) workDir() (string, func(), error) {
+	if m.WorkDir != "" {
+		return m.WorkDir, func() {}, nil
+	}
+	dir, err := secure.ScratchDir()
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, func() { _ = os.RemoveAll(dir) }, nil
+}
+
+// Verify opens a backup read-only and runs PRAGMA integrity_check on it.
+func Verify(path string) error {
+	conn, err := sql.Open("sqlite", "file:"+filepath.ToSlash(path)+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("backup %s does not open: %w", filepath.Base(path), err)
+	}
+	defer conn.Close()
+
+	var result string
+	if err := conn.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("backup %s failed integrity check: %w", filepath.Base(path), err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup %s failed integrity check: %s", filepath.Base(path), result)
+	}
+
+	var n int
+	if err := conn.QueryRow("SELECT COUNT(*) FROM restaurants").Scan(&n); err != nil {
+		return fmt.Errorf("backup %s is not a toni database: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func (m *Manager) exists(id string) bool {
+	matches, _ := filepath.Glob(filepath.Join(m.Dir, filePrefix+id+"_*"))
+	return len(matches) > 0
+}
+
+func (m *Manager) describe(path string) (Backup, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Backup{}, err
+	}
+	base := filepath.Base(path)
+	encrypted := strings.HasSuffix(base, sealedSuffix)
+	name := strings.TrimPrefix(base, filePrefix)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, sealedSuffix), fileSuffix)
+	id, reason, _ := strings.Cut(name, "_")
+	created, err := time.Parse(idLayout, strings.SplitN(id, "-", 2)[0])
+	if err != nil {
+		return Backup{}, fmt.Errorf("unrecognized backup name %s", filepath.Base(path))
+	}
+	return Backup{
+		ID:        id,
+		Path:      path,
+		Reason:    reason,
+		CreatedAt: created,
+		Size:      info.Size(),
+		Encrypted: encrypted,
+	}, nil
+}
+
+// IsAutomatic reports whether reason labels a backup toni took on its own:
+// the startup backup or a safety copy taken before a risky operation
+// ("pre-migration-v2", "pre-restore", "pre-import", ...).
+func IsAutomatic(reason string) bool {
+	return reason == ReasonStartup || strings.HasPrefix(reason, "pre-")
+}
+
+func isBackupName(name string) bool {
+	return strings.HasPrefix(name, filePrefix) &&
+		(strings.HasSuffix(name, fileSuffix) || strings.HasSuffix(name, sealedSuffix))
+}
+
+func sanitizeReason(reason string) string {
+	reason = reasonSanitizer.ReplaceAllString(strings.ToLower(strings.TrimSpace(reason)), "-")
+	reason = strings.Trim(reason, "-")
+	if reason == "" {
+		return ReasonManual
+	}
+	return reason
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,171 @@
+// Package bundle shares a list of restaurants with a friend without handing
+// over the whole database: a chosen subset, optionally with your ratings and
+// redacted notes, in a file signed with your key so the recipient can tell
+// who made it and that it arrived unchanged.
+//
+// A bundle file is JSON holding the list, the sender's ed25519 public key
+// and a signature over the list in compact form.
+package bundle
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"toni/internal/secure"
+)
+
+const (
+	format  = "toni-bundle"
+	version = 1
+)
+
+// ErrBadSignature is returned by Read when a bundle doesn't match its
+// signature, because it was changed after it was signed.
+var ErrBadSignature = errors.New("bundle signature doesn't match; it was changed after it was signed")
+
+// Bundle is a shared list of restaurants.
+type Bundle struct {
+	Name string `json:"name"`
+	// From is the sender's name, as they gave it.
+	From        string       `json:"from,omitempty"`
+	Created     time.Time    `json:"created"`
+	Restaurants []Restaurant `json:"restaurants"`
+}
+
+// Restaurant is a restaurant in a bundle. The rating, visit count, would
+// return answer and notes are the sender's, and only present when they
+// chose to share them.
+type Restaurant struct {
+	Name         string   `json:"name"`
+	Address      string   `json:"address,omitempty"`
+	City         string   `json:"city,omitempty"`
+	Neighborhood string   `json:"neighborhood,omitempty"`
+	Cuisine      string   `json:"cuisine,omitempty"`
+	PriceRange   string   `json:"price_range,omitempty"`
+	Latitude     *float64 `json:"latitude,omitempty"`
+	Longitude    *float64 `json:"longitude,omitempty"`
+	PlaceID      string   `json:"place_id,omitempty"`
+	Rating       *float64 `json:"rating,omitempty"`
+	Visits       int      `json:"visits,omitempty"`
+	WouldReturn  *bool    `json:"would_return,omitempty"`
+	Notes        string   `json:"notes,omitempty"`
+}
+
+// Signer identifies who signed a bundle.
+type Signer struct {
+	PublicKey ed25519.PublicKey
+}
+
+// Fingerprint is a short form of the signer's key for people to compare,
+// e.g. "3f9a 12bc 88e0 41d7".
+func (s Signer) Fingerprint() string {
+	sum := sha256.Sum256(s.PublicKey)
+	h := hex.EncodeToString(sum[:8])
+	return h[0:4] + " " + h[4:8] + " " + h[8:12] + " " + h[12:16]
+}
+
+type envelope struct {
+	Format    string          `json:"format"`
+	Version   int             `json:"version"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+	Bundle    json.RawMessage `json:"bundle"`
+}
+
+// LoadOrCreateKey reads the signing key at path, creating one the first time.
+func LoadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("signing key %s is damaged", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key.Seed()) + "\n"
+	if err := secure.WriteFileAtomic(path, []byte(encoded)); err != nil {
+		return nil, fmt.Errorf("failed to save signing key: %w", err)
+	}
+	return key, nil
+}
+
+// Write signs b with key and writes it to w.
+func Write(w io.Writer, b Bundle, key ed25519.PrivateKey) error {
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	env := envelope{
+		Format:    format,
+		Version:   version,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+		Bundle:    payload,
+	}
+	data, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// Read reads a bundle and checks its signature.
+func Read(r io.Reader) (Bundle, Signer, error) {
+	var env envelope
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return Bundle{}, Signer{}, fmt.Errorf("not a toni bundle: %w", err)
+	}
+	if env.Format != format {
+		return Bundle{}, Signer{}, errors.New("not a toni bundle")
+	}
+	if env.Version > version {
+		return Bundle{}, Signer{}, fmt.Errorf("bundle version %d is newer than this toni understands; update toni", env.Version)
+	}
+	pub, err := base64.StdEncoding.DecodeString(env.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return Bundle{}, Signer{}, errors.New("bundle has no valid public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signature)
+	if err != nil {
+		return Bundle{}, Signer{}, ErrBadSignature
+	}
+	// The payload was signed compact; indenting the file doesn't change it.
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, env.Bundle); err != nil {
+		return Bundle{}, Signer{}, fmt.Errorf("not a toni bundle: %w", err)
+	}
+	if !ed25519.Verify(pub, payload.Bytes(), sig) {
+		return Bundle{}, Signer{}, ErrBadSignature
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(payload.Bytes(), &b); err != nil {
+		return Bundle{}, Signer{}, fmt.Errorf("not a toni bundle: %w", err)
+	}
+	return b, Signer{PublicKey: pub}, nil
+}
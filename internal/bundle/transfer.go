@@ -0,0 +1,283 @@
+package bundle
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"toni/internal/compare"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/util"
+)
+
+// ExportOptions says what to put in a bundle.
+type ExportOptions struct {
+	// Limit keeps only the best rated restaurants; 0 keeps them all.
+	Limit int
+	// Ratings shares your average rating, visit count and latest would
+	// return answer.
+	Ratings bool
+	// Notes shares the notes of your latest visit that has any, redacted.
+	Notes bool
+}
+
+// Collect returns the restaurants matching an SQL condition over the columns
+// of db.RestaurantQuerySchema, best rated first, ready to bundle.
+func Collect(database *sql.DB, where string, args []any, opts ExportOptions) ([]Restaurant, error) {
+	rows, err := db.ListRestaurantsWhere(database, where, args)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		ri, rj := rows[i].AvgRating, rows[j].AvgRating
+		if ri == nil || rj == nil {
+			return ri != nil
+		}
+		return *ri > *rj
+	})
+	if opts.Limit > 0 && len(rows) > opts.Limit {
+		rows = rows[:opts.Limit]
+	}
+
+	out := make([]Restaurant, 0, len(rows))
+	for _, row := range rows {
+		r, err := db.GetRestaurant(database, row.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load restaurant %q: %w", row.Name, err)
+		}
+		entry := Restaurant{
+			Name:         r.Name,
+			Address:      r.Address,
+			City:         r.City,
+			Neighborhood: r.Neighborhood,
+			Cuisine:      r.Cuisine,
+			PriceRange:   r.PriceRange,
+			Latitude:     r.Latitude,
+			Longitude:    r.Longitude,
+			PlaceID:      r.PlaceID,
+		}
+		if opts.Ratings || opts.Notes {
+			visits, err := db.GetVisitsByRestaurant(database, r.ID)
+			if err != nil {
+				return nil, err
+			}
+			// Latest first.
+			sort.SliceStable(visits, func(i, j int) bool { return visits[i].VisitedOn > visits[j].VisitedOn })
+			for _, v := range visits {
+				if opts.Ratings && entry.WouldReturn == nil && v.WouldReturn != nil {
+					entry.WouldReturn = v.WouldReturn
+				}
+				if opts.Notes && entry.Notes == "" {
+					entry.Notes = Redact(v.Notes)
+				}
+			}
+			if opts.Ratings {
+				entry.Rating = row.AvgRating
+				entry.Visits = row.VisitCount
+			}
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+var (
+	emailPattern   = regexp.MustCompile(`[\w.+-]+@[\w-]+(\.[\w-]+)+`)
+	phonePattern   = regexp.MustCompile(`\+\d[\d\s.-]{7,}\d|\(?\b\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}\b`)
+	mentionPattern = regexp.MustCompile(`(^|\s)@\w+`)
+)
+
+// Redact strips what notes shouldn't share: lines starting with "private:"
+// are dropped, and email addresses, phone numbers and @mentions are
+// replaced with [redacted].
+func Redact(notes string) string {
+	var kept []string
+	for _, line := range strings.Split(notes, "\n") {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), "private:") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	s := strings.Join(kept, "\n")
+	s = emailPattern.ReplaceAllString(s, "[redacted]")
+	s = phonePattern.ReplaceAllString(s, "[redacted]")
+	s = mentionPattern.ReplaceAllString(s, "$1[redacted]")
+	return strings.TrimSpace(s)
+}
+
+// Status is what importing did with a restaurant.
+type Status int
+
+const (
+	// StatusNew means the restaurant was new and was added to the wishlist.
+	StatusNew Status = iota
+	// StatusAdded means the restaurant was already in the database and was
+	// added to the wishlist.
+	StatusAdded
+	// StatusListed means the restaurant was already on the wishlist and was
+	// left alone.
+	StatusListed
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusNew:
+		return "new, added to wishlist"
+	case StatusAdded:
+		return "already in your restaurants, added to wishlist"
+	default:
+		return "already on your wishlist"
+	}
+}
+
+// ImportOptions says how to import a bundle.
+type ImportOptions struct {
+	// Priority is given to the new wishlist entries.
+	Priority *int
+	// DryRun reports what would be imported without changing anything.
+	DryRun bool
+}
+
+// Imported is a restaurant of a bundle and what importing did with it.
+type Imported struct {
+	Name   string
+	Status Status
+}
+
+var errDryRun = errors.New("dry run")
+
+// Import adds the restaurants of b to the want to visit list in one
+// transaction. Restaurants already in the database or earlier in the bundle,
+// found with compare.Index.Lookup, are reused rather than duplicated. Each
+// new entry records where it came from as its source, and the sender's
+// rating and notes in its notes.
+func Import(database *sql.DB, b Bundle, signer Signer, opts ImportOptions) ([]Imported, error) {
+	source := Source(b, signer)
+	var results []Imported
+	err := db.InTx(database, func(tx *sql.Tx) error {
+		// places holds the restaurants in the database, including those
+		// added by this import, in the order of index.
+		places, err := db.ListRestaurantStats(tx)
+		if err != nil {
+			return err
+		}
+		index := compare.NewIndex(places)
+
+		for _, r := range b.Restaurants {
+			if strings.TrimSpace(r.Name) == "" {
+				continue
+			}
+			result := Imported{Name: r.Name, Status: StatusNew}
+			i, ok := index.Lookup(r.stats())
+			if ok {
+				if places[i].Wishlisted {
+					results = append(results, Imported{Name: places[i].Name, Status: StatusListed})
+					continue
+				}
+				result = Imported{Name: places[i].Name, Status: StatusAdded}
+			} else {
+				stats := r.stats()
+				stats.RestaurantID, err = db.InsertRestaurant(tx, r.newRestaurant())
+				if err != nil {
+					return err
+				}
+				i = len(places)
+				places = append(places, stats)
+				index.Add(stats)
+			}
+			if _, err := db.InsertWantToVisit(tx, model.NewWantToVisit{
+				RestaurantID: places[i].RestaurantID,
+				Notes:        entryNotes(r, b.From),
+				Priority:     opts.Priority,
+				Source:       source,
+			}); err != nil {
+				return fmt.Errorf("failed to add %q to the wishlist: %w", r.Name, err)
+			}
+			places[i].Wishlisted = true
+			results = append(results, result)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return results, nil
+}
+
+// Source describes where a bundle came from, as recorded on the wishlist
+// entries it adds: `"Top ramen" from Alex (key 3f9a 12bc 88e0 41d7)`.
+func Source(b Bundle, signer Signer) string {
+	s := fmt.Sprintf("%q", b.Name)
+	if b.From != "" {
+		s += " from " + b.From
+	}
+	return s + " (key " + signer.Fingerprint() + ")"
+}
+
+// entryNotes is the wishlist note for a bundled restaurant: what the sender
+// thought of it, then their notes.
+func entryNotes(r Restaurant, from string) string {
+	if from == "" {
+		from = "Sender"
+	}
+	var verdict []string
+	if r.Rating != nil {
+		verdict = append(verdict, util.FormatRating(r.Rating))
+	}
+	if r.Visits > 0 {
+		verdict = append(verdict, fmt.Sprintf("%d %s", r.Visits, plural(r.Visits, "visit", "visits")))
+	}
+	if r.WouldReturn != nil {
+		if *r.WouldReturn {
+			verdict = append(verdict, "would return")
+		} else {
+			verdict = append(verdict, "wouldn't return")
+		}
+	}
+	var parts []string
+	if len(verdict) > 0 {
+		parts = append(parts, from+": "+strings.Join(verdict, ", "))
+	}
+	if r.Notes != "" {
+		parts = append(parts, r.Notes)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+func (r Restaurant) stats() model.RestaurantStats {
+	return model.RestaurantStats{Name: r.Name, Address: r.Address, City: r.City, PlaceID: r.PlaceID}
+}
+
+func (r Restaurant) newRestaurant() model.NewRestaurant {
+	price := r.PriceRange
+	switch price {
+	case "$", "$$", "$$$", "$$$$":
+	default:
+		// Anything else would break the price range constraint.
+		price = ""
+	}
+	return model.NewRestaurant{
+		Name:         strings.TrimSpace(r.Name),
+		Address:      r.Address,
+		City:         r.City,
+		Neighborhood: r.Neighborhood,
+		Cuisine:      r.Cuisine,
+		PriceRange:   price,
+		Latitude:     r.Latitude,
+		Longitude:    r.Longitude,
+		PlaceID:      r.PlaceID,
+	}
+}
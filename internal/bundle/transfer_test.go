@@ -0,0 +1,65 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"toni/internal/db"
+	"toni/internal/model"
+)
+
+func TestImport(t *testing.T) {
+	database, err := db.Open(filepath.Join(t.TempDir(), "toni.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	listed, err := db.InsertRestaurant(database, model.NewRestaurant{Name: "Lucali", City: "Brooklyn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.InsertWantToVisit(database, model.NewWantToVisit{RestaurantID: listed}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.InsertRestaurant(database, model.NewRestaurant{Name: "Di Fara", City: "Brooklyn"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := Bundle{Name: "Pizza", Restaurants: []Restaurant{
+		{Name: "Lucali", City: "Brooklyn"},
+		{Name: "Di Fara", City: "Brooklyn"},
+		{Name: "L&B Spumoni Gardens", City: "Brooklyn"},
+		// Listed twice, as bundles merged by hand can be.
+		{Name: "Di Fara", City: "Brooklyn"},
+		{Name: "L&B Spumoni Gardens", City: "Brooklyn"},
+	}}
+	signer := Signer{PublicKey: make(ed25519.PublicKey, ed25519.PublicKeySize)}
+	results, err := Import(database, b, signer, ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Imported{
+		{Name: "Lucali", Status: StatusListed},
+		{Name: "Di Fara", Status: StatusAdded},
+		{Name: "L&B Spumoni Gardens", Status: StatusNew},
+		{Name: "Di Fara", Status: StatusListed},
+		{Name: "L&B Spumoni Gardens", Status: StatusListed},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Import = %v, want %v", results, want)
+	}
+
+	stats, err := db.ListRestaurantStats(database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 {
+		t.Errorf("Import left %d restaurants, want 3", len(stats))
+	}
+	for _, s := range stats {
+		if !s.Wishlisted {
+			t.Errorf("%s isn't on the wishlist", s.Name)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+// Package compare sets your restaurants beside a friend's: it matches the
+// restaurants of two databases, finds where your ratings agree and disagree,
+// lists the places they loved that you haven't been to, and scores how
+// similar your tastes are.
+//
+// Restaurants match when they share a place ID, or else when their names and
+// addresses match once case, punctuation and common street abbreviations are
+// set aside. When either lacks an address, the name and city must match
+// instead.
+package compare
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"toni/internal/db"
+	"toni/internal/model"
+	"unicode"
+)
+
+const (
+	// AgreeWithin is the largest rating difference that counts as agreeing.
+	AgreeWithin = 1.0
+	// DisagreeBy is the smallest rating difference that counts as
+	// disagreeing.
+	DisagreeBy = 2.0
+	// LovedRating is the average rating that makes a place loved.
+	LovedRating = 8.0
+	// MinShared is how many places you must both have rated before tastes
+	// are scored.
+	MinShared = 3
+)
+
+// Pair is a restaurant you have both rated.
+type Pair struct {
+	Mine, Theirs model.RestaurantStats
+}
+
+// Diff is how much higher they rated the restaurant than you did.
+func (p Pair) Diff() float64 {
+	return *p.Theirs.AvgRating - *p.Mine.AvgRating
+}
+
+// Loved is a place they loved that you haven't been to.
+type Loved struct {
+	Theirs model.RestaurantStats
+	// Mine is the restaurant in your database, when you have it, e.g. on
+	// the want to visit list.
+	Mine *model.RestaurantStats
+}
+
+// Report is the outcome of a comparison.
+type Report struct {
+	// MineCount and TheirsCount are how many restaurants each database
+	// holds, and Matched how many of them are in both.
+	MineCount   int
+	TheirsCount int
+	Matched     int
+	// Rated holds the restaurants you have both rated, closest ratings
+	// first.
+	Rated []Pair
+	// TheyLoved holds the places they loved that you haven't been to, best
+	// first.
+	TheyLoved []Loved
+	// Similarity is from 0 (opposite tastes) to 1 (the same), or nil when
+	// fewer than MinShared places were rated by both or either of you gave
+	// them all the same rating.
+	Similarity *float64
+	// Offset is how much higher they rate than you on average over Rated.
+	Offset float64
+}
+
+// Load compares the restaurants of mine with those of theirs. Both are only
+// read.
+func Load(mine, theirs *sql.DB) (Report, error) {
+	m, err := db.ListRestaurantStats(mine)
+	if err != nil {
+		return Report{}, err
+	}
+	t, err := db.ListRestaurantStats(theirs)
+	if err != nil {
+		return Report{}, err
+	}
+	return Compare(m, t), nil
+}
+
+// Compare compares two lists of restaurants.
+func Compare(mine, theirs []model.RestaurantStats) Report {
+	r := Report{MineCount: len(mine), TheirsCount: len(theirs)}
+	matches := match(mine, theirs)
+	r.Matched = len(matches)
+
+	// matchOf maps each of their restaurants to its index in mine.
+	matchOf := make(map[int]int, len(matches))
+	for mi, ti := range matches {
+		matchOf[ti] = mi
+		if mine[mi].AvgRating != nil && theirs[ti].AvgRating != nil {
+			r.Rated = append(r.Rated, Pair{Mine: mine[mi], Theirs: theirs[ti]})
+		}
+	}
+	sort.SliceStable(r.Rated, func(i, j int) bool {
+		di, dj := math.Abs(r.Rated[i].Diff()), math.Abs(r.Rated[j].Diff())
+		if di != dj {
+			return di < dj
+		}
+		return r.Rated[i].Mine.Name < r.Rated[j].Mine.Name
+	})
+
+	for ti, t := range theirs {
+		if !loved(t) {
+			continue
+		}
+		l := Loved{Theirs: t}
+		if mi, ok := matchOf[ti]; ok {
+			if mine[mi].VisitCount > 0 {
+				continue
+			}
+			l.Mine = &mine[mi]
+		}
+		r.TheyLoved = append(r.TheyLoved, l)
+	}
+	sort.SliceStable(r.TheyLoved, func(i, j int) bool {
+		return *r.TheyLoved[i].Theirs.AvgRating > *r.TheyLoved[j].Theirs.AvgRating
+	})
+
+	r.Similarity, r.Offset = similarity(r.Rated)
+	return r
+}
+
+// Agreements returns the rated pairs whose ratings are within AgreeWithin,
+// closest first.
+func (r Report) Agreements() []Pair {
+	var out []Pair
+	for _, p := range r.Rated {
+		if math.Abs(p.Diff()) <= AgreeWithin {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Disagreements returns the rated pairs whose ratings are at least
+// DisagreeBy apart, furthest first.
+func (r Report) Disagreements() []Pair {
+	var out []Pair
+	for i := len(r.Rated) - 1; i >= 0; i-- {
+		if p := r.Rated[i]; math.Abs(p.Diff()) >= DisagreeBy {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// loved reports whether a restaurant averaged at least LovedRating and its
+// latest visit didn't say never again.
+func loved(s model.RestaurantStats) bool {
+	return s.AvgRating != nil && *s.AvgRating >= LovedRating &&
+		(s.WouldReturn == nil || *s.WouldReturn)
+}
+
+// match pairs restaurants of mine with those of theirs, returning the index
+// in theirs for each matched index in mine. Place IDs are matched first, so
+// a name match can't take a restaurant a place ID would have claimed.
+func match(mine, theirs []model.RestaurantStats) map[int]int {
+	index := NewIndex(theirs)
+	matches := make(map[int]int)
+	for mi, m := range mine {
+		if ti, ok := index.FindPlace(m); ok {
+			matches[mi] = ti
+		}
+	}
+	for mi, m := range mine {
+		if _, ok := matches[mi]; ok {
+			continue
+		}
+		if ti, ok := index.FindName(m); ok {
+			matches[mi] = ti
+		}
+	}
+	return matches
+}
+
+// Index finds the restaurants of a list that match others. Find and its
+// variants claim what they return, so each restaurant in the list matches at
+// most once; Lookup doesn't.
+type Index struct {
+	list    []model.RestaurantStats
+	byPlace map[string][]int
+	byName  map[string][]int
+	taken   map[int]bool
+}
+
+// NewIndex indexes list.
+func NewIndex(list []model.RestaurantStats) *Index {
+	x := &Index{
+		list:    list,
+		byPlace: make(map[string][]int),
+		byName:  make(map[string][]int),
+		taken:   make(map[int]bool),
+	}
+	for i, r := range list {
+		if r.PlaceID != "" {
+			x.byPlace[r.PlaceID] = append(x.byPlace[r.PlaceID], i)
+		}
+		if n := normalize(r.Name); n != "" {
+			x.byName[n] = append(x.byName[n], i)
+		}
+	}
+	return x
+}
+
+// Find returns the index in the list of the restaurant matching s, by place
+// ID or else by name and address.
+func (x *Index) Find(s model.RestaurantStats) (int, bool) {
+	if i, ok := x.FindPlace(s); ok {
+		return i, true
+	}
+	return x.FindName(s)
+}
+
+// FindPlace returns the index of the restaurant sharing s's place ID.
+func (x *Index) FindPlace(s model.RestaurantStats) (int, bool) {
+	if s.PlaceID == "" {
+		return 0, false
+	}
+	return x.claim(x.byPlace[s.PlaceID], func(int) bool { return true })
+}
+
+// FindName returns the index of the restaurant whose name and address, or
+// name and city when either lacks an address, match s's. Restaurants with
+// different place IDs never match.
+func (x *Index) FindName(s model.RestaurantStats) (int, bool) {
+	return x.claim(x.byName[normalize(s.Name)], func(i int) bool { return x.sameName(s, i) })
+}
+
+// sameName reports whether s matches the restaurant at i by name and address,
+// or name and city.
+func (x *Index) sameName(s model.RestaurantStats, i int) bool {
+	r := x.list[i]
+	if s.PlaceID != "" && r.PlaceID != "" {
+		return false
+	}
+	if s.Address != "" && r.Address != "" {
+		return normalizeAddress(s.Address) == normalizeAddress(r.Address)
+	}
+	return normalize(s.City) == normalize(r.City)
+}
+
+// Lookup returns the index of the restaurant matching s as Find does, but
+// without claiming it, so later lookups may return it again. When s has
+// neither an address nor a city, its name alone is enough if only one
+// restaurant in the list has it.
+func (x *Index) Lookup(s model.RestaurantStats) (int, bool) {
+	if ids := x.byPlace[s.PlaceID]; s.PlaceID != "" && len(ids) > 0 {
+		return ids[0], true
+	}
+	name := x.byName[normalize(s.Name)]
+	for _, i := range name {
+		if x.sameName(s, i) {
+			return i, true
+		}
+	}
+	if s.Address == "" && s.City == "" && len(name) == 1 && (s.PlaceID == "" || x.list[name[0]].PlaceID == "") {
+		return name[0], true
+	}
+	return 0, false
+}
+
+// Add adds s to the end of the list.
+func (x *Index) Add(s model.RestaurantStats) {
+	i := len(x.list)
+	x.list = append(x.list, s)
+	if s.PlaceID != "" {
+		x.byPlace[s.PlaceID] = append(x.byPlace[s.PlaceID], i)
+	}
+	if n := normalize(s.Name); n != "" {
+		x.byName[n] = append(x.byName[n], i)
+	}
+}
+
+func (x *Index) claim(candidates []int, ok func(i int) bool) (int, bool) {
+	for _, i := range candidates {
+		if !x.taken[i] && ok(i) {
+			x.taken[i] = true
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// normalize lowercases s and reduces it to words of letters and digits.
+func normalize(s string) string {
+	return strings.Join(words(s), " ")
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+var streetAbbreviations = map[string]string{
+	"street":    "st",
+	"avenue":    "ave",
+	"av":        "ave",
+	"road":      "rd",
+	"boulevard": "blvd",
+	"drive":     "dr",
+	"place":     "pl",
+	"lane":      "ln",
+	"square":    "sq",
+	"north":     "n",
+	"south":     "s",
+	"east":      "e",
+	"west":      "w",
+}
+
+// normalizeAddress is normalize with street words abbreviated.
+func normalizeAddress(s string) string {
+	w := words(s)
+	for i, word := range w {
+		if abbr, ok := streetAbbreviations[word]; ok {
+			w[i] = abbr
+		}
+	}
+	return strings.Join(w, " ")
+}
+
+// similarity correlates the ratings of pairs after taking out each rater's
+// own average, so a harsh and a generous critic who rank places alike still
+// score as similar. It also returns the difference between the averages.
+func similarity(pairs []Pair) (*float64, float64) {
+	if len(pairs) == 0 {
+		return nil, 0
+	}
+	var mineMean, theirsMean float64
+	for _, p := range pairs {
+		mineMean += *p.Mine.AvgRating
+		theirsMean += *p.Theirs.AvgRating
+	}
+	n := float64(len(pairs))
+	mineMean /= n
+	theirsMean /= n
+	offset := theirsMean - mineMean
+	if len(pairs) < MinShared {
+		return nil, offset
+	}
+
+	var cov, mineVar, theirsVar float64
+	for _, p := range pairs {
+		dm := *p.Mine.AvgRating - mineMean
+		dt := *p.Theirs.AvgRating - theirsMean
+		cov += dm * dt
+		mineVar += dm * dm
+		theirsVar += dt * dt
+	}
+	if mineVar == 0 || theirsVar == 0 {
+		return nil, offset
+	}
+	s := (cov/math.Sqrt(mineVar*theirsVar) + 1) / 2
+	return &s, offset
+}
+
+// Summary describes the similarity in a sentence, e.g. "74% similar taste
+// over 18 places; they rate 0.6 higher on average".
+func (r Report) Summary() string {
+	if r.Similarity == nil {
+		if len(r.Rated) < MinShared {
+			return fmt.Sprintf("Too few places rated by both to compare tastes (%d of %d needed)", len(r.Rated), MinShared)
+		}
+		return fmt.Sprintf("Can't compare tastes: one of you gave all %d shared places the same rating", len(r.Rated))
+	}
+	s := fmt.Sprintf("%.0f%% similar taste over %d places", *r.Similarity*100, len(r.Rated))
+	switch {
+	case r.Offset >= 0.05:
+		s += fmt.Sprintf("; they rate %.1f higher on average", r.Offset)
+	case r.Offset <= -0.05:
+		s += fmt.Sprintf("; they rate %.1f lower on average", -r.Offset)
+	}
+	return s
+}
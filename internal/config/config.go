@@ -0,0 +1,359 @@
+// Package config reads and writes toni's TOML configuration file.
+//
+// The file holds named profiles, each pointing at its own database and
+// carrying its own provider settings, location bias and UI preferences:
+//
+//	default_profile = "personal"
+//
+//	[profiles.personal]
+//	db_path = "~/.toni/toni.db"
+//	location = "Brooklyn, NY"
+//
+//	[profiles.personal.providers.yelp]
+//	enabled = true
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultProfileName is the profile used when none is configured.
+const DefaultProfileName = "default"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ErrUnknownKey is returned by Get and Set for keys the file does not define.
+var ErrUnknownKey = errors.New("unknown config key")
+
+// Config is the contents of the config file.
+type Config struct {
+	// DefaultProfile is used when neither --profile nor TONI_PROFILE is set.
+	DefaultProfile string `toml:"default_profile,omitempty"`
+	// Onboarded records that first-run setup has been completed.
+	Onboarded bool                `toml:"onboarded"`
+	Profiles  map[string]*Profile `toml:"profiles,omitempty"`
+}
+
+// Profile is one named set of settings.
+type Profile struct {
+	// DBPath is the SQLite database. "~" is expanded.
+	DBPath string `toml:"db_path,omitempty"`
+	// BackupDir overrides where backups of this profile's database go.
+	BackupDir string `toml:"backup_dir,omitempty"`
+	// PrefsPath overrides where UI preferences are stored. By default they
+	// live next to the database.
+	PrefsPath string `toml:"prefs_path,omitempty"`
+	// Location biases restaurant search, e.g. "Brooklyn, NY".
+	Location string `toml:"location,omitempty"`
+	// EncryptDatabase keeps the database encrypted with a passphrase.
+	EncryptDatabase bool `toml:"encrypt_database"`
+	// CredentialCommand is an external helper that prints a credential,
+	// with {name} replaced by the credential name.
+	CredentialCommand string `toml:"credential_command,omitempty"`
+	// KeymapPath overrides where key bindings are read from. By default they
+	// live in keymap.toml next to the config file.
+	KeymapPath string `toml:"keymap_path,omitempty"`
+	// Theme is a built-in theme (auto, dark, light, high-contrast, none) or
+	// the name of a file in the themes directory next to the config file.
+	Theme string `toml:"theme,omitempty"`
+	// DateOrder is how numeric dates like 3/4 are read: "mdy" (March 4,
+	// the default) or "dmy" (April 3).
+	DateOrder string `toml:"date_order,omitempty"`
+	// Providers holds per-search-provider settings keyed by provider name.
+	Providers map[string]*Provider `toml:"providers,omitempty"`
+}
+
+// Provider configures one search provider.
+type Provider struct {
+	Enabled bool `toml:"enabled"`
+	// Credential names the stored API key to use, so profiles can use
+	// different accounts. It defaults to the provider name.
+	Credential string `toml:"credential,omitempty"`
+}
+
+// Path returns the config file location: $TONI_CONFIG if set, otherwise
+// toni/config.toml under the XDG config directory ($XDG_CONFIG_HOME or
+// ~/.config).
+func Path() (string, error) {
+	if p := os.Getenv("TONI_CONFIG"); p != "" {
+		return ExpandHome(p)
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "toni", "config.toml"), nil
+}
+
+// Load reads the config file at path. A missing file yields an empty config
+// and exists=false.
+func Load(path string) (cfg *Config, exists bool, err error) {
+	cfg = &Config{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read config: %w", err)
+	}
+	md, err := toml.Decode(string(data), cfg)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, true, fmt.Errorf("%s: unknown key %s", path, undecoded[0])
+	}
+	return cfg, true, nil
+}
+
+// Save writes cfg to path, creating the directory if needed.
+func (c *Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// Profile returns the named profile, or nil if it does not exist.
+func (c *Config) Profile(name string) *Profile {
+	return c.Profiles[name]
+}
+
+// EnsureProfile returns the named profile, creating it if needed.
+func (c *Config) EnsureProfile(name string) (*Profile, error) {
+	if !profileNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Profile)
+	}
+	p := c.Profiles[name]
+	if p == nil {
+		p = &Profile{}
+		c.Profiles[name] = p
+	}
+	return p, nil
+}
+
+// ProfileNames returns the configured profile names in order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Provider returns the settings for a provider, creating them if needed.
+func (p *Profile) Provider(name string) *Provider {
+	if p.Providers == nil {
+		p.Providers = make(map[string]*Provider)
+	}
+	prov := p.Providers[name]
+	if prov == nil {
+		prov = &Provider{}
+		p.Providers[name] = prov
+	}
+	return prov
+}
+
+// ProviderEnabled reports whether a provider is switched on.
+func (p *Profile) ProviderEnabled(name string) bool {
+	prov := p.Providers[name]
+	return prov != nil && prov.Enabled
+}
+
+// CredentialName returns the credential a provider's API key is stored
+// under.
+func (p *Profile) CredentialName(provider string) string {
+	if prov := p.Providers[provider]; prov != nil && prov.Credential != "" {
+		return prov.Credential
+	}
+	return provider
+}
+
+// ExpandHome replaces a leading "~" with the user's home directory.
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// Flatten returns every set value as dotted key/value pairs, sorted by key.
+func (c *Config) Flatten() ([][2]string, error) {
+	tree, err := c.tree()
+	if err != nil {
+		return nil, err
+	}
+	var out [][2]string
+	var walk func(prefix string, node map[string]any)
+	walk = func(prefix string, node map[string]any) {
+		for k, v := range node {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if child, ok := v.(map[string]any); ok {
+				walk(key, child)
+				continue
+			}
+			out = append(out, [2]string{key, fmt.Sprint(v)})
+		}
+	}
+	walk("", tree)
+	sort.Slice(out, func(i, j int) bool { return out[i][0] < out[j][0] })
+	return out, nil
+}
+
+// Get returns the value at a dotted key such as
+// "profiles.personal.location".
+func (c *Config) Get(key string) (string, error) {
+	tree, err := c.tree()
+	if err != nil {
+		return "", err
+	}
+	var node any = tree
+	for _, part := range strings.Split(key, ".") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownKey, key)
+		}
+		if node, ok = m[part]; !ok {
+			if isKnownKey(key) {
+				return "", nil
+			}
+			return "", fmt.Errorf("%w: %s", ErrUnknownKey, key)
+		}
+	}
+	if _, ok := node.(map[string]any); ok {
+		return "", fmt.Errorf("%s is a table; use a key inside it", key)
+	}
+	return fmt.Sprint(node), nil
+}
+
+// Set assigns value to a dotted key, creating profiles and providers as
+// needed. Booleans accept true/false; everything else is a string.
+func (c *Config) Set(key, value string) error {
+	if !isKnownKey(key) {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, key)
+	}
+	tree, err := c.tree()
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(key, ".")
+	node := tree
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := node[part].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			node[part] = child
+		}
+		node = child
+	}
+
+	leaf := parts[len(parts)-1]
+	if isBoolKey(leaf) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false", key)
+		}
+		node[leaf] = b
+	} else {
+		node[leaf] = value
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(tree); err != nil {
+		return err
+	}
+	updated := &Config{}
+	if _, err := toml.Decode(buf.String(), updated); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	for name := range updated.Profiles {
+		if !profileNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid profile name %q", name)
+		}
+	}
+	*c = *updated
+	return nil
+}
+
+// tree converts the config into nested maps keyed by TOML names.
+func (c *Config) tree() (map[string]any, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return nil, err
+	}
+	tree := make(map[string]any)
+	if _, err := toml.Decode(buf.String(), &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+var (
+	topLevelKeys = map[string]bool{"default_profile": true, "onboarded": true}
+	profileKeys  = map[string]bool{
+		"db_path": true, "backup_dir": true, "prefs_path": true, "location": true,
+		"encrypt_database": true, "credential_command": true, "keymap_path": true,
+		"theme": true, "date_order": true,
+	}
+	providerKeys = map[string]bool{"enabled": true, "credential": true}
+	boolKeys     = map[string]bool{"onboarded": true, "encrypt_database": true, "enabled": true}
+)
+
+// isKnownKey reports whether key names a setting, e.g. "default_profile",
+// "profiles.<name>.location" or "profiles.<name>.providers.<provider>.enabled".
+func isKnownKey(key string) bool {
+	parts := strings.Split(key, ".")
+	switch {
+	case len(parts) == 1:
+		return topLevelKeys[parts[0]]
+	case len(parts) == 3 && parts[0] == "profiles":
+		return profileKeys[parts[2]]
+	case len(parts) == 5 && parts[0] == "profiles" && parts[2] == "providers":
+		return providerKeys[parts[4]]
+	default:
+		return false
+	}
+}
+
+func isBoolKey(leaf string) bool {
+	return boolKeys[leaf]
+}
+
+// IsTopLevelKey reports whether key is a setting outside any profile.
+func IsTopLevelKey(key string) bool {
+	return topLevelKeys[key]
+}
@@ -0,0 +1,60 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const commandTimeout = 10 * time.Second
+
+// Command reads credentials from the output of an external helper such as a
+// password manager. Template is split on whitespace and every "{name}" is
+// replaced with the credential name, e.g. "pass show toni/{name}" or
+// "op read op://Private/toni/{name}". No shell is involved.
+type Command struct {
+	Template string
+}
+
+func (c Command) Get(name string) (string, error) {
+	fields := strings.Fields(c.Template)
+	if len(fields) == 0 {
+		return "", ErrNotFound
+	}
+	for i, f := range fields {
+		fields[i] = strings.ReplaceAll(f, "{name}", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, fields[0], fields[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// Helpers exit non-zero for missing entries; let the next source
+			// try.
+			return "", fmt.Errorf("%w: %s exited with %d: %s", ErrNotFound, fields[0], exitErr.ExitCode(), strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("failed to run %s: %w", fields[0], err)
+	}
+
+	// Password managers conventionally put the secret on the first line.
+	value, _, _ := strings.Cut(stdout.String(), "\n")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (Command) Set(name, value string) error { return ErrReadOnly }
+
+func (Command) Delete(name string) error { return ErrReadOnly }
@@ -0,0 +1,141 @@
+// Package credentials stores and resolves API keys for search providers.
+//
+// Keys are looked up through a chain of sources in priority order: the
+// environment, an external command (a password manager such as `pass` or
+// `op read`), the passphrase-encrypted vault, and finally the legacy
+// plaintext key files.
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Yelp is the credential name of the Yelp Fusion API key.
+const Yelp = "yelp"
+
+var (
+	// ErrNotFound is returned when a store has no value for a name.
+	ErrNotFound = errors.New("credential not found")
+	// ErrReadOnly is returned by stores that cannot be written to.
+	ErrReadOnly = errors.New("credential source is read-only")
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Store reads and writes credentials by name.
+type Store interface {
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+}
+
+// Source is a store together with the label reported to the user.
+type Source struct {
+	Label string
+	Store Store
+}
+
+// Chain resolves a credential from the first source that has it.
+type Chain []Source
+
+// Lookup returns the value of name and the label of the source it came from.
+// Sources that do not have the credential are skipped; any other error stops
+// the lookup.
+func (c Chain) Lookup(name string) (string, string, error) {
+	for _, src := range c {
+		value, err := src.Store.Get(name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", src.Label, fmt.Errorf("%s: %w", src.Label, err)
+		}
+		return value, src.Label, nil
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// ValidateName checks that name is usable as a credential name.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid credential name %q (use lowercase letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+// EnvVar returns the environment variable holding credential name, e.g.
+// YELP_API_KEY for "yelp".
+func EnvVar(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_API_KEY"
+}
+
+// Env reads credentials from environment variables named by EnvVar.
+type Env struct{}
+
+func (Env) Get(name string) (string, error) {
+	value := strings.TrimSpace(os.Getenv(EnvVar(name)))
+	if value == "" {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (Env) Set(name, value string) error { return ErrReadOnly }
+
+func (Env) Delete(name string) error { return ErrReadOnly }
+
+// Lazy defers opening a store until it is first used, so that a vault only
+// asks for its passphrase when an earlier source did not have the key.
+func Lazy(open func() (Store, error)) Store {
+	return &lazyStore{open: open}
+}
+
+type lazyStore struct {
+	open  func() (Store, error)
+	store Store
+	err   error
+}
+
+func (l *lazyStore) get() (Store, error) {
+	if l.store == nil && l.err == nil {
+		l.store, l.err = l.open()
+	}
+	return l.store, l.err
+}
+
+func (l *lazyStore) Get(name string) (string, error) {
+	s, err := l.get()
+	if err != nil {
+		return "", err
+	}
+	return s.Get(name)
+}
+
+func (l *lazyStore) Set(name, value string) error {
+	s, err := l.get()
+	if err != nil {
+		return err
+	}
+	return s.Set(name, value)
+}
+
+func (l *lazyStore) Delete(name string) error {
+	s, err := l.get()
+	if err != nil {
+		return err
+	}
+	return s.Delete(name)
+}
+
+// WorldReadable reports whether the file at path can be read by any user.
+func WorldReadable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return info.Mode().Perm()&0004 != 0
+}
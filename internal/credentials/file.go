@@ -0,0 +1,50 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File stores each credential in plaintext as <Dir>/<name>_api_key with
+// owner-only permissions. It is how toni kept the Yelp key before the vault
+// existed and remains the fallback when no vault is in use.
+type File struct {
+	Dir string
+}
+
+// Path returns the file holding credential name.
+func (f File) Path(name string) string {
+	return filepath.Join(f.Dir, name+"_api_key")
+}
+
+func (f File) Get(name string) (string, error) {
+	data, err := os.ReadFile(f.Path(name))
+	if os.IsNotExist(err) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (f File) Set(name, value string) error {
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return err
+	}
+	// Owner read/write only.
+	return os.WriteFile(f.Path(name), []byte(strings.TrimSpace(value)+"\n"), 0600)
+}
+
+func (f File) Delete(name string) error {
+	err := os.Remove(f.Path(name))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
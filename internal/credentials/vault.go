@@ -0,0 +1,97 @@
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"toni/internal/secure"
+)
+
+// Vault is a passphrase-encrypted file holding credentials as a sealed JSON
+// object.
+type Vault struct {
+	path    string
+	key     *secure.Key
+	entries map[string]string
+}
+
+// OpenVault opens the vault at path with key. A missing file is an empty
+// vault that is created on the first Set.
+func OpenVault(path string, key *secure.Key) (*Vault, error) {
+	v := &Vault{path: path, key: key, entries: make(map[string]string)}
+
+	sealed, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential vault: %w", err)
+	}
+	plaintext, err := key.Unseal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(plaintext, &v.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse credential vault: %w", err)
+	}
+	return v, nil
+}
+
+func (v *Vault) Get(name string) (string, error) {
+	value, ok := v.entries[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (v *Vault) Set(name, value string) error {
+	v.entries[name] = value
+	return v.save()
+}
+
+func (v *Vault) Delete(name string) error {
+	if _, ok := v.entries[name]; !ok {
+		return ErrNotFound
+	}
+	delete(v.entries, name)
+	return v.save()
+}
+
+// Names returns the stored credential names in order.
+func (v *Vault) Names() []string {
+	names := make([]string, 0, len(v.entries))
+	for name := range v.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rekey re-seals the vault with a new key.
+func (v *Vault) Rekey(key *secure.Key) error {
+	old := v.key
+	v.key = key
+	if err := v.save(); err != nil {
+		v.key = old
+		return err
+	}
+	return nil
+}
+
+func (v *Vault) save() error {
+	plaintext, err := json.Marshal(v.entries)
+	if err != nil {
+		return err
+	}
+	sealed, err := v.key.Seal(plaintext)
+	if err != nil {
+		return err
+	}
+	if err := secure.WriteFileAtomic(v.path, sealed); err != nil {
+		return fmt.Errorf("failed to write credential vault: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"toni/internal/model"
+)
+
+// Bulk operations act on many rows in a single transaction: either every row
+// changes or none does. Each returns what it needs to be undone, and the
+// matching Restore function puts that back, again in one transaction.
+
+// DeleteVisits deletes visits and returns them as they were.
+func DeleteVisits(db *sql.DB, ids []int64) ([]model.Visit, error) {
+	var deleted []model.Visit
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			v, err := GetVisit(tx, id)
+			if err != nil {
+				return err
+			}
+			if err := DeleteVisit(tx, id); err != nil {
+				return err
+			}
+			deleted = append(deleted, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RestoreVisits puts back visits removed by DeleteVisits.
+func RestoreVisits(db *sql.DB, visits []model.Visit) error {
+	return InTx(db, func(tx *sql.Tx) error {
+		for _, v := range visits {
+			if err := InsertVisitWithID(tx, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteWantToVisits deletes want_to_visit entries and returns them as they
+// were.
+func DeleteWantToVisits(db *sql.DB, ids []int64) ([]model.WantToVisit, error) {
+	var deleted []model.WantToVisit
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			w, err := GetWantToVisit(tx, id)
+			if err != nil {
+				return err
+			}
+			if err := DeleteWantToVisit(tx, id); err != nil {
+				return err
+			}
+			deleted = append(deleted, w)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RestoreWantToVisits puts back entries removed by DeleteWantToVisits.
+func RestoreWantToVisits(db *sql.DB, entries []model.WantToVisit) error {
+	return InTx(db, func(tx *sql.Tx) error {
+		for _, w := range entries {
+			if err := InsertWantToVisitWithID(tx, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SnapshotRestaurant returns a restaurant with its visits, want_to_visit
+// entries, planned visits and tags.
+func SnapshotRestaurant(db Querier, id int64) (model.RestaurantSnapshot, error) {
+	r, err := GetRestaurant(db, id)
+	if err != nil {
+		return model.RestaurantSnapshot{}, err
+	}
+	visits, err := GetVisitsByRestaurant(db, id)
+	if err != nil {
+		return model.RestaurantSnapshot{}, err
+	}
+	entries, err := GetWantToVisitByRestaurant(db, id)
+	if err != nil {
+		return model.RestaurantSnapshot{}, err
+	}
+	plans, err := GetPlannedVisitsByRestaurant(db, id)
+	if err != nil {
+		return model.RestaurantSnapshot{}, err
+	}
+	tags, err := GetRestaurantTags(db, id)
+	if err != nil {
+		return model.RestaurantSnapshot{}, err
+	}
+	return model.RestaurantSnapshot{Restaurant: r, Visits: visits, WantToVisit: entries, PlannedVisits: plans, Tags: tags}, nil
+}
+
+// RestoreRestaurant inserts a restaurant and everything that hung off it.
+func RestoreRestaurant(db Querier, s model.RestaurantSnapshot) error {
+	if err := InsertRestaurantWithID(db, s.Restaurant); err != nil {
+		return err
+	}
+	for _, w := range s.WantToVisit {
+		if err := InsertWantToVisitWithID(db, w); err != nil {
+			return err
+		}
+	}
+	for _, v := range s.Visits {
+		if err := InsertVisitWithID(db, v); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.PlannedVisits {
+		if err := InsertPlannedVisitWithID(db, p); err != nil {
+			return err
+		}
+	}
+	for _, tag := range s.Tags {
+		if _, err := AddRestaurantTag(db, s.Restaurant.ID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteRestaurants deletes restaurants with their visits, want_to_visit
+// entries, planned visits and tags, and returns snapshots of them.
+func DeleteRestaurants(db *sql.DB, ids []int64) ([]model.RestaurantSnapshot, error) {
+	var deleted []model.RestaurantSnapshot
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			s, err := SnapshotRestaurant(tx, id)
+			if err != nil {
+				return err
+			}
+			if err := deleteRestaurant(tx, id); err != nil {
+				return err
+			}
+			deleted = append(deleted, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// RestoreRestaurants puts back restaurants removed by DeleteRestaurants.
+func RestoreRestaurants(db *sql.DB, snapshots []model.RestaurantSnapshot) error {
+	return InTx(db, func(tx *sql.Tx) error {
+		for _, s := range snapshots {
+			if err := RestoreRestaurant(tx, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// restaurantFieldColumns lists the restaurant fields SetRestaurantField can
+// change, by the name used in commands.
+var restaurantFieldColumns = map[string]string{
+	"city":    "city",
+	"cuisine": "cuisine",
+}
+
+// SetRestaurantField sets the city or cuisine of restaurants and returns the
+// previous values. An empty value clears the field.
+func SetRestaurantField(db *sql.DB, field string, ids []int64, value string) ([]model.FieldValue, error) {
+	column, ok := restaurantFieldColumns[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown restaurant field %q (want city or cuisine)", field)
+	}
+	var before []model.FieldValue
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			var old sql.NullString
+			if err := tx.QueryRow("SELECT "+column+" FROM restaurants WHERE id = ?", id).Scan(&old); err != nil {
+				return fmt.Errorf("failed to get restaurant %s: %w", field, err)
+			}
+			if err := setRestaurantColumn(tx, column, id, value); err != nil {
+				return err
+			}
+			before = append(before, model.FieldValue{ID: id, Value: old.String})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+// RestoreRestaurantField puts back the values returned by SetRestaurantField.
+func RestoreRestaurantField(db *sql.DB, field string, values []model.FieldValue) error {
+	column, ok := restaurantFieldColumns[field]
+	if !ok {
+		return fmt.Errorf("unknown restaurant field %q (want city or cuisine)", field)
+	}
+	return InTx(db, func(tx *sql.Tx) error {
+		for _, v := range values {
+			if err := setRestaurantColumn(tx, column, v.ID, v.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func setRestaurantColumn(db Querier, column string, id int64, value string) error {
+	var v interface{}
+	if value != "" {
+		v = value
+	}
+	if _, err := db.Exec("UPDATE restaurants SET "+column+" = ? WHERE id = ?", v, id); err != nil {
+		return fmt.Errorf("failed to update restaurant %s: %w", column, err)
+	}
+	return nil
+}
+
+// SetWantToVisitPriority sets the priority of want_to_visit entries and
+// returns them as they were. A nil priority clears it.
+func SetWantToVisitPriority(db *sql.DB, ids []int64, priority *int) ([]model.WantToVisit, error) {
+	var before []model.WantToVisit
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			w, err := GetWantToVisit(tx, id)
+			if err != nil {
+				return err
+			}
+			update := model.UpdateWantToVisit{ID: w.ID, RestaurantID: w.RestaurantID, Notes: w.Notes, Priority: priority}
+			if err := UpdateWantToVisit(tx, update); err != nil {
+				return err
+			}
+			before = append(before, w)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return before, nil
+}
+
+// RestoreWantToVisitPriorities puts back the priorities of entries returned
+// by SetWantToVisitPriority.
+func RestoreWantToVisitPriorities(db *sql.DB, entries []model.WantToVisit) error {
+	return InTx(db, func(tx *sql.Tx) error {
+		for _, w := range entries {
+			update := model.UpdateWantToVisit{ID: w.ID, RestaurantID: w.RestaurantID, Notes: w.Notes, Priority: w.Priority}
+			if err := UpdateWantToVisit(tx, update); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddToWantToVisit adds restaurants to the want_to_visit list and returns
+// the new entries. Restaurants already on the list are skipped.
+func AddToWantToVisit(db *sql.DB, restaurantIDs []int64) ([]model.WantToVisit, error) {
+	var added []model.WantToVisit
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, id := range restaurantIDs {
+			var listed bool
+			if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM want_to_visit WHERE restaurant_id = ?)", id).Scan(&listed); err != nil {
+				return fmt.Errorf("failed to check want_to_visit list: %w", err)
+			}
+			if listed {
+				continue
+			}
+			entryID, err := InsertWantToVisit(tx, model.NewWantToVisit{RestaurantID: id})
+			if err != nil {
+				return err
+			}
+			w, err := GetWantToVisit(tx, entryID)
+			if err != nil {
+				return err
+			}
+			added = append(added, w)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// AddRestaurantTags adds tags to restaurants and returns the ones that were
+// new.
+func AddRestaurantTags(db *sql.DB, tags []model.RestaurantTag) ([]model.RestaurantTag, error) {
+	var added []model.RestaurantTag
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, t := range tags {
+			ok, err := AddRestaurantTag(tx, t.RestaurantID, t.Tag)
+			if err != nil {
+				return err
+			}
+			if ok {
+				added = append(added, t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// RemoveRestaurantTags removes tags from restaurants and returns the ones
+// that were there.
+func RemoveRestaurantTags(db *sql.DB, tags []model.RestaurantTag) ([]model.RestaurantTag, error) {
+	var removed []model.RestaurantTag
+	err := InTx(db, func(tx *sql.Tx) error {
+		for _, t := range tags {
+			ok, err := RemoveRestaurantTag(tx, t.RestaurantID, t.Tag)
+			if err != nil {
+				return err
+			}
+			if ok {
+				removed = append(removed, t)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
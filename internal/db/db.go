@@ -1,12 +1,23 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"toni/internal/secure"
 
 	_ "modernc.org/sqlite"
 )
 
+// busyTimeoutMillis is how long a connection waits on a locked database
+// before giving up with SQLITE_BUSY.
+const busyTimeoutMillis = 5000
+
 const schema = `
 CREATE TABLE IF NOT EXISTS restaurants (
     id           INTEGER PRIMARY KEY,
@@ -46,12 +57,31 @@ CREATE INDEX IF NOT EXISTS idx_want_to_visit_restaurant_id ON want_to_visit(rest
 CREATE INDEX IF NOT EXISTS idx_want_to_visit_priority ON want_to_visit(priority DESC);
 `
 
-// Open opens or creates the SQLite database and initializes the schema.
+// Options customizes how Open prepares the database.
+type Options struct {
+	// BeforeMigrate is called when schema migrations are about to run on a
+	// database that already holds data, so callers can take a backup first.
+	// Returning an error aborts the open.
+	BeforeMigrate func(db *sql.DB, from, to int) error
+}
+
+// Open opens or creates the SQLite database, configures the connection and
+// brings the schema up to date.
+//
+// Every connection runs in WAL mode with foreign keys enforced and a busy
+// timeout, and the pool is limited to a single connection so all writes are
+// serialized through one writer.
 func Open(dbPath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	return OpenWithOptions(dbPath, Options{})
+}
+
+// OpenWithOptions is Open with caller-supplied hooks.
+func OpenWithOptions(dbPath string, opts Options) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", dsn(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(1)
 
 	if err := db.Ping(); err != nil {
 		db.Close()
@@ -63,5 +93,99 @@ func Open(dbPath string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	if err := migrate(context.Background(), db, opts.BeforeMigrate); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return db, nil
 }
+
+// OpenReadOnly opens an existing database, such as one shared by someone
+// else, without ever writing to it: the file is opened read-only, nothing is
+// created or migrated, and the connection refuses writes. Queries on it must
+// only use tables of the original schema, since the file may be older than
+// this version of toni.
+func OpenReadOnly(dbPath string) (*sql.DB, error) {
+	head := make([]byte, 64)
+	f, err := os.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	n, _ := io.ReadFull(f, head)
+	f.Close()
+	if secure.IsSealed(head[:n]) {
+		return nil, fmt.Errorf("%s is encrypted; ask for an unencrypted copy", dbPath)
+	}
+
+	abs, err := filepath.Abs(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	params := url.Values{}
+	params.Set("mode", "ro")
+	// A database in WAL mode gets -wal and -shm files created next to it
+	// even when opened read-only. Unless it is in use and has a write-ahead
+	// log already, it is opened as immutable, which creates nothing.
+	if _, err := os.Stat(abs + "-wal"); err != nil {
+		params.Set("immutable", "1")
+	}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeoutMillis))
+	params.Add("_pragma", "query_only(1)")
+	uri := url.URL{Scheme: "file", Path: abs, RawQuery: params.Encode()}
+	db, err := sql.Open("sqlite", uri.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	var tables int
+	err = db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('restaurants', 'visits')`).Scan(&tables)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to read database: %w", err)
+	}
+	if tables != 2 {
+		db.Close()
+		return nil, fmt.Errorf("%s is not a toni database", dbPath)
+	}
+	return db, nil
+}
+
+// dsn builds the driver connection string for dbPath. The pragmas are applied
+// by the driver to every new connection, so they survive pool recycling.
+func dsn(dbPath string) string {
+	params := url.Values{}
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeoutMillis))
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Set("_txlock", "immediate")
+	return dbPath + "?" + params.Encode()
+}
+
+// Querier is satisfied by both *sql.DB and *sql.Tx, so single-row helpers can
+// run on their own or as part of a larger transaction.
+type Querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// InTx runs fn in a transaction, committing if it returns nil and rolling
+// back otherwise. The pool holds a single connection, so fn must only use tx.
+func InTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package db
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"toni/internal/secure"
+)
+
+// EncryptedDB is a database whose file at rest is sealed with a passphrase.
+//
+// On open the sealed file is decrypted into a private scratch directory
+// (memory-backed where the platform offers one) and SQLite works on that copy.
+// Sync seals the working copy back over the encrypted file; Close syncs and
+// wipes the scratch directory.
+type EncryptedDB struct {
+	*sql.DB
+
+	path    string
+	workDir string
+	key     *secure.Key
+
+	mu       sync.Mutex
+	lastHash [sha256.Size]byte
+	stop     chan struct{}
+	closed   bool
+}
+
+// OpenEncrypted opens the sealed database at path with key. A missing file
+// starts a new empty database that is sealed on the first Sync.
+func OpenEncrypted(path string, key *secure.Key, opts Options) (*EncryptedDB, error) {
+	workDir, err := secure.ScratchDir()
+	if err != nil {
+		return nil, err
+	}
+	e := &EncryptedDB{path: path, workDir: workDir, key: key}
+
+	sealed, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		plaintext, err := key.Unseal(sealed)
+		if err != nil {
+			e.wipe()
+			return nil, err
+		}
+		if err := os.WriteFile(e.plainPath(), plaintext, 0600); err != nil {
+			e.wipe()
+			return nil, fmt.Errorf("failed to write working copy: %w", err)
+		}
+		e.lastHash = sha256.Sum256(plaintext)
+	case os.IsNotExist(err):
+	default:
+		e.wipe()
+		return nil, fmt.Errorf("failed to read encrypted database: %w", err)
+	}
+
+	e.DB, err = OpenWithOptions(e.plainPath(), opts)
+	if err != nil {
+		e.wipe()
+		return nil, err
+	}
+	if err := e.Sync(); err != nil {
+		e.DB.Close()
+		e.wipe()
+		return nil, err
+	}
+	return e, nil
+}
+
+// EncryptFile seals the plaintext database at plainPath into encPath with key.
+// The plaintext file is left in place for the caller to remove.
+func EncryptFile(plainPath, encPath string, key *secure.Key) error {
+	source, err := OpenWithOptions(plainPath, Options{})
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	workDir, err := secure.ScratchDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	plaintext, err := snapshotBytes(source, workDir)
+	if err != nil {
+		return err
+	}
+	sealed, err := key.Seal(plaintext)
+	if err != nil {
+		return err
+	}
+	if err := secure.WriteFileAtomic(encPath, sealed); err != nil {
+		return fmt.Errorf("failed to write encrypted database: %w", err)
+	}
+	return nil
+}
+
+// snapshotBytes returns a consistent copy of database, taken with VACUUM INTO
+// a scratch file in workDir. The copy is read in one transaction, so writes
+// made meanwhile can't tear it, and it folds in any WAL content.
+func snapshotBytes(database *sql.DB, workDir string) ([]byte, error) {
+	snapshot := filepath.Join(workDir, "snapshot.db")
+	// VACUUM INTO refuses to overwrite a file.
+	if err := os.Remove(snapshot); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove old snapshot: %w", err)
+	}
+	defer os.Remove(snapshot)
+	if _, err := database.Exec("VACUUM INTO ?", snapshot); err != nil {
+		return nil, fmt.Errorf("failed to snapshot database: %w", err)
+	}
+	plaintext, err := os.ReadFile(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return plaintext, nil
+}
+
+// RemovePlaintext deletes a plaintext database together with its WAL and
+// shared-memory files.
+func RemovePlaintext(dbPath string) error {
+	for _, p := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Key returns the key the database is sealed with.
+func (e *EncryptedDB) Key() *secure.Key {
+	return e.key
+}
+
+// WorkDir returns the scratch directory holding the decrypted working copy.
+// Other plaintext scratch files (backup snapshots) belong there too so they
+// are wiped on Close.
+func (e *EncryptedDB) WorkDir() string {
+	return e.workDir
+}
+
+// Sync seals a snapshot of the working copy over the encrypted file. It does
+// nothing if the data has not changed since the last sync.
+func (e *EncryptedDB) Sync() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.syncLocked(false)
+}
+
+func (e *EncryptedDB) syncLocked(force bool) error {
+	if e.closed {
+		return errors.New("encrypted database is closed")
+	}
+	// The TUI keeps writing while auto-sync runs, so the working copy itself
+	// may be mid-commit; seal a snapshot of it instead.
+	plaintext, err := snapshotBytes(e.DB, e.workDir)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(plaintext)
+	if !force && bytes.Equal(hash[:], e.lastHash[:]) {
+		if _, err := os.Stat(e.path); err == nil {
+			return nil
+		}
+	}
+	sealed, err := e.key.Seal(plaintext)
+	if err != nil {
+		return err
+	}
+	if err := secure.WriteFileAtomic(e.path, sealed); err != nil {
+		return fmt.Errorf("failed to write encrypted database: %w", err)
+	}
+	e.lastHash = hash
+	return nil
+}
+
+// Rekey re-seals the database with a new key.
+func (e *EncryptedDB) Rekey(key *secure.Key) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old := e.key
+	e.key = key
+	if err := e.syncLocked(true); err != nil {
+		e.key = old
+		return err
+	}
+	return nil
+}
+
+// StartAutoSync seals the working copy every interval until Close, so a
+// crash loses at most one interval of changes. Errors are passed to onError.
+func (e *EncryptedDB) StartAutoSync(interval time.Duration, onError func(error)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stop != nil || e.closed {
+		return
+	}
+	e.stop = make(chan struct{})
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := e.Sync(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}(e.stop)
+}
+
+// Close seals the latest changes, closes the database and removes the
+// decrypted working copy. The scratch directory is removed even if sealing
+// fails, in which case the encrypted file keeps its previous contents.
+func (e *EncryptedDB) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	if e.stop != nil {
+		close(e.stop)
+	}
+	syncErr := e.syncLocked(false)
+	e.closed = true
+	closeErr := e.DB.Close()
+	e.wipe()
+	if syncErr != nil {
+		return syncErr
+	}
+	return closeErr
+}
+
+func (e *EncryptedDB) plainPath() string {
+	return filepath.Join(e.workDir, "toni.db")
+}
+
+func (e *EncryptedDB) wipe() {
+	_ = os.RemoveAll(e.workDir)
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// IntegrityReport summarizes problems found by CheckIntegrity.
+type IntegrityReport struct {
+	// Problems holds the messages returned by PRAGMA integrity_check when the
+	// file itself is damaged. It is empty for a healthy database.
+	Problems []string
+	// OrphanedVisits holds IDs of visits whose restaurant no longer exists.
+	OrphanedVisits []int64
+	// OrphanedWantToVisit holds IDs of wishlist entries whose restaurant no
+	// longer exists.
+	OrphanedWantToVisit []int64
+}
+
+// OK reports whether no problems were found.
+func (r IntegrityReport) OK() bool {
+	return len(r.Problems) == 0 && !r.HasOrphans()
+}
+
+// HasOrphans reports whether any rows reference a missing restaurant.
+func (r IntegrityReport) HasOrphans() bool {
+	return len(r.OrphanedVisits) > 0 || len(r.OrphanedWantToVisit) > 0
+}
+
+// CheckIntegrity runs SQLite's integrity check and looks for rows left
+// behind by versions of toni that did not enforce foreign keys.
+func CheckIntegrity(db *sql.DB) (IntegrityReport, error) {
+	var report IntegrityReport
+
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return report, fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			rows.Close()
+			return report, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		if msg != "ok" {
+			report.Problems = append(report.Problems, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return report, fmt.Errorf("error iterating integrity check: %w", err)
+	}
+	rows.Close()
+
+	rows, err = db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return report, fmt.Errorf("failed to run foreign key check: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int64
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			return report, fmt.Errorf("failed to scan foreign key check: %w", err)
+		}
+		if !rowID.Valid {
+			continue
+		}
+		switch table {
+		case "visits":
+			report.OrphanedVisits = append(report.OrphanedVisits, rowID.Int64)
+		case "want_to_visit":
+			report.OrphanedWantToVisit = append(report.OrphanedWantToVisit, rowID.Int64)
+		default:
+			report.Problems = append(report.Problems, fmt.Sprintf("row %d in %s references missing %s", rowID.Int64, table, parent))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("error iterating foreign key check: %w", err)
+	}
+
+	return report, nil
+}
+
+// RepairOrphans deletes the orphaned rows listed in report in a single
+// transaction.
+func RepairOrphans(db *sql.DB, report IntegrityReport) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range report.OrphanedVisits {
+		if _, err := tx.Exec("DELETE FROM visits WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete orphaned visit %d: %w", id, err)
+		}
+	}
+	for _, id := range report.OrphanedWantToVisit {
+		if _, err := tx.Exec("DELETE FROM want_to_visit WHERE id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete orphaned want_to_visit %d: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration upgrades the schema by one version. Migrations run inside a
+// transaction with foreign key enforcement switched off so tables can be
+// rebuilt without tripping over rows that predate the constraints.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations lists every schema change in order. Each version is recorded in
+// PRAGMA user_version once it has been applied.
+var migrations = []migration{
+	{version: 1, name: "cascade deletes from restaurants", up: migrateCascadeDeletes},
+	{version: 2, name: "restaurant tags", up: migrateRestaurantTags},
+	{version: 3, name: "want to visit source", up: migrateWantToVisitSource},
+	{version: 4, name: "planned visits", up: migratePlannedVisits},
+	{version: 5, name: "want to visit notes on visits", up: migrateVisitWishlistNotes},
+}
+
+// SchemaVersion returns the schema version recorded in the database.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// LatestSchemaVersion returns the version the schema is migrated to by Open.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func migrate(ctx context.Context, db *sql.DB, beforeMigrate func(db *sql.DB, from, to int) error) error {
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if current >= LatestSchemaVersion() {
+		return nil
+	}
+
+	if beforeMigrate != nil {
+		var restaurants int
+		if err := db.QueryRow("SELECT COUNT(*) FROM restaurants").Scan(&restaurants); err != nil {
+			return fmt.Errorf("failed to inspect database before migration: %w", err)
+		}
+		if current > 0 || restaurants > 0 {
+			if err := beforeMigrate(db, current, LatestSchemaVersion()); err != nil {
+				return fmt.Errorf("pre-migration hook failed: %w", err)
+			}
+		}
+	}
+
+	// PRAGMA foreign_keys is a no-op inside a transaction, so pin a single
+	// connection and toggle it around the migration transactions.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return fmt.Errorf("failed to disable foreign keys for migration: %w", err)
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+	}
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.version)); err != nil {
+		return fmt.Errorf("failed to record schema version %d: %w", m.version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// migrateCascadeDeletes rebuilds the child tables so their restaurant
+// references cascade on delete. Orphaned rows are carried over untouched and
+// reported by CheckIntegrity rather than silently dropped.
+func migrateCascadeDeletes(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE visits_new (
+			id            INTEGER PRIMARY KEY,
+			restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+			visited_on    TEXT,
+			rating        REAL CHECK(rating BETWEEN 1 AND 10 OR rating IS NULL),
+			notes         TEXT,
+			would_return  INTEGER CHECK(would_return IN (0,1) OR would_return IS NULL),
+			created_at    TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		)`,
+		`INSERT INTO visits_new (id, restaurant_id, visited_on, rating, notes, would_return, created_at)
+			SELECT id, restaurant_id, visited_on, rating, notes, would_return, created_at FROM visits`,
+		`DROP TABLE visits`,
+		`ALTER TABLE visits_new RENAME TO visits`,
+		`CREATE INDEX IF NOT EXISTS idx_visits_restaurant_id ON visits(restaurant_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_visits_visited_on ON visits(visited_on DESC)`,
+
+		`CREATE TABLE want_to_visit_new (
+			id            INTEGER PRIMARY KEY,
+			restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+			notes         TEXT,
+			priority      INTEGER CHECK(priority BETWEEN 1 AND 5 OR priority IS NULL),
+			created_at    TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		)`,
+		`INSERT INTO want_to_visit_new (id, restaurant_id, notes, priority, created_at)
+			SELECT id, restaurant_id, notes, priority, created_at FROM want_to_visit`,
+		`DROP TABLE want_to_visit`,
+		`ALTER TABLE want_to_visit_new RENAME TO want_to_visit`,
+		`CREATE INDEX IF NOT EXISTS idx_want_to_visit_restaurant_id ON want_to_visit(restaurant_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_want_to_visit_priority ON want_to_visit(priority DESC)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateRestaurantTags adds the table holding free-form restaurant tags.
+func migrateRestaurantTags(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS restaurant_tags (
+			restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+			tag           TEXT NOT NULL,
+			PRIMARY KEY (restaurant_id, tag)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_restaurant_tags_tag ON restaurant_tags(tag)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateWantToVisitSource records where want_to_visit entries came from,
+// such as a bundle shared by a friend.
+func migrateWantToVisitSource(tx *sql.Tx) error {
+	_, err := tx.Exec(`ALTER TABLE want_to_visit ADD COLUMN source TEXT`)
+	return err
+}
+
+// migratePlannedVisits adds the table of visits planned for later, such as
+// reservations. planned_at is the local time as HH:MM, or NULL for a plan
+// without one.
+func migratePlannedVisits(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS planned_visits (
+			id            INTEGER PRIMARY KEY,
+			restaurant_id INTEGER NOT NULL REFERENCES restaurants(id) ON DELETE CASCADE,
+			planned_on    TEXT NOT NULL,
+			planned_at    TEXT,
+			party_size    INTEGER CHECK(party_size > 0 OR party_size IS NULL),
+			notes         TEXT,
+			created_at    TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_planned_visits_restaurant_id ON planned_visits(restaurant_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_planned_visits_planned_on ON planned_visits(planned_on)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateVisitWishlistNotes keeps the notes and priority of the want_to_visit
+// entry a visit was marked visited from on the visit, for reference.
+func migrateVisitWishlistNotes(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE visits ADD COLUMN wishlist_notes TEXT`,
+		`ALTER TABLE visits ADD COLUMN wishlist_priority INTEGER`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
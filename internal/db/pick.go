@@ -0,0 +1,117 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"toni/internal/model"
+	"toni/internal/query"
+)
+
+// PickQuerySchema describes the fields picker queries can use. Columns refer
+// to the row source in ListPickCandidatesWhere.
+var PickQuerySchema = query.Schema{
+	Fields: []query.Field{
+		{Name: "name", Column: "name", Kind: query.KindText},
+		{Name: "city", Column: "city", Kind: query.KindText},
+		{Name: "address", Column: "address", Kind: query.KindText},
+		{Name: "area", Aliases: []string{"neighborhood", "neighbourhood"}, Column: "neighborhood", Kind: query.KindText},
+		{Name: "cuisine", Column: "cuisine", Kind: query.KindText},
+		{Name: "price", Column: "price_range", Kind: query.KindText},
+		{Name: "rating", Column: "avg_rating", Kind: query.KindNumber},
+		{Name: "priority", Column: "priority", Kind: query.KindNumber},
+		{Name: "visits", Column: "visit_count", Kind: query.KindNumber},
+		{Name: "return", Aliases: []string{"would_return"}, Column: "would_return", Kind: query.KindBool},
+		tagQueryField("l.id"),
+		{Name: "visited", Aliases: []string{"last"}, Column: "last_visit", Kind: query.KindDate},
+	},
+	Text: []string{"name", "city"},
+}
+
+// ListPickCandidatesWhere returns the restaurants matching an SQL condition
+// over the columns of PickQuerySchema, as compiled by the query package,
+// with their wishlist priority and visit stats. Those columns include
+// RestaurantQuerySchema's, so conditions compiled with either work. An
+// empty condition matches every restaurant.
+func ListPickCandidatesWhere(db *sql.DB, where string, args []any) ([]model.PickCandidate, error) {
+	if where == "" {
+		where = "1"
+	}
+	query := `
+		SELECT
+			id,
+			name,
+			COALESCE(address, ''),
+			COALESCE(city, ''),
+			COALESCE(neighborhood, ''),
+			COALESCE(cuisine, ''),
+			COALESCE(price_range, ''),
+			latitude,
+			longitude,
+			wishlisted,
+			priority,
+			avg_rating,
+			visit_count,
+			last_visit,
+			would_return
+		FROM (
+			SELECT
+				r.id, r.name, r.address, r.city, r.neighborhood, r.cuisine, r.price_range,
+				r.latitude, r.longitude,
+				EXISTS (SELECT 1 FROM want_to_visit w WHERE w.restaurant_id = r.id) AS wishlisted,
+				(SELECT MAX(w.priority) FROM want_to_visit w WHERE w.restaurant_id = r.id) AS priority,
+				(SELECT AVG(v.rating) FROM visits v WHERE v.restaurant_id = r.id) AS avg_rating,
+				(SELECT COUNT(*) FROM visits v WHERE v.restaurant_id = r.id) AS visit_count,
+				(SELECT MAX(v.visited_on) FROM visits v WHERE v.restaurant_id = r.id) AS last_visit,
+				(SELECT v.would_return FROM visits v
+					WHERE v.restaurant_id = r.id AND v.would_return IS NOT NULL
+					ORDER BY v.visited_on DESC, v.id DESC LIMIT 1) AS would_return
+			FROM restaurants r
+		) l
+		WHERE ` + where + `
+		ORDER BY name
+	`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pick candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var results []model.PickCandidate
+	for rows.Next() {
+		var c model.PickCandidate
+		var lat, lng, avgRating sql.NullFloat64
+		var priority sql.NullInt64
+		var lastVisit sql.NullString
+		var wouldReturn sql.NullBool
+		if err := rows.Scan(
+			&c.RestaurantID, &c.Name, &c.Address, &c.City, &c.Neighborhood, &c.Cuisine, &c.PriceRange,
+			&lat, &lng, &c.Wishlisted, &priority, &avgRating, &c.VisitCount, &lastVisit, &wouldReturn,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan pick candidate: %w", err)
+		}
+		if lat.Valid && lng.Valid {
+			c.Latitude, c.Longitude = &lat.Float64, &lng.Float64
+		}
+		if priority.Valid {
+			p := int(priority.Int64)
+			c.Priority = &p
+		}
+		if avgRating.Valid {
+			c.AvgRating = &avgRating.Float64
+		}
+		if lastVisit.Valid {
+			c.LastVisit = lastVisit.String
+		}
+		if wouldReturn.Valid {
+			c.WouldReturn = &wouldReturn.Bool
+		}
+		results = append(results, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pick candidates: %w", err)
+	}
+
+	return results, nil
+}
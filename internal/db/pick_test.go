@@ -0,0 +1,25 @@
+package db
+
+import "testing"
+
+// toni export lists pick candidates with queries compiled by
+// RestaurantQuerySchema, so every one of its fields must work there.
+func TestRestaurantQueriesListPickCandidates(t *testing.T) {
+	database := openTestDB(t)
+	values := map[string]string{"rating": "8", "visits": "2", "visited": "2025-03", "tag": "favs"}
+	for _, f := range RestaurantQuerySchema.Fields {
+		value, ok := values[f.Name]
+		if !ok {
+			value = "x"
+		}
+		q := f.Name + ":" + value
+		where, args, err := RestaurantQuerySchema.Compile(q)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", q, err)
+			continue
+		}
+		if _, err := ListPickCandidatesWhere(database, where, args); err != nil {
+			t.Errorf("ListPickCandidatesWhere(%q): %v", q, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"toni/internal/model"
+)
+
+// ListPlannedVisits returns the visits planned on or after from (YYYY-MM-DD),
+// soonest first, with restaurant info. An empty from returns every plan.
+func ListPlannedVisits(db *sql.DB, from string) ([]model.PlannedVisitRow, error) {
+	rows, err := db.Query(`
+		SELECT
+			p.id,
+			p.planned_on,
+			COALESCE(p.planned_at, ''),
+			p.party_size,
+			COALESCE(p.notes, ''),
+			p.restaurant_id,
+			r.name,
+			COALESCE(r.address, ''),
+			COALESCE(r.city, ''),
+			r.latitude,
+			r.longitude
+		FROM planned_visits p
+		JOIN restaurants r ON p.restaurant_id = r.id
+		WHERE ? = '' OR p.planned_on >= ?
+		ORDER BY p.planned_on, p.planned_at NULLS FIRST, p.id
+	`, from, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list planned visits: %w", err)
+	}
+	defer rows.Close()
+
+	var results []model.PlannedVisitRow
+	for rows.Next() {
+		var p model.PlannedVisitRow
+		var partySize sql.NullInt64
+		var latitude, longitude sql.NullFloat64
+		if err := rows.Scan(&p.ID, &p.PlannedOn, &p.PlannedAt, &partySize, &p.Notes, &p.RestaurantID,
+			&p.RestaurantName, &p.Address, &p.City, &latitude, &longitude); err != nil {
+			return nil, fmt.Errorf("failed to scan planned visit: %w", err)
+		}
+		if partySize.Valid {
+			n := int(partySize.Int64)
+			p.PartySize = &n
+		}
+		if latitude.Valid && longitude.Valid {
+			p.Latitude = &latitude.Float64
+			p.Longitude = &longitude.Float64
+		}
+		results = append(results, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating planned visits: %w", err)
+	}
+	return results, nil
+}
+
+// GetPlannedVisit returns a single planned visit by ID.
+func GetPlannedVisit(db Querier, id int64) (model.PlannedVisit, error) {
+	row := db.QueryRow(`
+		SELECT id, restaurant_id, planned_on, planned_at, party_size, notes, created_at
+		FROM planned_visits
+		WHERE id = ?
+	`, id)
+	p, err := scanPlannedVisit(row)
+	if err != nil {
+		return model.PlannedVisit{}, fmt.Errorf("failed to get planned visit: %w", err)
+	}
+	return p, nil
+}
+
+// InsertPlannedVisit creates a planned visit.
+func InsertPlannedVisit(db Querier, p model.NewPlannedVisit) (int64, error) {
+	result, err := db.Exec(`
+		INSERT INTO planned_visits (restaurant_id, planned_on, planned_at, party_size, notes)
+		VALUES (?, ?, NULLIF(?, ''), ?, NULLIF(?, ''))
+	`, p.RestaurantID, p.PlannedOn, p.PlannedAt, nullableInt(p.PartySize), p.Notes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert planned visit: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get planned visit id: %w", err)
+	}
+	return id, nil
+}
+
+// UpdatePlannedVisit updates an existing planned visit.
+func UpdatePlannedVisit(db Querier, p model.UpdatePlannedVisit) error {
+	_, err := db.Exec(`
+		UPDATE planned_visits
+		SET restaurant_id = ?, planned_on = ?, planned_at = NULLIF(?, ''), party_size = ?, notes = NULLIF(?, '')
+		WHERE id = ?
+	`, p.RestaurantID, p.PlannedOn, p.PlannedAt, nullableInt(p.PartySize), p.Notes, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update planned visit: %w", err)
+	}
+	return nil
+}
+
+// DeletePlannedVisit deletes a planned visit.
+func DeletePlannedVisit(db Querier, id int64) error {
+	if _, err := db.Exec("DELETE FROM planned_visits WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete planned visit: %w", err)
+	}
+	return nil
+}
+
+func scanPlannedVisit(row interface{ Scan(...any) error }) (model.PlannedVisit, error) {
+	var p model.PlannedVisit
+	var plannedAt, notes sql.NullString
+	var partySize sql.NullInt64
+	var createdAt string
+	if err := row.Scan(&p.ID, &p.RestaurantID, &p.PlannedOn, &plannedAt, &partySize, &notes, &createdAt); err != nil {
+		return model.PlannedVisit{}, err
+	}
+	p.PlannedAt = plannedAt.String
+	p.Notes = notes.String
+	if partySize.Valid {
+		n := int(partySize.Int64)
+		p.PartySize = &n
+	}
+	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
+		p.CreatedAt = t
+	}
+	return p, nil
+}
+
+// nullableInt returns n as a query argument, NULL when it is nil.
+func nullableInt(n *int) interface{} {
+	if n == nil {
+		return nil
+	}
+	return *n
+}
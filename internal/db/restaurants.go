@@ -5,30 +5,66 @@ import (
 	"fmt"
 	"time"
 	"toni/internal/model"
+	"toni/internal/query"
 )
 
+// RestaurantQuerySchema describes the fields queries over restaurants can
+// use. Columns refer to the row source in ListRestaurantsWhere.
+var RestaurantQuerySchema = query.Schema{
+	Fields: []query.Field{
+		{Name: "name", Column: "name", Kind: query.KindText},
+		{Name: "city", Column: "city", Kind: query.KindText},
+		{Name: "address", Column: "address", Kind: query.KindText},
+		{Name: "area", Aliases: []string{"neighborhood"}, Column: "neighborhood", Kind: query.KindText},
+		{Name: "cuisine", Column: "cuisine", Kind: query.KindText},
+		{Name: "price", Column: "price_range", Kind: query.KindText},
+		{Name: "rating", Column: "avg_rating", Kind: query.KindNumber},
+		{Name: "visits", Column: "visit_count", Kind: query.KindNumber},
+		tagQueryField("l.id"),
+		{Name: "visited", Aliases: []string{"last"}, Column: "last_visit", Kind: query.KindDate},
+	},
+	Text: []string{"name", "city"},
+}
+
 // ListRestaurants retrieves all restaurants with aggregate stats, optionally filtered.
 func ListRestaurants(db *sql.DB, filter string) ([]model.RestaurantRow, error) {
+	return ListRestaurantsWhere(db, "(? = '' OR name LIKE '%' || ? || '%' OR city LIKE '%' || ? || '%')", []any{filter, filter, filter})
+}
+
+// ListRestaurantsWhere retrieves the restaurants matching an SQL condition
+// over the columns of RestaurantQuerySchema, as compiled by the query
+// package. An empty condition matches every restaurant.
+func ListRestaurantsWhere(db *sql.DB, where string, args []any) ([]model.RestaurantRow, error) {
+	if where == "" {
+		where = "1"
+	}
 	query := `
 		SELECT
-			r.id,
-			r.name,
-			COALESCE(r.address, ''),
-			COALESCE(r.city, ''),
-			COALESCE(r.neighborhood, ''),
-			COALESCE(r.cuisine, ''),
-			COALESCE(r.price_range, ''),
-			AVG(v.rating) as avg_rating,
-			COUNT(v.id) as visit_count,
-			MAX(v.visited_on) as last_visit
-		FROM restaurants r
-		LEFT JOIN visits v ON r.id = v.restaurant_id
-		WHERE (? = '' OR r.name LIKE '%' || ? || '%' OR r.city LIKE '%' || ? || '%')
-		GROUP BY r.id
-		ORDER BY r.name
+			id,
+			name,
+			COALESCE(address, ''),
+			COALESCE(city, ''),
+			COALESCE(neighborhood, ''),
+			COALESCE(cuisine, ''),
+			COALESCE(price_range, ''),
+			avg_rating,
+			visit_count,
+			last_visit
+		FROM (
+			SELECT
+				r.id, r.name, r.address, r.city, r.neighborhood, r.cuisine, r.price_range,
+				AVG(v.rating) as avg_rating,
+				COUNT(v.id) as visit_count,
+				MAX(v.visited_on) as last_visit
+			FROM restaurants r
+			LEFT JOIN visits v ON r.id = v.restaurant_id
+			GROUP BY r.id
+		) l
+		WHERE ` + where + `
+		ORDER BY name
 	`
 
-	rows, err := db.Query(query, filter, filter, filter)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list restaurants: %w", err)
 	}
@@ -59,7 +95,7 @@ func ListRestaurants(db *sql.DB, filter string) ([]model.RestaurantRow, error) {
 }
 
 // GetRestaurant retrieves a single restaurant by ID.
-func GetRestaurant(db *sql.DB, id int64) (model.Restaurant, error) {
+func GetRestaurant(db Querier, id int64) (model.Restaurant, error) {
 	query := `
 		SELECT id, name, address, city, neighborhood, cuisine, price_range, latitude, longitude, place_id, created_at
 		FROM restaurants
@@ -149,14 +185,20 @@ func GetRestaurantWithStats(db *sql.DB, id int64) (model.RestaurantDetail, error
 		visits = append(visits, v)
 	}
 
+	tags, err := GetRestaurantTags(db, id)
+	if err != nil {
+		return model.RestaurantDetail{}, err
+	}
+
 	return model.RestaurantDetail{
 		Restaurant: restaurant,
 		Visits:     visits,
+		Tags:       tags,
 	}, nil
 }
 
 // InsertRestaurant creates a new restaurant.
-func InsertRestaurant(db *sql.DB, r model.NewRestaurant) (int64, error) {
+func InsertRestaurant(db Querier, r model.NewRestaurant) (int64, error) {
 	query := `
 		INSERT INTO restaurants (name, address, city, neighborhood, cuisine, price_range, latitude, longitude, place_id)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
@@ -204,7 +246,7 @@ func InsertRestaurant(db *sql.DB, r model.NewRestaurant) (int64, error) {
 }
 
 // UpdateRestaurant updates an existing restaurant.
-func UpdateRestaurant(db *sql.DB, r model.UpdateRestaurant) error {
+func UpdateRestaurant(db Querier, r model.UpdateRestaurant) error {
 	query := `
 		UPDATE restaurants
 		SET name = ?, address = ?, city = ?, neighborhood = ?, cuisine = ?, price_range = ?, latitude = ?, longitude = ?, place_id = ?
@@ -249,26 +291,30 @@ func UpdateRestaurant(db *sql.DB, r model.UpdateRestaurant) error {
 
 // DeleteRestaurant deletes a restaurant and all its visits.
 func DeleteRestaurant(db *sql.DB, id int64) error {
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
+	return InTx(db, func(tx *sql.Tx) error {
+		return deleteRestaurant(tx, id)
+	})
+}
 
-	if _, err := tx.Exec("DELETE FROM visits WHERE restaurant_id = ?", id); err != nil {
+func deleteRestaurant(db Querier, id int64) error {
+	if _, err := db.Exec("DELETE FROM visits WHERE restaurant_id = ?", id); err != nil {
 		return fmt.Errorf("failed to delete visits: %w", err)
 	}
 
-	if _, err := tx.Exec("DELETE FROM want_to_visit WHERE restaurant_id = ?", id); err != nil {
+	if _, err := db.Exec("DELETE FROM want_to_visit WHERE restaurant_id = ?", id); err != nil {
 		return fmt.Errorf("failed to delete want_to_visit entries: %w", err)
 	}
 
-	if _, err := tx.Exec("DELETE FROM restaurants WHERE id = ?", id); err != nil {
-		return fmt.Errorf("failed to delete restaurant: %w", err)
+	if _, err := db.Exec("DELETE FROM planned_visits WHERE restaurant_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete planned visits: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if _, err := db.Exec("DELETE FROM restaurant_tags WHERE restaurant_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete restaurant tags: %w", err)
+	}
+
+	if _, err := db.Exec("DELETE FROM restaurants WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete restaurant: %w", err)
 	}
 
 	return nil
@@ -324,3 +370,65 @@ func SearchRestaurants(db *sql.DB, query string) ([]model.Restaurant, error) {
 
 	return results, nil
 }
+
+// ListRestaurantStats returns every restaurant with its visit stats. It only
+// reads tables of the original schema, so it works on databases opened with
+// OpenReadOnly whatever their version.
+func ListRestaurantStats(db Querier) ([]model.RestaurantStats, error) {
+	query := `
+		SELECT
+			r.id,
+			r.name,
+			COALESCE(r.address, ''),
+			COALESCE(r.city, ''),
+			COALESCE(r.neighborhood, ''),
+			COALESCE(r.cuisine, ''),
+			COALESCE(r.price_range, ''),
+			COALESCE(r.place_id, ''),
+			EXISTS (SELECT 1 FROM want_to_visit w WHERE w.restaurant_id = r.id),
+			(SELECT AVG(v.rating) FROM visits v WHERE v.restaurant_id = r.id),
+			(SELECT COUNT(*) FROM visits v WHERE v.restaurant_id = r.id),
+			(SELECT MAX(v.visited_on) FROM visits v WHERE v.restaurant_id = r.id),
+			(SELECT v.would_return FROM visits v
+				WHERE v.restaurant_id = r.id AND v.would_return IS NOT NULL
+				ORDER BY v.visited_on DESC, v.id DESC LIMIT 1)
+		FROM restaurants r
+		ORDER BY r.name
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list restaurant stats: %w", err)
+	}
+	defer rows.Close()
+
+	var results []model.RestaurantStats
+	for rows.Next() {
+		var s model.RestaurantStats
+		var avgRating sql.NullFloat64
+		var lastVisit sql.NullString
+		var wouldReturn sql.NullBool
+		if err := rows.Scan(
+			&s.RestaurantID, &s.Name, &s.Address, &s.City, &s.Neighborhood, &s.Cuisine, &s.PriceRange,
+			&s.PlaceID, &s.Wishlisted, &avgRating, &s.VisitCount, &lastVisit, &wouldReturn,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan restaurant stats: %w", err)
+		}
+		if avgRating.Valid {
+			s.AvgRating = &avgRating.Float64
+		}
+		if lastVisit.Valid {
+			s.LastVisit = lastVisit.String
+		}
+		if wouldReturn.Valid {
+			s.WouldReturn = &wouldReturn.Bool
+		}
+		results = append(results, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating restaurant stats: %w", err)
+	}
+
+	return results, nil
+}
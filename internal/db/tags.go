@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"toni/internal/query"
+)
+
+// NormalizeTag lower-cases a tag and trims it. Tags are single words, so
+// inner whitespace is replaced with dashes.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), "-"))
+}
+
+// tagQueryField is the tag field of the list query schemas. Their list
+// queries name the row being matched l, and restaurantID is the column of l
+// holding its restaurant.
+func tagQueryField(restaurantID string) query.Field {
+	return query.Field{
+		Name:    "tag",
+		Aliases: []string{"tags"},
+		Column:  "t.tag",
+		Kind:    query.KindSet,
+		Exists:  "SELECT 1 FROM restaurant_tags t WHERE t.restaurant_id = " + restaurantID,
+	}
+}
+
+// GetRestaurantTags returns the tags of a restaurant in alphabetical order.
+func GetRestaurantTags(db Querier, restaurantID int64) ([]string, error) {
+	rows, err := db.Query("SELECT tag FROM restaurant_tags WHERE restaurant_id = ? ORDER BY tag", restaurantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get restaurant tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan restaurant tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// ListTags returns every tag in use, for completion.
+func ListTags(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT tag FROM restaurant_tags ORDER BY tag")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// AddRestaurantTag tags a restaurant. It reports whether the tag was new.
+func AddRestaurantTag(db Querier, restaurantID int64, tag string) (bool, error) {
+	res, err := db.Exec("INSERT OR IGNORE INTO restaurant_tags (restaurant_id, tag) VALUES (?, ?)", restaurantID, tag)
+	if err != nil {
+		return false, fmt.Errorf("failed to add restaurant tag: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to add restaurant tag: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RemoveRestaurantTag removes a tag from a restaurant. It reports whether
+// the restaurant had the tag.
+func RemoveRestaurantTag(db Querier, restaurantID int64, tag string) (bool, error) {
+	res, err := db.Exec("DELETE FROM restaurant_tags WHERE restaurant_id = ? AND tag = ?", restaurantID, tag)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove restaurant tag: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove restaurant tag: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"toni/internal/model"
+	"toni/internal/query"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	database, err := Open(filepath.Join(t.TempDir(), "toni.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+func TestTagQueries(t *testing.T) {
+	database := openTestDB(t)
+	tags := map[string][]string{
+		"Lucali":     {"favs", "date-night"},
+		"Ivan Ramen": {"ramen"},
+		"Di Fara":    nil,
+	}
+	for name, restaurantTags := range tags {
+		id, err := InsertRestaurant(database, model.NewRestaurant{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tag := range restaurantTags {
+			if _, err := AddRestaurantTag(database, id, tag); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := InsertVisit(database, model.NewVisit{RestaurantID: id, VisitedOn: "2025-03-14"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := InsertWantToVisit(database, model.NewWantToVisit{RestaurantID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lists := []struct {
+		name   string
+		schema query.Schema
+		list   func(where string, args []any) ([]string, error)
+	}{
+		{"restaurants", RestaurantQuerySchema, func(where string, args []any) ([]string, error) {
+			rows, err := ListRestaurantsWhere(database, where, args)
+			var names []string
+			for _, r := range rows {
+				names = append(names, r.Name)
+			}
+			return names, err
+		}},
+		{"pick", PickQuerySchema, func(where string, args []any) ([]string, error) {
+			rows, err := ListPickCandidatesWhere(database, where, args)
+			var names []string
+			for _, r := range rows {
+				names = append(names, r.Name)
+			}
+			return names, err
+		}},
+		{"visits", VisitQuerySchema, func(where string, args []any) ([]string, error) {
+			rows, err := ListVisitsWhere(database, where, args)
+			var names []string
+			for _, r := range rows {
+				names = append(names, r.RestaurantName)
+			}
+			return names, err
+		}},
+		{"wishlist", WantToVisitQuerySchema, func(where string, args []any) ([]string, error) {
+			rows, err := ListWantToVisitWhere(database, where, args)
+			var names []string
+			for _, r := range rows {
+				names = append(names, r.RestaurantName)
+			}
+			return names, err
+		}},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"tag=favs", []string{"Lucali"}},
+		{"tag=date-night", []string{"Lucali"}},
+		{"tags=FAVS", []string{"Lucali"}},
+		{"tag=ram", nil},
+		{"tag:ram", []string{"Ivan Ramen"}},
+		{"tag:night", []string{"Lucali"}},
+		{"tag=(favs|ramen)", []string{"Ivan Ramen", "Lucali"}},
+		{"tag=favs tag=date-night", []string{"Lucali"}},
+		{"tag!=favs", []string{"Di Fara", "Ivan Ramen"}},
+		{"-tag=favs", []string{"Di Fara", "Ivan Ramen"}},
+		{"tag:none", []string{"Di Fara"}},
+		{"tag!=none", []string{"Ivan Ramen", "Lucali"}},
+	}
+	for _, l := range lists {
+		for _, tt := range tests {
+			where, args, err := l.schema.Compile(tt.query)
+			if err != nil {
+				t.Errorf("%s: Compile(%q): %v", l.name, tt.query, err)
+				continue
+			}
+			got, err := l.list(where, args)
+			if err != nil {
+				t.Errorf("%s %q: %v", l.name, tt.query, err)
+				continue
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s %q = %v, want %v", l.name, tt.query, got, tt.want)
+			}
+		}
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"toni/internal/model"
 )
 
-func InsertRestaurantWithID(db *sql.DB, r model.Restaurant) error {
+func InsertRestaurantWithID(db Querier, r model.Restaurant) error {
 	query := `
 		INSERT INTO restaurants (id, name, address, city, neighborhood, cuisine, price_range, latitude, longitude, place_id, created_at)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
@@ -50,10 +50,10 @@ func InsertRestaurantWithID(db *sql.DB, r model.Restaurant) error {
 	return nil
 }
 
-func InsertVisitWithID(db *sql.DB, v model.Visit) error {
+func InsertVisitWithID(db Querier, v model.Visit) error {
 	query := `
-		INSERT INTO visits (id, restaurant_id, visited_on, rating, notes, would_return, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO visits (id, restaurant_id, visited_on, rating, notes, would_return, wishlist_notes, wishlist_priority, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)
 	`
 	var visitedOn interface{}
 	var rating, wouldReturn interface{}
@@ -79,16 +79,16 @@ func InsertVisitWithID(db *sql.DB, v model.Visit) error {
 		createdAt = v.CreatedAt.UTC().Format(time.RFC3339)
 	}
 
-	if _, err := db.Exec(query, v.ID, v.RestaurantID, visitedOn, rating, notes, wouldReturn, createdAt); err != nil {
+	if _, err := db.Exec(query, v.ID, v.RestaurantID, visitedOn, rating, notes, wouldReturn, v.WishlistNotes, nullableInt(v.WishlistPriority), createdAt); err != nil {
 		return fmt.Errorf("failed to insert visit with id: %w", err)
 	}
 	return nil
 }
 
-func InsertWantToVisitWithID(db *sql.DB, w model.WantToVisit) error {
+func InsertWantToVisitWithID(db Querier, w model.WantToVisit) error {
 	query := `
-		INSERT INTO want_to_visit (id, restaurant_id, notes, priority, created_at)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO want_to_visit (id, restaurant_id, notes, priority, source, created_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?)
 	`
 	var priority interface{}
 	if w.Priority != nil {
@@ -98,15 +98,15 @@ func InsertWantToVisitWithID(db *sql.DB, w model.WantToVisit) error {
 	if !w.CreatedAt.IsZero() {
 		createdAt = w.CreatedAt.UTC().Format(time.RFC3339)
 	}
-	if _, err := db.Exec(query, w.ID, w.RestaurantID, w.Notes, priority, createdAt); err != nil {
+	if _, err := db.Exec(query, w.ID, w.RestaurantID, w.Notes, priority, w.Source, createdAt); err != nil {
 		return fmt.Errorf("failed to insert want_to_visit with id: %w", err)
 	}
 	return nil
 }
 
-func GetVisitsByRestaurant(db *sql.DB, restaurantID int64) ([]model.Visit, error) {
+func GetVisitsByRestaurant(db Querier, restaurantID int64) ([]model.Visit, error) {
 	rows, err := db.Query(`
-		SELECT id, restaurant_id, visited_on, rating, notes, would_return, created_at
+		SELECT id, restaurant_id, visited_on, rating, notes, would_return, wishlist_notes, wishlist_priority, created_at
 		FROM visits
 		WHERE restaurant_id = ?
 		ORDER BY id
@@ -121,12 +121,17 @@ func GetVisitsByRestaurant(db *sql.DB, restaurantID int64) ([]model.Visit, error
 		var v model.Visit
 		var visitedOn sql.NullString
 		var rating sql.NullFloat64
-		var notes sql.NullString
-		var wouldReturn sql.NullInt64
+		var notes, wishlistNotes sql.NullString
+		var wouldReturn, wishlistPriority sql.NullInt64
 		var createdAt string
-		if err := rows.Scan(&v.ID, &v.RestaurantID, &visitedOn, &rating, &notes, &wouldReturn, &createdAt); err != nil {
+		if err := rows.Scan(&v.ID, &v.RestaurantID, &visitedOn, &rating, &notes, &wouldReturn, &wishlistNotes, &wishlistPriority, &createdAt); err != nil {
 			return nil, fmt.Errorf("failed to scan visit: %w", err)
 		}
+		v.WishlistNotes = wishlistNotes.String
+		if wishlistPriority.Valid {
+			p := int(wishlistPriority.Int64)
+			v.WishlistPriority = &p
+		}
 		v.VisitedOn = visitedOn.String
 		if rating.Valid {
 			r := rating.Float64
@@ -145,9 +150,9 @@ func GetVisitsByRestaurant(db *sql.DB, restaurantID int64) ([]model.Visit, error
 	return visits, rows.Err()
 }
 
-func GetWantToVisitByRestaurant(db *sql.DB, restaurantID int64) ([]model.WantToVisit, error) {
+func GetWantToVisitByRestaurant(db Querier, restaurantID int64) ([]model.WantToVisit, error) {
 	rows, err := db.Query(`
-		SELECT id, restaurant_id, notes, priority, created_at
+		SELECT id, restaurant_id, notes, priority, source, created_at
 		FROM want_to_visit
 		WHERE restaurant_id = ?
 		ORDER BY id
@@ -160,11 +165,14 @@ func GetWantToVisitByRestaurant(db *sql.DB, restaurantID int64) ([]model.WantToV
 	var entries []model.WantToVisit
 	for rows.Next() {
 		var w model.WantToVisit
+		var notes, source sql.NullString
 		var priority sql.NullInt64
 		var createdAt string
-		if err := rows.Scan(&w.ID, &w.RestaurantID, &w.Notes, &priority, &createdAt); err != nil {
+		if err := rows.Scan(&w.ID, &w.RestaurantID, &notes, &priority, &source, &createdAt); err != nil {
 			return nil, fmt.Errorf("failed to scan want_to_visit: %w", err)
 		}
+		w.Notes = notes.String
+		w.Source = source.String
 		if priority.Valid {
 			p := int(priority.Int64)
 			w.Priority = &p
@@ -176,3 +184,41 @@ func GetWantToVisitByRestaurant(db *sql.DB, restaurantID int64) ([]model.WantToV
 	}
 	return entries, rows.Err()
 }
+
+func InsertPlannedVisitWithID(db Querier, p model.PlannedVisit) error {
+	query := `
+		INSERT INTO planned_visits (id, restaurant_id, planned_on, planned_at, party_size, notes, created_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?)
+	`
+	createdAt := time.Now().UTC().Format(time.RFC3339)
+	if !p.CreatedAt.IsZero() {
+		createdAt = p.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	if _, err := db.Exec(query, p.ID, p.RestaurantID, p.PlannedOn, p.PlannedAt, nullableInt(p.PartySize), p.Notes, createdAt); err != nil {
+		return fmt.Errorf("failed to insert planned visit with id: %w", err)
+	}
+	return nil
+}
+
+func GetPlannedVisitsByRestaurant(db Querier, restaurantID int64) ([]model.PlannedVisit, error) {
+	rows, err := db.Query(`
+		SELECT id, restaurant_id, planned_on, planned_at, party_size, notes, created_at
+		FROM planned_visits
+		WHERE restaurant_id = ?
+		ORDER BY id
+	`, restaurantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query planned visits by restaurant: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []model.PlannedVisit
+	for rows.Next() {
+		p, err := scanPlannedVisit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan planned visit: %w", err)
+		}
+		plans = append(plans, p)
+	}
+	return plans, rows.Err()
+}
@@ -5,29 +5,66 @@ import (
 	"fmt"
 	"time"
 	"toni/internal/model"
+	"toni/internal/query"
 )
 
+// VisitQuerySchema describes the fields queries over visits can use. Columns
+// refer to the row source in ListVisitsWhere.
+var VisitQuerySchema = query.Schema{
+	Fields: []query.Field{
+		{Name: "name", Column: "name", Kind: query.KindText},
+		{Name: "city", Column: "city", Kind: query.KindText},
+		{Name: "address", Column: "address", Kind: query.KindText},
+		{Name: "area", Aliases: []string{"neighborhood"}, Column: "neighborhood", Kind: query.KindText},
+		{Name: "cuisine", Column: "cuisine", Kind: query.KindText},
+		{Name: "price", Column: "price_range", Kind: query.KindText},
+		{Name: "rating", Column: "rating", Kind: query.KindNumber},
+		{Name: "return", Aliases: []string{"would_return"}, Column: "would_return", Kind: query.KindBool},
+		tagQueryField("l.restaurant_id"),
+		{Name: "notes", Column: "notes", Kind: query.KindText},
+		{Name: "visited", Aliases: []string{"date"}, Column: "visited_on", Kind: query.KindDate},
+	},
+	Text: []string{"name", "notes"},
+}
+
 // ListVisits retrieves all visits with restaurant info, optionally filtered.
 func ListVisits(db *sql.DB, filter string) ([]model.VisitRow, error) {
+	return ListVisitsWhere(db, "(? = '' OR name LIKE '%' || ? || '%' OR notes LIKE '%' || ? || '%')", []any{filter, filter, filter})
+}
+
+// ListVisitsWhere retrieves the visits matching an SQL condition over the
+// columns of VisitQuerySchema, as compiled by the query package. An empty
+// condition matches every visit.
+func ListVisitsWhere(db *sql.DB, where string, args []any) ([]model.VisitRow, error) {
+	if where == "" {
+		where = "1"
+	}
 	query := `
 		SELECT
-			v.id,
-			COALESCE(v.visited_on, ''),
-			r.name,
-			COALESCE(r.city, ''),
-			COALESCE(r.address, ''),
-			COALESCE(r.price_range, ''),
-			v.rating,
-			v.would_return,
-			COALESCE(v.notes, ''),
-			v.restaurant_id
-		FROM visits v
-		JOIN restaurants r ON v.restaurant_id = r.id
-		WHERE (? = '' OR r.name LIKE '%' || ? || '%' OR v.notes LIKE '%' || ? || '%')
-		ORDER BY v.visited_on DESC
+			id,
+			COALESCE(visited_on, ''),
+			name,
+			COALESCE(city, ''),
+			COALESCE(address, ''),
+			COALESCE(price_range, ''),
+			rating,
+			would_return,
+			COALESCE(notes, ''),
+			restaurant_id,
+			latitude,
+			longitude
+		FROM (
+			SELECT
+				v.id, v.visited_on, v.rating, v.would_return, v.notes, v.restaurant_id,
+				r.name, r.city, r.address, r.neighborhood, r.cuisine, r.price_range, r.latitude, r.longitude
+			FROM visits v
+			JOIN restaurants r ON v.restaurant_id = r.id
+		) l
+		WHERE ` + where + `
+		ORDER BY visited_on DESC
 	`
 
-	rows, err := db.Query(query, filter, filter, filter)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list visits: %w", err)
 	}
@@ -38,8 +75,9 @@ func ListVisits(db *sql.DB, filter string) ([]model.VisitRow, error) {
 		var v model.VisitRow
 		var rating sql.NullFloat64
 		var wouldReturn sql.NullInt64
+		var latitude, longitude sql.NullFloat64
 
-		if err := rows.Scan(&v.ID, &v.VisitedOn, &v.RestaurantName, &v.City, &v.Address, &v.PriceRange, &rating, &wouldReturn, &v.Notes, &v.RestaurantID); err != nil {
+		if err := rows.Scan(&v.ID, &v.VisitedOn, &v.RestaurantName, &v.City, &v.Address, &v.PriceRange, &rating, &wouldReturn, &v.Notes, &v.RestaurantID, &latitude, &longitude); err != nil {
 			return nil, fmt.Errorf("failed to scan visit row: %w", err)
 		}
 
@@ -51,6 +89,10 @@ func ListVisits(db *sql.DB, filter string) ([]model.VisitRow, error) {
 			wr := wouldReturn.Int64 == 1
 			v.WouldReturn = &wr
 		}
+		if latitude.Valid && longitude.Valid {
+			v.Latitude = &latitude.Float64
+			v.Longitude = &longitude.Float64
+		}
 
 		results = append(results, v)
 	}
@@ -63,9 +105,9 @@ func ListVisits(db *sql.DB, filter string) ([]model.VisitRow, error) {
 }
 
 // GetVisit retrieves a single visit by ID.
-func GetVisit(db *sql.DB, id int64) (model.Visit, error) {
+func GetVisit(db Querier, id int64) (model.Visit, error) {
 	query := `
-		SELECT id, restaurant_id, visited_on, rating, notes, would_return, created_at
+		SELECT id, restaurant_id, visited_on, rating, notes, would_return, wishlist_notes, wishlist_priority, created_at
 		FROM visits
 		WHERE id = ?
 	`
@@ -73,16 +115,21 @@ func GetVisit(db *sql.DB, id int64) (model.Visit, error) {
 	var v model.Visit
 	var visitedOn sql.NullString
 	var rating sql.NullFloat64
-	var notes sql.NullString
-	var wouldReturn sql.NullInt64
+	var notes, wishlistNotes sql.NullString
+	var wouldReturn, wishlistPriority sql.NullInt64
 	var createdAt string
 
 	err := db.QueryRow(query, id).Scan(
-		&v.ID, &v.RestaurantID, &visitedOn, &rating, &notes, &wouldReturn, &createdAt,
+		&v.ID, &v.RestaurantID, &visitedOn, &rating, &notes, &wouldReturn, &wishlistNotes, &wishlistPriority, &createdAt,
 	)
 	if err != nil {
 		return model.Visit{}, fmt.Errorf("failed to get visit: %w", err)
 	}
+	v.WishlistNotes = wishlistNotes.String
+	if wishlistPriority.Valid {
+		p := int(wishlistPriority.Int64)
+		v.WishlistPriority = &p
+	}
 
 	v.VisitedOn = visitedOn.String
 	if rating.Valid {
@@ -102,10 +149,10 @@ func GetVisit(db *sql.DB, id int64) (model.Visit, error) {
 }
 
 // InsertVisit creates a new visit.
-func InsertVisit(db *sql.DB, v model.NewVisit) (int64, error) {
+func InsertVisit(db Querier, v model.NewVisit) (int64, error) {
 	query := `
-		INSERT INTO visits (restaurant_id, visited_on, rating, notes, would_return)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO visits (restaurant_id, visited_on, rating, notes, would_return, wishlist_notes, wishlist_priority)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?)
 	`
 
 	var visitedOn interface{}
@@ -129,7 +176,7 @@ func InsertVisit(db *sql.DB, v model.NewVisit) (int64, error) {
 		}
 	}
 
-	result, err := db.Exec(query, v.RestaurantID, visitedOn, rating, notes, wouldReturn)
+	result, err := db.Exec(query, v.RestaurantID, visitedOn, rating, notes, wouldReturn, v.WishlistNotes, nullableInt(v.WishlistPriority))
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert visit: %w", err)
 	}
@@ -180,10 +227,228 @@ func UpdateVisit(db *sql.DB, v model.UpdateVisit) error {
 }
 
 // DeleteVisit deletes a visit.
-func DeleteVisit(db *sql.DB, id int64) error {
+func DeleteVisit(db Querier, id int64) error {
 	_, err := db.Exec("DELETE FROM visits WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete visit: %w", err)
 	}
 	return nil
 }
+
+// LogPlannedVisit records that the visit planned as planID happened. In one
+// transaction it inserts v, deletes the plan and takes the restaurant's
+// oldest want_to_visit entry off the list, keeping the entry's notes and
+// priority on the visit. When v has no notes of its own it gets the plan's.
+// A visit to another restaurant than the plan's is logged as a plain visit,
+// and the plan and want_to_visit list are left alone.
+func LogPlannedVisit(db *sql.DB, planID int64, v model.NewVisit) (model.LoggedVisit, error) {
+	var logged model.LoggedVisit
+	err := InTx(db, func(tx *sql.Tx) error {
+		plan, err := GetPlannedVisit(tx, planID)
+		if err != nil {
+			return err
+		}
+		if plan.RestaurantID != v.RestaurantID {
+			logged, err = logVisit(tx, v, nil, nil)
+			return err
+		}
+		if v.Notes == "" {
+			v.Notes = plan.Notes
+		}
+		entries, err := GetWantToVisitByRestaurant(tx, v.RestaurantID)
+		if err != nil {
+			return err
+		}
+		logged, err = logVisit(tx, v, &plan, entries[:min(len(entries), 1)])
+		return err
+	})
+	if err != nil {
+		return model.LoggedVisit{}, err
+	}
+	return logged, nil
+}
+
+// LogWantToVisit records a visit to the restaurant of want_to_visit entry
+// wtvID. In one transaction it inserts v, keeping the entry's notes and
+// priority on it, and deletes the entry. A visit to another restaurant is
+// logged as a plain visit and the entry is kept.
+func LogWantToVisit(db *sql.DB, wtvID int64, v model.NewVisit) (model.LoggedVisit, error) {
+	var logged model.LoggedVisit
+	err := InTx(db, func(tx *sql.Tx) error {
+		entry, err := GetWantToVisit(tx, wtvID)
+		if err != nil {
+			return fmt.Errorf("failed to get want to visit entry: %w", err)
+		}
+		var entries []model.WantToVisit
+		if entry.RestaurantID == v.RestaurantID {
+			entries = append(entries, entry)
+		}
+		logged, err = logVisit(tx, v, nil, entries)
+		return err
+	})
+	if err != nil {
+		return model.LoggedVisit{}, err
+	}
+	return logged, nil
+}
+
+// logVisit inserts v and deletes plan, when set, and entries. The first
+// entry's notes and priority are kept on the visit.
+func logVisit(tx *sql.Tx, v model.NewVisit, plan *model.PlannedVisit, entries []model.WantToVisit) (model.LoggedVisit, error) {
+	if len(entries) > 0 {
+		v.WishlistNotes = entries[0].Notes
+		v.WishlistPriority = entries[0].Priority
+	}
+	id, err := InsertVisit(tx, v)
+	if err != nil {
+		return model.LoggedVisit{}, err
+	}
+	if plan != nil {
+		if err := DeletePlannedVisit(tx, plan.ID); err != nil {
+			return model.LoggedVisit{}, err
+		}
+	}
+	for _, w := range entries {
+		if err := DeleteWantToVisit(tx, w.ID); err != nil {
+			return model.LoggedVisit{}, fmt.Errorf("failed to delete want to visit entry: %w", err)
+		}
+	}
+	visit, err := GetVisit(tx, id)
+	if err != nil {
+		return model.LoggedVisit{}, err
+	}
+	return model.LoggedVisit{Visit: visit, Plan: plan, WantToVisit: entries}, nil
+}
+
+// UnlogVisit undoes LogPlannedVisit or LogWantToVisit: it deletes the visit
+// and puts back the plan and want_to_visit entries it removed.
+func UnlogVisit(db *sql.DB, l model.LoggedVisit) error {
+	return InTx(db, func(tx *sql.Tx) error {
+		if err := DeleteVisit(tx, l.Visit.ID); err != nil {
+			return err
+		}
+		if l.Plan != nil {
+			if err := InsertPlannedVisitWithID(tx, *l.Plan); err != nil {
+				return err
+			}
+		}
+		for _, w := range l.WantToVisit {
+			if err := InsertWantToVisitWithID(tx, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RelogVisit redoes a visit that UnlogVisit undid.
+func RelogVisit(db *sql.DB, l model.LoggedVisit) error {
+	return InTx(db, func(tx *sql.Tx) error {
+		if err := InsertVisitWithID(tx, l.Visit); err != nil {
+			return err
+		}
+		if l.Plan != nil {
+			if err := DeletePlannedVisit(tx, l.Plan.ID); err != nil {
+				return err
+			}
+		}
+		for _, w := range l.WantToVisit {
+			if err := DeleteWantToVisit(tx, w.ID); err != nil {
+				return fmt.Errorf("failed to delete want to visit entry: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ListVisitDays summarizes the visits on each day from from up to but not
+// including to, both YYYY-MM-DD. Days without visits are left out.
+func ListVisitDays(db *sql.DB, from, to string) ([]model.VisitDay, error) {
+	query := `
+		SELECT v.visited_on, r.name, v.rating
+		FROM visits v
+		JOIN restaurants r ON v.restaurant_id = r.id
+		WHERE v.visited_on >= ? AND v.visited_on < ?
+		ORDER BY v.visited_on, v.id
+	`
+
+	rows, err := db.Query(query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list visit days: %w", err)
+	}
+	defer rows.Close()
+
+	var days []model.VisitDay
+	var ratingSum float64
+	var rated int
+	for rows.Next() {
+		var date, name string
+		var rating sql.NullFloat64
+		if err := rows.Scan(&date, &name, &rating); err != nil {
+			return nil, fmt.Errorf("failed to scan visit day: %w", err)
+		}
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, model.VisitDay{Date: date})
+			ratingSum, rated = 0, 0
+		}
+		day := &days[len(days)-1]
+		day.Restaurants = append(day.Restaurants, name)
+		if rating.Valid {
+			ratingSum += rating.Float64
+			rated++
+			avg := ratingSum / float64(rated)
+			day.AvgRating = &avg
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating visit days: %w", err)
+	}
+
+	return days, nil
+}
+
+// ListVisitTraits returns every visit's rating and would-return answer with
+// the cuisine, price range and neighborhood of its restaurant.
+func ListVisitTraits(db *sql.DB) ([]model.VisitTraits, error) {
+	query := `
+		SELECT
+			COALESCE(r.cuisine, ''),
+			COALESCE(r.price_range, ''),
+			COALESCE(r.neighborhood, ''),
+			v.rating,
+			v.would_return
+		FROM visits v
+		JOIN restaurants r ON v.restaurant_id = r.id
+		ORDER BY v.id
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list visit traits: %w", err)
+	}
+	defer rows.Close()
+
+	var results []model.VisitTraits
+	for rows.Next() {
+		var t model.VisitTraits
+		var rating sql.NullFloat64
+		var wouldReturn sql.NullBool
+		if err := rows.Scan(&t.Cuisine, &t.PriceRange, &t.Neighborhood, &rating, &wouldReturn); err != nil {
+			return nil, fmt.Errorf("failed to scan visit traits: %w", err)
+		}
+		if rating.Valid {
+			t.Rating = &rating.Float64
+		}
+		if wouldReturn.Valid {
+			t.WouldReturn = &wouldReturn.Bool
+		}
+		results = append(results, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating visit traits: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"testing"
+	"toni/internal/model"
+)
+
+func TestLogPlannedVisit(t *testing.T) {
+	priority := 3
+	tests := []struct {
+		name string
+		// other logs the visit at another restaurant than the plan's.
+		other     bool
+		notes     string
+		wantNotes string
+		wantPlan  bool
+		wantLeft  int
+	}{
+		{name: "plan's restaurant", wantNotes: "Table for 4", wantPlan: true, wantLeft: 1},
+		{name: "own notes", notes: "Great pies", wantNotes: "Great pies", wantPlan: true, wantLeft: 1},
+		{name: "other restaurant", other: true, wantLeft: 2},
+	}
+	for _, tt := range tests {
+		database := openTestDB(t)
+		planned, err := InsertRestaurant(database, model.NewRestaurant{Name: "Lucali"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := InsertRestaurant(database, model.NewRestaurant{Name: "Di Fara"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		planID, err := InsertPlannedVisit(database, model.NewPlannedVisit{RestaurantID: planned, PlannedOn: "2025-03-14", Notes: "Table for 4"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range []model.NewWantToVisit{
+			{RestaurantID: planned, Notes: "Clam pie", Priority: &priority},
+			{RestaurantID: planned, Notes: "Second list"},
+		} {
+			if _, err := InsertWantToVisit(database, entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		v := model.NewVisit{RestaurantID: planned, VisitedOn: "2025-03-14", Notes: tt.notes}
+		if tt.other {
+			v.RestaurantID = other
+		}
+		logged, err := LogPlannedVisit(database, planID, v)
+		if err != nil {
+			t.Fatalf("%s: LogPlannedVisit: %v", tt.name, err)
+		}
+		if got := logged.Visit.Notes; got != tt.wantNotes {
+			t.Errorf("%s: visit notes = %q, want %q", tt.name, got, tt.wantNotes)
+		}
+		if got := logged.Plan != nil; got != tt.wantPlan {
+			t.Errorf("%s: plan consumed = %v, want %v", tt.name, got, tt.wantPlan)
+		}
+		if _, err := GetPlannedVisit(database, planID); (err != nil) != tt.wantPlan {
+			t.Errorf("%s: GetPlannedVisit after logging = %v, want the plan deleted: %v", tt.name, err, tt.wantPlan)
+		}
+		left, err := GetWantToVisitByRestaurant(database, planned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(left) != tt.wantLeft {
+			t.Errorf("%s: %d want to visit entries left, want %d", tt.name, len(left), tt.wantLeft)
+		}
+		if tt.wantPlan && (logged.Visit.WishlistNotes != "Clam pie" || logged.Visit.WishlistPriority == nil || *logged.Visit.WishlistPriority != priority) {
+			t.Errorf("%s: visit kept wishlist notes %q and priority %v, want the oldest entry's", tt.name, logged.Visit.WishlistNotes, logged.Visit.WishlistPriority)
+		}
+
+		if err := UnlogVisit(database, logged); err != nil {
+			t.Fatalf("%s: UnlogVisit: %v", tt.name, err)
+		}
+		if _, err := GetPlannedVisit(database, planID); err != nil {
+			t.Errorf("%s: plan not restored by UnlogVisit: %v", tt.name, err)
+		}
+		if left, err := GetWantToVisitByRestaurant(database, planned); err != nil || len(left) != 2 {
+			t.Errorf("%s: %d want to visit entries after UnlogVisit, want 2 (%v)", tt.name, len(left), err)
+		}
+	}
+}
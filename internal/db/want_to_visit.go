@@ -5,33 +5,63 @@ import (
 	"fmt"
 	"time"
 	"toni/internal/model"
+	"toni/internal/query"
 )
 
-// GetWantToVisitList returns all want_to_visit entries with restaurant info.
-func GetWantToVisitList(db *sql.DB, orderBy string) ([]model.WantToVisitRow, error) {
-	if orderBy == "" {
-		orderBy = "w.priority DESC NULLS LAST, w.created_at DESC"
+// WantToVisitQuerySchema describes the fields queries over the want to
+// visit list can use. Columns refer to the row source in
+// ListWantToVisitWhere.
+var WantToVisitQuerySchema = query.Schema{
+	Fields: []query.Field{
+		{Name: "name", Column: "name", Kind: query.KindText},
+		{Name: "city", Column: "city", Kind: query.KindText},
+		{Name: "address", Column: "address", Kind: query.KindText},
+		{Name: "area", Aliases: []string{"neighborhood"}, Column: "neighborhood", Kind: query.KindText},
+		{Name: "cuisine", Column: "cuisine", Kind: query.KindText},
+		{Name: "price", Column: "price_range", Kind: query.KindText},
+		{Name: "priority", Column: "priority", Kind: query.KindNumber},
+		tagQueryField("l.restaurant_id"),
+		{Name: "notes", Column: "notes", Kind: query.KindText},
+		{Name: "source", Aliases: []string{"from"}, Column: "source", Kind: query.KindText},
+		{Name: "added", Column: "created_at", Kind: query.KindDate},
+	},
+	Text: []string{"name", "notes"},
+}
+
+// ListWantToVisitWhere returns the want_to_visit entries matching an SQL
+// condition over the columns of WantToVisitQuerySchema, as compiled by the
+// query package. An empty condition matches every entry.
+func ListWantToVisitWhere(db *sql.DB, where string, args []any) ([]model.WantToVisitRow, error) {
+	if where == "" {
+		where = "1"
 	}
 
 	query := fmt.Sprintf(`
 		SELECT
-			w.id,
-			r.name,
-			COALESCE(r.address, ''),
-			COALESCE(r.city, ''),
-			COALESCE(r.neighborhood, ''),
-			COALESCE(r.cuisine, ''),
-			COALESCE(r.price_range, ''),
-			w.priority,
-			COALESCE(w.notes, ''),
-			w.restaurant_id,
-			w.created_at
-		FROM want_to_visit w
-		JOIN restaurants r ON w.restaurant_id = r.id
-		ORDER BY %s
-	`, orderBy)
-
-	rows, err := db.Query(query)
+			id,
+			name,
+			COALESCE(address, ''),
+			COALESCE(city, ''),
+			COALESCE(neighborhood, ''),
+			COALESCE(cuisine, ''),
+			COALESCE(price_range, ''),
+			priority,
+			COALESCE(notes, ''),
+			COALESCE(source, ''),
+			restaurant_id,
+			created_at
+		FROM (
+			SELECT
+				w.id, w.priority, w.notes, w.source, w.restaurant_id, w.created_at,
+				r.name, r.address, r.city, r.neighborhood, r.cuisine, r.price_range
+			FROM want_to_visit w
+			JOIN restaurants r ON w.restaurant_id = r.id
+		) l
+		WHERE %s
+		ORDER BY priority DESC NULLS LAST, created_at DESC
+	`, where)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +81,7 @@ func GetWantToVisitList(db *sql.DB, orderBy string) ([]model.WantToVisitRow, err
 			&row.PriceRange,
 			&row.Priority,
 			&row.Notes,
+			&row.Source,
 			&row.RestaurantID,
 			&createdAt,
 		); err != nil {
@@ -66,16 +97,16 @@ func GetWantToVisitList(db *sql.DB, orderBy string) ([]model.WantToVisitRow, err
 }
 
 // GetWantToVisit returns a single want_to_visit entry by ID.
-func GetWantToVisit(db *sql.DB, id int64) (model.WantToVisit, error) {
+func GetWantToVisit(db Querier, id int64) (model.WantToVisit, error) {
 	var wtv model.WantToVisit
 	var createdAt string
-	var notes sql.NullString
+	var notes, source sql.NullString
 	var priority sql.NullInt64
 	err := db.QueryRow(`
-		SELECT id, restaurant_id, notes, priority, created_at
+		SELECT id, restaurant_id, notes, priority, source, created_at
 		FROM want_to_visit
 		WHERE id = ?
-	`, id).Scan(&wtv.ID, &wtv.RestaurantID, &notes, &priority, &createdAt)
+	`, id).Scan(&wtv.ID, &wtv.RestaurantID, &notes, &priority, &source, &createdAt)
 	if err != nil {
 		return wtv, err
 	}
@@ -86,6 +117,7 @@ func GetWantToVisit(db *sql.DB, id int64) (model.WantToVisit, error) {
 		p := int(priority.Int64)
 		wtv.Priority = &p
 	}
+	wtv.Source = source.String
 
 	if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
 		wtv.CreatedAt = t
@@ -95,11 +127,11 @@ func GetWantToVisit(db *sql.DB, id int64) (model.WantToVisit, error) {
 }
 
 // InsertWantToVisit creates a new want_to_visit entry.
-func InsertWantToVisit(db *sql.DB, wtv model.NewWantToVisit) (int64, error) {
+func InsertWantToVisit(db Querier, wtv model.NewWantToVisit) (int64, error) {
 	result, err := db.Exec(`
-		INSERT INTO want_to_visit (restaurant_id, notes, priority)
-		VALUES (?, ?, ?)
-	`, wtv.RestaurantID, wtv.Notes, wtv.Priority)
+		INSERT INTO want_to_visit (restaurant_id, notes, priority, source)
+		VALUES (?, ?, ?, NULLIF(?, ''))
+	`, wtv.RestaurantID, wtv.Notes, wtv.Priority, wtv.Source)
 
 	if err != nil {
 		return 0, err
@@ -109,7 +141,7 @@ func InsertWantToVisit(db *sql.DB, wtv model.NewWantToVisit) (int64, error) {
 }
 
 // UpdateWantToVisit updates an existing want_to_visit entry.
-func UpdateWantToVisit(db *sql.DB, wtv model.UpdateWantToVisit) error {
+func UpdateWantToVisit(db Querier, wtv model.UpdateWantToVisit) error {
 	_, err := db.Exec(`
 		UPDATE want_to_visit
 		SET restaurant_id = ?, notes = ?, priority = ?
@@ -120,23 +152,7 @@ func UpdateWantToVisit(db *sql.DB, wtv model.UpdateWantToVisit) error {
 }
 
 // DeleteWantToVisit deletes a want_to_visit entry.
-func DeleteWantToVisit(db *sql.DB, id int64) error {
+func DeleteWantToVisit(db Querier, id int64) error {
 	_, err := db.Exec("DELETE FROM want_to_visit WHERE id = ?", id)
 	return err
 }
-
-// ConvertWantToVisitToVisit deletes a want_to_visit entry and returns its restaurant ID.
-// The caller should then create a visit form with this restaurant pre-filled.
-func ConvertWantToVisitToVisit(db *sql.DB, wtvID int64) (int64, error) {
-	wtv, err := GetWantToVisit(db, wtvID)
-	if err != nil {
-		return 0, err
-	}
-
-	// Delete the want_to_visit entry
-	if err := DeleteWantToVisit(db, wtvID); err != nil {
-		return 0, err
-	}
-
-	return wtv.RestaurantID, nil
-}
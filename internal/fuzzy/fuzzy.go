@@ -0,0 +1,221 @@
+// Package fuzzy ranks strings against a typed pattern the way fzf does: the
+// pattern's characters must appear in order, and matches score higher when
+// they are consecutive, start words or sit close together.
+//
+// A pattern may hold several space-separated terms, all of which must match.
+// Terms are case-insensitive unless they contain an upper-case letter.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scoring follows fzf's v1 algorithm.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	// bonusBoundary rewards a match at the start of a word.
+	bonusBoundary = scoreMatch / 2
+	// bonusCamel rewards a match at a camelCase or letter-to-digit change.
+	bonusCamel = bonusBoundary - 1
+	// bonusConsecutive rewards a match right after another one.
+	bonusConsecutive = -(scoreGapStart + scoreGapExtension)
+	// The first character of a term counts its bonus twice.
+	bonusFirstCharMultiplier = 2
+)
+
+// Result is a successful match. Positions are the rune indexes of the
+// matched characters in ascending order, for highlighting.
+type Result struct {
+	Score     int
+	Positions []int
+}
+
+// Match matches pattern against text. An empty pattern matches everything
+// with a zero score.
+func Match(pattern, text string) (Result, bool) {
+	terms := strings.Fields(pattern)
+	if len(terms) == 0 {
+		return Result{}, true
+	}
+	runes := []rune(text)
+	var res Result
+	seen := make(map[int]bool)
+	for _, term := range terms {
+		score, positions, ok := matchTerm([]rune(term), runes)
+		if !ok {
+			return Result{}, false
+		}
+		res.Score += score
+		for _, p := range positions {
+			if !seen[p] {
+				seen[p] = true
+				res.Positions = append(res.Positions, p)
+			}
+		}
+	}
+	sort.Ints(res.Positions)
+	return res, true
+}
+
+// Ranked is one of the texts given to Rank with its match.
+type Ranked struct {
+	Index int
+	Result
+}
+
+// Rank returns the texts pattern matches, best first. Ties go to the shorter
+// text, then to the earlier one.
+func Rank(pattern string, texts []string) []Ranked {
+	var out []Ranked
+	for i, text := range texts {
+		if res, ok := Match(pattern, text); ok {
+			out = append(out, Ranked{Index: i, Result: res})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if la, lb := len(texts[a.Index]), len(texts[b.Index]); la != lb {
+			return la < lb
+		}
+		return a.Index < b.Index
+	})
+	return out
+}
+
+// matchTerm finds the shortest window of text holding term's characters in
+// order, preferring the first such window, and scores it.
+func matchTerm(term, text []rune) (int, []int, bool) {
+	caseSensitive := false
+	for _, r := range term {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	eq := func(a, b rune) bool {
+		if caseSensitive {
+			return a == b
+		}
+		return unicode.ToLower(a) == unicode.ToLower(b)
+	}
+
+	// Scan forward for the first place the whole term matches...
+	pi, end := 0, -1
+	for i, r := range text {
+		if eq(r, term[pi]) {
+			pi++
+			if pi == len(term) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+	// ...then back from its end to the latest start, shortening the window.
+	pi, start := len(term)-1, end
+	for i := end; i >= 0; i-- {
+		if eq(text[i], term[pi]) {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	score, positions := scoreWindow(term, text, start, end, eq)
+	return score, positions, true
+}
+
+type charClass int
+
+const (
+	classNonWord charClass = iota
+	classLower
+	classUpper
+	classLetter
+	classDigit
+)
+
+func classOf(r rune) charClass {
+	switch {
+	case unicode.IsLower(r):
+		return classLower
+	case unicode.IsUpper(r):
+		return classUpper
+	case unicode.IsLetter(r):
+		return classLetter
+	case unicode.IsDigit(r):
+		return classDigit
+	}
+	return classNonWord
+}
+
+func bonusFor(prev, cur charClass) int {
+	switch {
+	case prev == classNonWord && cur != classNonWord:
+		return bonusBoundary
+	case prev == classLower && cur == classUpper,
+		prev != classDigit && cur == classDigit:
+		return bonusCamel
+	}
+	return 0
+}
+
+func scoreWindow(term, text []rune, start, end int, eq func(a, b rune) bool) (int, []int) {
+	positions := make([]int, 0, len(term))
+	score, pi := 0, 0
+	inGap := false
+	consecutive, firstBonus := 0, 0
+	prevClass := classNonWord
+	if start > 0 {
+		prevClass = classOf(text[start-1])
+	}
+
+	for i := start; i <= end; i++ {
+		class := classOf(text[i])
+		if pi < len(term) && eq(text[i], term[pi]) {
+			positions = append(positions, i)
+			score += scoreMatch
+			bonus := bonusFor(prevClass, class)
+			if consecutive == 0 {
+				firstBonus = bonus
+			} else {
+				// A consecutive run keeps the bonus of the character that
+				// started it, unless a new word starts within it.
+				if bonus == bonusBoundary {
+					firstBonus = bonus
+				}
+				bonus = max(bonus, firstBonus, bonusConsecutive)
+			}
+			if pi == 0 {
+				score += bonus * bonusFirstCharMultiplier
+			} else {
+				score += bonus
+			}
+			inGap = false
+			consecutive++
+			pi++
+		} else {
+			if inGap {
+				score += scoreGapExtension
+			} else {
+				score += scoreGapStart
+			}
+			inGap = true
+			consecutive, firstBonus = 0, 0
+		}
+		prevClass = class
+	}
+	return score, positions
+}
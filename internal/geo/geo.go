@@ -0,0 +1,285 @@
+// Package geo writes restaurants as map features, in GeoJSON for GIS tools
+// and web maps and in KML for Google Earth and My Maps, so they can be taken
+// into any map app.
+//
+// Each place carries its average rating, visit count, last visit and want to
+// visit priority, and is coloured by them: green for places rated 8 or more,
+// yellow for 5 or more, red below that, blue for the wishlist and grey for
+// places visited but never rated.
+package geo
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"toni/internal/model"
+	"toni/internal/util"
+)
+
+// category is how a place is coloured on the map.
+type category int
+
+const (
+	categoryLoved category = iota
+	categoryLiked
+	categoryDisliked
+	categoryWishlist
+	categoryUnrated
+)
+
+var categories = []struct {
+	id, name string
+	// color is #rrggbb.
+	color string
+}{
+	categoryLoved:    {"loved", "Rated 8 or more", "#2e7d32"},
+	categoryLiked:    {"liked", "Rated 5 to 8", "#f9a825"},
+	categoryDisliked: {"disliked", "Rated below 5", "#c62828"},
+	categoryWishlist: {"wishlist", "Want to visit", "#1565c0"},
+	categoryUnrated:  {"unrated", "Not rated", "#757575"},
+}
+
+// categoryOf returns the colour category of p. Rated places go by their
+// average rating even when they're also on the wishlist.
+func categoryOf(p model.PickCandidate) category {
+	switch {
+	case p.AvgRating != nil && *p.AvgRating >= 8:
+		return categoryLoved
+	case p.AvgRating != nil && *p.AvgRating >= 5:
+		return categoryLiked
+	case p.AvgRating != nil:
+		return categoryDisliked
+	case p.Wishlisted:
+		return categoryWishlist
+	default:
+		return categoryUnrated
+	}
+}
+
+// Located returns the places that have coordinates, and how many didn't.
+func Located(places []model.PickCandidate) ([]model.PickCandidate, int) {
+	var out []model.PickCandidate
+	for _, p := range places {
+		if p.Latitude != nil && p.Longitude != nil {
+			out = append(out, p)
+		}
+	}
+	return out, len(places) - len(out)
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type feature struct {
+	Type       string         `json:"type"`
+	ID         int64          `json:"id"`
+	Geometry   point          `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type point struct {
+	Type string `json:"type"`
+	// Coordinates are longitude then latitude, as GeoJSON orders them.
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON writes places as a GeoJSON FeatureCollection of points.
+// Places without coordinates are left out. Properties that a place lacks
+// are left out rather than written as empty, and marker-color follows the
+// simplestyle convention that geojson.io and GitHub draw.
+func WriteGeoJSON(w io.Writer, places []model.PickCandidate) error {
+	located, _ := Located(places)
+	fc := featureCollection{Type: "FeatureCollection", Features: make([]feature, 0, len(located))}
+	for _, p := range located {
+		props := map[string]any{
+			"name":         p.Name,
+			"visit_count":  p.VisitCount,
+			"wishlist":     p.Wishlisted,
+			"category":     categories[categoryOf(p)].id,
+			"marker-color": categories[categoryOf(p)].color,
+		}
+		for key, value := range map[string]string{
+			"address":      p.Address,
+			"city":         p.City,
+			"neighborhood": p.Neighborhood,
+			"cuisine":      p.Cuisine,
+			"price":        p.PriceRange,
+			"last_visit":   p.LastVisit,
+		} {
+			if value != "" {
+				props[key] = value
+			}
+		}
+		if p.AvgRating != nil {
+			props["avg_rating"] = math.Round(*p.AvgRating*10) / 10
+		}
+		if p.Priority != nil {
+			props["priority"] = *p.Priority
+		}
+		if p.WouldReturn != nil {
+			props["would_return"] = *p.WouldReturn
+		}
+		fc.Features = append(fc.Features, feature{
+			Type:       "Feature",
+			ID:         p.RestaurantID,
+			Geometry:   point{Type: "Point", Coordinates: [2]float64{*p.Longitude, *p.Latitude}},
+			Properties: props,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(fc); err != nil {
+		return fmt.Errorf("failed to write GeoJSON: %w", err)
+	}
+	return nil
+}
+
+type kmlDocument struct {
+	XMLName  xml.Name `xml:"kml"`
+	NS       string   `xml:"xmlns,attr"`
+	Document struct {
+		Name    string      `xml:"name"`
+		Styles  []kmlStyle  `xml:"Style"`
+		Folders []kmlFolder `xml:"Folder"`
+	} `xml:"Document"`
+}
+
+type kmlStyle struct {
+	ID    string `xml:"id,attr"`
+	Color string `xml:"IconStyle>color"`
+}
+
+type kmlFolder struct {
+	Name  string         `xml:"name"`
+	Marks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string    `xml:"name"`
+	Description string    `xml:"description,omitempty"`
+	Address     string    `xml:"address,omitempty"`
+	StyleURL    string    `xml:"styleUrl"`
+	Data        []kmlData `xml:"ExtendedData>Data"`
+	Point       string    `xml:"Point>coordinates"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// WriteKML writes places as a KML document called name, with a folder of
+// placemarks for each colour category. Places without coordinates are left
+// out.
+func WriteKML(w io.Writer, name string, places []model.PickCandidate) error {
+	located, _ := Located(places)
+	var doc kmlDocument
+	doc.NS = "http://www.opengis.net/kml/2.2"
+	doc.Document.Name = name
+
+	folders := make([]kmlFolder, len(categories))
+	for i, c := range categories {
+		doc.Document.Styles = append(doc.Document.Styles, kmlStyle{ID: c.id, Color: kmlColor(c.color)})
+		folders[i].Name = c.name
+	}
+	for _, p := range located {
+		c := categoryOf(p)
+		folders[c].Marks = append(folders[c].Marks, kmlPlacemark{
+			Name:        p.Name,
+			Description: describe(p),
+			Address:     joinNonEmpty(", ", p.Address, p.City),
+			StyleURL:    "#" + categories[c].id,
+			Data:        kmlExtendedData(p),
+			Point:       fmt.Sprintf("%g,%g", *p.Longitude, *p.Latitude),
+		})
+	}
+	for _, f := range folders {
+		if len(f.Marks) > 0 {
+			doc.Document.Folders = append(doc.Document.Folders, f)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write KML: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write KML: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write KML: %w", err)
+	}
+	return nil
+}
+
+func kmlExtendedData(p model.PickCandidate) []kmlData {
+	var data []kmlData
+	add := func(name, value string) {
+		if value != "" {
+			data = append(data, kmlData{Name: name, Value: value})
+		}
+	}
+	add("cuisine", p.Cuisine)
+	add("neighborhood", p.Neighborhood)
+	add("price", p.PriceRange)
+	if p.AvgRating != nil {
+		add("avg_rating", fmt.Sprintf("%.1f", *p.AvgRating))
+	}
+	add("visit_count", fmt.Sprint(p.VisitCount))
+	add("last_visit", p.LastVisit)
+	if p.Priority != nil {
+		add("priority", fmt.Sprint(*p.Priority))
+	}
+	if p.WouldReturn != nil {
+		add("would_return", util.FormatWouldReturn(p.WouldReturn))
+	}
+	return data
+}
+
+// describe summarises a place for map popups: its cuisine, area and price on
+// one line, and how it went, e.g. "8.5/10 over 3 visits, last 2025-03-14", on
+// the next.
+func describe(p model.PickCandidate) string {
+	var lines []string
+	if s := joinNonEmpty(" · ", p.Cuisine, p.Neighborhood, p.PriceRange); s != "" {
+		lines = append(lines, s)
+	}
+	switch {
+	case p.VisitCount > 0:
+		s := fmt.Sprintf("%d visits", p.VisitCount)
+		if p.VisitCount == 1 {
+			s = "1 visit"
+		}
+		if p.AvgRating != nil {
+			s = util.FormatAvgRating(p.AvgRating) + "/10 over " + s
+		}
+		lines = append(lines, s+", last "+p.LastVisit)
+	case p.Wishlisted && p.Priority != nil:
+		lines = append(lines, fmt.Sprintf("Want to visit, priority %d", *p.Priority))
+	case p.Wishlisted:
+		lines = append(lines, "Want to visit")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// kmlColor converts #rrggbb to KML's aabbggrr.
+func kmlColor(hex string) string {
+	h := strings.TrimPrefix(hex, "#")
+	return "ff" + h[4:6] + h[2:4] + h[0:2]
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, sep)
+}
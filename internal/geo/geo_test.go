@@ -0,0 +1,166 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"toni/internal/model"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestCategoryOf(t *testing.T) {
+	tests := []struct {
+		name string
+		p    model.PickCandidate
+		want category
+	}{
+		{"loved", model.PickCandidate{AvgRating: ptr(8.0)}, categoryLoved},
+		{"liked", model.PickCandidate{AvgRating: ptr(7.9)}, categoryLiked},
+		{"liked at 5", model.PickCandidate{AvgRating: ptr(5.0)}, categoryLiked},
+		{"disliked", model.PickCandidate{AvgRating: ptr(4.5)}, categoryDisliked},
+		{"rated and wishlisted", model.PickCandidate{AvgRating: ptr(9.0), Wishlisted: true}, categoryLoved},
+		{"wishlist", model.PickCandidate{Wishlisted: true}, categoryWishlist},
+		{"unrated", model.PickCandidate{VisitCount: 2}, categoryUnrated},
+	}
+	for _, tt := range tests {
+		if got := categoryOf(tt.p); got != tt.want {
+			t.Errorf("%s: categoryOf = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKMLColor(t *testing.T) {
+	tests := map[string]string{
+		"#2e7d32": "ff327d2e",
+		"#1565c0": "ffc06515",
+	}
+	for in, want := range tests {
+		if got := kmlColor(in); got != want {
+			t.Errorf("kmlColor(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		p    model.PickCandidate
+		want string
+	}{
+		{model.PickCandidate{}, ""},
+		{model.PickCandidate{Cuisine: "Pizza", PriceRange: "$$"}, "Pizza · $$"},
+		{
+			model.PickCandidate{Cuisine: "Pizza", Neighborhood: "Carroll Gardens", AvgRating: ptr(8.5), VisitCount: 3, LastVisit: "2025-03-14"},
+			"Pizza · Carroll Gardens\n8.5/10 over 3 visits, last 2025-03-14",
+		},
+		{model.PickCandidate{VisitCount: 1, LastVisit: "2025-03-14"}, "1 visit, last 2025-03-14"},
+		{model.PickCandidate{Wishlisted: true, Priority: ptr(4)}, "Want to visit, priority 4"},
+		{model.PickCandidate{Wishlisted: true}, "Want to visit"},
+	}
+	for _, tt := range tests {
+		if got := describe(tt.p); got != tt.want {
+			t.Errorf("describe(%+v) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+var testPlaces = []model.PickCandidate{
+	{RestaurantID: 1, Name: "Lucali", City: "Brooklyn", Cuisine: "Pizza", Latitude: ptr(40.68), Longitude: ptr(-73.99), AvgRating: ptr(8.66), VisitCount: 3, LastVisit: "2025-03-14"},
+	{RestaurantID: 2, Name: "Via Carota", Latitude: ptr(40.73), Longitude: ptr(-74.0), Wishlisted: true, Priority: ptr(5)},
+	{RestaurantID: 3, Name: "Nowhere", AvgRating: ptr(3.0)},
+}
+
+func TestLocated(t *testing.T) {
+	located, missing := Located(testPlaces)
+	if len(located) != 2 || missing != 1 {
+		t.Errorf("Located = %d places, %d missing, want 2 and 1", len(located), missing)
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGeoJSON(&buf, testPlaces); err != nil {
+		t.Fatal(err)
+	}
+	var fc featureCollection
+	if err := json.Unmarshal(buf.Bytes(), &fc); err != nil {
+		t.Fatalf("invalid GeoJSON: %v\n%s", err, buf.String())
+	}
+	if fc.Type != "FeatureCollection" || len(fc.Features) != 2 {
+		t.Fatalf("got %s with %d features, want a FeatureCollection of 2", fc.Type, len(fc.Features))
+	}
+
+	lucali := fc.Features[0]
+	if lucali.ID != 1 || lucali.Geometry.Coordinates != [2]float64{-73.99, 40.68} {
+		t.Errorf("Lucali = %+v, want id 1 at longitude -73.99, latitude 40.68", lucali)
+	}
+	for key, want := range map[string]any{
+		"name":         "Lucali",
+		"avg_rating":   8.7,
+		"category":     "loved",
+		"marker-color": "#2e7d32",
+		"visit_count":  3.0,
+	} {
+		if got := lucali.Properties[key]; got != want {
+			t.Errorf("Lucali %s = %v, want %v", key, got, want)
+		}
+	}
+	for _, key := range []string{"address", "neighborhood", "priority", "would_return"} {
+		if _, ok := lucali.Properties[key]; ok {
+			t.Errorf("Lucali has %s, want it left out", key)
+		}
+	}
+	if got := fc.Features[1].Properties["priority"]; got != 5.0 {
+		t.Errorf("Via Carota priority = %v, want 5", got)
+	}
+}
+
+func TestWriteKML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteKML(&buf, "toni & friends", testPlaces); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Errorf("KML does not start with the XML header")
+	}
+	var doc kmlDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid KML: %v\n%s", err, out)
+	}
+	if doc.Document.Name != "toni & friends" {
+		t.Errorf("name = %q", doc.Document.Name)
+	}
+	if len(doc.Document.Styles) != len(categories) {
+		t.Errorf("got %d styles, want one for each of %d categories", len(doc.Document.Styles), len(categories))
+	}
+	// Empty folders are left out.
+	if len(doc.Document.Folders) != 2 {
+		t.Fatalf("got %d folders, want 2", len(doc.Document.Folders))
+	}
+	loved := doc.Document.Folders[0]
+	if loved.Name != "Rated 8 or more" || len(loved.Marks) != 1 {
+		t.Fatalf("first folder = %+v", loved)
+	}
+	mark := loved.Marks[0]
+	if mark.Point != "-73.99,40.68" || mark.StyleURL != "#loved" || mark.Address != "Brooklyn" {
+		t.Errorf("Lucali placemark = %+v", mark)
+	}
+	if want := []kmlData{{"cuisine", "Pizza"}, {"avg_rating", "8.7"}, {"visit_count", "3"}, {"last_visit", "2025-03-14"}}; !equalData(mark.Data, want) {
+		t.Errorf("Lucali data = %v, want %v", mark.Data, want)
+	}
+}
+
+func equalData(a, b []kmlData) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+// Package ics writes visits and planned visits as an iCalendar (RFC 5545)
+// file, so the journal can be subscribed to or imported in calendar apps.
+//
+// Visits are all-day events on the day they happened, with the rating and
+// notes in the description. Planned visits with a time are timed events of
+// PlanDuration starting then; without one they are all-day events too. Every
+// event keeps the same UID across exports, so importing a newer file updates
+// events rather than duplicating them.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"toni/internal/model"
+	"toni/internal/util"
+)
+
+// PlanDuration is how long a planned visit with a time is shown as lasting.
+const PlanDuration = 2 * time.Hour
+
+// Event is one calendar event.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Latitude    *float64
+	Longitude   *float64
+	// Date is the day of an all-day event, as YYYY-MM-DD. It is ignored when
+	// Start is set.
+	Date string
+	// Start and End bound a timed event.
+	Start, End time.Time
+}
+
+// VisitEvent returns the all-day event for a visit. Visits without a date
+// have no place in a calendar, so ok is false for them.
+func VisitEvent(v model.VisitRow) (e Event, ok bool) {
+	if util.ValidateDate(v.VisitedOn) != nil {
+		return Event{}, false
+	}
+	var lines []string
+	if v.Rating != nil {
+		lines = append(lines, "Rating: "+util.FormatRating(v.Rating))
+	}
+	if v.WouldReturn != nil {
+		lines = append(lines, "Would return: "+util.FormatWouldReturn(v.WouldReturn))
+	}
+	if v.Notes != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, v.Notes)
+	}
+	return Event{
+		UID:         fmt.Sprintf("visit-%d@toni", v.ID),
+		Summary:     v.RestaurantName,
+		Description: strings.Join(lines, "\n"),
+		Location:    joinNonEmpty(", ", v.Address, v.City),
+		Latitude:    v.Latitude,
+		Longitude:   v.Longitude,
+		Date:        v.VisitedOn,
+	}, true
+}
+
+// PlanEvent returns the event for a planned visit. Its time is read in loc.
+func PlanEvent(p model.PlannedVisitRow, loc *time.Location) Event {
+	var lines []string
+	if p.PartySize != nil {
+		lines = append(lines, fmt.Sprintf("Party of %d", *p.PartySize))
+	}
+	if p.Notes != "" {
+		lines = append(lines, p.Notes)
+	}
+	e := Event{
+		UID:         fmt.Sprintf("plan-%d@toni", p.ID),
+		Summary:     p.RestaurantName,
+		Description: strings.Join(lines, "\n"),
+		Location:    joinNonEmpty(", ", p.Address, p.City),
+		Latitude:    p.Latitude,
+		Longitude:   p.Longitude,
+		Date:        p.PlannedOn,
+	}
+	if p.PlannedAt != "" {
+		if start, err := time.ParseInLocation("2006-01-02 15:04", p.PlannedOn+" "+p.PlannedAt, loc); err == nil {
+			e.Start, e.End = start, start.Add(PlanDuration)
+		}
+	}
+	return e
+}
+
+// Write writes events as a calendar called name. now is the time the
+// calendar is stamped with.
+func Write(w io.Writer, name string, events []Event, now time.Time) error {
+	cw := &writer{w: bufio.NewWriter(w)}
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:-//toni//toni//EN")
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	cw.line("X-WR-CALNAME:" + escape(name))
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		cw.line("BEGIN:VEVENT")
+		cw.line("UID:" + e.UID)
+		cw.line("DTSTAMP:" + stamp)
+		if !e.Start.IsZero() {
+			cw.line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+			cw.line("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+		} else {
+			day, err := time.Parse("2006-01-02", e.Date)
+			if err != nil {
+				return fmt.Errorf("invalid date for event %s: %w", e.UID, err)
+			}
+			cw.line("DTSTART;VALUE=DATE:" + day.Format("20060102"))
+			cw.line("DTEND;VALUE=DATE:" + day.AddDate(0, 0, 1).Format("20060102"))
+			cw.line("TRANSP:TRANSPARENT")
+		}
+		cw.line("SUMMARY:" + escape(e.Summary))
+		if e.Location != "" {
+			cw.line("LOCATION:" + escape(e.Location))
+		}
+		if e.Latitude != nil && e.Longitude != nil {
+			cw.line(fmt.Sprintf("GEO:%.6f;%.6f", *e.Latitude, *e.Longitude))
+		}
+		if e.Description != "" {
+			cw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		cw.line("END:VEVENT")
+	}
+	cw.line("END:VCALENDAR")
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	if cw.err != nil {
+		return fmt.Errorf("failed to write calendar: %w", cw.err)
+	}
+	return nil
+}
+
+// writer writes content lines, ending them with CRLF and folding them at 75
+// octets as RFC 5545 asks. The first error is kept and later writes skipped.
+type writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (cw *writer) line(s string) {
+	if cw.err != nil {
+		return
+	}
+	limit := 75
+	for len(s) > limit {
+		// Never split a UTF-8 sequence across lines.
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, cw.err = cw.w.WriteString(s[:cut] + "\r\n "); cw.err != nil {
+			return
+		}
+		s = s[cut:]
+		// Continuation lines start with a space, which counts towards them.
+		limit = 74
+	}
+	_, cw.err = cw.w.WriteString(s + "\r\n")
+}
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return strings.Join(out, sep)
+}
@@ -0,0 +1,133 @@
+package ics
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"toni/internal/model"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"Lucali, Brooklyn", `Lucali\, Brooklyn`},
+		{"a;b", `a\;b`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines", `two\nlines`},
+		{"crlf\r\nline", `crlf\nline`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLineFolding(t *testing.T) {
+	tests := []string{
+		"SHORT",
+		strings.Repeat("a", 75),
+		strings.Repeat("a", 76),
+		strings.Repeat("b", 300),
+		"DESCRIPTION:" + strings.Repeat("é", 100),
+		"SUMMARY:" + strings.Repeat("🍜", 40),
+	}
+	for _, line := range tests {
+		var buf bytes.Buffer
+		cw := &writer{w: bufio.NewWriter(&buf)}
+		cw.line(line)
+		if err := cw.w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Errorf("line %q does not end with CRLF", line)
+		}
+		parts := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		var unfolded strings.Builder
+		for i, p := range parts {
+			if len(p) > 75 {
+				t.Errorf("folded line %d of %q is %d octets", i, line, len(p))
+			}
+			if !utf8.ValidString(p) {
+				t.Errorf("folded line %d of %q splits a UTF-8 sequence", i, line)
+			}
+			if i > 0 {
+				if !strings.HasPrefix(p, " ") {
+					t.Errorf("continuation line %d of %q does not start with a space", i, line)
+				}
+				p = p[1:]
+			}
+			unfolded.WriteString(p)
+		}
+		if unfolded.String() != line {
+			t.Errorf("unfolding gave %q, want %q", unfolded.String(), line)
+		}
+	}
+}
+
+func TestPlanEvent(t *testing.T) {
+	party := 4
+	plan := model.PlannedVisitRow{
+		ID: 7, PlannedOn: "2026-10-24", PlannedAt: "19:30", PartySize: &party, Notes: "conf 123",
+		RestaurantName: "Lucali", City: "Brooklyn",
+	}
+	loc := time.FixedZone("EDT", -4*3600)
+
+	e := PlanEvent(plan, loc)
+	if e.UID != "plan-7@toni" || e.Summary != "Lucali" || e.Location != "Brooklyn" {
+		t.Errorf("PlanEvent = %+v", e)
+	}
+	if e.Description != "Party of 4\nconf 123" {
+		t.Errorf("Description = %q", e.Description)
+	}
+	if want := time.Date(2026, 10, 24, 23, 30, 0, 0, time.UTC); !e.Start.Equal(want) || !e.End.Equal(want.Add(PlanDuration)) {
+		t.Errorf("Start, End = %v, %v", e.Start, e.End)
+	}
+
+	plan.PlannedAt = ""
+	if e := PlanEvent(plan, loc); !e.Start.IsZero() || e.Date != "2026-10-24" {
+		t.Errorf("PlanEvent without a time = %+v, want an all-day event", e)
+	}
+}
+
+func TestVisitEventWithoutDate(t *testing.T) {
+	if _, ok := VisitEvent(model.VisitRow{ID: 1, RestaurantName: "Lucali"}); ok {
+		t.Error("VisitEvent without a date is ok, want skipped")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	events := []Event{
+		{UID: "visit-1@toni", Summary: "Lucali", Location: "Brooklyn, NY", Date: "2025-03-14"},
+		{UID: "plan-2@toni", Summary: "Via Carota", Start: time.Date(2026, 10, 24, 23, 30, 0, 0, time.UTC), End: time.Date(2026, 10, 25, 1, 30, 0, 0, time.UTC)},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "toni", events, time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTAMP:20261001T120000Z\r\n",
+		"DTSTART;VALUE=DATE:20250314\r\nDTEND;VALUE=DATE:20250315\r\n",
+		`LOCATION:Brooklyn\, NY` + "\r\n",
+		"DTSTART:20261024T233000Z\r\nDTEND:20261025T013000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, out)
+		}
+	}
+
+	err := Write(&bytes.Buffer{}, "toni", []Event{{UID: "bad", Date: "2025-13-01"}}, time.Now())
+	if err == nil {
+		t.Error("Write with an invalid date succeeded")
+	}
+}
@@ -0,0 +1,468 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"toni/internal/model"
+	"toni/internal/util"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Fields lists the toni fields a CSV column can be mapped to.
+var Fields = []string{
+	"name", "address", "city", "neighborhood", "cuisine", "price", "latitude", "longitude",
+	"date", "rating", "would_return", "notes", "priority", "list",
+}
+
+// Mapping describes a CSV export: which of its columns hold which of toni's
+// fields, and how to read their values. Rows with a date or rating become
+// visits; the rest, and rows on one of WishlistLists, become want to visit
+// entries.
+type Mapping struct {
+	// Source is recorded on the want to visit entries imported, e.g.
+	// "Beli". It defaults to the file's name.
+	Source string `toml:"source,omitempty"`
+	// Columns maps toni's fields to the headers of the columns holding
+	// them, matched regardless of case. A header may list alternatives
+	// separated by |, in which case the first the file has is used.
+	Columns map[string]string `toml:"columns"`
+	// Rating is the scale of the export's ratings.
+	Rating Scale `toml:"rating,omitempty"`
+	// DateOrder says how numeric dates like 3/4 are read: "mdy" (the
+	// default) or "dmy".
+	DateOrder string `toml:"date_order,omitempty"`
+	// DefaultDate is the date given to rated rows without one, which are
+	// otherwise skipped.
+	DefaultDate string `toml:"default_date,omitempty"`
+	// WishlistLists names the values of the list column that mark rows for
+	// the want to visit list, e.g. "Want to Try".
+	WishlistLists []string `toml:"wishlist_lists,omitempty"`
+}
+
+// Scale is the range an export's ratings are given in.
+type Scale struct {
+	Min float64 `toml:"min"`
+	Max float64 `toml:"max"`
+}
+
+// Convert scales a rating on s to toni's 1-10, in proportion to the top of
+// the scale: 4 of 5 stars is 8, 73 of 100 is 7.3. Ratings that come out
+// below 1 are raised to it.
+func (s Scale) Convert(v float64) float64 {
+	r := math.Round(v*10/s.Max*10) / 10
+	return math.Max(1, math.Min(10, r))
+}
+
+func (s Scale) String() string {
+	return strconv.FormatFloat(s.Min, 'f', -1, 64) + "-" + strconv.FormatFloat(s.Max, 'f', -1, 64)
+}
+
+// BeliMapping reads Beli's CSV export. Beli scores places from 0 to 10.
+var BeliMapping = Mapping{
+	Source: "Beli",
+	Columns: map[string]string{
+		"name":         "Name|Restaurant|Place",
+		"address":      "Address|Street Address",
+		"city":         "City",
+		"neighborhood": "Neighborhood|Neighbourhood",
+		"cuisine":      "Cuisine|Cuisines|Category",
+		"price":        "Price|Price Range",
+		"latitude":     "Latitude|Lat",
+		"longitude":    "Longitude|Lng|Lon",
+		"date":         "Date Visited|Visit Date|Visited|Date",
+		"rating":       "Score|Your Score|Rating",
+		"notes":        "Notes|Note|Review",
+		"list":         "List|Status|Type",
+	},
+	Rating:        Scale{Min: 0, Max: 10},
+	WishlistLists: []string{"Want to Try", "Want to Go", "Bookmarked"},
+}
+
+// YelpMapping reads Yelp bookmarks and collections exported as CSV. Their
+// ratings are Yelp's, not yours, so they aren't mapped and every row goes on
+// the want to visit list.
+var YelpMapping = Mapping{
+	Source: "Yelp bookmarks",
+	Columns: map[string]string{
+		"name":      "Name|Business Name|Business",
+		"address":   "Address|Street Address|Address 1",
+		"city":      "City",
+		"cuisine":   "Categories|Category|Cuisine",
+		"price":     "Price|Price Range",
+		"latitude":  "Latitude|Lat",
+		"longitude": "Longitude|Lng|Lon",
+		"notes":     "Notes|Note|Comment",
+	},
+}
+
+// LoadMapping reads a mapping file, written in TOML like:
+//
+//	source = "My spreadsheet"
+//	date_order = "dmy"
+//
+//	[columns]
+//	name = "Restaurant"
+//	rating = "Stars"
+//	date = "When|Date"
+//
+//	[rating]
+//	min = 1
+//	max = 5
+func LoadMapping(path string) (*Mapping, error) {
+	var m Mapping
+	md, err := toml.DecodeFile(path, &m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %s", path, undecoded[0])
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// Encode writes m as a mapping file, as a starting point for one's own.
+func (m Mapping) Encode(w io.Writer) error {
+	if m.Rating == (Scale{}) {
+		m.Rating = Scale{Min: 1, Max: 10}
+	}
+	return toml.NewEncoder(w).Encode(m)
+}
+
+func (m Mapping) validate() error {
+	for field := range m.Columns {
+		if !isField(field) {
+			return fmt.Errorf("unknown field %q in columns (fields: %s)", field, strings.Join(Fields, ", "))
+		}
+	}
+	if m.Columns["name"] == "" {
+		return errors.New("columns must map name")
+	}
+	if s := m.scale(); s.Max <= s.Min || s.Max <= 0 {
+		return fmt.Errorf("rating scale %s is empty; max must be above min and 0", s)
+	}
+	if _, err := util.ParseDateOrder(m.DateOrder); err != nil {
+		return err
+	}
+	if m.DefaultDate != "" {
+		if err := util.ValidateDate(m.DefaultDate); err != nil {
+			return fmt.Errorf("default_date: %w", err)
+		}
+	}
+	return nil
+}
+
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// scale is the rating scale, 1-10 unless the mapping says otherwise.
+func (m Mapping) scale() Scale {
+	if m.Rating == (Scale{}) {
+		return Scale{Min: 1, Max: 10}
+	}
+	return m.Rating
+}
+
+// Read reads the CSV file at path. Rows that can't be imported are
+// reported as problems along with the columns the mapping leaves out.
+func (m Mapping) Read(path string) ([]Record, []Problem, error) {
+	if err := m.validate(); err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer f.Close()
+	return m.ReadCSV(filepath.Base(path), f)
+}
+
+// ReadCSV reads CSV from r, naming it name in problems.
+func (m Mapping) ReadCSV(name string, r io.Reader) ([]Record, []Problem, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	headerIndex := make(map[string]int, len(header))
+	for i, h := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if _, ok := headerIndex[key]; !ok {
+			headerIndex[key] = i
+		}
+	}
+	col := make(map[string]int)
+	used := make(map[int]bool)
+	for field, headers := range m.Columns {
+		for _, h := range strings.Split(headers, "|") {
+			if i, ok := headerIndex[strings.ToLower(strings.TrimSpace(h))]; ok {
+				col[field] = i
+				used[i] = true
+				break
+			}
+		}
+	}
+	if _, ok := col["name"]; !ok {
+		return nil, nil, fmt.Errorf("%s has no %s column for restaurant names (columns: %s)",
+			name, strings.ReplaceAll(m.Columns["name"], "|", " or "), strings.Join(header, ", "))
+	}
+
+	var problems []Problem
+	var unused []string
+	for i, h := range header {
+		if !used[i] && strings.TrimSpace(h) != "" {
+			unused = append(unused, strings.TrimPrefix(h, "\ufeff"))
+		}
+	}
+	if len(unused) > 0 {
+		problems = append(problems, Problem{File: name, Reason: "columns not imported: " + strings.Join(unused, ", ")})
+	}
+
+	rows := &csvRows{mapping: m, col: col, source: m.Source}
+	if rows.source == "" {
+		rows.source = name
+	}
+	rows.order, _ = util.ParseDateOrder(m.DateOrder)
+	var records []Record
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				problems = append(problems, Problem{File: name, Line: parseErr.Line, Reason: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		line, _ := cr.FieldPos(0)
+		record, err := rows.record(row)
+		switch {
+		case err != nil:
+			problems = append(problems, Problem{File: name, Line: line, Place: rows.field(row, "name"), Reason: err.Error()})
+		case record != nil:
+			records = append(records, *record)
+		}
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return records, problems, nil
+}
+
+// csvRows turns the rows of a CSV export into records.
+type csvRows struct {
+	mapping Mapping
+	col     map[string]int
+	order   util.DateOrder
+	source  string
+}
+
+func (c *csvRows) field(row []string, field string) string {
+	if i, ok := c.col[field]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+// record reads a row, returning nil for blank rows and an error saying what
+// is wrong with invalid ones.
+func (c *csvRows) record(row []string) (*Record, error) {
+	name := c.field(row, "name")
+	if name == "" {
+		for _, v := range row {
+			if strings.TrimSpace(v) != "" {
+				return nil, errors.New("no name")
+			}
+		}
+		return nil, nil
+	}
+
+	r := model.NewRestaurant{
+		Name:         name,
+		Address:      c.field(row, "address"),
+		City:         c.field(row, "city"),
+		Neighborhood: c.field(row, "neighborhood"),
+		Cuisine:      c.field(row, "cuisine"),
+	}
+	var err error
+	if r.PriceRange, err = parsePrice(c.field(row, "price")); err != nil {
+		return nil, err
+	}
+	if r.Latitude, err = parseCoordinate(c.field(row, "latitude"), 90); err != nil {
+		return nil, err
+	}
+	if r.Longitude, err = parseCoordinate(c.field(row, "longitude"), 180); err != nil {
+		return nil, err
+	}
+	notes := c.field(row, "notes")
+
+	rating, err := c.rating(c.field(row, "rating"))
+	if err != nil {
+		return nil, err
+	}
+	date, err := c.date(c.field(row, "date"))
+	if err != nil {
+		return nil, err
+	}
+	wishlisted := false
+	if list := c.field(row, "list"); list != "" {
+		for _, l := range c.mapping.WishlistLists {
+			if strings.EqualFold(l, list) {
+				wishlisted = true
+			}
+		}
+	}
+
+	if wishlisted || (date == "" && rating == nil) {
+		priority, err := parsePriority(c.field(row, "priority"))
+		if err != nil {
+			return nil, err
+		}
+		return &Record{
+			Restaurant: r,
+			Wishlist:   &model.NewWantToVisit{Notes: notes, Priority: priority, Source: c.source},
+		}, nil
+	}
+
+	if date == "" {
+		date = c.mapping.DefaultDate
+		if date == "" {
+			return nil, errors.New("rated but has no visit date; set default_date in a mapping to import it")
+		}
+	}
+	wouldReturn, err := parseYesNo(c.field(row, "would_return"))
+	if err != nil {
+		return nil, err
+	}
+	return &Record{
+		Restaurant: r,
+		Visit:      &model.NewVisit{VisitedOn: date, Rating: rating, Notes: notes, WouldReturn: wouldReturn},
+	}, nil
+}
+
+var (
+	leadingNumber = regexp.MustCompile(`^[+-]?\d+(?:[.,]\d+)?`)
+	// timestampPattern matches dates with a time after them, as spreadsheets
+	// and apps often write them.
+	timestampPattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})[T ]\d{1,2}:\d{2}`)
+)
+
+// rating reads a rating such as "8.5", "4/5", "4 stars" or "★★★★" and
+// converts it to toni's scale.
+func (c *csvRows) rating(s string) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var v float64
+	if n := leadingNumber.FindString(s); n != "" {
+		v, _ = strconv.ParseFloat(strings.Replace(n, ",", ".", 1), 64)
+	} else if stars := strings.Count(s, "★"); stars > 0 {
+		v = float64(stars)
+	} else {
+		return nil, fmt.Errorf("rating %q isn't a number", s)
+	}
+	scale := c.mapping.scale()
+	if v < scale.Min || v > scale.Max {
+		return nil, fmt.Errorf("rating %s is outside %s", s, scale)
+	}
+	converted := scale.Convert(v)
+	return &converted, nil
+}
+
+// date reads a visit date, with or without a time after it.
+func (c *csvRows) date(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if m := timestampPattern.FindStringSubmatch(s); m != nil {
+		s = m[1]
+	}
+	if t, err := time.Parse(time.RFC1123, s); err == nil {
+		s = t.Format("2006-01-02")
+	}
+	return util.ParseVisitDateInput(s, c.order, false)
+}
+
+// parsePrice reads "$$", or a number of symbols from 1 to 4, in any
+// currency.
+func parsePrice(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	n := 0
+	if v, err := strconv.Atoi(s); err == nil {
+		n = v
+	} else {
+		runes := []rune(s)
+		for _, r := range runes {
+			if r != runes[0] || r == ' ' {
+				return "", fmt.Errorf("price %q isn't $ to $$$$", s)
+			}
+		}
+		n = len(runes)
+	}
+	if n < 1 || n > 4 {
+		return "", fmt.Errorf("price %q isn't $ to $$$$", s)
+	}
+	return strings.Repeat("$", n), nil
+}
+
+func parseCoordinate(s string, limit float64) (*float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.Abs(v) > limit {
+		return nil, fmt.Errorf("coordinate %q is invalid", s)
+	}
+	return &v, nil
+}
+
+func parseYesNo(s string) (*bool, error) {
+	var v bool
+	switch strings.ToLower(s) {
+	case "":
+		return nil, nil
+	case "yes", "y", "true", "1":
+		v = true
+	case "no", "n", "false", "0":
+		v = false
+	default:
+		return nil, fmt.Errorf("would return %q isn't yes or no", s)
+	}
+	return &v, nil
+}
+
+func parsePriority(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 || v > 5 {
+		return nil, fmt.Errorf("priority %q isn't 1-5", s)
+	}
+	return &v, nil
+}
@@ -0,0 +1,239 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"toni/internal/model"
+)
+
+func TestScaleConvert(t *testing.T) {
+	tests := []struct {
+		scale Scale
+		v     float64
+		want  float64
+	}{
+		{Scale{Min: 1, Max: 5}, 4, 8},
+		{Scale{Min: 1, Max: 5}, 4.5, 9},
+		{Scale{Min: 1, Max: 5}, 1, 2},
+		{Scale{Min: 0, Max: 10}, 8.4, 8.4},
+		{Scale{Min: 0, Max: 10}, 0, 1},
+		{Scale{Min: 0, Max: 100}, 73, 7.3},
+		{Scale{Min: 1, Max: 3}, 2, 6.7},
+	}
+	for _, tt := range tests {
+		if got := tt.scale.Convert(tt.v); got != tt.want {
+			t.Errorf("Scale %s Convert(%v) = %v, want %v", tt.scale, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRating(t *testing.T) {
+	rows := &csvRows{mapping: Mapping{Rating: Scale{Min: 1, Max: 5}}}
+	tests := []struct {
+		in   string
+		want float64
+	}{
+		{"4", 8},
+		{"4.5/5", 9},
+		{"4,5", 9},
+		{"4 stars", 8},
+		{"★★★★", 8},
+		{"★★★☆☆", 6},
+	}
+	for _, tt := range tests {
+		got, err := rows.rating(tt.in)
+		if err != nil || got == nil || *got != tt.want {
+			t.Errorf("rating(%q) = %v, %v, want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if got, err := rows.rating(""); got != nil || err != nil {
+		t.Errorf("rating(\"\") = %v, %v, want nil", got, err)
+	}
+	for _, in := range []string{"great", "6", "0.5"} {
+		if _, err := rows.rating(in); err == nil {
+			t.Errorf("rating(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := map[string]string{
+		"":     "",
+		"$":    "$",
+		"$$$$": "$$$$",
+		"€€":   "$$",
+		"££££": "$$$$",
+		"2":    "$$",
+	}
+	for in, want := range tests {
+		if got, err := parsePrice(in); err != nil || got != want {
+			t.Errorf("parsePrice(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"$$$$$", "$€", "0", "5", "cheap", "$ $"} {
+		if got, err := parsePrice(in); err == nil {
+			t.Errorf("parsePrice(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestMappingValidate(t *testing.T) {
+	tests := []struct {
+		m   Mapping
+		msg string
+	}{
+		{Mapping{Columns: map[string]string{"name": "Name"}}, ""},
+		{Mapping{Columns: map[string]string{"city": "City"}}, "columns must map name"},
+		{Mapping{Columns: map[string]string{"name": "Name", "stars": "Stars"}}, `unknown field "stars"`},
+		{Mapping{Columns: map[string]string{"name": "Name"}, Rating: Scale{Min: 5, Max: 1}}, "rating scale 5-1 is empty"},
+		{Mapping{Columns: map[string]string{"name": "Name"}, DateOrder: "ymd"}, "unknown date order"},
+		{Mapping{Columns: map[string]string{"name": "Name"}, DefaultDate: "2025-02-30"}, "default_date"},
+		{BeliMapping, ""},
+		{YelpMapping, ""},
+	}
+	for _, tt := range tests {
+		err := tt.m.validate()
+		if tt.msg == "" && err != nil {
+			t.Errorf("validate(%+v) = %v, want nil", tt.m, err)
+		}
+		if tt.msg != "" && (err == nil || !strings.Contains(err.Error(), tt.msg)) {
+			t.Errorf("validate(%+v) = %v, want %q", tt.m, err, tt.msg)
+		}
+	}
+}
+
+func TestReadCSVBeli(t *testing.T) {
+	input := "\ufeffname,CITY,Score,Date Visited,Price,List,Notes,Friends\n" +
+		"Lucali,Brooklyn,8.4,2025-03-14,$$,Been,Great pie,Alex\n" +
+		"Via Carota,New York,,,$$$,Want to Try,Get the salad,\n" +
+		"Rated Later,Brooklyn,7,,,Been,,\n" +
+		",,,,,,,\n" +
+		",Queens,9,2025-01-01,,,,\n" +
+		"Bad Price,Brooklyn,8,2025-01-02,cheap,,,\n" +
+		"Peter Luger,Brooklyn,9.1,2024-12-31 19:30,$$$$,Been,,\n"
+	records, problems, err := BeliMapping.ReadCSV("beli.csv", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eight4, nine1 := 8.4, 9.1
+	want := []Record{
+		{
+			Restaurant: model.NewRestaurant{Name: "Lucali", City: "Brooklyn", PriceRange: "$$"},
+			Visit:      &model.NewVisit{VisitedOn: "2025-03-14", Rating: &eight4, Notes: "Great pie"},
+		},
+		{
+			Restaurant: model.NewRestaurant{Name: "Via Carota", City: "New York", PriceRange: "$$$"},
+			Wishlist:   &model.NewWantToVisit{Notes: "Get the salad", Source: "Beli"},
+		},
+		{
+			Restaurant: model.NewRestaurant{Name: "Peter Luger", City: "Brooklyn", PriceRange: "$$$$"},
+			Visit:      &model.NewVisit{VisitedOn: "2024-12-31", Rating: &nine1},
+		},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records =\n%s\nwant\n%s", dumpRecords(records), dumpRecords(want))
+	}
+
+	wantProblems := []Problem{
+		{File: "beli.csv", Reason: "columns not imported: Friends"},
+		{File: "beli.csv", Line: 4, Place: "Rated Later", Reason: "rated but has no visit date; set default_date in a mapping to import it"},
+		{File: "beli.csv", Line: 6, Reason: "no name"},
+		{File: "beli.csv", Line: 7, Place: "Bad Price", Reason: `price "cheap" isn't $ to $$$$`},
+	}
+	if !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("problems = %v, want %v", problems, wantProblems)
+	}
+}
+
+func TestReadCSVCustomMapping(t *testing.T) {
+	m := Mapping{
+		Columns:     map[string]string{"name": "Restaurant", "rating": "Stars", "date": "When|Date", "would_return": "Again", "priority": "Priority"},
+		Rating:      Scale{Min: 1, Max: 5},
+		DateOrder:   "dmy",
+		DefaultDate: "2020-01-01",
+	}
+	input := "Restaurant,Stars,Date,Again,Priority\n" +
+		"Lucali,4,14/3/2025,yes,\n" +
+		"Di Fara,5,,no,\n" +
+		"Roberta's,,,,4\n" +
+		"Bad,4,14/3/2025,maybe,\n"
+	records, problems, err := m.ReadCSV("mine.csv", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3:\n%s", len(records), dumpRecords(records))
+	}
+	if v := records[0].Visit; v == nil || v.VisitedOn != "2025-03-14" || *v.Rating != 8 || !*v.WouldReturn {
+		t.Errorf("Lucali = %s", dumpRecords(records[:1]))
+	}
+	if v := records[1].Visit; v == nil || v.VisitedOn != "2020-01-01" || *v.Rating != 10 || *v.WouldReturn {
+		t.Errorf("Di Fara = %s, want the default date", dumpRecords(records[1:2]))
+	}
+	if w := records[2].Wishlist; w == nil || *w.Priority != 4 || w.Source != "mine.csv" {
+		t.Errorf("Roberta's = %s, want on the wishlist from mine.csv", dumpRecords(records[2:]))
+	}
+	if len(problems) != 1 || problems[0].Place != "Bad" || !strings.Contains(problems[0].Reason, "would return") {
+		t.Errorf("problems = %v", problems)
+	}
+}
+
+func TestReadCSVMissingName(t *testing.T) {
+	_, _, err := YelpMapping.ReadCSV("yelp.csv", strings.NewReader("Title,City\nLucali,Brooklyn\n"))
+	if err == nil || !strings.Contains(err.Error(), "Name or Business Name or Business") {
+		t.Errorf("err = %v, want the name columns listed", err)
+	}
+}
+
+func TestMappingRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := BeliMapping.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "beli.toml")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadMapping(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*m, BeliMapping) {
+		t.Errorf("LoadMapping(Encode(BeliMapping)) = %+v, want %+v", *m, BeliMapping)
+	}
+
+	if err := os.WriteFile(path, []byte("[columns]\nname = \"Name\"\n[extra]\nx = 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMapping(path); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("LoadMapping with an unknown key = %v, want an error", err)
+	}
+}
+
+func dumpRecords(records []Record) string {
+	var lines []string
+	for _, r := range records {
+		line := fmt.Sprintf("%+v", r.Restaurant)
+		if v := r.Visit; v != nil {
+			line += fmt.Sprintf(" visit %s rating %v return %v notes %q", v.VisitedOn, deref(v.Rating), deref(v.WouldReturn), v.Notes)
+		}
+		if w := r.Wishlist; w != nil {
+			line += fmt.Sprintf(" wishlist priority %v notes %q source %q", deref(w.Priority), w.Notes, w.Source)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,235 @@
+// Package importer brings restaurants, visits and want to visit entries in
+// from other apps. Readers turn an app's export into Records, and Import adds
+// them in one transaction, reusing the restaurants already in the database
+// and skipping what was imported before.
+package importer
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"toni/internal/compare"
+	"toni/internal/db"
+	"toni/internal/model"
+)
+
+// Record is a restaurant to import, with a visit to it or a want to visit
+// entry for it. The RestaurantID of Visit and Wishlist is filled in by
+// Import.
+type Record struct {
+	Restaurant model.NewRestaurant
+	Visit      *model.NewVisit
+	Wishlist   *model.NewWantToVisit
+}
+
+// Status is what importing did with a record.
+type Status int
+
+const (
+	// StatusAdded means the visit or want to visit entry was added.
+	StatusAdded Status = iota
+	// StatusDuplicate means the restaurant already has a visit that day.
+	StatusDuplicate
+	// StatusListed means the restaurant is already on the wishlist.
+	StatusListed
+	// StatusVisited means a place saved for later has already been visited,
+	// so it wasn't added to the wishlist.
+	StatusVisited
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusAdded:
+		return "added"
+	case StatusDuplicate:
+		return "already logged that day"
+	case StatusListed:
+		return "already on your wishlist"
+	default:
+		return "already visited"
+	}
+}
+
+// Result is a record and what importing did with it.
+type Result struct {
+	Record Record
+	// Name is the restaurant's name as the database has it.
+	Name string
+	// NewRestaurant is set when the restaurant was added by this import.
+	NewRestaurant bool
+	Status        Status
+}
+
+// Options says how to import.
+type Options struct {
+	// DryRun works out what would be imported without changing anything.
+	DryRun bool
+}
+
+var errDryRun = errors.New("dry run")
+
+// Import adds records to the database in one transaction. Visits are added
+// before want to visit entries, so a place both saved and reviewed ends up
+// visited rather than on the wishlist. Restaurants already in the database,
+// found with compare.Index.Lookup, are reused rather than duplicated.
+func Import(database *sql.DB, records []Record, opts Options) ([]Result, error) {
+	ordered := make([]Record, 0, len(records))
+	for _, r := range records {
+		if r.Visit != nil {
+			ordered = append(ordered, r)
+		}
+	}
+	for _, r := range records {
+		if r.Visit == nil && r.Wishlist != nil {
+			ordered = append(ordered, r)
+		}
+	}
+
+	var results []Result
+	err := db.InTx(database, func(tx *sql.Tx) error {
+		existing, err := db.ListRestaurantStats(tx)
+		if err != nil {
+			return err
+		}
+		s := &session{
+			tx:      tx,
+			places:  existing,
+			index:   compare.NewIndex(existing),
+			visited: make(map[int64]bool),
+			listed:  make(map[int64]bool),
+		}
+		for _, p := range existing {
+			s.visited[p.RestaurantID] = p.VisitCount > 0
+			s.listed[p.RestaurantID] = p.Wishlisted
+		}
+		for _, r := range ordered {
+			result, err := s.add(r)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return results, nil
+}
+
+// session is the state of one import.
+type session struct {
+	tx *sql.Tx
+	// places holds the restaurants in the database, including those added by
+	// this import, in the order of index.
+	places  []model.RestaurantStats
+	index   *compare.Index
+	visited map[int64]bool
+	listed  map[int64]bool
+}
+
+func (s *session) add(r Record) (Result, error) {
+	result := Result{Record: r, Status: StatusAdded}
+	place, isNew, err := s.restaurant(r.Restaurant)
+	if err != nil {
+		return Result{}, err
+	}
+	id := place.RestaurantID
+	result.Name = place.Name
+	result.NewRestaurant = isNew
+
+	if r.Visit != nil {
+		visits, err := db.GetVisitsByRestaurant(s.tx, id)
+		if err != nil {
+			return Result{}, err
+		}
+		for _, v := range visits {
+			if v.VisitedOn == r.Visit.VisitedOn {
+				result.Status = StatusDuplicate
+				return result, nil
+			}
+		}
+		v := *r.Visit
+		v.RestaurantID = id
+		if _, err := db.InsertVisit(s.tx, v); err != nil {
+			return Result{}, err
+		}
+		s.visited[id] = true
+		return result, nil
+	}
+
+	switch {
+	case s.listed[id]:
+		result.Status = StatusListed
+	case s.visited[id]:
+		result.Status = StatusVisited
+	default:
+		w := *r.Wishlist
+		w.RestaurantID = id
+		if _, err := db.InsertWantToVisit(s.tx, w); err != nil {
+			return Result{}, err
+		}
+		s.listed[id] = true
+	}
+	return result, nil
+}
+
+// restaurant returns r's restaurant, adding it when the database doesn't have
+// it yet, and reports whether it was added.
+func (s *session) restaurant(r model.NewRestaurant) (model.RestaurantStats, bool, error) {
+	stats := model.RestaurantStats{Name: r.Name, Address: r.Address, City: r.City, PlaceID: r.PlaceID}
+	if i, ok := s.index.Lookup(stats); ok {
+		return s.places[i], false, nil
+	}
+
+	id, err := db.InsertRestaurant(s.tx, r)
+	if err != nil {
+		return model.RestaurantStats{}, false, err
+	}
+	stats.RestaurantID = id
+	s.places = append(s.places, stats)
+	s.index.Add(stats)
+	return stats, true, nil
+}
+
+// SplitAddress splits a one-line address such as "575 Henry St, Brooklyn,
+// NY 11231, USA" into the street address and the city. Exports that give
+// addresses this way don't say which part is the city, so it is a guess: the
+// part before the state and postcode, or the last part with any postcode
+// taken out.
+func SplitAddress(address string) (street, city string) {
+	var parts []string
+	for _, p := range strings.Split(address, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if len(parts) < 2 {
+		return address, ""
+	}
+	street = parts[0]
+	rest := parts[1:]
+	// The country comes last and has no postcode in it.
+	if len(rest) > 1 && !hasDigit(rest[len(rest)-1]) {
+		rest = rest[:len(rest)-1]
+	}
+	last := rest[len(rest)-1]
+	if len(rest) > 1 && hasDigit(last) && len(strings.Fields(last)) == 2 && !hasDigit(strings.Fields(last)[0]) {
+		// "NY 11231": a state and postcode, so the city is before it.
+		return street, rest[len(rest)-2]
+	}
+	var words []string
+	for _, w := range strings.Fields(last) {
+		if !hasDigit(w) {
+			words = append(words, w)
+		}
+	}
+	return street, strings.Join(words, " ")
+}
+
+func hasDigit(s string) bool {
+	return strings.ContainsAny(s, "0123456789")
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source is an app toni can import from. Sources that export CSV are read
+// through a Mapping of their columns; others have a Read function of their
+// own.
+type Source struct {
+	Name    string
+	Aliases []string
+	// Help describes the export the source reads.
+	Help string
+	// Mapping maps the columns of a CSV export onto toni's fields. A mapping
+	// file given when importing replaces it.
+	Mapping *Mapping
+	// Read reads an export that isn't CSV.
+	Read func(path string) ([]Record, []Problem, error)
+}
+
+// Sources lists the apps toni imports from.
+var Sources = []Source{
+	{
+		Name:    "google-takeout",
+		Aliases: []string{"takeout"},
+		Help:    "Google Maps saved places, lists and reviews from Google Takeout",
+		Read:    ReadTakeout,
+	},
+	{
+		Name:    "beli",
+		Help:    "Beli's CSV export of places you've been to and want to try",
+		Mapping: &BeliMapping,
+	},
+	{
+		Name:    "yelp",
+		Help:    "Yelp bookmarks or collections exported as CSV",
+		Mapping: &YelpMapping,
+	},
+	{
+		Name: "csv",
+		Help: "Any CSV, with its columns described by a mapping file (--mapping)",
+	},
+}
+
+// FindSource returns the source called name.
+func FindSource(name string) (Source, bool) {
+	name = strings.ToLower(name)
+	for _, s := range Sources {
+		if s.Name == name {
+			return s, true
+		}
+		for _, a := range s.Aliases {
+			if a == name {
+				return s, true
+			}
+		}
+	}
+	return Source{}, false
+}
+
+// ReadExport reads the export at path. When mapping is set it is used in
+// place of the source's own.
+func (s Source) ReadExport(path string, mapping *Mapping) ([]Record, []Problem, error) {
+	if mapping == nil {
+		mapping = s.Mapping
+	}
+	switch {
+	case mapping != nil:
+		return mapping.Read(path)
+	case s.Read != nil:
+		return s.Read(path)
+	default:
+		return nil, nil, fmt.Errorf("importing from %s needs a mapping file; run toni import mapping %s for a template to fill in", s.Name, s.Name)
+	}
+}
+
+// Problem is a row of an export that couldn't be imported, or something
+// else about the export worth knowing, such as columns that were left out.
+type Problem struct {
+	File string
+	// Line is the row's line in the file, or 0 when it isn't known.
+	Line int
+	// Place names the row's restaurant, when it has one.
+	Place  string
+	Reason string
+}
+
+func (p Problem) String() string {
+	s := p.File
+	if p.Line > 0 {
+		s += fmt.Sprintf(":%d", p.Line)
+	}
+	if p.Place != "" {
+		s += ": " + p.Place
+	}
+	return s + ": " + p.Reason
+}
@@ -0,0 +1,313 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"toni/internal/model"
+)
+
+// Google Maps exports, found in a Takeout archive under "Maps (your
+// places)" and "Saved", come in three shapes:
+//
+//   - Saved Places.json: GeoJSON of starred places.
+//   - Reviews.json: GeoJSON of the places you reviewed, with star ratings.
+//   - Saved/<list>.csv: one CSV per saved list (Want to go, Favourites, ...)
+//     with the title, note and Maps URL of each place, but no address.
+//
+// Older exports capitalise the GeoJSON properties differently
+// ("Location", "Business Name", "Star Rating"); both are read.
+
+// ReadTakeout reads the Google Maps places and reviews of a Takeout export at
+// path: the .zip Google provides, the folder it unpacks to, or one of the
+// files in it. Reviews become visits, with 1-5 stars scaled to a 2-10
+// rating; starred and saved places become want to visit entries. Places that
+// had to be skipped are returned as problems.
+func ReadTakeout(path string) ([]Record, []Problem, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open takeout: %w", err)
+	}
+
+	var fsys fs.FS
+	switch {
+	case info.IsDir():
+		fsys = os.DirFS(path)
+	case strings.EqualFold(pathExt(path), ".zip"):
+		z, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open takeout: %w", err)
+		}
+		defer z.Close()
+		fsys = z
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open takeout: %w", err)
+		}
+		defer f.Close()
+		t := &takeout{}
+		ok, err := t.readFile(info.Name(), f)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, fmt.Errorf("%s isn't a Google Maps places, reviews or saved list file", path)
+		}
+		return t.records, t.skipped, nil
+	}
+
+	t := &takeout{}
+	found := false
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if ext := strings.ToLower(pathExt(name)); ext != ".json" && ext != ".csv" {
+			return nil
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		ok, err := t.readFile(name, f)
+		if err != nil {
+			// Takeout holds plenty of other JSON and CSV files; only those
+			// that look like places but can't be read are worth stopping
+			// for, and readFile only fails for those.
+			return err
+		}
+		found = found || ok
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read takeout: %w", err)
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("no Google Maps places, reviews or saved lists found in %s", path)
+	}
+	return t.records, t.skipped, nil
+}
+
+type takeout struct {
+	records []Record
+	skipped []Problem
+}
+
+// readFile reads the file called name if it is a Google Maps export, and
+// reports whether it was.
+func (t *takeout) readFile(name string, r io.Reader) (bool, error) {
+	switch strings.ToLower(pathExt(name)) {
+	case ".json":
+		return t.readGeoJSON(name, r)
+	case ".csv":
+		return t.readSavedList(name, r)
+	}
+	return false, nil
+}
+
+// takeoutFeature is a place in Saved Places.json or Reviews.json. JSON keys
+// match field names regardless of case, so "location" and "Location" both
+// land in Location.
+type takeoutFeature struct {
+	Geometry struct {
+		Coordinates []float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		Title     string `json:"Title"`
+		Date      string `json:"date"`
+		Published string `json:"Published"`
+		Location  struct {
+			Name         string `json:"name"`
+			BusinessName string `json:"Business Name"`
+			Address      string `json:"address"`
+			Geo          *struct {
+				Latitude  flexFloat `json:"Latitude"`
+				Longitude flexFloat `json:"Longitude"`
+			} `json:"Geo Coordinates"`
+		} `json:"location"`
+		Stars      *flexFloat `json:"five_star_rating_published"`
+		StarRating *flexFloat `json:"Star Rating"`
+		Review     string     `json:"review_text_published"`
+		Comment    string     `json:"Review Comment"`
+		Note       string     `json:"Comment"`
+	} `json:"properties"`
+}
+
+// readGeoJSON reads Saved Places.json or Reviews.json.
+func (t *takeout) readGeoJSON(name string, r io.Reader) (bool, error) {
+	var collection struct {
+		Type     string           `json:"type"`
+		Features []takeoutFeature `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&collection); err != nil || collection.Type != "FeatureCollection" {
+		// Not GeoJSON, so not ours.
+		return false, nil
+	}
+
+	for _, f := range collection.Features {
+		p := f.Properties
+		restaurant := model.NewRestaurant{Name: firstNonEmpty(p.Location.Name, p.Location.BusinessName, p.Title)}
+		restaurant.Address, restaurant.City = SplitAddress(p.Location.Address)
+		if strings.TrimSpace(restaurant.Name) == "" {
+			t.skip(name, p.Location.Address, "no name")
+			continue
+		}
+		restaurant.Name = strings.TrimSpace(restaurant.Name)
+		if c := f.Geometry.Coordinates; len(c) == 2 && (c[0] != 0 || c[1] != 0) {
+			lng, lat := c[0], c[1]
+			restaurant.Latitude, restaurant.Longitude = &lat, &lng
+		} else if g := p.Location.Geo; g != nil && (g.Latitude != 0 || g.Longitude != 0) {
+			lat, lng := float64(g.Latitude), float64(g.Longitude)
+			restaurant.Latitude, restaurant.Longitude = &lat, &lng
+		}
+
+		stars := p.Stars
+		if stars == nil {
+			stars = p.StarRating
+		}
+		review := firstNonEmpty(p.Review, p.Comment)
+		if stars == nil && review == "" {
+			t.records = append(t.records, Record{
+				Restaurant: restaurant,
+				Wishlist: &model.NewWantToVisit{
+					Notes:  strings.TrimSpace(p.Note),
+					Source: "Google Maps starred places",
+				},
+			})
+			continue
+		}
+
+		date, ok := takeoutDate(firstNonEmpty(p.Date, p.Published))
+		if !ok {
+			t.skip(name, restaurant.Name, "review has no date")
+			continue
+		}
+		visit := &model.NewVisit{VisitedOn: date, Notes: strings.TrimSpace(review)}
+		if stars != nil && *stars >= 1 && *stars <= 5 {
+			rating := takeoutStars.Convert(float64(*stars))
+			visit.Rating = &rating
+		}
+		t.records = append(t.records, Record{Restaurant: restaurant, Visit: visit})
+	}
+	return true, nil
+}
+
+// readSavedList reads one of the CSV files of saved lists.
+func (t *takeout) readSavedList(name string, r io.Reader) (bool, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return false, nil
+	}
+	col := make(map[string]int)
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	if _, ok := col["title"]; !ok {
+		return false, nil
+	}
+	if _, ok := col["url"]; !ok {
+		return false, nil
+	}
+	field := func(row []string, key string) string {
+		if i, ok := col[key]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	list := strings.TrimSuffix(path.Base(name), pathExt(name))
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return true, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		title := field(row, "title")
+		if title == "" {
+			// Exports start with an empty row.
+			continue
+		}
+		var notes []string
+		for _, key := range []string{"note", "comment"} {
+			if v := field(row, key); v != "" {
+				notes = append(notes, v)
+			}
+		}
+		t.records = append(t.records, Record{
+			Restaurant: model.NewRestaurant{Name: title},
+			Wishlist: &model.NewWantToVisit{
+				Notes:  strings.Join(notes, "\n\n"),
+				Source: "Google Maps list " + strconv.Quote(list),
+			},
+		})
+	}
+	return true, nil
+}
+
+func (t *takeout) skip(file, place, reason string) {
+	if place == "" {
+		place = "unnamed place"
+	}
+	t.skipped = append(t.skipped, Problem{File: path.Base(file), Place: place, Reason: reason})
+}
+
+// takeoutStars is the scale of Google Maps reviews.
+var takeoutStars = Scale{Min: 1, Max: 5}
+
+// takeoutDate turns a Takeout timestamp into the local date it fell on.
+func takeoutDate(s string) (string, bool) {
+	if s == "" {
+		return "", false
+	}
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", false
+	}
+	return ts.Local().Format("2006-01-02"), true
+}
+
+// flexFloat is a number that older exports write as a string.
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func pathExt(name string) string {
+	return path.Ext(strings.ReplaceAll(name, `\`, "/"))
+}
@@ -34,6 +34,10 @@ type VisitSavedMsg struct {
 	Operation string // insert, update
 	Before    *Visit
 	After     Visit
+	// Plan and WantToVisit are what saving a visit logged from a planned
+	// visit or a want_to_visit entry removed.
+	Plan        *PlannedVisit
+	WantToVisit []WantToVisit
 }
 
 // RestaurantSavedMsg is sent when a restaurant is successfully saved.
@@ -60,10 +64,12 @@ type DeleteVisitMsg struct {
 
 // DeleteRestaurantMsg is sent to delete a restaurant.
 type DeleteRestaurantMsg struct {
-	ID                 int64
-	Deleted            Restaurant
-	DeletedVisits      []Visit
-	DeletedWantToVisit []WantToVisit
+	ID                   int64
+	Deleted              Restaurant
+	DeletedVisits        []Visit
+	DeletedWantToVisit   []WantToVisit
+	DeletedPlannedVisits []PlannedVisit
+	DeletedTags          []string
 }
 
 // WantToVisitLoadedMsg is sent when want_to_visit list is loaded.
@@ -85,11 +91,23 @@ type DeleteWantToVisitMsg struct {
 	Deleted WantToVisit
 }
 
-// ConvertToVisitMsg is sent to convert want_to_visit to actual visit.
-type ConvertToVisitMsg struct {
-	WantToVisitID int64
-	RestaurantID  int64
-	Deleted       WantToVisit
+// PlannedVisitsLoadedMsg is sent when planned visits are loaded.
+type PlannedVisitsLoadedMsg struct {
+	Plans []PlannedVisitRow
+}
+
+// PlannedVisitSavedMsg is sent when a planned visit is successfully saved.
+type PlannedVisitSavedMsg struct {
+	ID        int64
+	Operation string // insert, update
+	Before    *PlannedVisit
+	After     PlannedVisit
+}
+
+// DeletePlannedVisitMsg is sent to delete a planned visit.
+type DeletePlannedVisitMsg struct {
+	ID      int64
+	Deleted PlannedVisit
 }
 
 // Screen represents different app screens.
@@ -105,6 +123,11 @@ const (
 	ScreenVisitForm
 	ScreenRestaurantForm
 	ScreenWantToVisitForm
+	ScreenVisitCalendar
+	ScreenPicker
+	ScreenCompare
+	ScreenUpcoming
+	ScreenPlanForm
 )
 
 // Mode represents the current interaction mode.
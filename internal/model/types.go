@@ -25,7 +25,11 @@ type Visit struct {
 	Rating       *float64
 	Notes        string
 	WouldReturn  *bool
-	CreatedAt    time.Time
+	// WishlistNotes and WishlistPriority are kept from the want_to_visit
+	// entry the visit was marked visited from, for reference.
+	WishlistNotes    string
+	WishlistPriority *int
+	CreatedAt        time.Time
 }
 
 // VisitRow represents a visit with joined restaurant data for list display.
@@ -40,6 +44,8 @@ type VisitRow struct {
 	WouldReturn    *bool
 	Notes          string
 	RestaurantID   int64
+	Latitude       *float64
+	Longitude      *float64
 }
 
 // RestaurantRow represents a restaurant with aggregate stats for list display.
@@ -60,6 +66,39 @@ type RestaurantRow struct {
 type RestaurantDetail struct {
 	Restaurant Restaurant
 	Visits     []Visit
+	Tags       []string
+}
+
+// RestaurantSnapshot holds a restaurant with everything deleting it removes,
+// so the deletion can be undone.
+type RestaurantSnapshot struct {
+	Restaurant    Restaurant
+	Visits        []Visit
+	WantToVisit   []WantToVisit
+	PlannedVisits []PlannedVisit
+	Tags          []string
+}
+
+// RestaurantTag is a tag on a restaurant.
+type RestaurantTag struct {
+	RestaurantID int64
+	Tag          string
+}
+
+// FieldValue is the value of one field of a row, used to restore it.
+type FieldValue struct {
+	ID    int64
+	Value string
+}
+
+// VisitDay summarizes the visits on one day.
+type VisitDay struct {
+	Date string
+	// Restaurants holds the name of the restaurant of each visit, in the
+	// order they were logged.
+	Restaurants []string
+	// AvgRating averages the rated visits; nil when none were rated.
+	AvgRating *float64
 }
 
 // NewRestaurant represents data for creating a restaurant.
@@ -77,11 +116,13 @@ type NewRestaurant struct {
 
 // NewVisit represents data for creating a visit.
 type NewVisit struct {
-	RestaurantID int64
-	VisitedOn    string
-	Rating       *float64
-	Notes        string
-	WouldReturn  *bool
+	RestaurantID     int64
+	VisitedOn        string
+	Rating           *float64
+	Notes            string
+	WouldReturn      *bool
+	WishlistNotes    string
+	WishlistPriority *int
 }
 
 // UpdateRestaurant represents data for updating a restaurant.
@@ -114,7 +155,10 @@ type WantToVisit struct {
 	RestaurantID int64
 	Notes        string
 	Priority     *int // 1-5, 5 being highest priority
-	CreatedAt    time.Time
+	// Source says where the entry came from when it wasn't added by hand,
+	// e.g. the bundle it was imported from.
+	Source    string
+	CreatedAt time.Time
 }
 
 // WantToVisitRow represents a want_to_visit with joined restaurant data for list display.
@@ -128,6 +172,7 @@ type WantToVisitRow struct {
 	PriceRange     string
 	Priority       *int
 	Notes          string
+	Source         string
 	RestaurantID   int64
 	CreatedAt      time.Time
 }
@@ -137,6 +182,7 @@ type NewWantToVisit struct {
 	RestaurantID int64
 	Notes        string
 	Priority     *int
+	Source       string
 }
 
 // UpdateWantToVisit represents data for updating a want_to_visit entry.
@@ -146,3 +192,110 @@ type UpdateWantToVisit struct {
 	Notes        string
 	Priority     *int
 }
+
+// PlannedVisit is a visit planned for later, such as a reservation.
+type PlannedVisit struct {
+	ID           int64
+	RestaurantID int64
+	PlannedOn    string // ISO 8601 date (YYYY-MM-DD)
+	PlannedAt    string // local time as HH:MM; empty when not set
+	PartySize    *int
+	Notes        string
+	CreatedAt    time.Time
+}
+
+// PlannedVisitRow represents a planned visit with joined restaurant data for
+// list display and calendar export.
+type PlannedVisitRow struct {
+	ID             int64
+	PlannedOn      string
+	PlannedAt      string
+	PartySize      *int
+	Notes          string
+	RestaurantID   int64
+	RestaurantName string
+	Address        string
+	City           string
+	Latitude       *float64
+	Longitude      *float64
+}
+
+// NewPlannedVisit represents data for creating a planned visit.
+type NewPlannedVisit struct {
+	RestaurantID int64
+	PlannedOn    string
+	PlannedAt    string
+	PartySize    *int
+	Notes        string
+}
+
+// UpdatePlannedVisit represents data for updating a planned visit.
+type UpdatePlannedVisit struct {
+	ID           int64
+	RestaurantID int64
+	PlannedOn    string
+	PlannedAt    string
+	PartySize    *int
+	Notes        string
+}
+
+// LoggedVisit is a visit logged from a planned visit or a want_to_visit
+// entry, with what logging it removed: the plan, if any, and the
+// want_to_visit entries.
+type LoggedVisit struct {
+	Visit       Visit
+	Plan        *PlannedVisit
+	WantToVisit []WantToVisit
+}
+
+// PickCandidate is a restaurant the "where should we eat?" picker can draw,
+// with what it needs to weigh and describe it.
+type PickCandidate struct {
+	RestaurantID int64
+	Name         string
+	Address      string
+	City         string
+	Neighborhood string
+	Cuisine      string
+	PriceRange   string
+	Latitude     *float64
+	Longitude    *float64
+	// Wishlisted is set for restaurants on the want to visit list, and
+	// Priority is their highest priority there.
+	Wishlisted bool
+	Priority   *int
+	AvgRating  *float64
+	VisitCount int
+	LastVisit  string
+	// WouldReturn is the answer of the latest visit that gave one.
+	WouldReturn *bool
+}
+
+// VisitTraits is a visit's rating with the traits of its restaurant, which
+// the taste model learns from.
+type VisitTraits struct {
+	Cuisine      string
+	PriceRange   string
+	Neighborhood string
+	Rating       *float64
+	WouldReturn  *bool
+}
+
+// RestaurantStats is a restaurant with a summary of its visits, as used to
+// compare two databases.
+type RestaurantStats struct {
+	RestaurantID int64
+	Name         string
+	Address      string
+	City         string
+	Neighborhood string
+	Cuisine      string
+	PriceRange   string
+	PlaceID      string
+	Wishlisted   bool
+	AvgRating    *float64
+	VisitCount   int
+	LastVisit    string
+	// WouldReturn is the answer of the latest visit that gave one.
+	WouldReturn *bool
+}
@@ -0,0 +1,307 @@
+// Package pick answers "where should we eat?". It draws a restaurant at
+// random from the want to visit list, weighted by priority, and from
+// favourites: restaurants rated highly on average whose latest visit said
+// you'd return.
+//
+// What to draw from is written in the filter query language, plus a few
+// terms of its own:
+//
+//	from:wishlist city:Brooklyn cuisine:(thai|lao) near:40.68,-73.99 within:2km unvisited:30
+//
+// The picker's own terms are:
+//
+//   - from:wishlist or from:favourites draws from one source only; both are
+//     used by default
+//   - near:LAT,LNG keeps restaurants within:DISTANCE of a point (5km by
+//     default; km, m and mi are understood). Restaurants without
+//     coordinates are left out.
+//   - unvisited:N leaves out restaurants visited in the last N days
+//   - min:RATING is the average rating that makes a favourite, 8 by default
+//
+// Every other term is a query over db.PickQuerySchema.
+package pick
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/query"
+)
+
+const (
+	// DefaultMinRating is the average rating that makes a favourite.
+	DefaultMinRating = 8.0
+	// DefaultWithinKm is how far from near:LAT,LNG restaurants may be when
+	// within: isn't given.
+	DefaultWithinKm = 5.0
+)
+
+// Point is a position in degrees.
+type Point struct {
+	Lat, Lng float64
+}
+
+// Criteria says what to draw from.
+type Criteria struct {
+	Wishlist   bool
+	Favourites bool
+	MinRating  float64
+	// Near, when set, keeps restaurants within WithinKm of it.
+	Near     *Point
+	WithinKm float64
+	// UnvisitedDays leaves out restaurants visited in that many days up to
+	// today; 0 keeps them all.
+	UnvisitedDays int
+
+	where string
+	args  []any
+}
+
+// Entry is a restaurant that can be drawn and the weight it is drawn with.
+type Entry struct {
+	Candidate model.PickCandidate
+	Weight    float64
+	// Wishlist and Favourite say which sources the entry was drawn from.
+	Wishlist  bool
+	Favourite bool
+	// DistanceKm is the distance from Criteria.Near, when set.
+	DistanceKm *float64
+}
+
+// Parse parses a picker query. Errors are *query.Error values pointing into
+// input.
+func Parse(input string) (Criteria, error) {
+	c := Criteria{Wishlist: true, Favourites: true, MinRating: DefaultMinRating}
+	n, err := query.Parse(input)
+	if err != nil {
+		return c, err
+	}
+
+	var terms []query.Node
+	switch n := n.(type) {
+	case nil:
+	case query.And:
+		terms = n.Terms
+	default:
+		terms = []query.Node{n}
+	}
+
+	var rest []query.Node
+	withinSet := false
+	for _, t := range terms {
+		cmp, ok := t.(query.Compare)
+		if !ok || !isPickField(cmp.Field) {
+			rest = append(rest, t)
+			continue
+		}
+		if (cmp.Op != ":" && cmp.Op != "=") || len(cmp.Values) != 1 {
+			return c, errorAt(input, cmp.FieldPos, cmp.FieldEnd, "%s takes a single value after :", cmp.Field)
+		}
+		v := cmp.Values[0]
+		switch strings.ToLower(cmp.Field) {
+		case "from":
+			switch strings.ToLower(v.Text) {
+			case "wishlist", "want_to_visit":
+				c.Favourites = false
+			case "favourites", "favorites", "favs":
+				c.Wishlist = false
+			case "all", "both":
+			default:
+				return c, errorAt(input, v.Pos, v.End, "from is wishlist, favourites or all, not %q", v.Text)
+			}
+		case "near":
+			p, ok := parsePoint(v.Text)
+			if !ok {
+				return c, errorAt(input, v.Pos, v.End, "near needs a position as LAT,LNG, not %q", v.Text)
+			}
+			c.Near = &p
+		case "within":
+			km, ok := parseDistance(v.Text)
+			if !ok {
+				return c, errorAt(input, v.Pos, v.End, "within needs a distance such as 2km, 800m or 1mi, not %q", v.Text)
+			}
+			c.WithinKm = km
+			withinSet = true
+		case "unvisited":
+			days, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(v.Text), "d"))
+			if err != nil || days < 0 {
+				return c, errorAt(input, v.Pos, v.End, "unvisited needs a number of days, not %q", v.Text)
+			}
+			c.UnvisitedDays = days
+		case "min":
+			r, err := strconv.ParseFloat(v.Text, 64)
+			if err != nil || r < 1 || r > 10 {
+				return c, errorAt(input, v.Pos, v.End, "min needs a rating from 1 to 10, not %q", v.Text)
+			}
+			c.MinRating = r
+		}
+	}
+	if withinSet && c.Near == nil {
+		return c, errorAt(input, 0, len(input), "within needs near:LAT,LNG")
+	}
+	if c.Near != nil && !withinSet {
+		c.WithinKm = DefaultWithinKm
+	}
+
+	var rem query.Node
+	switch len(rest) {
+	case 0:
+	case 1:
+		rem = rest[0]
+	default:
+		rem = query.And{Terms: rest}
+	}
+	c.where, c.args, err = db.PickQuerySchema.CompileNode(rem)
+	if err != nil {
+		if qe, ok := err.(*query.Error); ok {
+			qe.Input = input
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+func isPickField(name string) bool {
+	switch strings.ToLower(name) {
+	case "from", "near", "within", "unvisited", "min":
+		return true
+	}
+	return false
+}
+
+func errorAt(input string, pos, end int, format string, args ...any) *query.Error {
+	return &query.Error{Input: input, Pos: pos, End: end, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parsePoint parses "LAT,LNG".
+func parsePoint(s string) (Point, bool) {
+	lat, lng, ok := strings.Cut(s, ",")
+	if !ok {
+		return Point{}, false
+	}
+	la, err1 := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	ln, err2 := strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if err1 != nil || err2 != nil || math.Abs(la) > 90 || math.Abs(ln) > 180 {
+		return Point{}, false
+	}
+	return Point{Lat: la, Lng: ln}, true
+}
+
+// parseDistance parses a distance in km, m or mi and returns it in km. A
+// bare number is in km.
+func parseDistance(s string) (float64, bool) {
+	s = strings.ToLower(s)
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(s, "km"):
+		s = strings.TrimSuffix(s, "km")
+	case strings.HasSuffix(s, "mi"):
+		s, scale = strings.TrimSuffix(s, "mi"), 1.609344
+	case strings.HasSuffix(s, "m"):
+		s, scale = strings.TrimSuffix(s, "m"), 0.001
+	}
+	d, err := strconv.ParseFloat(s, 64)
+	if err != nil || d <= 0 {
+		return 0, false
+	}
+	return d * scale, true
+}
+
+// Distance returns the great-circle distance between a and b in km.
+func Distance(a, b Point) float64 {
+	const earthRadiusKm = 6371.0
+	rad := math.Pi / 180
+	dLat := (b.Lat - a.Lat) * rad
+	dLng := (b.Lng - a.Lng) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat*rad)*math.Cos(b.Lat*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// Pool returns the restaurants c draws from, as of today.
+func Pool(database *sql.DB, c Criteria, today time.Time) ([]Entry, error) {
+	candidates, err := db.ListPickCandidatesWhere(database, c.where, c.args)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := ""
+	if c.UnvisitedDays > 0 {
+		cutoff = today.AddDate(0, 0, -c.UnvisitedDays).Format("2006-01-02")
+	}
+
+	var pool []Entry
+	for _, cand := range candidates {
+		e := Entry{Candidate: cand}
+		if c.Wishlist && cand.Wishlisted {
+			// Entries without a priority count as the lowest.
+			e.Wishlist = true
+			e.Weight = 1
+			if cand.Priority != nil {
+				e.Weight = float64(*cand.Priority)
+			}
+		}
+		if c.Favourites && cand.AvgRating != nil && *cand.AvgRating >= c.MinRating &&
+			cand.WouldReturn != nil && *cand.WouldReturn {
+			e.Favourite = true
+			e.Weight += 1 + *cand.AvgRating - c.MinRating
+		}
+		if !e.Wishlist && !e.Favourite {
+			continue
+		}
+		if cutoff != "" && cand.LastVisit > cutoff {
+			continue
+		}
+		if c.Near != nil {
+			if cand.Latitude == nil || cand.Longitude == nil {
+				continue
+			}
+			d := Distance(*c.Near, Point{Lat: *cand.Latitude, Lng: *cand.Longitude})
+			if d > c.WithinKm {
+				continue
+			}
+			e.DistanceKm = &d
+		}
+		pool = append(pool, e)
+	}
+	return pool, nil
+}
+
+// Draw picks an entry of pool at random, in proportion to its weight. pool
+// must not be empty.
+func Draw(pool []Entry, r *rand.Rand) Entry {
+	total := 0.0
+	for _, e := range pool {
+		total += e.Weight
+	}
+	x := r.Float64() * total
+	for _, e := range pool {
+		x -= e.Weight
+		if x < 0 {
+			return e
+		}
+	}
+	return pool[len(pool)-1]
+}
+
+// Reason says why e is in the pool: "wishlist, priority 4", "favourite,
+// 9.1 average" or both.
+func (e Entry) Reason() string {
+	var parts []string
+	if e.Wishlist {
+		if e.Candidate.Priority != nil {
+			parts = append(parts, fmt.Sprintf("wishlist, priority %d", *e.Candidate.Priority))
+		} else {
+			parts = append(parts, "wishlist")
+		}
+	}
+	if e.Favourite {
+		parts = append(parts, fmt.Sprintf("favourite, %.1f average", *e.Candidate.AvgRating))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,436 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of a field, which decides how its values are parsed and
+// compared.
+type Kind int
+
+const (
+	// KindText fields match substrings with ":" and whole values with "=".
+	KindText Kind = iota
+	// KindNumber fields compare numerically and accept ranges ("8..10").
+	KindNumber
+	// KindBool fields accept yes/no, true/false or 1/0.
+	KindBool
+	// KindDate fields hold YYYY-MM-DD dates. Values may be a year, a month or
+	// a day, and ":" matches the whole period ("visited:2025-03").
+	KindDate
+	// KindSet fields hold several values each, such as tags. ":" matches a
+	// value containing the text, "=" a whole value and "!=" rows without it.
+	KindSet
+)
+
+// Field is a field that queries can name. Column is the SQL expression it
+// compiles to.
+type Field struct {
+	Name    string
+	Aliases []string
+	Column  string
+	Kind    Kind
+	// Exists is a correlated subquery selecting the rows that hold the
+	// values of a KindSet field, ending in a WHERE clause; Column is the
+	// value in those rows.
+	Exists string
+}
+
+// Schema lists the fields of one list. Bare words match any of the Text
+// columns.
+type Schema struct {
+	Fields []Field
+	Text   []string
+}
+
+// Field returns the field called name or one of its aliases, ignoring case.
+func (s Schema) Field(name string) (Field, bool) {
+	for _, f := range s.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+		for _, alias := range f.Aliases {
+			if strings.EqualFold(alias, name) {
+				return f, true
+			}
+		}
+	}
+	return Field{}, false
+}
+
+// FieldNames returns the primary name of each field.
+func (s Schema) FieldNames() []string {
+	names := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Compile parses input and compiles it to an SQL condition with "?"
+// placeholders. An empty query compiles to an empty condition. Errors are
+// *Error values pointing into input.
+func (s Schema) Compile(input string) (string, []any, error) {
+	n, err := Parse(input)
+	if err != nil {
+		return "", nil, err
+	}
+	where, args, err := s.CompileNode(n)
+	if err != nil {
+		return "", nil, withInput(err, input)
+	}
+	return where, args, nil
+}
+
+// Check reports whether input is a valid query for the schema.
+func (s Schema) Check(input string) error {
+	_, _, err := s.Compile(input)
+	return err
+}
+
+// CompileNode compiles a parsed query. A nil node compiles to an empty
+// condition.
+func (s Schema) CompileNode(n Node) (string, []any, error) {
+	if n == nil {
+		return "", nil, nil
+	}
+	c := &compiler{schema: s}
+	where, err := c.compile(n)
+	if err != nil {
+		return "", nil, err
+	}
+	return where, c.args, nil
+}
+
+func withInput(err error, input string) error {
+	if e, ok := err.(*Error); ok {
+		e.Input = input
+	}
+	return err
+}
+
+type compiler struct {
+	schema Schema
+	args   []any
+}
+
+func (c *compiler) arg(v any) string {
+	c.args = append(c.args, v)
+	return "?"
+}
+
+func (c *compiler) compile(n Node) (string, error) {
+	switch n := n.(type) {
+	case And:
+		return c.join(n.Terms, " AND ")
+	case Or:
+		return c.join(n.Terms, " OR ")
+	case Not:
+		inner, err := c.compile(n.Term)
+		if err != nil {
+			return "", err
+		}
+		// NULL comparisons are unknown rather than false, so treat them as
+		// not matching before negating.
+		return "NOT COALESCE(" + inner + ", 0)", nil
+	case Text:
+		return c.compileText(n.Value)
+	case Compare:
+		return c.compileCompare(n)
+	}
+	return "", fmt.Errorf("unknown query node %T", n)
+}
+
+func (c *compiler) join(terms []Node, sep string) (string, error) {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		part, err := c.compile(t)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	return "(" + strings.Join(parts, sep) + ")", nil
+}
+
+func (c *compiler) compileText(v Value) (string, error) {
+	if len(c.schema.Text) == 0 {
+		return "", v.errorf("bare words are not supported here; name a field")
+	}
+	pattern := likePattern(v.Text)
+	parts := make([]string, len(c.schema.Text))
+	for i, col := range c.schema.Text {
+		parts[i] = col + ` LIKE ` + c.arg(pattern) + ` ESCAPE '\'`
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", nil
+}
+
+func (c *compiler) compileCompare(n Compare) (string, error) {
+	f, ok := c.schema.Field(n.Field)
+	if !ok {
+		return "", &Error{
+			Pos: n.FieldPos, End: n.FieldEnd,
+			Msg: fmt.Sprintf("unknown field %q (fields: %s)", n.Field, strings.Join(c.schema.FieldNames(), ", ")),
+		}
+	}
+
+	parts := make([]string, len(n.Values))
+	for i, v := range n.Values {
+		var part string
+		var err error
+		switch f.Kind {
+		case KindText:
+			part, err = c.compileTextField(f, n.Op, v)
+		case KindNumber:
+			part, err = c.compileNumber(f, n.Op, v)
+		case KindBool:
+			part, err = c.compileBool(f, n.Op, v)
+		case KindDate:
+			part, err = c.compileDate(f, n.Op, v)
+		case KindSet:
+			part, err = c.compileSet(f, n.Op, v)
+		}
+		if err != nil {
+			return "", err
+		}
+		parts[i] = part
+	}
+	if len(parts) == 1 {
+		return parts[0], nil
+	}
+	// "field!=(a|b)" excludes both values; any other operator matches either.
+	sep := " OR "
+	if n.Op == "!=" {
+		sep = " AND "
+	}
+	return "(" + strings.Join(parts, sep) + ")", nil
+}
+
+func (c *compiler) compileTextField(f Field, op string, v Value) (string, error) {
+	if !v.Quoted && strings.Contains(v.Text, "..") {
+		return "", v.errorf("%s is a text field and cannot take a range", f.Name)
+	}
+	col := "COALESCE(" + f.Column + ", '')"
+	switch op {
+	case ":":
+		if v.Text == "" {
+			return col + " = ''", nil
+		}
+		return col + ` LIKE ` + c.arg(likePattern(v.Text)) + ` ESCAPE '\'`, nil
+	case "=":
+		return col + " = " + c.arg(v.Text) + " COLLATE NOCASE", nil
+	case "!=":
+		return col + " <> " + c.arg(v.Text) + " COLLATE NOCASE", nil
+	default:
+		return col + " " + op + " " + c.arg(v.Text) + " COLLATE NOCASE", nil
+	}
+}
+
+// compileNone handles "field:none", which matches missing values.
+func (c *compiler) compileNone(f Field, op string, v Value) (string, bool, error) {
+	if v.Quoted || !strings.EqualFold(v.Text, "none") {
+		return "", false, nil
+	}
+	switch op {
+	case ":", "=":
+		return f.Column + " IS NULL", true, nil
+	case "!=":
+		return f.Column + " IS NOT NULL", true, nil
+	}
+	return "", true, v.errorf("none can only be used with :, = or !=")
+}
+
+func (c *compiler) compileNumber(f Field, op string, v Value) (string, error) {
+	if sql, ok, err := c.compileNone(f, op, v); ok {
+		return sql, err
+	}
+	if lo, hi, ok := splitRange(v); ok {
+		if op != ":" && op != "=" {
+			return "", v.errorf("ranges can only be used with : or =")
+		}
+		var parts []string
+		if lo != "" {
+			n, err := parseNumber(f, v, lo)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, f.Column+" >= "+c.arg(n))
+		}
+		if hi != "" {
+			n, err := parseNumber(f, v, hi)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, f.Column+" <= "+c.arg(n))
+		}
+		return "(" + strings.Join(parts, " AND ") + ")", nil
+	}
+
+	n, err := parseNumber(f, v, v.Text)
+	if err != nil {
+		return "", err
+	}
+	switch op {
+	case ":", "=":
+		return f.Column + " = " + c.arg(n), nil
+	case "!=":
+		return "COALESCE(" + f.Column + " <> " + c.arg(n) + ", 1)", nil
+	default:
+		return f.Column + " " + op + " " + c.arg(n), nil
+	}
+}
+
+func parseNumber(f Field, v Value, text string) (float64, error) {
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, v.errorf("%s needs a number, not %q", f.Name, text)
+	}
+	return n, nil
+}
+
+func (c *compiler) compileBool(f Field, op string, v Value) (string, error) {
+	if sql, ok, err := c.compileNone(f, op, v); ok {
+		return sql, err
+	}
+	var b int
+	switch strings.ToLower(v.Text) {
+	case "yes", "y", "true", "1":
+		b = 1
+	case "no", "n", "false", "0":
+		b = 0
+	default:
+		return "", v.errorf("%s needs yes or no, not %q", f.Name, v.Text)
+	}
+	switch op {
+	case ":", "=":
+		return f.Column + " = " + c.arg(b), nil
+	case "!=":
+		return "COALESCE(" + f.Column + " <> " + c.arg(b) + ", 1)", nil
+	}
+	return "", v.errorf("%s is yes or no and cannot be compared with %s", f.Name, op)
+}
+
+func (c *compiler) compileDate(f Field, op string, v Value) (string, error) {
+	if sql, ok, err := c.compileNone(f, op, v); ok {
+		return sql, err
+	}
+	if lo, hi, ok := splitRange(v); ok {
+		if op != ":" && op != "=" {
+			return "", v.errorf("ranges can only be used with : or =")
+		}
+		var parts []string
+		if lo != "" {
+			start, _, err := datePeriod(f, v, lo)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, f.Column+" >= "+c.arg(start))
+		}
+		if hi != "" {
+			_, end, err := datePeriod(f, v, hi)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, f.Column+" < "+c.arg(end))
+		}
+		return "(" + strings.Join(parts, " AND ") + ")", nil
+	}
+
+	start, end, err := datePeriod(f, v, v.Text)
+	if err != nil {
+		return "", err
+	}
+	switch op {
+	case ":", "=":
+		return c.within(f, start, end), nil
+	case "!=":
+		return "NOT COALESCE(" + c.within(f, start, end) + ", 0)", nil
+	case ">":
+		return f.Column + " >= " + c.arg(end), nil
+	case ">=":
+		return f.Column + " >= " + c.arg(start), nil
+	case "<":
+		return f.Column + " < " + c.arg(start), nil
+	default: // "<="
+		return f.Column + " < " + c.arg(end), nil
+	}
+}
+
+// within matches dates of f from start up to but not including end.
+func (c *compiler) within(f Field, start, end string) string {
+	return "(" + f.Column + " >= " + c.arg(start) + " AND " + f.Column + " < " + c.arg(end) + ")"
+}
+
+func (c *compiler) compileSet(f Field, op string, v Value) (string, error) {
+	if !v.Quoted && strings.Contains(v.Text, "..") {
+		return "", v.errorf("%s cannot take a range", f.Name)
+	}
+	if !v.Quoted && strings.EqualFold(v.Text, "none") {
+		switch op {
+		case ":", "=":
+			return "NOT EXISTS (" + f.Exists + ")", nil
+		case "!=":
+			return "EXISTS (" + f.Exists + ")", nil
+		}
+		return "", v.errorf("none can only be used with :, = or !=")
+	}
+	switch op {
+	case ":":
+		return "EXISTS (" + f.Exists + " AND " + f.Column + ` LIKE ` + c.arg(likePattern(v.Text)) + ` ESCAPE '\')`, nil
+	case "=":
+		return "EXISTS (" + f.Exists + " AND " + f.Column + " = " + c.arg(v.Text) + " COLLATE NOCASE)", nil
+	case "!=":
+		return "NOT EXISTS (" + f.Exists + " AND " + f.Column + " = " + c.arg(v.Text) + " COLLATE NOCASE)", nil
+	}
+	return "", v.errorf("%s can only be matched with :, = or !=", f.Name)
+}
+
+// datePeriod returns the first day of the year, month or day text names and
+// the first day after it, as YYYY-MM-DD.
+func datePeriod(f Field, v Value, text string) (string, string, error) {
+	const day = "2006-01-02"
+	for _, p := range []struct {
+		layout  string
+		y, m, d int
+	}{
+		{"2006", 1, 0, 0},
+		{"2006-01", 0, 1, 0},
+		{day, 0, 0, 1},
+	} {
+		if len(text) != len(p.layout) {
+			continue
+		}
+		if t, err := time.Parse(p.layout, text); err == nil {
+			return t.Format(day), t.AddDate(p.y, p.m, p.d).Format(day), nil
+		}
+	}
+	return "", "", v.errorf("%s needs a date as YYYY, YYYY-MM or YYYY-MM-DD, not %q", f.Name, text)
+}
+
+// splitRange splits an unquoted "lo..hi" value. Either end may be empty.
+func splitRange(v Value) (string, string, bool) {
+	if v.Quoted {
+		return "", "", false
+	}
+	lo, hi, ok := strings.Cut(v.Text, "..")
+	if !ok {
+		return "", "", false
+	}
+	return lo, hi, lo != "" || hi != ""
+}
+
+func likePattern(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(s) + "%"
+}
+
+func (v Value) errorf(format string, args ...any) *Error {
+	end := v.End
+	if end <= v.Pos {
+		end = v.Pos + 1
+	}
+	return &Error{Pos: v.Pos, End: end, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,121 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testSchema = Schema{
+	Fields: []Field{
+		{Name: "name", Column: "r.name", Kind: KindText},
+		{Name: "rating", Column: "v.rating", Kind: KindNumber},
+		{Name: "return", Column: "v.would_return", Kind: KindBool},
+		{Name: "visited", Aliases: []string{"date"}, Column: "v.visited_on", Kind: KindDate},
+		{Name: "tag", Column: "t.tag", Kind: KindSet, Exists: "SELECT 1 FROM restaurant_tags t WHERE t.restaurant_id = r.id"},
+	},
+	Text: []string{"r.name", "r.city"},
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		input string
+		where string
+		args  []any
+	}{
+		{"", "", nil},
+		{"luc", `(r.name LIKE ? ESCAPE '\' OR r.city LIKE ? ESCAPE '\')`, []any{"%luc%", "%luc%"}},
+		{"name=Lucali", "COALESCE(r.name, '') = ? COLLATE NOCASE", []any{"Lucali"}},
+		{"rating>=8", "v.rating >= ?", []any{8.0}},
+		{"rating:8..10", "(v.rating >= ? AND v.rating <= ?)", []any{8.0, 10.0}},
+		{"rating:none", "v.rating IS NULL", nil},
+		{"return:no", "v.would_return = ?", []any{0}},
+		{"visited:2025-03", "(v.visited_on >= ? AND v.visited_on < ?)", []any{"2025-03-01", "2025-04-01"}},
+		{"visited!=2025", "NOT COALESCE((v.visited_on >= ? AND v.visited_on < ?), 0)", []any{"2025-01-01", "2026-01-01"}},
+		{"visited>2025-03", "v.visited_on >= ?", []any{"2025-04-01"}},
+		{"visited>=2025-03", "v.visited_on >= ?", []any{"2025-03-01"}},
+		{"visited<2025-03-14", "v.visited_on < ?", []any{"2025-03-14"}},
+		{"visited<=2025-03-14", "v.visited_on < ?", []any{"2025-03-15"}},
+		{"date:2024-06..", "(v.visited_on >= ?)", []any{"2024-06-01"}},
+		{"visited>2025-03 rating>=8", "(v.visited_on >= ? AND v.rating >= ?)", []any{"2025-04-01", 8.0}},
+		{"-return:no", "NOT COALESCE(v.would_return = ?, 0)", []any{0}},
+		{"tag=favs", "EXISTS (SELECT 1 FROM restaurant_tags t WHERE t.restaurant_id = r.id AND t.tag = ? COLLATE NOCASE)", []any{"favs"}},
+		{"tag:fav", `EXISTS (SELECT 1 FROM restaurant_tags t WHERE t.restaurant_id = r.id AND t.tag LIKE ? ESCAPE '\')`, []any{"%fav%"}},
+		{"tag!=favs", "NOT EXISTS (SELECT 1 FROM restaurant_tags t WHERE t.restaurant_id = r.id AND t.tag = ? COLLATE NOCASE)", []any{"favs"}},
+		{"tag:none", "NOT EXISTS (SELECT 1 FROM restaurant_tags t WHERE t.restaurant_id = r.id)", nil},
+	}
+	for _, tt := range tests {
+		where, args, err := testSchema.Compile(tt.input)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.input, err)
+			continue
+		}
+		if where != tt.where || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("Compile(%q) = %q %v, want %q %v", tt.input, where, args, tt.where, tt.args)
+		}
+	}
+}
+
+func TestCompilePlaceholdersMatchArgs(t *testing.T) {
+	values := map[string][]string{
+		"name":    {"thai", `"New York"`, "(thai|lao)"},
+		"rating":  {"8", "7.5", "(8|9)"},
+		"return":  {"yes", "(yes|no)"},
+		"visited": {"2025", "2025-03", "2025-03-14", "(2024|2025-03)"},
+		"tag":     {"favs", `"date night"`, "(favs|ramen)"},
+	}
+	ranges := map[string][]string{
+		"rating":  {"8..10", "8..", "..5"},
+		"visited": {"2024..2025", "2024-06..", "..2025-03-14"},
+	}
+	for _, op := range []string{":", "=", "!=", "<", "<=", ">", ">="} {
+		for field, vals := range values {
+			if (field == "return" || field == "tag") && op != ":" && op != "=" && op != "!=" {
+				continue
+			}
+			if op == ":" || op == "=" {
+				vals = append(vals, ranges[field]...)
+			}
+			for _, val := range vals {
+				input := field + op + val
+				checkPlaceholders(t, input)
+				checkPlaceholders(t, input+" rating>=8")
+			}
+		}
+	}
+}
+
+func checkPlaceholders(t *testing.T, input string) {
+	t.Helper()
+	where, args, err := testSchema.Compile(input)
+	if err != nil {
+		t.Errorf("Compile(%q): %v", input, err)
+		return
+	}
+	if n := strings.Count(where, "?"); n != len(args) {
+		t.Errorf("Compile(%q) = %q with %d placeholders but %d args %v", input, where, n, len(args), args)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+	}{
+		{"cuisine:thai", "unknown field"},
+		{"rating:good", "needs a number"},
+		{"return:maybe", "needs yes or no"},
+		{"return>yes", "cannot be compared"},
+		{"visited:march", "needs a date"},
+		{"visited>2024..2025", "ranges can only be used"},
+		{"rating<none", "none can only be used"},
+		{"tag>favs", "can only be matched with"},
+		{"tag:a..b", "cannot take a range"},
+	}
+	for _, tt := range tests {
+		_, _, err := testSchema.Compile(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("Compile(%q) error = %v, want %q", tt.input, err, tt.msg)
+		}
+	}
+}
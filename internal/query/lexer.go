@@ -0,0 +1,174 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokPipe
+	tokNot
+	tokOr
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "end of query"
+	case tokWord:
+		return "word"
+	case tokString:
+		return "string"
+	case tokOp:
+		return "operator"
+	case tokLParen:
+		return `"("`
+	case tokRParen:
+		return `")"`
+	case tokPipe:
+		return `"|"`
+	case tokNot:
+		return `"-"`
+	case tokOr:
+		return "OR"
+	}
+	return "token"
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // byte offset in the input
+	end  int
+}
+
+// describe names the token for error messages.
+func (t token) describe() string {
+	switch t.kind {
+	case tokWord, tokOp:
+		return fmt.Sprintf("%q", t.text)
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return t.kind.String()
+}
+
+// operators in the order they are matched, longest first.
+var operators = []string{">=", "<=", "!=", ":", "=", ">", "<"}
+
+func isOpStart(r rune) bool {
+	return r == ':' || r == '=' || r == '<' || r == '>' || r == '!'
+}
+
+func isSpecial(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '|' || r == '"' || isOpStart(r)
+}
+
+// lex splits input into tokens.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i, i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i, i + 1})
+			i++
+		case r == '|':
+			tokens = append(tokens, token{tokPipe, "|", i, i + 1})
+			i++
+		case r == '"':
+			tok, next, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		case isOpStart(r):
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(input[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, &Error{Pos: i, End: i + 1, Msg: fmt.Sprintf("unexpected %q", r)}
+			}
+			tokens = append(tokens, token{tokOp, op, i, i + len(op)})
+			i += len(op)
+		case r == '-' && startsTerm(tokens, input, i):
+			tokens = append(tokens, token{tokNot, "-", i, i + 1})
+			i++
+		default:
+			start := i
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if isSpecial(r) {
+					break
+				}
+				i += size
+			}
+			text := input[start:i]
+			kind := tokWord
+			if text == "OR" {
+				kind = tokOr
+			}
+			tokens = append(tokens, token{kind, text, start, i})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(input), len(input)}), nil
+}
+
+// startsTerm reports whether a "-" at i negates the term after it rather than
+// being part of a value such as "2025-03".
+func startsTerm(tokens []token, input string, i int) bool {
+	if i+1 >= len(input) || unicode.IsSpace(rune(input[i+1])) {
+		return false
+	}
+	if len(tokens) == 0 {
+		return true
+	}
+	last := tokens[len(tokens)-1]
+	if last.end < i {
+		return last.kind != tokOp
+	}
+	return last.kind == tokLParen || last.kind == tokNot
+}
+
+func lexString(input string, start int) (token, int, error) {
+	var b strings.Builder
+	i := start + 1
+	for i < len(input) {
+		switch input[i] {
+		case '\\':
+			if i+1 < len(input) {
+				b.WriteByte(input[i+1])
+				i += 2
+				continue
+			}
+			i++
+		case '"':
+			return token{tokString, b.String(), start, i + 1}, i + 1, nil
+		default:
+			b.WriteByte(input[i])
+			i++
+		}
+	}
+	return token{}, 0, &Error{Pos: start, End: len(input), Msg: "unterminated string"}
+}
@@ -0,0 +1,222 @@
+package query
+
+import (
+	"fmt"
+)
+
+// Node is a parsed query expression.
+type Node interface {
+	node()
+}
+
+// And matches when all of its terms match.
+type And struct {
+	Terms []Node
+}
+
+// Or matches when any of its terms matches.
+type Or struct {
+	Terms []Node
+}
+
+// Not matches when its term does not.
+type Not struct {
+	Term Node
+}
+
+// Text is a bare word or string matched against the schema's text fields.
+type Text struct {
+	Value Value
+}
+
+// Compare matches a field against one or more values, any of which may
+// match.
+type Compare struct {
+	Field    string
+	FieldPos int
+	FieldEnd int
+	Op       string
+	Values   []Value
+}
+
+// Value is a literal in a query. Unquoted values may be ranges ("8..10",
+// "2025..").
+type Value struct {
+	Text   string
+	Quoted bool
+	Pos    int
+	End    int
+}
+
+func (And) node()     {}
+func (Or) node()      {}
+func (Not) node()     {}
+func (Text) node()    {}
+func (Compare) node() {}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a query. An empty query yields a nil Node, which matches
+// everything.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, withInput(err, input)
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, withInput(err, input)
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, withInput(unexpected(t), input)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func unexpected(t token) *Error {
+	end := t.end
+	if end == t.pos {
+		end = t.pos + 1
+	}
+	return &Error{Pos: t.pos, End: end, Msg: "unexpected " + t.describe()}
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Node{first}
+	for p.peek().kind == tokOr {
+		p.next()
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, n)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return Or{Terms: terms}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	var terms []Node
+	for {
+		switch p.peek().kind {
+		case tokEOF, tokRParen, tokOr:
+			if len(terms) == 0 {
+				t := p.peek()
+				if t.kind == tokEOF {
+					return nil, &Error{Pos: t.pos, End: t.pos + 1, Msg: "expected a term"}
+				}
+				return nil, unexpected(t)
+			}
+			if len(terms) == 1 {
+				return terms[0], nil
+			}
+			return And{Terms: terms}, nil
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, n)
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Term: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, &Error{Pos: t.pos, End: t.end, Msg: `unclosed "("`}
+		}
+		return n, nil
+	case tokString:
+		return Text{Value: Value{Text: t.text, Quoted: true, Pos: t.pos, End: t.end}}, nil
+	case tokWord:
+		if p.peek().kind != tokOp {
+			return Text{Value: Value{Text: t.text, Pos: t.pos, End: t.end}}, nil
+		}
+		op := p.next()
+		values, err := p.parseValues(op)
+		if err != nil {
+			return nil, err
+		}
+		return Compare{Field: t.text, FieldPos: t.pos, FieldEnd: t.end, Op: op.text, Values: values}, nil
+	case tokOp:
+		return nil, &Error{Pos: t.pos, End: t.end, Msg: fmt.Sprintf("missing field name before %q", t.text)}
+	}
+	return nil, unexpected(t)
+}
+
+// parseValues parses the value after an operator: a word, a string or a
+// parenthesised list of alternatives separated by "|".
+func (p *parser) parseValues(op token) ([]Value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokWord, tokOr:
+		return []Value{{Text: t.text, Pos: t.pos, End: t.end}}, nil
+	case tokString:
+		return []Value{{Text: t.text, Quoted: true, Pos: t.pos, End: t.end}}, nil
+	case tokLParen:
+		var values []Value
+		for {
+			v := p.next()
+			switch v.kind {
+			case tokWord, tokOr:
+				values = append(values, Value{Text: v.text, Pos: v.pos, End: v.end})
+			case tokString:
+				values = append(values, Value{Text: v.text, Quoted: true, Pos: v.pos, End: v.end})
+			default:
+				return nil, &Error{Pos: v.pos, End: max(v.end, v.pos+1), Msg: "expected a value, got " + v.describe()}
+			}
+			switch sep := p.next(); sep.kind {
+			case tokPipe:
+				continue
+			case tokRParen:
+				return values, nil
+			default:
+				return nil, &Error{Pos: sep.pos, End: max(sep.end, sep.pos+1), Msg: `expected "|" or ")", got ` + sep.describe()}
+			}
+		}
+	}
+	return nil, &Error{Pos: op.pos, End: op.end, Msg: fmt.Sprintf("missing value after %q", op.text)}
+}
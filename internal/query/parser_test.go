@@ -0,0 +1,71 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	word := func(text string, pos int) Value {
+		return Value{Text: text, Pos: pos, End: pos + len(text)}
+	}
+	tests := []struct {
+		input string
+		want  Node
+	}{
+		{"", nil},
+		{"a OR b", Or{Terms: []Node{Text{word("a", 0)}, Text{word("b", 5)}}}},
+		{"a b OR c", Or{Terms: []Node{
+			And{Terms: []Node{Text{word("a", 0)}, Text{word("b", 2)}}},
+			Text{word("c", 7)},
+		}}},
+		{"-(a b)", Not{Term: And{Terms: []Node{Text{word("a", 2)}, Text{word("b", 4)}}}}},
+		{"cuisine:(thai|lao)", Compare{
+			Field: "cuisine", FieldPos: 0, FieldEnd: 7, Op: ":",
+			Values: []Value{word("thai", 9), word("lao", 14)},
+		}},
+		{`city:"New York"`, Compare{
+			Field: "city", FieldPos: 0, FieldEnd: 4, Op: ":",
+			Values: []Value{{Text: "New York", Quoted: true, Pos: 5, End: 15}},
+		}},
+		{"rating>=8", Compare{
+			Field: "rating", FieldPos: 0, FieldEnd: 6, Op: ">=",
+			Values: []Value{word("8", 8)},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		msg      string
+		pos, end int
+	}{
+		{"(a", `unclosed "("`, 0, 1},
+		{"a)", `unexpected ")"`, 1, 2},
+		{"rating>=", `missing value after ">="`, 6, 8},
+		{`city:"New`, "unterminated string", 5, 9},
+		{"rating:(8|", "expected a value, got end of query", 10, 11},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Parse(%q) error = %v, want *Error", tt.input, err)
+			continue
+		}
+		if e.Msg != tt.msg || e.Pos != tt.pos || e.End != tt.end {
+			t.Errorf("Parse(%q) error = %q at %d-%d, want %q at %d-%d", tt.input, e.Msg, e.Pos, e.End, tt.msg, tt.pos, tt.end)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Package query parses the filter language used by the list screens and
+// `toni list`, and compiles it to parameterised SQL.
+//
+// A query is a list of terms, all of which must match:
+//
+//	rating>=8 city:"New York" cuisine:(thai|lao) -return:no visited:2025..
+//
+// A term is a bare word (matched against the schema's text fields), a field
+// comparison, a parenthesised group, or any of those negated with "-". Terms
+// separated by OR match if either does. Comparisons use ":" (contains, or
+// within a date period), "=", "!=", "<", "<=", ">" and ">=". A value may be a
+// word, a quoted string, "none" for a missing value, a range such as 8..10
+// or 2024-06.., or alternatives in parentheses separated by "|".
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a query syntax or type error. Pos and End are byte offsets of the
+// offending text in Input.
+type Error struct {
+	Input string
+	Pos   int
+	End   int
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at column %d", e.Msg, e.Column())
+}
+
+// Column returns the 1-based column, in characters, where the error starts.
+func (e *Error) Column() int {
+	if e.Pos > len(e.Input) {
+		return e.Pos + 1
+	}
+	return utf8.RuneCountInString(e.Input[:e.Pos]) + 1
+}
+
+// Caret returns the query with the offending text underlined on the line
+// below it.
+func (e *Error) Caret() string {
+	width := 1
+	if e.End <= len(e.Input) && e.End > e.Pos {
+		width = utf8.RuneCountInString(e.Input[e.Pos:e.End])
+	}
+	return e.Input + "\n" + strings.Repeat(" ", e.Column()-1) + strings.Repeat("^", width)
+}
@@ -0,0 +1,321 @@
+// Package recommend predicts how much you'd like a restaurant you haven't
+// been to from how you rated the ones you have.
+//
+// The taste model is additive: a prediction is your average visit score
+// plus an effect for the restaurant's cuisine, one for its price range and
+// one for its neighbourhood. Each effect is how far visits with that trait
+// scored from what the other traits explain, shrunk towards zero when there
+// are only a few of them, so one great Ethiopian dinner doesn't make every
+// Ethiopian place a 9. A visit's score is its rating, half a point higher if
+// you said you'd return and a point lower if you said you wouldn't; unrated
+// visits count only when they say whether you'd return.
+package recommend
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"toni/internal/db"
+)
+
+const (
+	// MinSamples is how many scored visits the model needs before it
+	// predicts anything.
+	MinSamples = 5
+	// prior is how many visits' worth of evidence an effect is shrunk
+	// towards zero with.
+	prior = 2.0
+	// iterations of backfitting; the effects settle well before this.
+	iterations = 20
+	// minEffect is the smallest effect worth reporting as a factor.
+	minEffect = 0.05
+)
+
+// Trait names, in the order factors are listed when effects tie.
+const (
+	TraitCuisine = "cuisine"
+	TraitPrice   = "price"
+	TraitArea    = "area"
+)
+
+var traitNames = []string{TraitCuisine, TraitPrice, TraitArea}
+
+// Traits are what the model knows about a restaurant. Cuisine may list
+// several cuisines separated by commas or slashes.
+type Traits struct {
+	Cuisine      string
+	PriceRange   string
+	Neighborhood string
+}
+
+// Sample is a visit the model learns from.
+type Sample struct {
+	Traits
+	Rating      *float64
+	WouldReturn *bool
+}
+
+// Factor is one trait's part in a prediction.
+type Factor struct {
+	Trait string
+	// Value is the trait as first written in your data, e.g. "Thai".
+	Value  string
+	Effect float64
+	// Visits is how many visits with this trait the effect was learned from.
+	Visits int
+}
+
+// Prediction is a predicted rating with the factors behind it, largest
+// first.
+type Prediction struct {
+	Rating  float64
+	Factors []Factor
+}
+
+type effect struct {
+	value  string
+	effect float64
+	visits int
+}
+
+// Model is a trained taste model. The zero value and nil predict nothing.
+type Model struct {
+	mean    float64
+	samples int
+	// effects maps each trait to its values, keyed in lower case.
+	effects map[string]map[string]*effect
+}
+
+// Load trains a model on every visit in the database.
+func Load(database *sql.DB) (*Model, error) {
+	rows, err := db.ListVisitTraits(database)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]Sample, len(rows))
+	for i, r := range rows {
+		samples[i] = Sample{
+			Traits:      Traits{Cuisine: r.Cuisine, PriceRange: r.PriceRange, Neighborhood: r.Neighborhood},
+			Rating:      r.Rating,
+			WouldReturn: r.WouldReturn,
+		}
+	}
+	return Train(samples), nil
+}
+
+// observation is a scored visit with its trait values keyed in lower case.
+type observation struct {
+	score  float64
+	values map[string][]string
+}
+
+// Train fits a model to samples.
+func Train(samples []Sample) *Model {
+	m := &Model{effects: make(map[string]map[string]*effect)}
+	for _, t := range traitNames {
+		m.effects[t] = make(map[string]*effect)
+	}
+
+	var ratingSum float64
+	var rated int
+	for _, s := range samples {
+		if s.Rating != nil {
+			ratingSum += *s.Rating
+			rated++
+		}
+	}
+	if rated == 0 {
+		return m
+	}
+	ratedMean := ratingSum / float64(rated)
+
+	var obs []observation
+	for _, s := range samples {
+		score, ok := visitScore(s, ratedMean)
+		if !ok {
+			continue
+		}
+		o := observation{score: score, values: make(map[string][]string)}
+		for _, t := range traitNames {
+			for _, v := range traitValues(s.Traits, t) {
+				key := strings.ToLower(v)
+				e := m.effects[t][key]
+				if e == nil {
+					e = &effect{value: v}
+					m.effects[t][key] = e
+				}
+				e.visits++
+				o.values[t] = append(o.values[t], key)
+			}
+		}
+		obs = append(obs, o)
+		m.mean += score
+	}
+	m.samples = len(obs)
+	if m.samples == 0 {
+		return m
+	}
+	m.mean /= float64(m.samples)
+
+	// Backfitting: each trait's effects are refitted in turn to what the
+	// mean and the other traits leave unexplained.
+	for i := 0; i < iterations; i++ {
+		for _, t := range traitNames {
+			sums := make(map[string]float64)
+			for _, o := range obs {
+				values := o.values[t]
+				if len(values) == 0 {
+					continue
+				}
+				residual := o.score - m.mean
+				for _, other := range traitNames {
+					if other != t {
+						residual -= m.traitEffect(other, o.values[other])
+					}
+				}
+				for _, v := range values {
+					sums[v] += residual / float64(len(values))
+				}
+			}
+			for key, e := range m.effects[t] {
+				e.effect = sums[key] / (float64(e.visits) + prior)
+			}
+		}
+	}
+	return m
+}
+
+// visitScore returns the score a visit counts as, and whether it counts.
+func visitScore(s Sample, ratedMean float64) (float64, bool) {
+	switch {
+	case s.Rating != nil:
+		score := *s.Rating
+		if s.WouldReturn != nil {
+			if *s.WouldReturn {
+				score += 0.5
+			} else {
+				score--
+			}
+		}
+		return math.Max(1, math.Min(10, score)), true
+	case s.WouldReturn != nil && *s.WouldReturn:
+		return math.Min(10, ratedMean+1), true
+	case s.WouldReturn != nil:
+		return math.Max(1, ratedMean-2), true
+	}
+	return 0, false
+}
+
+// traitValues returns the values of one trait. Cuisines are split into
+// each cuisine listed.
+func traitValues(t Traits, trait string) []string {
+	var raw string
+	switch trait {
+	case TraitCuisine:
+		parts := strings.FieldsFunc(t.Cuisine, func(r rune) bool { return r == ',' || r == '/' })
+		var values []string
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				values = append(values, p)
+			}
+		}
+		return values
+	case TraitPrice:
+		raw = t.PriceRange
+	case TraitArea:
+		raw = t.Neighborhood
+	}
+	if raw = strings.TrimSpace(raw); raw == "" {
+		return nil
+	}
+	return []string{raw}
+}
+
+// traitEffect is the combined effect of a trait's values: the average of
+// those the model knows.
+func (m *Model) traitEffect(trait string, keys []string) float64 {
+	var sum float64
+	var n int
+	for _, k := range keys {
+		if e := m.effects[trait][k]; e != nil {
+			sum += e.effect
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// Ready reports whether the model has learned from enough visits to predict.
+func (m *Model) Ready() bool {
+	return m != nil && m.samples >= MinSamples
+}
+
+// Samples returns how many visits the model learned from.
+func (m *Model) Samples() int {
+	if m == nil {
+		return 0
+	}
+	return m.samples
+}
+
+// Predict predicts the rating of a restaurant with traits t. It reports
+// false when the model isn't ready or knows none of the traits, since the
+// prediction would then just be your average.
+func (m *Model) Predict(t Traits) (Prediction, bool) {
+	if !m.Ready() {
+		return Prediction{}, false
+	}
+	p := Prediction{Rating: m.mean}
+	known := false
+	for _, trait := range traitNames {
+		// As in traitEffect, values the model doesn't know are left out
+		// of the average rather than counted as no effect.
+		var effects []*effect
+		for _, v := range traitValues(t, trait) {
+			if e := m.effects[trait][strings.ToLower(v)]; e != nil {
+				effects = append(effects, e)
+			}
+		}
+		for _, e := range effects {
+			known = true
+			share := e.effect / float64(len(effects))
+			p.Rating += share
+			if math.Abs(share) >= minEffect {
+				p.Factors = append(p.Factors, Factor{Trait: trait, Value: e.value, Effect: share, Visits: e.visits})
+			}
+		}
+	}
+	if !known {
+		return Prediction{}, false
+	}
+	p.Rating = math.Max(1, math.Min(10, p.Rating))
+	sort.SliceStable(p.Factors, func(i, j int) bool {
+		return math.Abs(p.Factors[i].Effect) > math.Abs(p.Factors[j].Effect)
+	})
+	return p, true
+}
+
+// Summary lists up to n factors, e.g. "Thai +0.9 · $$$ −0.4".
+func (p Prediction) Summary(n int) string {
+	var parts []string
+	for i, f := range p.Factors {
+		if i == n {
+			break
+		}
+		parts = append(parts, f.String())
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (f Factor) String() string {
+	sign := "+"
+	if f.Effect < 0 {
+		sign = "−"
+	}
+	return fmt.Sprintf("%s %s%.1f", f.Value, sign, math.Abs(f.Effect))
+}
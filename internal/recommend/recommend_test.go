@@ -0,0 +1,175 @@
+package recommend
+
+import (
+	"math"
+	"testing"
+)
+
+func rated(cuisine, price, area string, rating float64) Sample {
+	return Sample{Traits: Traits{Cuisine: cuisine, PriceRange: price, Neighborhood: area}, Rating: &rating}
+}
+
+func repeat(n int, samples ...Sample) []Sample {
+	var out []Sample
+	for i := 0; i < n; i++ {
+		out = append(out, samples...)
+	}
+	return out
+}
+
+// grid is five visits to each of four kinds of place, where Thai and $ are
+// worth 4.5 points each, plus five 10s in Astoria at places otherwise rated
+// 1. A Thai place at $ in Astoria adds up to well over 10.
+var grid = repeat(5,
+	rated("Thai", "$", "", 10),
+	rated("Thai", "$$$", "", 5.5),
+	rated("Pizza", "$", "", 5.5),
+	rated("Pizza", "$$$", "", 1),
+	rated("Pizza", "$$$", "Astoria", 10),
+)
+
+// flip mirrors ratings around the middle of the scale.
+func flip(samples []Sample) []Sample {
+	out := make([]Sample, len(samples))
+	for i, s := range samples {
+		out[i] = rated(s.Cuisine, s.PriceRange, s.Neighborhood, 11-*s.Rating)
+	}
+	return out
+}
+
+func TestPredict(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []Sample
+		traits  Traits
+		want    float64
+		ok      bool
+	}{
+		{
+			name:    "fewer than MinSamples",
+			samples: repeat(MinSamples-1, rated("Thai", "", "", 8)),
+			traits:  Traits{Cuisine: "Thai"},
+		},
+		{
+			name:    "unscored visits don't count towards MinSamples",
+			samples: append(repeat(MinSamples-1, rated("Thai", "", "", 8)), Sample{Traits: Traits{Cuisine: "Thai"}}),
+			traits:  Traits{Cuisine: "Thai"},
+		},
+		{
+			name:    "MinSamples",
+			samples: repeat(MinSamples, rated("Thai", "", "", 8)),
+			traits:  Traits{Cuisine: "Thai"},
+			want:    8,
+			ok:      true,
+		},
+		{
+			name:    "unknown traits",
+			samples: repeat(MinSamples, rated("Thai", "", "", 8)),
+			traits:  Traits{Cuisine: "Ethiopian"},
+		},
+		{
+			// Mean 6.8; one visit 3.2 above it is shrunk to 3.2 / (1+2).
+			name:    "one visit is shrunk",
+			samples: append(repeat(4, rated("Thai", "", "", 6)), rated("Ethiopian", "", "", 10)),
+			traits:  Traits{Cuisine: "Ethiopian"},
+			want:    6.8 + 3.2/3,
+			ok:      true,
+		},
+		{
+			// Mean 8; four visits 2 above it are shrunk to 8 / (4+2).
+			name:    "more visits are shrunk less",
+			samples: append(repeat(4, rated("Thai", "", "", 6)), repeat(4, rated("Ethiopian", "", "", 10))...),
+			traits:  Traits{Cuisine: "Ethiopian"},
+			want:    8 + 8.0/6,
+			ok:      true,
+		},
+		{
+			name:    "cuisines are split",
+			samples: append(repeat(4, rated("Pizza", "", "", 6)), repeat(4, rated("Thai, Lao", "", "", 10))...),
+			traits:  Traits{Cuisine: "lao"},
+			want:    8 + 4.0/6,
+			ok:      true,
+		},
+		{
+			name:    "unknown cuisines don't dilute known ones",
+			samples: append(repeat(4, rated("Pizza", "", "", 6)), repeat(4, rated("Thai", "", "", 10))...),
+			traits:  Traits{Cuisine: "Thai / Martian"},
+			want:    8 + 8.0/6,
+			ok:      true,
+		},
+		{
+			name:    "several known cuisines are averaged",
+			samples: append(repeat(4, rated("Pizza", "", "", 6)), repeat(4, rated("Thai", "", "", 10))...),
+			traits:  Traits{Cuisine: "Thai/Pizza"},
+			want:    8,
+			ok:      true,
+		},
+		{
+			name:    "clamped to 10",
+			samples: grid,
+			traits:  Traits{Cuisine: "Thai", PriceRange: "$", Neighborhood: "Astoria"},
+			want:    10,
+			ok:      true,
+		},
+		{
+			name:    "clamped to 1",
+			samples: flip(grid),
+			traits:  Traits{Cuisine: "Thai", PriceRange: "$", Neighborhood: "Astoria"},
+			want:    1,
+			ok:      true,
+		},
+	}
+	for _, tt := range tests {
+		got, ok := Train(tt.samples).Predict(tt.traits)
+		if ok != tt.ok {
+			t.Errorf("%s: Predict ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && math.Abs(got.Rating-tt.want) > 1e-6 {
+			t.Errorf("%s: Predict = %.4f, want %.4f", tt.name, got.Rating, tt.want)
+		}
+	}
+}
+
+func TestPredictFactors(t *testing.T) {
+	m := Train(grid)
+	got, ok := m.Predict(Traits{Cuisine: "Thai", PriceRange: "$", Neighborhood: "Astoria"})
+	if !ok {
+		t.Fatal("Predict ok = false")
+	}
+	sum := m.mean
+	for _, f := range got.Factors {
+		sum += f.Effect
+	}
+	if sum <= 10 {
+		t.Errorf("mean plus effects = %.2f, want over 10 so the clamp is exercised", sum)
+	}
+	if len(got.Factors) != 3 || got.Factors[0].Value != "Astoria" || got.Factors[0].Visits != 5 {
+		t.Errorf("Factors = %+v, want Astoria from 5 visits first of 3", got.Factors)
+	}
+}
+
+func TestVisitScore(t *testing.T) {
+	yes, no := true, false
+	rating := func(r float64) *float64 { return &r }
+	tests := []struct {
+		sample Sample
+		want   float64
+		ok     bool
+	}{
+		{Sample{Rating: rating(8)}, 8, true},
+		{Sample{Rating: rating(8), WouldReturn: &yes}, 8.5, true},
+		{Sample{Rating: rating(10), WouldReturn: &yes}, 10, true},
+		{Sample{Rating: rating(8), WouldReturn: &no}, 7, true},
+		{Sample{Rating: rating(1), WouldReturn: &no}, 1, true},
+		{Sample{WouldReturn: &yes}, 7, true},
+		{Sample{WouldReturn: &no}, 4, true},
+		{Sample{}, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := visitScore(tt.sample, 6)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("visitScore(%+v) = %v, %v, want %v, %v", tt.sample, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -11,17 +11,30 @@ import (
 
 const yelpAPIBase = "https://api.yelp.com/v3"
 
+// defaultLocation biases searches that do not name a location.
+const defaultLocation = "New York, NY"
+
 // YelpClient wraps the Yelp Fusion API.
 type YelpClient struct {
-	apiKey     string
-	httpClient *http.Client
+	apiKey          string
+	httpClient      *http.Client
+	defaultLocation string
 }
 
 // NewYelpClient creates a new Yelp Fusion API client.
 func NewYelpClient(apiKey string) *YelpClient {
 	return &YelpClient{
-		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: 5 * time.Second},
+		apiKey:          apiKey,
+		httpClient:      &http.Client{Timeout: 5 * time.Second},
+		defaultLocation: defaultLocation,
+	}
+}
+
+// SetDefaultLocation sets the location searches are biased towards when the
+// caller does not give one, e.g. "Brooklyn, NY".
+func (c *YelpClient) SetDefaultLocation(location string) {
+	if location != "" {
+		c.defaultLocation = location
 	}
 }
 
@@ -56,8 +69,8 @@ func (c *YelpClient) Autocomplete(ctx context.Context, query, location string) (
 	if location != "" {
 		params.Set("location", location)
 	} else {
-		// Default to New York if no location specified
-		params.Set("location", "New York, NY")
+		// Fall back to the configured location bias
+		params.Set("location", c.defaultLocation)
 	}
 
 	reqURL := fmt.Sprintf("%s/businesses/search?%s", yelpAPIBase, params.Encode())
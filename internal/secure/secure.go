@@ -0,0 +1,253 @@
+// Package secure implements passphrase-based authenticated encryption for
+// files toni keeps at rest.
+//
+// A sealed blob is a fixed header followed by AES-256-GCM ciphertext:
+//
+//	magic (8) | salt (16) | scrypt N, r, p (3×uint32) | nonce (12) | ciphertext
+//
+// The key is derived from the passphrase with scrypt, and the header is bound
+// to the ciphertext as additional authenticated data so it cannot be altered.
+package secure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltSize   = 16
+	nonceSize  = 12
+	keySize    = 32
+	headerSize = len(magic) + saltSize + 3*4 + nonceSize
+)
+
+const magic = "TONIENC1"
+
+// Default scrypt cost parameters (about 100ms on a laptop).
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Limits on the scrypt parameters accepted from a sealed file, so a crafted
+// header can't make unlocking take gigabytes of memory or minutes of CPU.
+// scrypt needs 128·N·r bytes; the limits allow 256 MiB.
+const (
+	maxScryptN      = 1 << 20
+	maxScryptR      = 32
+	maxScryptP      = 16
+	maxScryptMemory = 256 << 20
+)
+
+var (
+	// ErrWrongPassphrase is returned when a blob cannot be authenticated with
+	// the given passphrase. A corrupted file produces the same error.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted data")
+	// ErrNotSealed is returned when data does not start with the sealed
+	// header.
+	ErrNotSealed = errors.New("data is not encrypted by toni")
+	// ErrBadParameters is returned when a sealed header asks for key
+	// derivation parameters outside the accepted limits.
+	ErrBadParameters = errors.New("encrypted file has invalid key derivation parameters")
+)
+
+// Key is a derived encryption key together with the salt and cost used to
+// derive it. Reusing a Key avoids paying for key derivation on every seal.
+type Key struct {
+	salt    []byte
+	n, r, p uint32
+	aead    cipher.AEAD
+}
+
+// NewKey derives a key from passphrase with a fresh random salt.
+func NewKey(passphrase []byte) (*Key, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	return deriveKey(passphrase, salt, scryptN, scryptR, scryptP)
+}
+
+func deriveKey(passphrase, salt []byte, n, r, p uint32) (*Key, error) {
+	raw, err := scrypt.Key(passphrase, salt, int(n), int(r), int(p), keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{salt: salt, n: n, r: r, p: p, aead: aead}, nil
+}
+
+// Seal encrypts plaintext with k.
+func (k *Key) Seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, k.salt...)
+	header = binary.BigEndian.AppendUint32(header, k.n)
+	header = binary.BigEndian.AppendUint32(header, k.r)
+	header = binary.BigEndian.AppendUint32(header, k.p)
+	header = append(header, nonce...)
+
+	return k.aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// Unseal decrypts a blob produced by Seal with the same key. Blobs sealed
+// under a different salt need Open instead.
+func (k *Key) Unseal(sealed []byte) ([]byte, error) {
+	h, err := parseHeader(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h.salt, k.salt) {
+		return nil, ErrWrongPassphrase
+	}
+	return k.open(sealed, h)
+}
+
+// Open decrypts a sealed blob with passphrase and returns the plaintext along
+// with the derived key so the caller can re-seal without deriving again.
+func Open(sealed, passphrase []byte) ([]byte, *Key, error) {
+	h, err := parseHeader(sealed)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := deriveKey(passphrase, h.salt, h.n, h.r, h.p)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := key.open(sealed, h)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, key, nil
+}
+
+// UnlockFile derives the key for the sealed file at path from passphrase.
+// It returns ErrWrongPassphrase if the passphrase does not match.
+func UnlockFile(path string, passphrase []byte) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	_, key, err := Open(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// IsSealed reports whether data starts with the sealed header.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+type header struct {
+	salt    []byte
+	n, r, p uint32
+	nonce   []byte
+}
+
+func parseHeader(sealed []byte) (header, error) {
+	if len(sealed) < headerSize || !IsSealed(sealed) {
+		return header{}, ErrNotSealed
+	}
+	rest := sealed[len(magic):]
+	h := header{salt: rest[:saltSize]}
+	rest = rest[saltSize:]
+	h.n = binary.BigEndian.Uint32(rest[0:4])
+	h.r = binary.BigEndian.Uint32(rest[4:8])
+	h.p = binary.BigEndian.Uint32(rest[8:12])
+	h.nonce = rest[12 : 12+nonceSize]
+	if err := checkParameters(h.n, h.r, h.p); err != nil {
+		return header{}, err
+	}
+	return h, nil
+}
+
+// checkParameters rejects scrypt parameters beyond the limits, and N values
+// that aren't powers of two.
+func checkParameters(n, r, p uint32) error {
+	if n < 2 || n&(n-1) != 0 || n > maxScryptN {
+		return fmt.Errorf("%w: N = %d", ErrBadParameters, n)
+	}
+	if r < 1 || r > maxScryptR || p < 1 || p > maxScryptP {
+		return fmt.Errorf("%w: r = %d, p = %d", ErrBadParameters, r, p)
+	}
+	if 128*uint64(n)*uint64(r) > maxScryptMemory {
+		return fmt.Errorf("%w: N = %d and r = %d need more than %d MiB", ErrBadParameters, n, r, maxScryptMemory>>20)
+	}
+	return nil
+}
+
+func (k *Key) open(sealed []byte, h header) ([]byte, error) {
+	plaintext, err := k.aead.Open(nil, h.nonce, sealed[headerSize:], sealed[:headerSize])
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// ScratchDir creates a private directory for decrypted working files. It
+// prefers a memory-backed filesystem so plaintext never reaches the disk, and
+// falls back to the system temp directory. The caller must remove it.
+func ScratchDir() (string, error) {
+	parent := ""
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		parent = "/dev/shm"
+	}
+	dir, err := os.MkdirTemp(parent, "toni-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	return dir, nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers see either the old contents or the new ones.
+func WriteFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package secure
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	key, err := NewKey([]byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := key.Seal([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, _, err := Open(sealed, []byte("correct horse"))
+	if err != nil || string(plaintext) != "plaintext" {
+		t.Errorf("Open = %q, %v, want plaintext", plaintext, err)
+	}
+	if _, _, err := Open(sealed, []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Open with the wrong passphrase = %v, want ErrWrongPassphrase", err)
+	}
+	if _, _, err := Open([]byte("SQLite format 3\x00"), []byte("correct horse")); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("Open of plaintext = %v, want ErrNotSealed", err)
+	}
+}
+
+func TestOpenRejectsBadParameters(t *testing.T) {
+	key, err := NewKey([]byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := key.Seal([]byte("plaintext"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		n, r, p uint32
+	}{
+		{"N not a power of two", 3 << 14, 8, 1},
+		{"N zero", 0, 8, 1},
+		{"N too large", 1 << 30, 8, 1},
+		{"r zero", 1 << 15, 0, 1},
+		{"r too large", 1 << 15, 1 << 20, 1},
+		{"p too large", 1 << 15, 8, 1 << 20},
+		{"too much memory", 1 << 20, 8, 1},
+	}
+	for _, tt := range tests {
+		crafted := append([]byte(nil), sealed...)
+		params := crafted[len(magic)+saltSize:]
+		binary.BigEndian.PutUint32(params[0:4], tt.n)
+		binary.BigEndian.PutUint32(params[4:8], tt.r)
+		binary.BigEndian.PutUint32(params[8:12], tt.p)
+		if _, _, err := Open(crafted, []byte("pw")); !errors.Is(err, ErrBadParameters) {
+			t.Errorf("%s: Open = %v, want ErrBadParameters", tt.name, err)
+		}
+	}
+}
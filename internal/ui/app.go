@@ -8,7 +8,10 @@ import (
 	"time"
 	"toni/internal/db"
 	"toni/internal/model"
+	"toni/internal/pick"
+	"toni/internal/recommend"
 	"toni/internal/search"
+	"toni/internal/util"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -27,7 +30,9 @@ type Model struct {
 	termCapabilities TerminalCapabilities
 	screen           model.Screen
 	mode             model.Mode
-	gState           GState
+	// pendingKeys holds the keys typed so far of a multi-key sequence such
+	// as "g g".
+	pendingKeys []tea.KeyMsg
 
 	width  int
 	height int
@@ -38,8 +43,23 @@ type Model struct {
 	columnJump   bool
 	returnScreen model.Screen
 
+	// cmdline is the open ":" prompt, nil when closed.
+	cmdline     *commandLine
+	history     []string
+	historyPath string
+
+	// queries holds the filter query each list screen is loaded with.
+	queries map[model.Screen]string
+	// views holds the name of the saved view last applied on each list
+	// screen, and viewPicker is the open view picker, nil when closed.
+	views      map[model.Screen]string
+	viewPicker *viewPicker
+	// finder is the open ctrl+p finder, nil when closed.
+	finder *finder
+
 	// Screen models
 	visits            *VisitsModel
+	upcoming          *UpcomingModel
 	restaurants       *RestaurantsModel
 	wantToVisit       *WantToVisitModel
 	visitDetail       *VisitDetailModel
@@ -48,33 +68,64 @@ type Model struct {
 	visitForm         *VisitFormModel
 	restaurantForm    *RestaurantFormModel
 	wantToVisitForm   *WantToVisitFormModel
+	planForm          *PlanFormModel
+	visitCalendar     *VisitCalendarModel
+	picker            *PickerModel
+	comparison        *CompareModel
+
+	// taste predicts ratings of unvisited restaurants; it is retrained
+	// whenever visits or the restaurants they were at change.
+	taste *recommend.Model
 
 	keys      KeyMap
-	formKeys  FormKeyMap
+	dateOrder util.DateOrder
 	prefs     UIPreferences
+	prefsPath string
 	undoStack []undoAction
 	redoStack []undoAction
 }
 
+// Options holds per-profile settings for the root model.
+type Options struct {
+	// PrefsPath is where UI preferences are loaded from and saved to. An
+	// empty path keeps preferences in memory only.
+	PrefsPath string
+	// Keys is the active keymap. The zero value uses DefaultKeyMap.
+	Keys KeyMap
+	// HistoryPath is where command line history is kept between sessions.
+	// An empty path keeps history in memory only.
+	HistoryPath string
+	// DateOrder is how numeric dates typed in forms are read.
+	DateOrder util.DateOrder
+}
+
 // New creates a new root model.
-func New(database *sql.DB, yelpClient *search.YelpClient, termCaps TerminalCapabilities) Model {
+func New(database *sql.DB, yelpClient *search.YelpClient, termCaps TerminalCapabilities, opts Options) Model {
+	keys := opts.Keys
+	if keys.bindings == nil {
+		keys = DefaultKeyMap()
+	}
 	return Model{
 		db:               database,
 		yelpClient:       yelpClient,
 		termCapabilities: termCaps,
 		screen:           model.ScreenVisits,
 		mode:             model.ModeNav,
-		gState:           GStateIdle,
-		keys:             DefaultKeyMap(),
-		formKeys:         DefaultFormKeyMap(),
-		prefs:            loadUIPreferences(),
+		keys:             keys,
+		dateOrder:        opts.DateOrder,
+		prefs:            loadUIPreferences(opts.PrefsPath),
+		prefsPath:        opts.PrefsPath,
+		history:          loadCommandHistory(opts.HistoryPath),
+		historyPath:      opts.HistoryPath,
+		queries:          make(map[model.Screen]string),
+		views:            make(map[model.Screen]string),
 		returnScreen:     model.ScreenVisits,
 	}
 }
 
 // Init initializes the model.
 func (m Model) Init() tea.Cmd {
-	return loadVisitsCmd(m.db, "")
+	return tea.Batch(loadVisitsCmd(m.db, m.queries[model.ScreenVisits]), loadPlannedVisitsCmd(m.db), loadTasteCmd(m.db))
 }
 
 // Update handles messages.
@@ -106,19 +157,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
-		// Handle ctrl+c globally
+		// ctrl+c always quits, whatever the keymap says
 		if msg.String() == "ctrl+c" {
 			return m, tea.Quit
 		}
 
-		// Handle help toggle
-		if msg.String() == "?" && m.mode == model.ModeNav {
-			m.showingHelp = !m.showingHelp
-			return m, nil
+		if m.cmdline != nil {
+			return m.handleCommandLine(msg)
+		}
+
+		if m.finder != nil {
+			return m.handleFinder(msg)
+		}
+
+		if m.viewPicker != nil {
+			return m.handleViewPicker(msg)
 		}
 
 		if m.showingHelp {
-			if msg.String() == "esc" || msg.String() == "?" {
+			if action, _, _ := m.resolveKey(msg, helpChain); action == ActionHelp {
 				m.showingHelp = false
 			}
 			return m, nil
@@ -136,12 +193,51 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case model.VisitsLoadedMsg:
 		m.visits = NewVisitsModel(msg.Visits)
+		m.visits.query = m.queries[model.ScreenVisits]
+		m.visits.view = m.views[model.ScreenVisits]
 		m.visits.ApplyPrefs(m.prefs.Visits)
 		m.error = ""
 		return m, nil
 
+	case model.PlannedVisitsLoadedMsg:
+		if m.upcoming == nil {
+			m.upcoming = NewUpcomingModel(msg.Plans)
+		} else {
+			m.upcoming.setPlans(msg.Plans)
+		}
+		if m.screen == model.ScreenUpcoming && len(msg.Plans) == 0 {
+			m.screen = model.ScreenVisits
+		}
+		return m, nil
+
+	case pickPoolLoadedMsg:
+		if m.picker != nil {
+			return m, m.picker.setPool(msg)
+		}
+		return m, nil
+
+	case pickerTickMsg:
+		if m.picker != nil {
+			return m, m.picker.advance(msg)
+		}
+		return m, nil
+
+	case compareLoadedMsg:
+		if m.comparison != nil {
+			m.comparison.setReport(msg)
+		}
+		return m, nil
+
+	case visitDaysLoadedMsg:
+		if m.visitCalendar != nil {
+			m.visitCalendar.setDays(msg)
+		}
+		return m, nil
+
 	case model.RestaurantsLoadedMsg:
 		m.restaurants = NewRestaurantsModel(msg.Restaurants)
+		m.restaurants.query = m.queries[model.ScreenRestaurants]
+		m.restaurants.view = m.views[model.ScreenRestaurants]
 		m.restaurants.ApplyPrefs(m.prefs.Restaurants)
 		m.error = ""
 		return m, nil
@@ -166,10 +262,23 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.screen = model.ScreenVisits
 		m.visitForm = nil
 		m.info = "Visit saved"
+		if msg.Plan != nil {
+			m.info = "Visit logged and plan cleared (u to undo)"
+			if len(msg.WantToVisit) > 0 {
+				m.info = "Visit logged, plan cleared and taken off want to visit (u to undo)"
+			}
+		} else if len(msg.WantToVisit) > 0 {
+			m.info = "Visit logged and taken off want to visit (u to undo)"
+		}
+		if len(msg.WantToVisit) > 0 {
+			m.wantToVisitDetail = nil
+		}
 		return m, tea.Batch(
-			loadVisitsCmd(m.db, ""),
-			loadRestaurantsCmd(m.db, ""),
-			loadWantToVisitCmd(m.db),
+			loadVisitsCmd(m.db, m.queries[model.ScreenVisits]),
+			loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]),
+			loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit]),
+			loadPlannedVisitsCmd(m.db),
+			loadTasteCmd(m.db),
 		)
 
 	case model.RestaurantSavedMsg:
@@ -181,16 +290,40 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.restaurantForm = nil
 		m.info = "Restaurant saved"
 		return m, tea.Batch(
-			loadRestaurantsCmd(m.db, ""),
-			loadVisitsCmd(m.db, ""),
-			loadWantToVisitCmd(m.db),
+			loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]),
+			loadVisitsCmd(m.db, m.queries[model.ScreenVisits]),
+			loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit]),
+			loadPlannedVisitsCmd(m.db),
+			loadTasteCmd(m.db),
 		)
 
+	case model.PlannedVisitSavedMsg:
+		if action := m.buildPlanSaveAction(msg); action != nil {
+			m.pushUndoAction(*action)
+		}
+		m.mode = model.ModeNav
+		m.screen = model.ScreenVisits
+		if m.returnScreen == model.ScreenUpcoming {
+			m.screen = model.ScreenUpcoming
+		}
+		m.planForm = nil
+		m.info = "Plan saved"
+		return m, tea.Batch(
+			loadPlannedVisitsCmd(m.db),
+			loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]),
+		)
+
+	case model.DeletePlannedVisitMsg:
+		m.pushUndoAction(m.buildDeletePlanAction(msg))
+		m.info = "Plan deleted (u to undo)"
+		return m, loadPlannedVisitsCmd(m.db)
+
 	case model.FormCancelledMsg:
 		m.mode = model.ModeNav
 		m.visitForm = nil
 		m.restaurantForm = nil
 		m.wantToVisitForm = nil
+		m.planForm = nil
 		m.screen = m.returnScreen
 		return m, nil
 
@@ -200,9 +333,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.visitDetail = nil
 		m.info = "Visit deleted (u to undo)"
 		return m, tea.Batch(
-			loadVisitsCmd(m.db, ""),
-			loadRestaurantsCmd(m.db, ""),
-			loadWantToVisitCmd(m.db),
+			loadVisitsCmd(m.db, m.queries[model.ScreenVisits]),
+			loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]),
+			loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit]),
+			loadTasteCmd(m.db),
 		)
 
 	case model.DeleteRestaurantMsg:
@@ -211,17 +345,26 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.restaurantDetail = nil
 		m.info = "Restaurant deleted (u to undo)"
 		return m, tea.Batch(
-			loadRestaurantsCmd(m.db, ""),
-			loadVisitsCmd(m.db, ""),
-			loadWantToVisitCmd(m.db),
+			loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]),
+			loadVisitsCmd(m.db, m.queries[model.ScreenVisits]),
+			loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit]),
+			loadPlannedVisitsCmd(m.db),
+			loadTasteCmd(m.db),
 		)
 
 	case model.WantToVisitLoadedMsg:
 		m.wantToVisit = NewWantToVisitModel(msg.WantToVisit)
+		m.wantToVisit.taste = m.taste
+		m.wantToVisit.query = m.queries[model.ScreenWantToVisit]
+		m.wantToVisit.view = m.views[model.ScreenWantToVisit]
 		m.wantToVisit.ApplyPrefs(m.prefs.WantToVisit)
 		m.error = ""
 		return m, nil
 
+	case tasteLoadedMsg:
+		m.applyTaste(msg.taste)
+		return m, nil
+
 	case model.WantToVisitSavedMsg:
 		if action := m.buildWantToVisitSaveAction(msg); action != nil {
 			m.pushUndoAction(*action)
@@ -231,8 +374,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.wantToVisitForm = nil
 		m.info = "Want-to-visit entry saved"
 		return m, tea.Batch(
-			loadWantToVisitCmd(m.db),
-			loadRestaurantsCmd(m.db, ""),
+			loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit]),
+			loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]),
 		)
 
 	case model.DeleteWantToVisitMsg:
@@ -240,22 +383,28 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.screen = model.ScreenWantToVisit
 		m.wantToVisitDetail = nil
 		m.info = "Want-to-visit entry deleted (u to undo)"
-		return m, loadWantToVisitCmd(m.db)
-
-	case model.ConvertToVisitMsg:
-		m.pushUndoAction(m.buildConvertAction(msg))
-		// Convert want_to_visit to visit - open visit form with restaurant pre-filled
-		m.mode = model.ModeInsert
-		m.screen = model.ScreenVisitForm
-		m.returnScreen = model.ScreenWantToVisit
-		m.visitForm = NewVisitFormModel(m.db, m.yelpClient, msg.RestaurantID)
-		m.wantToVisitDetail = nil
-		m.info = "Converted to visit (u to undo)"
-		return m, loadWantToVisitCmd(m.db)
+		return m, loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit])
 
 	case undoAppliedMsg:
 		return m, m.applyUndoResult(msg)
 
+	case bulkAppliedMsg:
+		return m, m.applyBulkResult(msg)
+
+	case notesEditedMsg:
+		return m, m.applyNotesEdited(msg)
+
+	case notesSavedMsg:
+		return m, m.applyNotesSaved(msg)
+
+	case finderItemsLoadedMsg:
+		if m.finder != nil {
+			m.finder.items = msg.items
+			m.finder.loading = false
+			m.finder.refilter()
+		}
+		return m, nil
+
 	case wantToVisitDetailLoadedMsg:
 		m.wantToVisitDetail = NewWantToVisitDetailModel(msg.entry, msg.restaurant)
 		m.screen = model.ScreenWantToVisitDetail
@@ -263,6 +412,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	default:
+		// Keep the prompt's cursor blinking
+		if m.finder != nil {
+			var cmd tea.Cmd
+			m.finder.input, cmd = m.finder.input.Update(msg)
+			return m, cmd
+		}
+		if m.cmdline != nil {
+			var cmd tea.Cmd
+			m.cmdline.input, cmd = m.cmdline.input.Update(msg)
+			if m.mode == model.ModeNav {
+				return m, cmd
+			}
+			next, formCmd := m.handleInsertMode(msg)
+			return next, tea.Batch(cmd, formCmd)
+		}
 		// Pass all other messages to forms
 		if m.mode == model.ModeInsert {
 			return m.handleInsertMode(msg)
@@ -282,7 +446,7 @@ func (m Model) View() string {
 		return renderTerminalTooSmall(m.width, m.height)
 	}
 	if m.showingHelp {
-		return RenderFullHelp(m.width, m.height)
+		return RenderFullHelp(m.keys, m.width, m.height)
 	}
 
 	var content string
@@ -290,12 +454,15 @@ func (m Model) View() string {
 
 	// Determine if this screen should show tabs
 	showTabs := m.screen == model.ScreenVisits ||
+		m.screen == model.ScreenUpcoming ||
 		m.screen == model.ScreenRestaurants ||
 		m.screen == model.ScreenWantToVisit
 
 	switch m.screen {
 	case model.ScreenVisits:
 		breadcrumbParts = []string{"Visits"}
+	case model.ScreenUpcoming:
+		breadcrumbParts = []string{"Visits", "Upcoming"}
 	case model.ScreenRestaurants:
 		breadcrumbParts = []string{"Restaurants"}
 	case model.ScreenWantToVisit:
@@ -321,14 +488,29 @@ func (m Model) View() string {
 		breadcrumbParts = []string{"Restaurants", "Form"}
 	case model.ScreenWantToVisitForm:
 		breadcrumbParts = []string{"Want to Visit", "Form"}
+	case model.ScreenPlanForm:
+		breadcrumbParts = []string{"Visits", "Plan"}
+	case model.ScreenVisitCalendar:
+		breadcrumbParts = []string{"Visits", "Calendar"}
+	case model.ScreenPicker:
+		breadcrumbParts = []string{"Pick"}
+	case model.ScreenCompare:
+		breadcrumbParts = []string{"Compare"}
 	}
 
 	header := renderHeader(breadcrumbParts, m.width)
 	tabs := ""
 	if showTabs {
-		tabs = renderTabs(m.screen, m.width)
+		tab := m.screen
+		if tab == model.ScreenUpcoming {
+			tab = model.ScreenVisits
+		}
+		tabs = renderTabs(tab, m.width)
+	}
+	footer := RenderHelp(m.keys, m.screen, m.mode, m.width)
+	if m.cmdline != nil {
+		footer = m.renderCommandLine()
 	}
-	footer := RenderHelp(m.screen, m.mode, m.width)
 	var banners []string
 	if m.error != "" {
 		banners = append(banners, ErrorStyle.Width(m.width).Render("Error: "+m.error))
@@ -349,9 +531,16 @@ func (m Model) View() string {
 	}
 
 	switch m.screen {
-	case model.ScreenVisits:
+	case model.ScreenVisits, model.ScreenUpcoming:
 		if m.visits != nil {
 			content = m.visits.View(m.width, contentHeight)
+			if m.upcoming != nil {
+				if upcoming := m.upcoming.View(m.width, m.screen == model.ScreenUpcoming); upcoming != "" {
+					if tableHeight := contentHeight - lipgloss.Height(upcoming); tableHeight >= 4 {
+						content = lipgloss.JoinVertical(lipgloss.Left, upcoming, m.visits.View(m.width, tableHeight))
+					}
+				}
+			}
 		}
 	case model.ScreenRestaurants:
 		if m.restaurants != nil {
@@ -385,6 +574,29 @@ func (m Model) View() string {
 		if m.wantToVisitForm != nil {
 			content = m.wantToVisitForm.View(m.width, contentHeight)
 		}
+	case model.ScreenPlanForm:
+		if m.planForm != nil {
+			content = m.planForm.View(m.width, contentHeight)
+		}
+	case model.ScreenVisitCalendar:
+		if m.visitCalendar != nil {
+			content = m.visitCalendar.View(m.width, contentHeight)
+		}
+	case model.ScreenPicker:
+		if m.picker != nil {
+			content = m.picker.View(m.width, contentHeight)
+		}
+	case model.ScreenCompare:
+		if m.comparison != nil {
+			content = m.comparison.View(m.width, contentHeight)
+		}
+	}
+
+	if m.viewPicker != nil && showTabs {
+		content = m.renderViewPicker(m.width, contentHeight)
+	}
+	if m.finder != nil {
+		content = m.renderFinder(m.width, contentHeight)
 	}
 
 	// Ensure content fills the available height to anchor footer at bottom
@@ -422,12 +634,12 @@ func (m Model) switchTopLevel(target model.Screen) (tea.Model, tea.Cmd) {
 		return m, nil
 	case model.ScreenWantToVisit:
 		if m.wantToVisit == nil {
-			return m, loadWantToVisitCmd(m.db)
+			return m, loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit])
 		}
 		return m, nil
 	case model.ScreenRestaurants:
 		if m.restaurants == nil {
-			return m, loadRestaurantsCmd(m.db, "")
+			return m, loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants])
 		}
 	}
 	return m, nil
@@ -543,25 +755,55 @@ func renderHeader(breadcrumbParts []string, width int) string {
 
 // handleNavMode handles navigation mode input.
 func (m Model) handleNavMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	action, pending, _ := m.resolveKey(msg, navContexts(m.screen))
+	if action == "" {
+		if !pending {
+			return m.handleViewNumber(msg)
+		}
+		return m, nil
+	}
+
+	switch action {
+	case ActionHelp:
+		m.showingHelp = true
+		return m, nil
+	case ActionUndo:
+		if len(m.undoStack) == 0 {
+			m.info = "Nothing to undo"
+			return m, nil
+		}
+		return m, m.undoCmd()
+	case ActionRedo:
+		if len(m.redoStack) == 0 {
+			m.info = "Nothing to redo"
+			return m, nil
+		}
+		return m, m.redoCmd()
+	case ActionCommandLine:
+		return m.openCommandLine()
+	case ActionFinder:
+		return m.openFinder()
+	}
+
 	if t := m.currentTable(); t != nil {
-		switch msg.String() {
-		case "tab":
+		switch action {
+		case ActionNextColumn:
 			t.NextColumn()
 			m.persistCurrentTablePrefs()
 			return m, nil
-		case "shift+tab":
+		case ActionPrevColumn:
 			t.PrevColumn()
 			m.persistCurrentTablePrefs()
 			return m, nil
-		case "/":
+		case ActionColumnJump:
 			m.columnJump = true
 			m.info = "Jump to column: press 1-9 (esc to cancel)"
 			return m, nil
-		case "s":
+		case ActionCycleSort:
 			m.info = t.CycleSortActiveColumn()
 			m.persistCurrentTablePrefs()
 			return m, nil
-		case "c":
+		case ActionHideColumn:
 			if t.HideActiveColumn() {
 				m.info = "Column hidden"
 				m.persistCurrentTablePrefs()
@@ -569,64 +811,95 @@ func (m Model) handleNavMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.info = "Cannot hide last visible column"
 			}
 			return m, nil
-		case "C":
+		case ActionShowColumns:
 			t.ShowAllColumns()
 			m.info = "All columns shown"
 			m.persistCurrentTablePrefs()
 			return m, nil
-		case "n":
+		case ActionCycleFilter:
 			m.info = t.CycleFilterBySelectedValue()
 			m.persistCurrentTablePrefs()
 			return m, nil
-		}
-	}
-
-	switch msg.String() {
-	case "u":
-		if len(m.undoStack) == 0 {
-			m.info = "Nothing to undo"
+		case ActionSearch:
+			return m.openCommandLineWith("filter " + m.queries[m.screen])
+		case ActionViews:
+			return m.openViewPicker()
+		case ActionToggleMark:
+			t.ToggleMark()
 			return m, nil
-		}
-		return m, m.undoCmd()
-	case "ctrl+r":
-		if len(m.redoStack) == 0 {
-			m.info = "Nothing to redo"
+		case ActionVisual:
+			if t.ToggleVisual() {
+				m.info = "Visual mode: move to extend, V to mark the range"
+			} else {
+				m.info = fmt.Sprintf("%d selected", t.SelectionCount())
+			}
 			return m, nil
-		}
-		return m, m.redoCmd()
-	}
-
-	// Handle "gg" state machine
-	if msg.String() == "g" {
-		if m.gState == GStateIdle {
-			m.gState = GStateFirstG
+		case ActionClearSelection:
+			if t.ClearSelection() {
+				m.info = "Selection cleared"
+			}
 			return m, nil
-		} else if m.gState == GStateFirstG {
-			m.gState = GStateIdle
-			// Jump to top
+		case ActionDelete:
+			cmd, err := m.bulkDelete()
+			if err != nil {
+				m.error = err.Error()
+			}
+			return m, cmd
+		case ActionTag:
+			return m.openCommandLineWith("tag ")
+		case ActionPick:
+			query := ""
+			if m.picker != nil {
+				query = m.picker.query
+			}
+			cmd, err := m.openPicker(query)
+			if err != nil {
+				m.error = err.Error()
+			}
+			return m, cmd
+		case ActionTop:
 			return m.handleJumpToTop()
-		}
-	} else {
-		// Any other key resets the state
-		if m.gState == GStateFirstG {
-			m.gState = GStateIdle
+		case ActionQuit:
+			return m, tea.Quit
+		case ActionPrevTab:
+			return m.switchTopLevel(prevTopLevelScreen(m.screen))
+		case ActionNextTab:
+			return m.switchTopLevel(nextTopLevelScreen(m.screen))
+		case ActionFirstTab:
+			return m.switchTopLevel(model.ScreenVisits)
+		case ActionLastTab:
+			return m.switchTopLevel(model.ScreenRestaurants)
+		case ActionVisits:
+			return m.switchTopLevel(model.ScreenVisits)
+		case ActionRestaurants:
+			return m.switchTopLevel(model.ScreenRestaurants)
+		case ActionWantToVisit:
+			return m.switchTopLevel(model.ScreenWantToVisit)
 		}
 	}
 
 	// Screen-specific navigation
 	switch m.screen {
 	case model.ScreenVisits:
-		return m.handleVisitsNav(msg)
+		return m.handleVisitsNav(action)
+	case model.ScreenUpcoming:
+		return m.handleUpcomingNav(action)
 	case model.ScreenRestaurants:
-		return m.handleRestaurantsNav(msg)
+		return m.handleRestaurantsNav(action)
 	case model.ScreenWantToVisit:
-		return m.handleWantToVisitNav(msg)
+		return m.handleWantToVisitNav(action)
 	case model.ScreenVisitDetail:
-		return m.handleVisitDetailNav(msg)
+		return m.handleVisitDetailNav(action)
 	case model.ScreenRestaurantDetail:
-		return m.handleRestaurantDetailNav(msg)
+		return m.handleRestaurantDetailNav(action)
 	case model.ScreenWantToVisitDetail:
-		return m.handleWantToVisitDetailNav(msg)
+		return m.handleWantToVisitDetailNav(action)
+	case model.ScreenVisitCalendar:
+		return m.handleVisitCalendarNav(action)
+	case model.ScreenPicker:
+		return m.handlePickerNav(action)
+	case model.ScreenCompare:
+		return m.handleCompareNav(action)
 	}
 
 	return m, nil
@@ -665,11 +938,54 @@ func (m *Model) persistCurrentTablePrefs() {
 			m.prefs.WantToVisit = m.wantToVisit.Prefs()
 		}
 	}
-	_ = saveUIPreferences(m.prefs)
+	_ = saveUIPreferences(m.prefsPath, m.prefs)
+}
+
+// formKeyMsg is a key press in a form together with the action it is bound
+// to, if any.
+type formKeyMsg struct {
+	tea.KeyMsg
+	action Action
 }
 
 // handleInsertMode handles insert/edit mode input.
 func (m Model) handleInsertMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m.updateForm(msg)
+	}
+
+	contexts := formChain
+	if m.formDropdownOpen() {
+		contexts = dropdownChain
+	}
+	if m.screen == model.ScreenVisitForm && m.visitForm != nil && m.visitForm.calendar != nil {
+		contexts = calendarChain
+	}
+	if m.screen == model.ScreenPlanForm && m.planForm != nil && m.planForm.calendar != nil {
+		contexts = calendarChain
+	}
+	action, pending, replay := m.resolveKey(keyMsg, contexts)
+	if action == ActionCommandLine && len(replay) == 0 {
+		return m.openCommandLine()
+	}
+
+	// Keys of an abandoned sequence are typed into the form as they were.
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	for _, k := range replay {
+		m, cmd = m.updateForm(formKeyMsg{KeyMsg: k})
+		cmds = append(cmds, cmd)
+	}
+	if !pending {
+		m, cmd = m.updateForm(formKeyMsg{KeyMsg: keyMsg, action: action})
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// updateForm passes a message to the open form.
+func (m Model) updateForm(msg tea.Msg) (Model, tea.Cmd) {
 	switch m.screen {
 	case model.ScreenVisitForm:
 		if m.visitForm != nil {
@@ -679,8 +995,8 @@ func (m Model) handleInsertMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 	case model.ScreenRestaurantForm:
 		if m.restaurantForm != nil {
-			// Restaurant form only handles KeyMsg
-			if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			// Restaurant form only handles key presses
+			if keyMsg, ok := msg.(formKeyMsg); ok {
 				newForm, cmd := m.restaurantForm.Update(keyMsg)
 				m.restaurantForm = &newForm
 				return m, cmd
@@ -692,10 +1008,31 @@ func (m Model) handleInsertMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.wantToVisitForm = &newForm
 			return m, cmd
 		}
+	case model.ScreenPlanForm:
+		if m.planForm != nil {
+			// Plan form only handles key presses
+			if keyMsg, ok := msg.(formKeyMsg); ok {
+				newForm, cmd := m.planForm.Update(keyMsg)
+				m.planForm = &newForm
+				return m, cmd
+			}
+		}
 	}
 	return m, nil
 }
 
+// formDropdownOpen reports whether the open form shows autocomplete
+// suggestions.
+func (m Model) formDropdownOpen() bool {
+	switch m.screen {
+	case model.ScreenVisitForm:
+		return m.visitForm != nil && m.visitForm.dropdownOpen()
+	case model.ScreenWantToVisitForm:
+		return m.wantToVisitForm != nil && m.wantToVisitForm.dropdownOpen()
+	}
+	return false
+}
+
 func (m Model) updateCurrentScreen(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
@@ -714,59 +1051,56 @@ func (m Model) handleJumpToTop() (tea.Model, tea.Cmd) {
 }
 
 // Navigation handlers for each screen
-func (m Model) handleVisitsNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+func (m Model) handleVisitsNav(action Action) (tea.Model, tea.Cmd) {
 	if m.visits == nil {
 		return m, nil
 	}
 
-	switch {
-	case msg.String() == "q":
-		return m, tea.Quit
-	case msg.String() == "left" || msg.String() == "b":
-		return m.switchTopLevel(prevTopLevelScreen(m.screen))
-	case msg.String() == "right" || msg.String() == "f":
-		return m.switchTopLevel(nextTopLevelScreen(m.screen))
-	case msg.String() == "B":
-		return m.switchTopLevel(model.ScreenVisits)
-	case msg.String() == "F":
-		return m.switchTopLevel(model.ScreenRestaurants)
-	case msg.String() == "r":
-		m.screen = model.ScreenRestaurants
-		if m.restaurants == nil {
-			return m, loadRestaurantsCmd(m.db, "")
-		}
-		return m, nil
-	case msg.String() == "w":
-		m.screen = model.ScreenWantToVisit
-		if m.wantToVisit == nil {
-			return m, loadWantToVisitCmd(m.db)
-		}
-		return m, nil
-	case msg.String() == "a":
+	switch action {
+	case ActionAdd:
 		m.returnScreen = model.ScreenVisits
 		m.mode = model.ModeInsert
 		m.screen = model.ScreenVisitForm
-		m.visitForm = NewVisitFormModel(m.db, m.yelpClient, 0)
+		m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.taste, m.dateOrder, 0)
 		return m, nil
-	case msg.String() == "enter" || msg.String() == "l":
+	case ActionOpen:
 		if len(m.visits.rows) > 0 && m.visits.cursor < len(m.visits.rows) {
 			visitID := m.visits.rows[m.visits.cursor].ID
 			return m, loadVisitDetailCmd(m.db, visitID)
 		}
 		return m, nil
-	case msg.String() == "j" || msg.String() == "down":
+	case ActionVisitCalendar:
+		return m.openVisitCalendar()
+	case ActionPlan:
+		return m.openPlanForm(0, model.ScreenVisits)
+	case ActionUpcoming:
+		if m.upcoming == nil || len(m.upcoming.plans) == 0 {
+			m.info = "No planned visits; press P to plan one"
+			return m, nil
+		}
+		m.screen = model.ScreenUpcoming
+		return m, nil
+	case ActionLogPlan:
+		if m.upcoming != nil {
+			if plan, ok := m.upcoming.firstDue(util.TodayISO()); ok {
+				return m.logPlan(plan, model.ScreenVisits)
+			}
+		}
+		m.info = "No planned visits are due to log"
+		return m, nil
+	case ActionDown:
 		m.visits.MoveDown()
 		return m, nil
-	case msg.String() == "k" || msg.String() == "up":
+	case ActionUp:
 		m.visits.MoveUp()
 		return m, nil
-	case msg.String() == "G":
+	case ActionBottom:
 		m.visits.JumpToBottom()
 		return m, nil
-	case msg.String() == "ctrl+d" || msg.String() == "pgdown":
+	case ActionHalfPageDown:
 		m.visits.HalfPageDown(m.height)
 		return m, nil
-	case msg.String() == "ctrl+u" || msg.String() == "pgup":
+	case ActionHalfPageUp:
 		m.visits.HalfPageUp(m.height)
 		return m, nil
 	}
@@ -774,65 +1108,245 @@ func (m Model) handleVisitsNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m Model) handleRestaurantsNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	if m.restaurants == nil {
+func (m Model) handleUpcomingNav(action Action) (tea.Model, tea.Cmd) {
+	if m.upcoming == nil {
 		return m, nil
 	}
 
-	switch {
-	case msg.String() == "q":
-		return m, tea.Quit
-	case msg.String() == "left" || msg.String() == "b":
-		return m.switchTopLevel(prevTopLevelScreen(m.screen))
-	case msg.String() == "right" || msg.String() == "f":
-		return m.switchTopLevel(nextTopLevelScreen(m.screen))
-	case msg.String() == "B":
-		return m.switchTopLevel(model.ScreenVisits)
-	case msg.String() == "F":
-		return m.switchTopLevel(model.ScreenRestaurants)
-	case msg.String() == "h":
-		return m.switchTopLevel(model.ScreenVisits)
-	case msg.String() == "w":
-		m.screen = model.ScreenWantToVisit
-		if m.wantToVisit == nil {
-			return m, loadWantToVisitCmd(m.db)
+	plan, ok := m.upcoming.selected()
+	switch action {
+	case ActionDown:
+		m.upcoming.move(1)
+	case ActionUp:
+		m.upcoming.move(-1)
+	case ActionOpen:
+		if ok {
+			return m, loadRestaurantDetailCmd(m.db, plan.RestaurantID)
+		}
+	case ActionLogVisit:
+		if ok {
+			return m.logPlan(plan, model.ScreenUpcoming)
+		}
+	case ActionAdd:
+		return m.openPlanForm(0, model.ScreenUpcoming)
+	case ActionEdit:
+		if ok {
+			m.returnScreen = model.ScreenUpcoming
+			m.mode = model.ModeInsert
+			m.screen = model.ScreenPlanForm
+			m.planForm = NewPlanFormModel(m.db, m.dateOrder, 0)
+			m.planForm.LoadPlan(model.PlannedVisit{
+				ID:           plan.ID,
+				RestaurantID: plan.RestaurantID,
+				PlannedOn:    plan.PlannedOn,
+				PlannedAt:    plan.PlannedAt,
+				PartySize:    plan.PartySize,
+				Notes:        plan.Notes,
+			})
+		}
+	case ActionDelete:
+		if ok {
+			return m, deletePlannedVisitCmd(m.db, plan.ID)
+		}
+	case ActionBack:
+		m.screen = model.ScreenVisits
+	}
+	return m, nil
+}
+
+// openPlanForm opens the form to plan a visit, to restaurantID when it is
+// set.
+func (m Model) openPlanForm(restaurantID int64, from model.Screen) (tea.Model, tea.Cmd) {
+	m.returnScreen = from
+	m.mode = model.ModeInsert
+	m.screen = model.ScreenPlanForm
+	m.planForm = NewPlanFormModel(m.db, m.dateOrder, restaurantID)
+	return m, nil
+}
+
+// logPlan opens the visit form to log plan as a visit, asking for the
+// rating. Plans whose day hasn't come yet can't be logged.
+func (m Model) logPlan(plan model.PlannedVisitRow, from model.Screen) (tea.Model, tea.Cmd) {
+	if !planDue(plan, util.TodayISO()) {
+		m.info = fmt.Sprintf("%s is planned for %s; log it once you've been", plan.RestaurantName, util.FormatDate(plan.PlannedOn))
+		return m, nil
+	}
+	m.returnScreen = from
+	m.mode = model.ModeInsert
+	m.screen = model.ScreenVisitForm
+	m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.taste, m.dateOrder, 0)
+	m.visitForm.LoadPlan(plan)
+	return m, nil
+}
+
+// openVisitCalendar shows the calendar screen, loading the year under its
+// cursor the first time.
+func (m Model) openVisitCalendar() (tea.Model, tea.Cmd) {
+	m.screen = model.ScreenVisitCalendar
+	if m.visitCalendar == nil {
+		m.visitCalendar = NewVisitCalendarModel(m.dateOrder)
+	}
+	return m, loadVisitDaysCmd(m.db, m.visitCalendar.cal.cursor.Year())
+}
+
+func (m Model) handleVisitCalendarNav(action Action) (tea.Model, tea.Cmd) {
+	if m.visitCalendar == nil {
+		return m, nil
+	}
+	if m.visitCalendar.handle(action) {
+		if m.visitCalendar.needsLoad() {
+			return m, loadVisitDaysCmd(m.db, m.visitCalendar.cal.cursor.Year())
 		}
 		return m, nil
-	case msg.String() == "a":
+	}
+
+	switch action {
+	case ActionOpen:
+		// Show the day in the Visits list, replacing any filter there.
+		q := "visited:" + m.visitCalendar.selectedDate()
+		if m.visits != nil {
+			m.visits.ClearFilter()
+		}
+		m.queries[model.ScreenVisits] = q
+		m.views[model.ScreenVisits] = ""
+		m.screen = model.ScreenVisits
+		m.info = "Filtered: " + q
+		return m, loadVisitsCmd(m.db, q)
+	case ActionBack:
+		m.screen = model.ScreenVisits
+		return m, nil
+	}
+	return m, nil
+}
+
+// openPicker shows the picker drawing from what query allows.
+func (m *Model) openPicker(query string) (tea.Cmd, error) {
+	if _, err := pick.Parse(query); err != nil {
+		return nil, err
+	}
+	from := m.screen
+	if m.screen == model.ScreenPicker && m.picker != nil {
+		from = m.picker.from
+	}
+	m.picker = NewPickerModel(query, from)
+	m.screen = model.ScreenPicker
+	m.error = ""
+	return loadPickPoolCmd(m.db, query), nil
+}
+
+func (m Model) handlePickerNav(action Action) (tea.Model, tea.Cmd) {
+	if m.picker == nil {
+		return m, nil
+	}
+
+	switch action {
+	case ActionReroll:
+		return m, m.picker.draw()
+	case ActionOpen:
+		if m.picker.spinning() {
+			m.picker.stop()
+			return m, nil
+		}
+		if e, ok := m.picker.picked(); ok {
+			return m, loadRestaurantDetailCmd(m.db, e.Candidate.RestaurantID)
+		}
+		return m, nil
+	case ActionLogVisit:
+		m.picker.stop()
+		if e, ok := m.picker.picked(); ok {
+			m.returnScreen = model.ScreenPicker
+			m.mode = model.ModeInsert
+			m.screen = model.ScreenVisitForm
+			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.taste, m.dateOrder, e.Candidate.RestaurantID)
+		}
+		return m, nil
+	case ActionEdit:
+		return m.openCommandLineWith(strings.TrimSpace("pick " + m.picker.query))
+	case ActionBack:
+		m.screen = m.picker.from
+		return m, nil
+	}
+	return m, nil
+}
+
+// openCompare compares the database with the one at path.
+func (m *Model) openCompare(path string) tea.Cmd {
+	from := m.screen
+	if m.screen == model.ScreenCompare && m.comparison != nil {
+		from = m.comparison.from
+	}
+	m.comparison = NewCompareModel(path, from)
+	m.screen = model.ScreenCompare
+	m.error = ""
+	return loadCompareCmd(m.db, path)
+}
+
+func (m Model) handleCompareNav(action Action) (tea.Model, tea.Cmd) {
+	if m.comparison == nil {
+		return m, nil
+	}
+
+	switch action {
+	case ActionDown:
+		m.comparison.move(1)
+	case ActionUp:
+		m.comparison.move(-1)
+	case ActionTop:
+		m.comparison.move(-len(m.comparison.rows))
+	case ActionBottom:
+		m.comparison.move(len(m.comparison.rows))
+	case ActionOpen:
+		if id, ok := m.comparison.selectedRestaurantID(); ok {
+			return m, loadRestaurantDetailCmd(m.db, id)
+		}
+		m.info = "Not in your restaurants"
+	case ActionBack:
+		m.screen = m.comparison.from
+	}
+	return m, nil
+}
+
+func (m Model) handleRestaurantsNav(action Action) (tea.Model, tea.Cmd) {
+	if m.restaurants == nil {
+		return m, nil
+	}
+
+	switch action {
+	case ActionAdd:
 		m.returnScreen = model.ScreenRestaurants
 		m.mode = model.ModeInsert
 		m.screen = model.ScreenRestaurantForm
 		m.restaurantForm = NewRestaurantFormModel(m.db, 0)
 		return m, nil
-	case msg.String() == "v":
+	case ActionLogVisit:
 		if len(m.restaurants.rows) > 0 && m.restaurants.cursor < len(m.restaurants.rows) {
 			restaurantID := m.restaurants.rows[m.restaurants.cursor].ID
 			m.returnScreen = model.ScreenRestaurants
 			m.mode = model.ModeInsert
 			m.screen = model.ScreenVisitForm
-			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, restaurantID)
+			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.taste, m.dateOrder, restaurantID)
 			return m, nil
 		}
 		return m, nil
-	case msg.String() == "enter" || msg.String() == "l":
+	case ActionOpen:
 		if len(m.restaurants.rows) > 0 && m.restaurants.cursor < len(m.restaurants.rows) {
 			restaurantID := m.restaurants.rows[m.restaurants.cursor].ID
 			return m, loadRestaurantDetailCmd(m.db, restaurantID)
 		}
 		return m, nil
-	case msg.String() == "j" || msg.String() == "down":
+	case ActionDown:
 		m.restaurants.MoveDown()
 		return m, nil
-	case msg.String() == "k" || msg.String() == "up":
+	case ActionUp:
 		m.restaurants.MoveUp()
 		return m, nil
-	case msg.String() == "G":
+	case ActionBottom:
 		m.restaurants.JumpToBottom()
 		return m, nil
-	case msg.String() == "ctrl+d" || msg.String() == "pgdown":
+	case ActionHalfPageDown:
 		m.restaurants.HalfPageDown(m.height)
 		return m, nil
-	case msg.String() == "ctrl+u" || msg.String() == "pgup":
+	case ActionHalfPageUp:
 		m.restaurants.HalfPageUp(m.height)
 		return m, nil
 	}
@@ -840,47 +1354,57 @@ func (m Model) handleRestaurantsNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m Model) handleVisitDetailNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch {
-	case msg.String() == "h" || msg.String() == "esc" || msg.String() == "b":
+func (m Model) handleVisitDetailNav(action Action) (tea.Model, tea.Cmd) {
+	switch action {
+	case ActionBack:
 		m.screen = model.ScreenVisits
 		m.visitDetail = nil
 		return m, nil
-	case msg.String() == "e":
+	case ActionEdit:
 		if m.visitDetail != nil {
 			m.returnScreen = model.ScreenVisitDetail
 			m.mode = model.ModeInsert
 			m.screen = model.ScreenVisitForm
-			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, 0)
+			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.taste, m.dateOrder, 0)
 			m.visitForm.LoadVisit(m.visitDetail.visit)
 			return m, nil
 		}
 		return m, nil
-	case msg.String() == "d":
+	case ActionDelete:
 		if m.visitDetail != nil {
 			return m, deleteVisitCmd(m.db, m.visitDetail.visit.ID)
 		}
 		return m, nil
+	case ActionEditNotes:
+		if m.visitDetail != nil {
+			return m, editNotesCmd(m.visitDetail.visit.Notes, notesVisit, m.visitDetail.visit.ID)
+		}
+		return m, nil
 	}
 	return m, nil
 }
 
-func (m Model) handleRestaurantDetailNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch {
-	case msg.String() == "h" || msg.String() == "esc" || msg.String() == "b":
+func (m Model) handleRestaurantDetailNav(action Action) (tea.Model, tea.Cmd) {
+	switch action {
+	case ActionBack:
 		m.screen = model.ScreenRestaurants
 		m.restaurantDetail = nil
 		return m, nil
-	case msg.String() == "v":
+	case ActionLogVisit:
 		if m.restaurantDetail != nil {
 			m.returnScreen = model.ScreenRestaurantDetail
 			m.mode = model.ModeInsert
 			m.screen = model.ScreenVisitForm
-			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.restaurantDetail.detail.Restaurant.ID)
+			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.taste, m.dateOrder, m.restaurantDetail.detail.Restaurant.ID)
 			return m, nil
 		}
 		return m, nil
-	case msg.String() == "e":
+	case ActionPlan:
+		if m.restaurantDetail != nil {
+			return m.openPlanForm(m.restaurantDetail.detail.Restaurant.ID, model.ScreenRestaurantDetail)
+		}
+		return m, nil
+	case ActionEdit:
 		if m.restaurantDetail != nil {
 			m.returnScreen = model.ScreenRestaurantDetail
 			m.mode = model.ModeInsert
@@ -890,7 +1414,7 @@ func (m Model) handleRestaurantDetailNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		return m, nil
-	case msg.String() == "d":
+	case ActionDelete:
 		if m.restaurantDetail != nil {
 			return m, deleteRestaurantCmd(m.db, m.restaurantDetail.detail.Restaurant.ID)
 		}
@@ -899,59 +1423,40 @@ func (m Model) handleRestaurantDetailNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m Model) handleWantToVisitNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+func (m Model) handleWantToVisitNav(action Action) (tea.Model, tea.Cmd) {
 	if m.wantToVisit == nil {
 		return m, nil
 	}
 
-	switch {
-	case msg.String() == "q":
-		return m, tea.Quit
-	case msg.String() == "left" || msg.String() == "b":
-		return m.switchTopLevel(prevTopLevelScreen(m.screen))
-	case msg.String() == "right" || msg.String() == "f":
-		return m.switchTopLevel(nextTopLevelScreen(m.screen))
-	case msg.String() == "B":
-		return m.switchTopLevel(model.ScreenVisits)
-	case msg.String() == "F":
-		return m.switchTopLevel(model.ScreenRestaurants)
-	case msg.String() == "v":
-		m.screen = model.ScreenVisits
-		return m, nil
-	case msg.String() == "r":
-		m.screen = model.ScreenRestaurants
-		if m.restaurants == nil {
-			return m, loadRestaurantsCmd(m.db, "")
-		}
-		return m, nil
-	case msg.String() == "a":
+	switch action {
+	case ActionAdd:
 		m.returnScreen = model.ScreenWantToVisit
 		m.mode = model.ModeInsert
 		m.screen = model.ScreenWantToVisitForm
-		m.wantToVisitForm = NewWantToVisitFormModel(m.db, m.yelpClient, 0)
+		m.wantToVisitForm = NewWantToVisitFormModel(m.db, m.yelpClient, m.taste, 0)
 		return m, nil
-	case msg.String() == "enter" || msg.String() == "l":
+	case ActionOpen:
 		entry := m.wantToVisit.SelectedEntry()
 		if entry != nil {
 			return m, loadWantToVisitDetailCmd(m.db, entry.ID)
 		}
 		return m, nil
-	case msg.String() == "j" || msg.String() == "down":
+	case ActionDown:
 		m.wantToVisit.CursorDown()
 		return m, nil
-	case msg.String() == "k" || msg.String() == "up":
+	case ActionUp:
 		m.wantToVisit.CursorUp()
 		return m, nil
-	case msg.String() == "G":
+	case ActionBottom:
 		m.wantToVisit.JumpToBottom()
 		return m, nil
-	case msg.String() == "ctrl+d" || msg.String() == "pgdown":
+	case ActionHalfPageDown:
 		// Half page down (approximate)
 		for i := 0; i < m.height/2; i++ {
 			m.wantToVisit.CursorDown()
 		}
 		return m, nil
-	case msg.String() == "ctrl+u" || msg.String() == "pgup":
+	case ActionHalfPageUp:
 		// Half page up (approximate)
 		for i := 0; i < m.height/2; i++ {
 			m.wantToVisit.CursorUp()
@@ -962,42 +1467,62 @@ func (m Model) handleWantToVisitNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m Model) handleWantToVisitDetailNav(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	switch {
-	case msg.String() == "h" || msg.String() == "esc" || msg.String() == "b":
+func (m Model) handleWantToVisitDetailNav(action Action) (tea.Model, tea.Cmd) {
+	switch action {
+	case ActionBack:
 		m.screen = model.ScreenWantToVisit
 		m.wantToVisitDetail = nil
 		return m, nil
-	case msg.String() == "c":
-		// Convert to visit - mark as visited
+	case ActionMarkVisited:
+		// The entry stays on the list until the visit is saved.
 		if m.wantToVisitDetail != nil {
-			return m, convertToVisitCmd(m.db, m.wantToVisitDetail.entry.ID)
+			entry := m.wantToVisitDetail.entry
+			m.returnScreen = model.ScreenWantToVisitDetail
+			m.mode = model.ModeInsert
+			m.screen = model.ScreenVisitForm
+			m.visitForm = NewVisitFormModel(m.db, m.yelpClient, m.taste, m.dateOrder, entry.RestaurantID)
+			m.visitForm.LoadWantToVisit(entry)
+			return m, nil
 		}
 		return m, nil
-	case msg.String() == "e":
+	case ActionPlan:
+		if m.wantToVisitDetail != nil {
+			return m.openPlanForm(m.wantToVisitDetail.entry.RestaurantID, model.ScreenWantToVisitDetail)
+		}
+		return m, nil
+	case ActionEdit:
 		if m.wantToVisitDetail != nil {
 			m.returnScreen = model.ScreenWantToVisitDetail
 			m.mode = model.ModeInsert
 			m.screen = model.ScreenWantToVisitForm
-			m.wantToVisitForm = NewWantToVisitFormModel(m.db, m.yelpClient, 0)
+			m.wantToVisitForm = NewWantToVisitFormModel(m.db, m.yelpClient, m.taste, 0)
 			m.wantToVisitForm.LoadWantToVisit(m.wantToVisitDetail.entry)
 			return m, nil
 		}
 		return m, nil
-	case msg.String() == "d":
+	case ActionDelete:
 		if m.wantToVisitDetail != nil {
 			return m, deleteWantToVisitCmd(m.db, m.wantToVisitDetail.entry.ID)
 		}
 		return m, nil
+	case ActionEditNotes:
+		if m.wantToVisitDetail != nil {
+			return m, editNotesCmd(m.wantToVisitDetail.entry.Notes, notesWantToVisit, m.wantToVisitDetail.entry.ID)
+		}
+		return m, nil
 	}
 	return m, nil
 }
 
 // Commands
 
-func loadVisitsCmd(database *sql.DB, filter string) tea.Cmd {
+func loadVisitsCmd(database *sql.DB, q string) tea.Cmd {
 	return func() tea.Msg {
-		visits, err := db.ListVisits(database, filter)
+		where, args, err := db.VisitQuerySchema.Compile(q)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		visits, err := db.ListVisitsWhere(database, where, args)
 		if err != nil {
 			return model.ErrorMsg{Err: err}
 		}
@@ -1005,9 +1530,13 @@ func loadVisitsCmd(database *sql.DB, filter string) tea.Cmd {
 	}
 }
 
-func loadRestaurantsCmd(database *sql.DB, filter string) tea.Cmd {
+func loadRestaurantsCmd(database *sql.DB, q string) tea.Cmd {
 	return func() tea.Msg {
-		restaurants, err := db.ListRestaurants(database, filter)
+		where, args, err := db.RestaurantQuerySchema.Compile(q)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		restaurants, err := db.ListRestaurantsWhere(database, where, args)
 		if err != nil {
 			return model.ErrorMsg{Err: err}
 		}
@@ -1015,6 +1544,16 @@ func loadRestaurantsCmd(database *sql.DB, filter string) tea.Cmd {
 	}
 }
 
+func loadPlannedVisitsCmd(database *sql.DB) tea.Cmd {
+	return func() tea.Msg {
+		plans, err := db.ListPlannedVisits(database, "")
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		return model.PlannedVisitsLoadedMsg{Plans: plans}
+	}
+}
+
 func loadVisitDetailCmd(database *sql.DB, visitID int64) tea.Cmd {
 	return func() tea.Msg {
 		visit, err := db.GetVisit(database, visitID)
@@ -1074,23 +1613,52 @@ func deleteRestaurantCmd(database *sql.DB, restaurantID int64) tea.Cmd {
 		if err != nil {
 			return model.ErrorMsg{Err: fmt.Errorf("failed to load related want_to_visit before delete: %w", err)}
 		}
+		plans, err := db.GetPlannedVisitsByRestaurant(database, restaurantID)
+		if err != nil {
+			return model.ErrorMsg{Err: fmt.Errorf("failed to load planned visits before delete: %w", err)}
+		}
+		tags, err := db.GetRestaurantTags(database, restaurantID)
+		if err != nil {
+			return model.ErrorMsg{Err: fmt.Errorf("failed to load restaurant tags before delete: %w", err)}
+		}
 
 		err = db.DeleteRestaurant(database, restaurantID)
 		if err != nil {
 			return model.ErrorMsg{Err: fmt.Errorf("failed to delete restaurant: %w", err)}
 		}
 		return model.DeleteRestaurantMsg{
-			ID:                 restaurantID,
-			Deleted:            restaurant,
-			DeletedVisits:      visits,
-			DeletedWantToVisit: wantToVisitEntries,
+			ID:                   restaurantID,
+			Deleted:              restaurant,
+			DeletedVisits:        visits,
+			DeletedWantToVisit:   wantToVisitEntries,
+			DeletedPlannedVisits: plans,
+			DeletedTags:          tags,
+		}
+	}
+}
+
+func deletePlannedVisitCmd(database *sql.DB, planID int64) tea.Cmd {
+	return func() tea.Msg {
+		plan, err := db.GetPlannedVisit(database, planID)
+		if err != nil {
+			return model.ErrorMsg{Err: fmt.Errorf("failed to load planned visit before delete: %w", err)}
 		}
+
+		err = db.DeletePlannedVisit(database, planID)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		return model.DeletePlannedVisitMsg{ID: planID, Deleted: plan}
 	}
 }
 
-func loadWantToVisitCmd(database *sql.DB) tea.Cmd {
+func loadWantToVisitCmd(database *sql.DB, q string) tea.Cmd {
 	return func() tea.Msg {
-		entries, err := db.GetWantToVisitList(database, "")
+		where, args, err := db.WantToVisitQuerySchema.Compile(q)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		entries, err := db.ListWantToVisitWhere(database, where, args)
 		if err != nil {
 			return model.ErrorMsg{Err: err}
 		}
@@ -1133,24 +1701,6 @@ func deleteWantToVisitCmd(database *sql.DB, wtvID int64) tea.Cmd {
 	}
 }
 
-func convertToVisitCmd(database *sql.DB, wtvID int64) tea.Cmd {
-	return func() tea.Msg {
-		wtv, err := db.GetWantToVisit(database, wtvID)
-		if err != nil {
-			return model.ErrorMsg{Err: fmt.Errorf("failed to load convert source: %w", err)}
-		}
-		restaurantID, err := db.ConvertWantToVisitToVisit(database, wtvID)
-		if err != nil {
-			return model.ErrorMsg{Err: fmt.Errorf("failed to convert: %w", err)}
-		}
-		return model.ConvertToVisitMsg{
-			WantToVisitID: wtvID,
-			RestaurantID:  restaurantID,
-			Deleted:       wtv,
-		}
-	}
-}
-
 // Local message type for want_to_visit detail
 type wantToVisitDetailLoadedMsg struct {
 	entry      model.WantToVisit
@@ -0,0 +1,421 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"toni/internal/db"
+	"toni/internal/model"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Bulk actions apply to the selected rows of the current list, or to the row
+// under the cursor when none are selected. Each runs in one transaction and
+// is undone as a whole.
+
+// bulkAppliedMsg reports a bulk action that has been applied. action is nil
+// when nothing changed.
+type bulkAppliedMsg struct {
+	action *undoAction
+	info   string
+}
+
+// bulkCmd runs apply off the UI loop and reports its result.
+func bulkCmd(apply func() (bulkAppliedMsg, error)) tea.Cmd {
+	return func() tea.Msg {
+		msg, err := apply()
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		return msg
+	}
+}
+
+// applyBulkResult records a bulk action for undo and reloads the lists.
+func (m *Model) applyBulkResult(msg bulkAppliedMsg) tea.Cmd {
+	m.info = msg.info
+	m.error = ""
+	if msg.action == nil {
+		return nil
+	}
+	m.pushUndoAction(*msg.action)
+	m.info += " (u to undo)"
+	return tea.Batch(m.reloadListsCmd(), loadTasteCmd(m.db))
+}
+
+// reloadListsCmd reloads every list that has been loaded. Reloading drops
+// the selection.
+func (m *Model) reloadListsCmd() tea.Cmd {
+	var cmds []tea.Cmd
+	if m.visits != nil {
+		cmds = append(cmds, loadVisitsCmd(m.db, m.queries[model.ScreenVisits]))
+	}
+	if m.restaurants != nil {
+		cmds = append(cmds, loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]))
+	}
+	if m.wantToVisit != nil {
+		cmds = append(cmds, loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit]))
+	}
+	if m.upcoming != nil {
+		cmds = append(cmds, loadPlannedVisitsCmd(m.db))
+	}
+	return tea.Batch(cmds...)
+}
+
+// selectedIDs returns the IDs of the rows a bulk action applies to.
+func (m *Model) selectedIDs() ([]int64, error) {
+	var ids []int64
+	switch m.screen {
+	case model.ScreenVisits:
+		for _, r := range m.visits.selectedRows() {
+			ids = append(ids, r.ID)
+		}
+	case model.ScreenRestaurants:
+		for _, r := range m.restaurants.selectedRows() {
+			ids = append(ids, r.ID)
+		}
+	case model.ScreenWantToVisit:
+		for _, r := range m.wantToVisit.selectedRows() {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("nothing selected")
+	}
+	return ids, nil
+}
+
+// selectedRestaurantIDs returns the restaurants of the rows a bulk action
+// applies to, each once.
+func (m *Model) selectedRestaurantIDs() ([]int64, error) {
+	var ids []int64
+	switch m.screen {
+	case model.ScreenVisits:
+		for _, r := range m.visits.selectedRows() {
+			ids = append(ids, r.RestaurantID)
+		}
+	case model.ScreenRestaurants:
+		for _, r := range m.restaurants.selectedRows() {
+			ids = append(ids, r.ID)
+		}
+	case model.ScreenWantToVisit:
+		for _, r := range m.wantToVisit.selectedRows() {
+			ids = append(ids, r.RestaurantID)
+		}
+	}
+	seen := make(map[int64]bool, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("nothing selected")
+	}
+	return unique, nil
+}
+
+func countNoun(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// bulkDelete deletes the selected rows of the current list.
+func (m *Model) bulkDelete() (tea.Cmd, error) {
+	if _, err := m.requireTable(); err != nil {
+		return nil, err
+	}
+	ids, err := m.selectedIDs()
+	if err != nil {
+		return nil, err
+	}
+	database := m.db
+	switch m.screen {
+	case model.ScreenVisits:
+		return bulkCmd(func() (bulkAppliedMsg, error) {
+			deleted, err := db.DeleteVisits(database, ids)
+			if err != nil {
+				return bulkAppliedMsg{}, err
+			}
+			label := countNoun(len(deleted), "visit", "visits") + " deleted"
+			return bulkAppliedMsg{info: capitalize(label), action: &undoAction{
+				label: label,
+				undo:  func() error { return db.RestoreVisits(database, deleted) },
+				redo: func() error {
+					_, err := db.DeleteVisits(database, ids)
+					return err
+				},
+			}}, nil
+		}), nil
+	case model.ScreenRestaurants:
+		return bulkCmd(func() (bulkAppliedMsg, error) {
+			deleted, err := db.DeleteRestaurants(database, ids)
+			if err != nil {
+				return bulkAppliedMsg{}, err
+			}
+			label := countNoun(len(deleted), "restaurant", "restaurants") + " deleted"
+			return bulkAppliedMsg{info: capitalize(label), action: &undoAction{
+				label: label,
+				undo:  func() error { return db.RestoreRestaurants(database, deleted) },
+				redo: func() error {
+					_, err := db.DeleteRestaurants(database, ids)
+					return err
+				},
+			}}, nil
+		}), nil
+	default:
+		return bulkCmd(func() (bulkAppliedMsg, error) {
+			deleted, err := db.DeleteWantToVisits(database, ids)
+			if err != nil {
+				return bulkAppliedMsg{}, err
+			}
+			label := countNoun(len(deleted), "want_to_visit entry", "want_to_visit entries") + " deleted"
+			return bulkAppliedMsg{info: capitalize(label), action: &undoAction{
+				label: label,
+				undo:  func() error { return db.RestoreWantToVisits(database, deleted) },
+				redo: func() error {
+					_, err := db.DeleteWantToVisits(database, ids)
+					return err
+				},
+			}}, nil
+		}), nil
+	}
+}
+
+// bulkTag adds (or with remove set, removes) tags on the restaurants of the
+// selected rows.
+func (m *Model) bulkTag(tags []string, remove bool) (tea.Cmd, error) {
+	if _, err := m.requireTable(); err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("name at least one tag")
+	}
+	ids, err := m.selectedRestaurantIDs()
+	if err != nil {
+		return nil, err
+	}
+	var pairs []model.RestaurantTag
+	for _, id := range ids {
+		for _, tag := range tags {
+			if tag = db.NormalizeTag(tag); tag != "" {
+				pairs = append(pairs, model.RestaurantTag{RestaurantID: id, Tag: tag})
+			}
+		}
+	}
+	database := m.db
+	add, undo := db.AddRestaurantTags, db.RemoveRestaurantTags
+	verb := "tagged"
+	if remove {
+		add, undo = undo, add
+		verb = "untagged"
+	}
+	return bulkCmd(func() (bulkAppliedMsg, error) {
+		changed, err := add(database, pairs)
+		if err != nil {
+			return bulkAppliedMsg{}, err
+		}
+		label := verb + " " + countNoun(len(ids), "restaurant", "restaurants")
+		if len(changed) == 0 {
+			return bulkAppliedMsg{info: capitalize(label) + ": nothing changed"}, nil
+		}
+		return bulkAppliedMsg{info: capitalize(label), action: &undoAction{
+			label: label,
+			undo: func() error {
+				_, err := undo(database, changed)
+				return err
+			},
+			redo: func() error {
+				_, err := add(database, changed)
+				return err
+			},
+		}}, nil
+	}), nil
+}
+
+// bulkAddToWantToVisit puts the restaurants of the selected rows on the want
+// to visit list.
+func (m *Model) bulkAddToWantToVisit() (tea.Cmd, error) {
+	if _, err := m.requireTable(); err != nil {
+		return nil, err
+	}
+	ids, err := m.selectedRestaurantIDs()
+	if err != nil {
+		return nil, err
+	}
+	database := m.db
+	return bulkCmd(func() (bulkAppliedMsg, error) {
+		added, err := db.AddToWantToVisit(database, ids)
+		if err != nil {
+			return bulkAppliedMsg{}, err
+		}
+		if len(added) == 0 {
+			return bulkAppliedMsg{info: "Already on the want to visit list"}, nil
+		}
+		addedIDs := make([]int64, len(added))
+		for i, w := range added {
+			addedIDs[i] = w.ID
+		}
+		label := countNoun(len(added), "restaurant", "restaurants") + " added to want to visit"
+		return bulkAppliedMsg{info: capitalize(label), action: &undoAction{
+			label: label,
+			undo: func() error {
+				_, err := db.DeleteWantToVisits(database, addedIDs)
+				return err
+			},
+			redo: func() error { return db.RestoreWantToVisits(database, added) },
+		}}, nil
+	}), nil
+}
+
+// bulkSetPriority sets the priority of the selected want to visit entries.
+// A nil priority clears it.
+func (m *Model) bulkSetPriority(priority *int) (tea.Cmd, error) {
+	if m.screen != model.ScreenWantToVisit || m.wantToVisit == nil {
+		return nil, fmt.Errorf(":priority only works on the want to visit list")
+	}
+	ids, err := m.selectedIDs()
+	if err != nil {
+		return nil, err
+	}
+	database := m.db
+	return bulkCmd(func() (bulkAppliedMsg, error) {
+		before, err := db.SetWantToVisitPriority(database, ids, priority)
+		if err != nil {
+			return bulkAppliedMsg{}, err
+		}
+		label := "priority set on " + countNoun(len(before), "entry", "entries")
+		if priority == nil {
+			label = "priority cleared on " + countNoun(len(before), "entry", "entries")
+		}
+		return bulkAppliedMsg{info: capitalize(label), action: &undoAction{
+			label: label,
+			undo:  func() error { return db.RestoreWantToVisitPriorities(database, before) },
+			redo: func() error {
+				_, err := db.SetWantToVisitPriority(database, ids, priority)
+				return err
+			},
+		}}, nil
+	}), nil
+}
+
+// bulkSetField sets the city or cuisine of the restaurants of the selected
+// rows. An empty value clears it.
+func (m *Model) bulkSetField(field, value string) (tea.Cmd, error) {
+	if _, err := m.requireTable(); err != nil {
+		return nil, err
+	}
+	ids, err := m.selectedRestaurantIDs()
+	if err != nil {
+		return nil, err
+	}
+	database := m.db
+	return bulkCmd(func() (bulkAppliedMsg, error) {
+		before, err := db.SetRestaurantField(database, field, ids, value)
+		if err != nil {
+			return bulkAppliedMsg{}, err
+		}
+		label := fmt.Sprintf("%s set on %s", field, countNoun(len(before), "restaurant", "restaurants"))
+		if value == "" {
+			label = fmt.Sprintf("%s cleared on %s", field, countNoun(len(before), "restaurant", "restaurants"))
+		}
+		return bulkAppliedMsg{info: capitalize(label), action: &undoAction{
+			label: label,
+			undo:  func() error { return db.RestoreRestaurantField(database, field, before) },
+			redo: func() error {
+				_, err := db.SetRestaurantField(database, field, ids, value)
+				return err
+			},
+		}}, nil
+	}), nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// Commands
+
+func runDelete(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("usage: :delete")
+	}
+	return m.bulkDelete()
+}
+
+func completeTag(m *Model, args []string) []string {
+	tags, err := db.ListTags(m.db)
+	if err != nil {
+		return nil
+	}
+	return tags
+}
+
+func runTag(m *Model, args []string) (tea.Cmd, error) {
+	return m.bulkTag(args, false)
+}
+
+func runUntag(m *Model, args []string) (tea.Cmd, error) {
+	return m.bulkTag(args, true)
+}
+
+func runWishlist(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("usage: :wishlist")
+	}
+	return m.bulkAddToWantToVisit()
+}
+
+func completePriority(m *Model, args []string) []string {
+	if len(args) > 1 {
+		return nil
+	}
+	return []string{"1", "2", "3", "4", "5", "none"}
+}
+
+func runPriority(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("usage: :priority 1-5|none")
+	}
+	if args[0] == "none" {
+		return m.bulkSetPriority(nil)
+	}
+	p, err := strconv.Atoi(args[0])
+	if err != nil || p < 1 || p > 5 {
+		return nil, fmt.Errorf("priority must be 1-5 or none, not %q", args[0])
+	}
+	return m.bulkSetPriority(&p)
+}
+
+func completeSet(m *Model, args []string) []string {
+	if len(args) == 1 {
+		return []string{"city", "cuisine"}
+	}
+	if len(args) == 2 {
+		if t := m.currentTable(); t != nil && containsString(t.ColumnKeys(), args[0]) {
+			var values []string
+			for _, v := range t.ColumnValues(args[0]) {
+				if !strings.Contains(v, " ") {
+					values = append(values, v)
+				}
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+func runSet(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) == 0 || (args[0] != "city" && args[0] != "cuisine") {
+		return nil, fmt.Errorf("usage: :set city|cuisine [value]")
+	}
+	return m.bulkSetField(args[0], strings.Join(args[1:], " "))
+}
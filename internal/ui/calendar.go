@@ -0,0 +1,103 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"toni/internal/util"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// calendar is the month view the visit form opens to pick a date. Days
+// after today are shown muted; picking one still needs confirming on save.
+type calendar struct {
+	cursor time.Time
+	today  time.Time
+	// upcoming mutes the days before today instead, for picking the day of a
+	// planned visit.
+	upcoming bool
+	// weekStart is the first column: Sunday for month/day dates as in the
+	// US, Monday otherwise.
+	weekStart time.Weekday
+}
+
+func newCalendar(selected, today time.Time, order util.DateOrder) *calendar {
+	c := &calendar{cursor: selected, today: today, weekStart: time.Sunday}
+	if order == util.DayFirst {
+		c.weekStart = time.Monday
+	}
+	return c
+}
+
+// handle moves the cursor for action and reports whether action is one the
+// calendar knows.
+func (c *calendar) handle(action Action) bool {
+	switch action {
+	case ActionPrevDay:
+		c.cursor = c.cursor.AddDate(0, 0, -1)
+	case ActionNextDay:
+		c.cursor = c.cursor.AddDate(0, 0, 1)
+	case ActionUp:
+		c.cursor = c.cursor.AddDate(0, 0, -7)
+	case ActionDown:
+		c.cursor = c.cursor.AddDate(0, 0, 7)
+	case ActionPrevMonth:
+		c.cursor = addMonths(c.cursor, -1)
+	case ActionNextMonth:
+		c.cursor = addMonths(c.cursor, 1)
+	case ActionToday:
+		c.cursor = c.today
+	default:
+		return false
+	}
+	return true
+}
+
+// addMonths moves t by n months, keeping to the last day of shorter months
+// rather than spilling into the next one.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, n, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// View renders the month holding the cursor.
+func (c *calendar) View() string {
+	const cell = 4
+	first := time.Date(c.cursor.Year(), c.cursor.Month(), 1, 0, 0, 0, 0, time.UTC)
+	title := lipgloss.NewStyle().Width(7 * cell).Align(lipgloss.Center).
+		Render(LabelStyle.Render(first.Format("January 2006")))
+
+	var header strings.Builder
+	for i := 0; i < 7; i++ {
+		day := time.Weekday((int(c.weekStart) + i) % 7)
+		header.WriteString(fmt.Sprintf("%*s", cell, day.String()[:2]))
+	}
+
+	lines := []string{title, HelpDescStyle.Render(header.String())}
+	offset := (int(first.Weekday()) - int(c.weekStart) + 7) % 7
+	row := strings.Repeat(" ", offset*cell)
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		label := fmt.Sprintf("%2d", day.Day())
+		style := NormalRowStyle
+		switch {
+		case day.Equal(c.cursor):
+			style = SelectedRowStyle
+		case day.Equal(c.today):
+			style = HelpKeyStyle.Bold(true).Underline(true)
+		case day.After(c.today) != c.upcoming:
+			style = HelpDescStyle
+		}
+		row += "  " + style.Render(label)
+		if (offset+day.Day())%7 == 0 {
+			lines = append(lines, row)
+			row = ""
+		}
+	}
+	if row != "" {
+		lines = append(lines, row)
+	}
+	lines = append(lines, "", HelpDescStyle.Render("hjkl move  H/L month  t today  enter pick  esc close"))
+	return BorderStyle.Render(strings.Join(lines, "\n"))
+}
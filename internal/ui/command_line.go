@@ -0,0 +1,959 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"toni/internal/config"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/query"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxCommandHistory caps the commands kept in the history file.
+const maxCommandHistory = 200
+
+// commandLine is the ":" prompt.
+type commandLine struct {
+	input textinput.Model
+
+	// historyPos indexes the history entry shown; len(history) is the line
+	// being typed, kept in draft while browsing.
+	historyPos int
+	draft      string
+
+	// completions cycle through the candidates for the word being completed,
+	// which replaces everything after completionBase.
+	completions    []string
+	completionPos  int
+	completionBase string
+}
+
+func newCommandLine(historyLen int) *commandLine {
+	input := textinput.New()
+	input.Prompt = ":"
+	input.Focus()
+	return &commandLine{input: input, historyPos: historyLen}
+}
+
+func (c *commandLine) resetCompletion() {
+	c.completions = nil
+	c.completionPos = 0
+	c.completionBase = ""
+}
+
+// exCommand is a command that can be run from the command line.
+type exCommand struct {
+	name    string
+	aliases []string
+	usage   string
+	help    string
+	// nav and form report where the command is available.
+	nav  bool
+	form bool
+	// rawArgs passes everything after the command name as a single argument,
+	// so values may contain spaces. Completion then works on the last term,
+	// split at whitespace outside quotes.
+	rawArgs bool
+	// complete returns candidates for the last of args; the earlier ones are
+	// complete.
+	complete func(m *Model, args []string) []string
+	run      func(m *Model, args []string) (tea.Cmd, error)
+}
+
+// exCommands lists the commands in the order completion offers them.
+var exCommands []exCommand
+
+func init() {
+	exCommands = []exCommand{
+		{
+			name:     "sort",
+			usage:    "sort [column [asc|desc]]",
+			help:     "Sort by a column; no column clears sorting",
+			nav:      true,
+			complete: completeSort,
+			run:      runSort,
+		},
+		{
+			name:     "filter",
+			usage:    "filter [query]",
+			help:     "Filter with a query, e.g. rating>=8 city:Brooklyn; no query clears",
+			nav:      true,
+			rawArgs:  true,
+			complete: completeFilter,
+			run:      runFilter,
+		},
+		{
+			name:     "view",
+			usage:    "view [name|save name|rm name|export path|import path]",
+			help:     "Apply, save or delete a saved view, or share views as a file; no name opens the picker",
+			nav:      true,
+			complete: completeView,
+			run:      runView,
+		},
+		{
+			name:     "hide",
+			usage:    "hide column...",
+			help:     "Hide columns",
+			nav:      true,
+			complete: completeColumns,
+			run:      runHide,
+		},
+		{
+			name:     "show",
+			usage:    "show column...|all",
+			help:     "Show columns, or all of them",
+			nav:      true,
+			complete: completeShow,
+			run:      runShow,
+		},
+		{
+			name:     "goto",
+			usage:    "goto visits|restaurants|want_to_visit",
+			help:     "Go to a screen",
+			nav:      true,
+			complete: completeGoto,
+			run:      runGoto,
+		},
+		{
+			name:    "pick",
+			usage:   "pick [query]",
+			help:    "Pick somewhere to eat from the wishlist and favourites, e.g. city:Brooklyn unvisited:30",
+			nav:     true,
+			rawArgs: true,
+			run:     runPick,
+		},
+		{
+			name:    "calendar",
+			aliases: []string{"cal"},
+			usage:   "calendar",
+			help:    "Show visits on a calendar",
+			nav:     true,
+			run:     runCalendar,
+		},
+		{
+			name:     "compare",
+			usage:    "compare path",
+			help:     "Compare your ratings with a friend's toni database, which is only read",
+			nav:      true,
+			complete: completeCompare,
+			run:      runCompare,
+		},
+		{
+			name:     "export",
+			usage:    "export csv path",
+			help:     "Export the rows shown, or the selected rows, to a CSV file",
+			nav:      true,
+			complete: completeExport,
+			run:      runExport,
+		},
+		{
+			name:  "delete",
+			usage: "delete",
+			help:  "Delete the selected rows, or the row under the cursor",
+			nav:   true,
+			run:   runDelete,
+		},
+		{
+			name:     "tag",
+			usage:    "tag tag...",
+			help:     "Tag the restaurants of the selected rows",
+			nav:      true,
+			complete: completeTag,
+			run:      runTag,
+		},
+		{
+			name:     "untag",
+			usage:    "untag tag...",
+			help:     "Remove tags from the restaurants of the selected rows",
+			nav:      true,
+			complete: completeTag,
+			run:      runUntag,
+		},
+		{
+			name:  "wishlist",
+			usage: "wishlist",
+			help:  "Add the restaurants of the selected rows to the want to visit list",
+			nav:   true,
+			run:   runWishlist,
+		},
+		{
+			name:     "priority",
+			usage:    "priority 1-5|none",
+			help:     "Set the priority of the selected want to visit entries",
+			nav:      true,
+			complete: completePriority,
+			run:      runPriority,
+		},
+		{
+			name:     "set",
+			usage:    "set city|cuisine [value]",
+			help:     "Set or clear the city or cuisine of the restaurants of the selected rows",
+			nav:      true,
+			complete: completeSet,
+			run:      runSet,
+		},
+		{
+			name:    "write",
+			aliases: []string{"w"},
+			usage:   "w",
+			help:    "Save the form",
+			nav:     true,
+			form:    true,
+			run:     runWrite,
+		},
+		{
+			name:    "quit",
+			aliases: []string{"q"},
+			usage:   "q",
+			help:    "Close the form without saving, or quit",
+			nav:     true,
+			form:    true,
+			run:     runQuit,
+		},
+		{
+			name:    "wq",
+			aliases: []string{"x"},
+			usage:   "wq",
+			help:    "Save the form, or quit",
+			nav:     true,
+			form:    true,
+			run:     runWriteQuit,
+		},
+		{
+			name:  "help",
+			usage: "help",
+			help:  "Show this help",
+			nav:   true,
+			run:   runHelp,
+		},
+	}
+}
+
+// findExCommand returns the command called name in the current mode.
+func (m *Model) findExCommand(name string) (*exCommand, error) {
+	for i := range exCommands {
+		c := &exCommands[i]
+		if c.name != name && !containsString(c.aliases, name) {
+			continue
+		}
+		if m.mode == model.ModeInsert && !c.form {
+			return nil, fmt.Errorf(":%s is not available in forms", name)
+		}
+		if m.mode == model.ModeNav && !c.nav {
+			return nil, fmt.Errorf(":%s is only available in forms", name)
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown command :%s", name)
+}
+
+// splitCommandLine splits a line into the command name and its arguments.
+func splitCommandLine(c *exCommand, line string) (string, []string) {
+	line = strings.TrimLeft(line, " ")
+	name, rest, _ := strings.Cut(line, " ")
+	if c != nil && c.rawArgs {
+		if rest = strings.TrimSpace(rest); rest == "" {
+			return name, nil
+		}
+		return name, []string{rest}
+	}
+	return name, strings.Fields(rest)
+}
+
+// runCommandLine runs a line typed at the prompt.
+func (m Model) runCommandLine(line string) (tea.Model, tea.Cmd) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return m, nil
+	}
+	name, _ := splitCommandLine(nil, line)
+	c, err := m.findExCommand(name)
+	if err != nil {
+		m.error = err.Error()
+		return m, nil
+	}
+	_, args := splitCommandLine(c, line)
+	m.error = ""
+	m.info = ""
+	cmd, err := c.run(&m, args)
+	if err != nil {
+		m.error = err.Error()
+		return m, nil
+	}
+	return m, cmd
+}
+
+// openCommandLine shows the prompt.
+func (m Model) openCommandLine() (tea.Model, tea.Cmd) {
+	return m.openCommandLineWith("")
+}
+
+// openCommandLineWith shows the prompt with line already typed.
+func (m Model) openCommandLineWith(line string) (tea.Model, tea.Cmd) {
+	m.cmdline = newCommandLine(len(m.history))
+	m.cmdline.input.SetValue(line)
+	m.cmdline.input.CursorEnd()
+	m.info = ""
+	return m, textinput.Blink
+}
+
+// handleCommandLine handles key presses while the prompt is open.
+func (m Model) handleCommandLine(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.cmdline
+	action, pending, replay := m.resolveKey(msg, commandLineChain)
+	if pending {
+		return m, nil
+	}
+
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	for _, k := range replay {
+		c.input, cmd = c.input.Update(k)
+		cmds = append(cmds, cmd)
+	}
+
+	switch action {
+	case ActionExecute:
+		line := c.input.Value()
+		m.cmdline = nil
+		m.addHistory(line)
+		return m.runCommandLine(line)
+	case ActionCancel:
+		m.cmdline = nil
+		return m, nil
+	case ActionComplete:
+		m.complete(1)
+		return m, tea.Batch(cmds...)
+	case ActionCompletePrev:
+		m.complete(-1)
+		return m, tea.Batch(cmds...)
+	case ActionHistoryPrev:
+		m.browseHistory(-1)
+		return m, tea.Batch(cmds...)
+	case ActionHistoryNext:
+		m.browseHistory(1)
+		return m, tea.Batch(cmds...)
+	}
+
+	// Backspace on an empty line closes the prompt, as in vim.
+	if msg.Type == tea.KeyBackspace && c.input.Value() == "" {
+		m.cmdline = nil
+		return m, nil
+	}
+	c.resetCompletion()
+	c.historyPos = len(m.history)
+	c.input, cmd = c.input.Update(msg)
+	cmds = append(cmds, cmd)
+	return m, tea.Batch(cmds...)
+}
+
+// complete replaces the word before the cursor with the next (dir 1) or
+// previous (dir -1) candidate.
+func (m *Model) complete(dir int) {
+	c := m.cmdline
+	if c.completions == nil {
+		base, candidates := m.completionCandidates(c.input.Value())
+		if len(candidates) == 0 {
+			return
+		}
+		c.completionBase = base
+		c.completions = candidates
+		c.completionPos = 0
+		if dir < 0 {
+			c.completionPos = len(candidates) - 1
+		}
+	} else {
+		c.completionPos = (c.completionPos + dir + len(c.completions)) % len(c.completions)
+	}
+
+	word := c.completions[c.completionPos]
+	// Finished words get a space; field names and directories are continued.
+	if len(c.completions) == 1 && !strings.HasSuffix(word, ":") && !strings.HasSuffix(word, "=") && !strings.HasSuffix(word, string(filepath.Separator)) {
+		word += " "
+	}
+	c.input.SetValue(c.completionBase + word)
+	c.input.CursorEnd()
+	if len(c.completions) == 1 {
+		c.resetCompletion()
+	}
+}
+
+// completionCandidates returns the candidates for the last word of line and
+// the text before that word.
+func (m *Model) completionCandidates(line string) (string, []string) {
+	name, rest, hasArgs := strings.Cut(line, " ")
+	if !hasArgs {
+		var names []string
+		for _, c := range exCommands {
+			if (m.mode == model.ModeInsert && c.form) || (m.mode == model.ModeNav && c.nav) {
+				names = append(names, c.name)
+			}
+		}
+		return "", matchPrefix(names, name)
+	}
+
+	c, err := m.findExCommand(name)
+	if err != nil || c.complete == nil {
+		return "", nil
+	}
+	var args []string
+	var base string
+	if c.rawArgs {
+		args = []string{rest[lastTermStart(rest):]}
+		base = line[:len(line)-len(args[0])]
+	} else {
+		args = strings.Fields(rest)
+		if rest == "" || strings.HasSuffix(rest, " ") {
+			args = append(args, "")
+		}
+		base = line[:len(line)-len(args[len(args)-1])]
+	}
+	return base, matchPrefix(c.complete(m, args), args[len(args)-1])
+}
+
+// lastTermStart returns where the last whitespace-separated term of s
+// starts, treating quoted text as part of a term.
+func lastTermStart(s string) int {
+	start, quoted := 0, false
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			start = i + 1
+		}
+	}
+	return start
+}
+
+// matchPrefix returns the candidates that start with prefix, ignoring case
+// and quotes.
+func matchPrefix(candidates []string, prefix string) []string {
+	var out []string
+	unquote := strings.NewReplacer(`"`, "")
+	lower := strings.ToLower(unquote.Replace(prefix))
+	for _, c := range candidates {
+		if strings.HasPrefix(strings.ToLower(unquote.Replace(c)), lower) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// browseHistory steps through earlier commands that start with what was
+// typed before browsing began.
+func (m *Model) browseHistory(dir int) {
+	c := m.cmdline
+	if c.historyPos == len(m.history) {
+		c.draft = c.input.Value()
+	}
+	for pos := c.historyPos + dir; pos >= 0 && pos <= len(m.history); pos += dir {
+		if pos == len(m.history) {
+			c.historyPos = pos
+			c.input.SetValue(c.draft)
+			break
+		}
+		if strings.HasPrefix(m.history[pos], c.draft) {
+			c.historyPos = pos
+			c.input.SetValue(m.history[pos])
+			break
+		}
+	}
+	c.input.CursorEnd()
+	c.resetCompletion()
+}
+
+// addHistory records a command and saves the history file.
+func (m *Model) addHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if n := len(m.history); n > 0 && m.history[n-1] == line {
+		return
+	}
+	m.history = append(m.history, line)
+	if len(m.history) > maxCommandHistory {
+		m.history = m.history[len(m.history)-maxCommandHistory:]
+	}
+	if err := saveCommandHistory(m.historyPath, m.history); err != nil {
+		m.error = err.Error()
+	}
+}
+
+// renderCommandLine renders the prompt in place of the help footer, with
+// the completion candidates above it.
+func (m Model) renderCommandLine() string {
+	c := m.cmdline
+	line := FooterStyle.Width(m.width).Render(c.input.View())
+	if len(c.completions) < 2 {
+		return line
+	}
+	items := make([]string, len(c.completions))
+	for i, candidate := range c.completions {
+		if i == c.completionPos {
+			items[i] = HelpKeyStyle.Render(candidate)
+		} else {
+			items[i] = HelpDescStyle.Render(candidate)
+		}
+	}
+	candidates := lipgloss.NewStyle().Width(m.width).MaxHeight(2).Render(strings.Join(items, "  "))
+	return lipgloss.JoinVertical(lipgloss.Left, candidates, line)
+}
+
+func loadCommandHistory(path string) []string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			history = append(history, line)
+		}
+	}
+	if len(history) > maxCommandHistory {
+		history = history[len(history)-maxCommandHistory:]
+	}
+	return history
+}
+
+func saveCommandHistory(path string, history []string) error {
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history dir: %w", err)
+	}
+	data := strings.Join(history, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		return fmt.Errorf("failed to write command history: %w", err)
+	}
+	return nil
+}
+
+// Commands
+
+func (m *Model) requireTable() (tableController, error) {
+	t := m.currentTable()
+	if t == nil {
+		return nil, fmt.Errorf("not on a list screen")
+	}
+	return t, nil
+}
+
+func tableColumns(m *Model) []string {
+	if t := m.currentTable(); t != nil {
+		return t.ColumnKeys()
+	}
+	return nil
+}
+
+func completeSort(m *Model, args []string) []string {
+	switch len(args) {
+	case 1:
+		return append(tableColumns(m), "none")
+	case 2:
+		return []string{"asc", "desc"}
+	}
+	return nil
+}
+
+func runSort(m *Model, args []string) (tea.Cmd, error) {
+	t, err := m.requireTable()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 || args[0] == "none" {
+		t.SortByColumn("", false)
+		m.info = "Sorting cleared"
+		m.persistCurrentTablePrefs()
+		return nil, nil
+	}
+	if len(args) > 2 {
+		return nil, fmt.Errorf("usage: :sort [column [asc|desc]]")
+	}
+	desc := false
+	if len(args) == 2 {
+		switch args[1] {
+		case "asc":
+		case "desc":
+			desc = true
+		default:
+			return nil, fmt.Errorf("sort order must be asc or desc, not %q", args[1])
+		}
+	}
+	if !t.SortByColumn(args[0], desc) {
+		return nil, fmt.Errorf("unknown column %q", args[0])
+	}
+	order := "ascending"
+	if desc {
+		order = "descending"
+	}
+	m.info = fmt.Sprintf("Sorted %s %s", strings.ToUpper(args[0]), order)
+	m.persistCurrentTablePrefs()
+	return nil, nil
+}
+
+// querySchema returns the query schema of the current list screen.
+func (m *Model) querySchema() (query.Schema, bool) {
+	return querySchemaFor(m.screen)
+}
+
+// querySchemaFor returns the query schema of a list screen.
+func querySchemaFor(screen model.Screen) (query.Schema, bool) {
+	switch screen {
+	case model.ScreenVisits:
+		return db.VisitQuerySchema, true
+	case model.ScreenRestaurants:
+		return db.RestaurantQuerySchema, true
+	case model.ScreenWantToVisit:
+		return db.WantToVisitQuerySchema, true
+	}
+	return query.Schema{}, false
+}
+
+// completeFilter completes the last term of a query: a field name, or a
+// value of the field already typed, taken from the rows loaded.
+func completeFilter(m *Model, args []string) []string {
+	schema, ok := m.querySchema()
+	t := m.currentTable()
+	if !ok || t == nil {
+		return nil
+	}
+	term := args[0]
+	neg := ""
+	if strings.HasPrefix(term, "-") {
+		neg, term = "-", term[1:]
+	}
+
+	opAt := strings.IndexAny(term, ":=<>!")
+	if opAt < 0 {
+		var fields []string
+		for _, name := range schema.FieldNames() {
+			fields = append(fields, neg+name+":")
+		}
+		return fields
+	}
+
+	name := term[:opAt]
+	op := term[opAt:]
+	if end := strings.IndexFunc(op, func(r rune) bool { return !strings.ContainsRune(":=<>!", r) }); end >= 0 {
+		op = op[:end]
+	}
+	f, ok := schema.Field(name)
+	if !ok {
+		return nil
+	}
+	column := ""
+	for _, key := range append([]string{f.Name}, f.Aliases...) {
+		if containsString(t.ColumnKeys(), key) {
+			column = key
+			break
+		}
+	}
+	if column == "" {
+		return nil
+	}
+	var values []string
+	for _, v := range t.ColumnValues(column) {
+		if strings.ContainsAny(v, " \t()|\":=<>!") {
+			v = strconv.Quote(v)
+		}
+		values = append(values, neg+name+op+v)
+	}
+	return values
+}
+
+// runFilter loads the current list with a query, replacing any filter.
+func runFilter(m *Model, args []string) (tea.Cmd, error) {
+	t, err := m.requireTable()
+	if err != nil {
+		return nil, err
+	}
+	schema, _ := m.querySchema()
+	q := ""
+	if len(args) > 0 {
+		q = args[0]
+	}
+	if err := schema.Check(q); err != nil {
+		return nil, err
+	}
+	t.ClearFilter()
+	m.queries[m.screen] = q
+	m.views[m.screen] = ""
+	if q == "" {
+		m.info = "Filter cleared"
+	} else {
+		m.info = "Filtered: " + q
+	}
+	return m.reloadCurrentTable(), nil
+}
+
+// reloadCurrentTable reloads the current list with its query.
+func (m *Model) reloadCurrentTable() tea.Cmd {
+	switch m.screen {
+	case model.ScreenVisits:
+		return loadVisitsCmd(m.db, m.queries[model.ScreenVisits])
+	case model.ScreenRestaurants:
+		return loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants])
+	case model.ScreenWantToVisit:
+		return loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit])
+	}
+	return nil
+}
+
+func completeColumns(m *Model, args []string) []string {
+	return tableColumns(m)
+}
+
+func completeShow(m *Model, args []string) []string {
+	return append(tableColumns(m), "all")
+}
+
+func runHide(m *Model, args []string) (tea.Cmd, error) {
+	return nil, m.setColumnsHidden(args, true)
+}
+
+func runShow(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) == 1 && args[0] == "all" {
+		t, err := m.requireTable()
+		if err != nil {
+			return nil, err
+		}
+		t.ShowAllColumns()
+		m.info = "All columns shown"
+		m.persistCurrentTablePrefs()
+		return nil, nil
+	}
+	return nil, m.setColumnsHidden(args, false)
+}
+
+func (m *Model) setColumnsHidden(keys []string, hidden bool) error {
+	t, err := m.requireTable()
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("name at least one column")
+	}
+	for _, key := range keys {
+		if !containsString(t.ColumnKeys(), key) {
+			return fmt.Errorf("unknown column %q", key)
+		}
+	}
+	for _, key := range keys {
+		if !t.SetColumnHidden(key, hidden) {
+			m.persistCurrentTablePrefs()
+			return fmt.Errorf("cannot hide last visible column")
+		}
+	}
+	m.info = "Columns shown: " + strings.Join(keys, ", ")
+	if hidden {
+		m.info = "Columns hidden: " + strings.Join(keys, ", ")
+	}
+	m.persistCurrentTablePrefs()
+	return nil
+}
+
+// screenNames maps the names :goto accepts to screens.
+var screenNames = map[string]model.Screen{
+	"visits":        model.ScreenVisits,
+	"restaurants":   model.ScreenRestaurants,
+	"want_to_visit": model.ScreenWantToVisit,
+	"wishlist":      model.ScreenWantToVisit,
+}
+
+func completeGoto(m *Model, args []string) []string {
+	if len(args) > 1 {
+		return nil
+	}
+	names := make([]string, 0, len(screenNames))
+	for name := range screenNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runGoto(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("usage: :goto visits|restaurants|want_to_visit")
+	}
+	screen, ok := screenNames[args[0]]
+	if !ok {
+		return nil, fmt.Errorf("unknown screen %q", args[0])
+	}
+	next, cmd := m.switchTopLevel(screen)
+	*m = next.(Model)
+	return cmd, nil
+}
+
+func runPick(m *Model, args []string) (tea.Cmd, error) {
+	q := ""
+	if len(args) > 0 {
+		q = args[0]
+	}
+	return m.openPicker(q)
+}
+
+func runCalendar(m *Model, args []string) (tea.Cmd, error) {
+	next, cmd := m.openVisitCalendar()
+	*m = next.(Model)
+	return cmd, nil
+}
+
+func completeCompare(m *Model, args []string) []string {
+	if len(args) == 1 {
+		return completePath(args[0])
+	}
+	return nil
+}
+
+func runCompare(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("usage: :compare path")
+	}
+	path, err := config.ExpandHome(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return m.openCompare(path), nil
+}
+
+func completeExport(m *Model, args []string) []string {
+	switch len(args) {
+	case 1:
+		return []string{"csv"}
+	case 2:
+		return completePath(args[1])
+	}
+	return nil
+}
+
+// completePath lists the files and directories starting with partial.
+// Directories end in a separator so completion can continue into them.
+func completePath(partial string) []string {
+	expanded, err := config.ExpandHome(partial)
+	if err != nil {
+		return nil
+	}
+	matches, _ := filepath.Glob(expanded + "*")
+	var out []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			match += string(filepath.Separator)
+		}
+		// Keep the "~" the user typed.
+		if expanded != partial {
+			match = partial + strings.TrimPrefix(match, expanded)
+		}
+		out = append(out, match)
+	}
+	return out
+}
+
+func runExport(m *Model, args []string) (tea.Cmd, error) {
+	t, err := m.requireTable()
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 2 || args[0] != "csv" {
+		return nil, fmt.Errorf("usage: :export csv path")
+	}
+	path, err := config.ExpandHome(args[1])
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := t.WriteCSV(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	m.info = "Exported to " + path
+	return nil, nil
+}
+
+// saveForm saves the open form. The form closes once the save succeeds.
+func (m *Model) saveForm() tea.Cmd {
+	switch m.screen {
+	case model.ScreenVisitForm:
+		if m.visitForm != nil {
+			return m.visitForm.save()
+		}
+	case model.ScreenRestaurantForm:
+		if m.restaurantForm != nil {
+			return m.restaurantForm.save()
+		}
+	case model.ScreenWantToVisitForm:
+		if m.wantToVisitForm != nil {
+			return m.wantToVisitForm.save()
+		}
+	case model.ScreenPlanForm:
+		if m.planForm != nil {
+			return m.planForm.save()
+		}
+	}
+	return nil
+}
+
+func cancelFormCmd() tea.Msg {
+	return model.FormCancelledMsg{}
+}
+
+func runWrite(m *Model, args []string) (tea.Cmd, error) {
+	if m.mode == model.ModeInsert {
+		return m.saveForm(), nil
+	}
+	m.info = "Nothing to write: changes are saved as you make them"
+	return nil, nil
+}
+
+func runQuit(m *Model, args []string) (tea.Cmd, error) {
+	if m.mode == model.ModeInsert {
+		return cancelFormCmd, nil
+	}
+	return tea.Quit, nil
+}
+
+func runWriteQuit(m *Model, args []string) (tea.Cmd, error) {
+	if m.mode == model.ModeInsert {
+		return m.saveForm(), nil
+	}
+	return tea.Quit, nil
+}
+
+func runHelp(m *Model, args []string) (tea.Cmd, error) {
+	m.showingHelp = true
+	return nil, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
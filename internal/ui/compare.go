@@ -0,0 +1,225 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"toni/internal/compare"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/util"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// CompareModel is the screen comparing your ratings with a friend's
+// database.
+type CompareModel struct {
+	path string
+	// report is nil until the comparison has loaded, and err is set if it
+	// failed to.
+	report *compare.Report
+	err    error
+	rows   []compareRow
+	cursor int
+	offset int
+	// from is the screen the comparison was opened from.
+	from model.Screen
+}
+
+// compareRow is a line of the comparison: a section heading, or a pair or
+// loved place under one.
+type compareRow struct {
+	heading string
+	pair    *compare.Pair
+	loved   *compare.Loved
+}
+
+// compareLoadedMsg carries the comparison with the database at path, or why
+// it couldn't be made.
+type compareLoadedMsg struct {
+	path   string
+	report compare.Report
+	err    error
+}
+
+// NewCompareModel creates a comparison with the database at path.
+func NewCompareModel(path string, from model.Screen) *CompareModel {
+	return &CompareModel{path: path, from: from}
+}
+
+// loadCompareCmd compares mine with the database at path, which is opened
+// read-only and closed again once read.
+func loadCompareCmd(mine *sql.DB, path string) tea.Cmd {
+	return func() tea.Msg {
+		theirs, err := db.OpenReadOnly(path)
+		if err != nil {
+			return compareLoadedMsg{path: path, err: err}
+		}
+		defer theirs.Close()
+		report, err := compare.Load(mine, theirs)
+		return compareLoadedMsg{path: path, report: report, err: err}
+	}
+}
+
+// setReport stores the comparison and lays out its rows.
+func (m *CompareModel) setReport(msg compareLoadedMsg) {
+	if msg.path != m.path {
+		return
+	}
+	if msg.err != nil {
+		m.err = msg.err
+		return
+	}
+	r := msg.report
+	m.report = &r
+	m.rows = nil
+	addPairs := func(title string, pairs []compare.Pair) {
+		m.rows = append(m.rows, compareRow{heading: fmt.Sprintf("%s (%d)", title, len(pairs))})
+		for i := range pairs {
+			m.rows = append(m.rows, compareRow{pair: &pairs[i]})
+		}
+	}
+	addPairs("Agree", r.Agreements())
+	addPairs("Disagree", r.Disagreements())
+	m.rows = append(m.rows, compareRow{heading: fmt.Sprintf("They loved, you haven't been (%d)", len(r.TheyLoved))})
+	for i := range r.TheyLoved {
+		m.rows = append(m.rows, compareRow{loved: &r.TheyLoved[i]})
+	}
+	m.cursor = -1
+	m.offset = 0
+	m.move(1)
+	m.cursor = max(0, m.cursor)
+}
+
+// move moves the cursor by n places, back when n is negative, skipping
+// headings and stopping at either end.
+func (m *CompareModel) move(n int) {
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for i := m.cursor + step; n > 0 && i >= 0 && i < len(m.rows); i += step {
+		if m.rows[i].heading == "" {
+			m.cursor = i
+			n--
+		}
+	}
+}
+
+// selectedRestaurantID returns your restaurant under the cursor, if you have
+// it.
+func (m *CompareModel) selectedRestaurantID() (int64, bool) {
+	if m.cursor >= len(m.rows) {
+		return 0, false
+	}
+	row := m.rows[m.cursor]
+	switch {
+	case row.pair != nil:
+		return row.pair.Mine.RestaurantID, true
+	case row.loved != nil && row.loved.Mine != nil:
+		return row.loved.Mine.RestaurantID, true
+	}
+	return 0, false
+}
+
+// View renders the comparison.
+func (m *CompareModel) View(width, height int) string {
+	shortcuts := HelpDescStyle.Render("j/k move  enter open your restaurant  esc back")
+	header := lipgloss.NewStyle().
+		Width(width - 4).
+		Align(lipgloss.Right).
+		Render(shortcuts)
+
+	title := LabelStyle.Render("Compared with") + "  " + HelpDescStyle.Render(m.path)
+	var body string
+	switch {
+	case m.err != nil:
+		body = ErrorStyle.Render(m.err.Error())
+	case m.report == nil:
+		body = HelpDescStyle.Render("Loading…")
+	default:
+		r := m.report
+		counts := HelpDescStyle.Render(fmt.Sprintf("%s in common · you have %d, they have %d",
+			countNoun(r.Matched, "restaurant", "restaurants"), r.MineCount, r.TheirsCount))
+		body = counts + "\n" + r.Summary() + "\n\n" + m.renderRows(width-8, height-12)
+	}
+
+	info := PanelStyle.
+		Width(width - 4).
+		Render(title + "\n\n" + body)
+	return lipgloss.JoinVertical(lipgloss.Left, header, info)
+}
+
+// renderRows renders as many rows as fit in height, scrolled to keep the
+// cursor in view.
+func (m *CompareModel) renderRows(width, height int) string {
+	height = max(3, height)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+		// Keep the heading above the first place of a section in view.
+		if m.offset > 0 && m.rows[m.offset-1].heading != "" {
+			m.offset--
+		}
+	}
+	if m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+
+	ratingStyle := lipgloss.NewStyle().Foreground(ColorYellow)
+	rating := func(r *float64) string {
+		return ratingStyle.Render(fmt.Sprintf("%-5s", util.FormatRatingWithStar(r)))
+	}
+	var lines []string
+	for i := m.offset; i < len(m.rows) && i < m.offset+height; i++ {
+		row := m.rows[i]
+		var line string
+		switch {
+		case row.heading != "":
+			lines = append(lines, LabelStyle.Render(row.heading))
+			continue
+		case row.pair != nil:
+			p := row.pair
+			diff := HelpDescStyle.Render(fmt.Sprintf("%+5.1f", p.Diff()))
+			if math.Abs(p.Diff()) < 0.05 {
+				diff = HelpDescStyle.Render("    =")
+			}
+			line = fmt.Sprintf("you %s  them %s  %s  %s",
+				rating(p.Mine.AvgRating), rating(p.Theirs.AvgRating), diff, comparePlace(p.Mine))
+		case row.loved != nil:
+			l := row.loved
+			line = fmt.Sprintf("them %s  %s", rating(l.Theirs.AvgRating), comparePlace(l.Theirs))
+			if l.Mine != nil && l.Mine.Wishlisted {
+				line += "  " + SuccessStyle.Render("on your wishlist")
+			}
+		}
+		line = ansi.Truncate(line, width-2, "…")
+		if i == m.cursor {
+			line = SelectedRowStyle.Render("▸ " + line)
+		} else {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return HelpDescStyle.Render("Nothing to compare yet.")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// comparePlace names a restaurant with its city and cuisine.
+func comparePlace(s model.RestaurantStats) string {
+	var details []string
+	for _, d := range []string{s.City, s.Cuisine} {
+		if d != "" {
+			details = append(details, d)
+		}
+	}
+	if len(details) == 0 {
+		return s.Name
+	}
+	return s.Name + "  " + HelpDescStyle.Render(strings.Join(details, " · "))
+}
@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/secure"
+	"toni/internal/util"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Notes can be written in the user's editor, which makes multi-line Markdown
+// notes practical. The editor runs on a file in a scratch directory - memory
+// backed where the system has one, so notes of an encrypted database don't
+// touch the disk - while toni's UI is suspended; the directory is removed
+// once the editor exits.
+
+// notesTarget says where notes edited in the editor go back to.
+type notesTarget int
+
+const (
+	notesForm notesTarget = iota
+	notesVisit
+	notesWantToVisit
+)
+
+// notesEditedMsg carries notes back from the editor. id is the visit or
+// want_to_visit entry for the detail screen targets.
+type notesEditedMsg struct {
+	target notesTarget
+	id     int64
+	notes  string
+	err    error
+}
+
+// notesSavedMsg reports notes saved from a detail screen.
+type notesSavedMsg struct {
+	target notesTarget
+	id     int64
+	action *undoAction
+}
+
+// editorCommand returns the command that edits path: $VISUAL, then $EDITOR,
+// then vi. The variables may carry arguments, e.g. "code --wait".
+func editorCommand(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	return exec.Command(args[0], append(args[1:], path)...)
+}
+
+// editNotesCmd opens notes in the editor and reports what was saved.
+func editNotesCmd(notes string, target notesTarget, id int64) tea.Cmd {
+	dir, err := secure.ScratchDir()
+	if err != nil {
+		return func() tea.Msg {
+			return model.ErrorMsg{Err: fmt.Errorf("failed to create notes file: %w", err)}
+		}
+	}
+	path := filepath.Join(dir, "notes.md")
+	if notes != "" {
+		notes += "\n"
+	}
+	if err := os.WriteFile(path, []byte(notes), 0600); err != nil {
+		os.RemoveAll(dir)
+		return func() tea.Msg {
+			return model.ErrorMsg{Err: fmt.Errorf("failed to write notes file: %w", err)}
+		}
+	}
+
+	return tea.ExecProcess(editorCommand(path), func(err error) tea.Msg {
+		defer os.RemoveAll(dir)
+		if err != nil {
+			return notesEditedMsg{target: target, id: id, err: fmt.Errorf("editor failed: %w", err)}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return notesEditedMsg{target: target, id: id, err: fmt.Errorf("failed to read notes file: %w", err)}
+		}
+		return notesEditedMsg{target: target, id: id, notes: cleanNotes(string(data))}
+	})
+}
+
+// cleanNotes normalizes line endings and drops trailing spaces and blank
+// lines at either end.
+func cleanNotes(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// saveNotesCmd saves notes edited from a detail screen.
+func (m *Model) saveNotesCmd(msg notesEditedMsg) tea.Cmd {
+	database := m.db
+	return func() tea.Msg {
+		var action *undoAction
+		switch msg.target {
+		case notesVisit:
+			before, err := db.GetVisit(database, msg.id)
+			if err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			if before.Notes == msg.notes {
+				break
+			}
+			after := before
+			after.Notes = msg.notes
+			if err := db.UpdateVisit(database, visitToUpdate(after)); err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			action = &undoAction{
+				label: "visit notes edited",
+				undo:  func() error { return db.UpdateVisit(database, visitToUpdate(before)) },
+				redo:  func() error { return db.UpdateVisit(database, visitToUpdate(after)) },
+			}
+		case notesWantToVisit:
+			before, err := db.GetWantToVisit(database, msg.id)
+			if err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			if before.Notes == msg.notes {
+				break
+			}
+			after := before
+			after.Notes = msg.notes
+			if err := db.UpdateWantToVisit(database, wantToVisitToUpdate(after)); err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			action = &undoAction{
+				label: "want_to_visit notes edited",
+				undo:  func() error { return db.UpdateWantToVisit(database, wantToVisitToUpdate(before)) },
+				redo:  func() error { return db.UpdateWantToVisit(database, wantToVisitToUpdate(after)) },
+			}
+		}
+		return notesSavedMsg{target: msg.target, id: msg.id, action: action}
+	}
+}
+
+// applyNotesEdited handles notes coming back from the editor.
+func (m *Model) applyNotesEdited(msg notesEditedMsg) tea.Cmd {
+	if msg.err != nil {
+		m.error = msg.err.Error()
+		return nil
+	}
+	switch msg.target {
+	case notesForm:
+		switch {
+		case m.screen == model.ScreenVisitForm && m.visitForm != nil:
+			setNotes(&m.visitForm.inputs[4], &m.visitForm.longNotes, msg.notes)
+		case m.screen == model.ScreenWantToVisitForm && m.wantToVisitForm != nil:
+			setNotes(&m.wantToVisitForm.inputs[2], &m.wantToVisitForm.longNotes, msg.notes)
+		case m.screen == model.ScreenPlanForm && m.planForm != nil:
+			setNotes(&m.planForm.inputs[4], &m.planForm.longNotes, msg.notes)
+		}
+		return nil
+	default:
+		return m.saveNotesCmd(msg)
+	}
+}
+
+// applyNotesSaved records saved notes for undo and reloads the detail screen
+// they were edited on.
+func (m *Model) applyNotesSaved(msg notesSavedMsg) tea.Cmd {
+	m.error = ""
+	if msg.action == nil {
+		m.info = "Notes unchanged"
+		return nil
+	}
+	m.pushUndoAction(*msg.action)
+	m.info = "Notes saved (u to undo)"
+	return tea.Batch(m.reloadDetailCmd(), m.reloadListsCmd())
+}
+
+// reloadDetailCmd reloads the visit or want_to_visit detail screen if one is
+// showing.
+func (m *Model) reloadDetailCmd() tea.Cmd {
+	switch {
+	case m.screen == model.ScreenVisitDetail && m.visitDetail != nil:
+		return loadVisitDetailCmd(m.db, m.visitDetail.visit.ID)
+	case m.screen == model.ScreenWantToVisitDetail && m.wantToVisitDetail != nil:
+		return loadWantToVisitDetailCmd(m.db, m.wantToVisitDetail.entry.ID)
+	}
+	return nil
+}
+
+// setNotes puts notes into a form's notes input. Notes the input can't hold
+// - more than one line, or more than its limit - are kept in long instead,
+// and the input is read-only until they are edited down again.
+func setNotes(input *textinput.Model, long *string, notes string) {
+	if strings.Contains(notes, "\n") || (input.CharLimit > 0 && len([]rune(notes)) > input.CharLimit) {
+		*long = notes
+		input.SetValue("")
+		return
+	}
+	*long = ""
+	input.SetValue(notes)
+}
+
+// notesValue returns the notes a form will save.
+func notesValue(input textinput.Model, long string) string {
+	if long != "" {
+		return long
+	}
+	return strings.TrimSpace(input.Value())
+}
+
+// renderNotesField renders a form's notes field. Notes kept aside by
+// setNotes are previewed, since the input can't show them.
+func renderNotesField(label string, input textinput.Model, long string, focused bool) string {
+	if long == "" {
+		return renderFormField(label+" (ctrl+x ctrl+e for editor)", input, focused)
+	}
+	style := BorderStyle
+	if focused {
+		style = ActiveBorderStyle
+	}
+
+	const previewLines = 3
+	lines := strings.Split(long, "\n")
+	preview := lines[:min(len(lines), previewLines)]
+	for i, line := range preview {
+		preview[i] = NormalRowStyle.Render(util.TruncateString(line, 60))
+	}
+	hint := fmt.Sprintf("%d lines · ctrl+x ctrl+e to edit", len(lines))
+	if len(lines) == 1 {
+		hint = fmt.Sprintf("%d characters · ctrl+x ctrl+e to edit", len([]rune(long)))
+	}
+
+	field := lipgloss.JoinVertical(
+		lipgloss.Left,
+		LabelStyle.Render(label),
+		strings.Join(preview, "\n"),
+		HelpDescStyle.Render(hint),
+	)
+	return style.Render(field)
+}
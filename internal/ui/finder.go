@@ -0,0 +1,362 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"toni/internal/db"
+	"toni/internal/fuzzy"
+	"toni/internal/model"
+	"toni/internal/util"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type finderKind int
+
+const (
+	finderRestaurant finderKind = iota
+	finderVisit
+	finderWantToVisit
+)
+
+func (k finderKind) String() string {
+	switch k {
+	case finderRestaurant:
+		return "restaurant"
+	case finderVisit:
+		return "visit"
+	default:
+		return "wishlist"
+	}
+}
+
+// finderItem is something the finder can open. Exactly one of the rows is
+// set, matching kind.
+type finderItem struct {
+	kind finderKind
+	// label is the text matched against and shown in the list.
+	label      string
+	restaurant *model.RestaurantRow
+	visit      *model.VisitRow
+	entry      *model.WantToVisitRow
+}
+
+// finder is the ctrl+p overlay that fuzzy-searches restaurants, visits and
+// wishlist entries together.
+type finder struct {
+	input   textinput.Model
+	items   []finderItem
+	matches []fuzzy.Ranked
+	loading bool
+	cursor  int
+	offset  int
+}
+
+// finderItemsLoadedMsg carries everything the finder searches.
+type finderItemsLoadedMsg struct {
+	items []finderItem
+}
+
+func newFinder() *finder {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "restaurant, visit or wishlist entry"
+	input.Focus()
+	return &finder{input: input, loading: true}
+}
+
+func loadFinderItemsCmd(database *sql.DB) tea.Cmd {
+	return func() tea.Msg {
+		restaurants, err := db.ListRestaurantsWhere(database, "", nil)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		visits, err := db.ListVisitsWhere(database, "", nil)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		entries, err := db.ListWantToVisitWhere(database, "", nil)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+
+		items := make([]finderItem, 0, len(restaurants)+len(visits)+len(entries))
+		for i := range restaurants {
+			r := &restaurants[i]
+			items = append(items, finderItem{kind: finderRestaurant, label: joinLabel(r.Name, r.City), restaurant: r})
+		}
+		for i := range entries {
+			e := &entries[i]
+			items = append(items, finderItem{kind: finderWantToVisit, label: joinLabel(e.RestaurantName, e.City), entry: e})
+		}
+		for i := range visits {
+			v := &visits[i]
+			items = append(items, finderItem{kind: finderVisit, label: joinLabel(v.RestaurantName, v.VisitedOn), visit: v})
+		}
+		return finderItemsLoadedMsg{items: items}
+	}
+}
+
+func joinLabel(name, detail string) string {
+	if detail == "" {
+		return name
+	}
+	return name + " · " + detail
+}
+
+// refilter ranks the items against the typed pattern and moves the cursor
+// back to the best match.
+func (f *finder) refilter() {
+	labels := make([]string, len(f.items))
+	for i, item := range f.items {
+		labels[i] = item.label
+	}
+	f.matches = fuzzy.Rank(f.input.Value(), labels)
+	f.cursor = 0
+	f.offset = 0
+}
+
+func (f *finder) selected() *finderItem {
+	if f.cursor >= len(f.matches) {
+		return nil
+	}
+	return &f.items[f.matches[f.cursor].Index]
+}
+
+// openFinder shows the finder and loads what it searches.
+func (m Model) openFinder() (tea.Model, tea.Cmd) {
+	m.finder = newFinder()
+	m.info = ""
+	return m, tea.Batch(textinput.Blink, loadFinderItemsCmd(m.db))
+}
+
+// handleFinder handles key presses while the finder is open.
+func (m Model) handleFinder(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.finder
+	action, pending, replay := m.resolveKey(msg, finderChain)
+	if pending {
+		return m, nil
+	}
+
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+	for _, k := range replay {
+		f.input, cmd = f.input.Update(k)
+		cmds = append(cmds, cmd)
+	}
+
+	switch action {
+	case ActionDown:
+		if f.cursor < len(f.matches)-1 {
+			f.cursor++
+		}
+		return m, tea.Batch(cmds...)
+	case ActionUp:
+		if f.cursor > 0 {
+			f.cursor--
+		}
+		return m, tea.Batch(cmds...)
+	case ActionSelect:
+		item := f.selected()
+		if item == nil {
+			return m, tea.Batch(cmds...)
+		}
+		m.finder = nil
+		return m, m.openFinderItem(*item)
+	case ActionDismiss:
+		m.finder = nil
+		return m, nil
+	}
+
+	before := f.input.Value()
+	f.input, cmd = f.input.Update(msg)
+	cmds = append(cmds, cmd)
+	if f.input.Value() != before || len(replay) > 0 {
+		f.refilter()
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// openFinderItem opens the detail screen of an item. The list the detail
+// screen returns to is loaded too if it hasn't been yet.
+func (m *Model) openFinderItem(item finderItem) tea.Cmd {
+	switch item.kind {
+	case finderRestaurant:
+		cmd := loadRestaurantDetailCmd(m.db, item.restaurant.ID)
+		if m.restaurants == nil {
+			cmd = tea.Batch(cmd, loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants]))
+		}
+		return cmd
+	case finderVisit:
+		return loadVisitDetailCmd(m.db, item.visit.ID)
+	default:
+		cmd := loadWantToVisitDetailCmd(m.db, item.entry.ID)
+		if m.wantToVisit == nil {
+			cmd = tea.Batch(cmd, loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit]))
+		}
+		return cmd
+	}
+}
+
+// renderFinder renders the finder in width x height: the prompt and matches
+// on the left and a preview of the selected item on the right.
+func (m Model) renderFinder(width, height int) string {
+	f := m.finder
+	listWidth := width * 11 / 20
+	previewWidth := width - listWidth - 1
+
+	count := fmt.Sprintf("%d/%d", len(f.matches), len(f.items))
+	if f.loading {
+		count = "loading…"
+	}
+	f.input.Width = max(1, listWidth-lipgloss.Width(count)-4)
+	prompt := lipgloss.NewStyle().Width(listWidth-lipgloss.Width(count)-1).Render(f.input.View()) +
+		" " + HelpDescStyle.Render(count)
+
+	rows := max(1, height-2)
+	if f.cursor < f.offset {
+		f.offset = f.cursor
+	}
+	if f.cursor >= f.offset+rows {
+		f.offset = f.cursor - rows + 1
+	}
+
+	lines := []string{prompt, TableDividerStyle.Render(strings.Repeat("─", listWidth))}
+	if !f.loading && len(f.matches) == 0 {
+		lines = append(lines, EmptyStateStyle.Padding(0, 1).Render("No matches"))
+	}
+	for i := f.offset; i < len(f.matches) && i < f.offset+rows; i++ {
+		match := f.matches[i]
+		lines = append(lines, renderFinderRow(f.items[match.Index], match.Positions, listWidth, i == f.cursor))
+	}
+	list := lipgloss.NewStyle().Width(listWidth).Height(height).Render(strings.Join(lines, "\n"))
+
+	preview := ""
+	if item := f.selected(); item != nil {
+		preview = renderFinderPreview(*item, previewWidth)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, list, " ", preview)
+}
+
+// renderFinderRow renders one match with its matched characters
+// highlighted.
+func renderFinderRow(item finderItem, positions []int, width int, selected bool) string {
+	const kindWidth = 11
+	base, highlight := NormalRowStyle, HelpKeyStyle.Bold(true)
+	if selected {
+		base = SelectedRowStyle
+		highlight = SelectedRowStyle.Underline(true)
+	}
+
+	label := []rune(item.label)
+	labelWidth := max(1, width-kindWidth-2)
+	truncated := len(label) > labelWidth
+	if truncated {
+		label = label[:labelWidth-1]
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		matched[p] = true
+	}
+
+	var b strings.Builder
+	b.WriteString(base.Render(" "))
+	for i, r := range label {
+		if matched[i] {
+			b.WriteString(highlight.Render(string(r)))
+		} else {
+			b.WriteString(base.Render(string(r)))
+		}
+	}
+	if truncated {
+		b.WriteString(base.Render("…"))
+	}
+	pad := max(0, labelWidth-len(label)-boolInt(truncated)+1)
+	kind := fmt.Sprintf("%*s", kindWidth, item.kind.String())
+	if selected {
+		b.WriteString(base.Render(strings.Repeat(" ", pad) + kind))
+	} else {
+		b.WriteString(strings.Repeat(" ", pad) + HelpDescStyle.Render(kind))
+	}
+	return b.String()
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// renderFinderPreview renders the fields of an item in a panel.
+func renderFinderPreview(item finderItem, width int) string {
+	var title string
+	var fields []string
+	notes := ""
+	switch item.kind {
+	case finderRestaurant:
+		r := item.restaurant
+		lastVisit := ""
+		if r.LastVisit != "" {
+			lastVisit = util.FormatDate(r.LastVisit)
+		}
+		title = r.Name
+		fields = []string{
+			renderField("Address", r.Address),
+			renderField("City", r.City),
+			renderField("Neighborhood", r.Neighborhood),
+			renderField("Cuisine", r.Cuisine),
+			renderField("Price Range", r.PriceRange),
+			renderField("Avg Rating", util.FormatAvgRating(r.AvgRating)),
+			renderField("Visits", fmt.Sprintf("%d", r.VisitCount)),
+			renderField("Last Visit", lastVisit),
+		}
+	case finderVisit:
+		v := item.visit
+		title = v.RestaurantName
+		fields = []string{
+			renderField("Visited", util.FormatDate(v.VisitedOn)),
+			renderField("Rating", util.FormatRating(v.Rating)),
+			renderField("Would Return", util.FormatWouldReturn(v.WouldReturn)),
+			renderField("Address", v.Address),
+			renderField("City", v.City),
+			renderField("Price Range", v.PriceRange),
+		}
+		notes = v.Notes
+	default:
+		e := item.entry
+		priority := "Not set"
+		if e.Priority != nil {
+			priority = fmt.Sprintf("%d/5", *e.Priority)
+		}
+		title = e.RestaurantName
+		fields = []string{
+			renderField("Priority", priority),
+			renderField("Added", util.FormatDate(e.CreatedAt.Format("2006-01-02"))),
+			renderField("Address", e.Address),
+			renderField("City", e.City),
+			renderField("Neighborhood", e.Neighborhood),
+			renderField("Cuisine", e.Cuisine),
+			renderField("Price Range", e.PriceRange),
+		}
+		notes = e.Notes
+	}
+
+	sections := []string{
+		LabelStyle.Render(title) + "  " + HelpDescStyle.Render(item.kind.String()),
+		strings.Join(fields, "\n"),
+	}
+	if notes != "" {
+		// Long notes are cut short; the detail screen shows them whole.
+		const maxNoteLines = 12
+		lines := strings.Split(renderMarkdown(notes, width-6), "\n")
+		if len(lines) > maxNoteLines {
+			lines = append(lines[:maxNoteLines], HelpDescStyle.Render("…"))
+		}
+		sections = append(sections, LabelStyle.Render("Notes:")+"\n"+strings.Join(lines, "\n"))
+	}
+	return PanelStyle.Width(max(1, width-2)).Render(strings.Join(sections, "\n\n"))
+}
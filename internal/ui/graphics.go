@@ -74,8 +74,8 @@ func convertToASCII(img image.Image, targetWidth, targetHeight int) string {
 	opts := convert.DefaultOptions
 	opts.FixedWidth = targetWidth
 	opts.FixedHeight = targetHeight
-	opts.Colored = true // Use ANSI colors
-	opts.Ratio = 0.5    // Adjust for terminal character aspect ratio
+	opts.Colored = !colorDisabled // Use ANSI colors unless NO_COLOR
+	opts.Ratio = 0.5              // Adjust for terminal character aspect ratio
 
 	// Convert image to ASCII
 	ascii := converter.Image2ASCIIString(img, &opts)
@@ -7,125 +7,145 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
-// RenderHelp renders context-sensitive help footer.
-func RenderHelp(screen model.Screen, mode model.Mode, width int) string {
-	if mode == model.ModeInsert {
-		return renderFormHelp(width)
-	}
-
-	switch screen {
-	case model.ScreenVisits:
-		return renderVisitsHelp(width)
-	case model.ScreenRestaurants:
-		return renderRestaurantsHelp(width)
-	case model.ScreenWantToVisit:
-		return renderWantToVisitHelp(width)
-	case model.ScreenVisitDetail:
-		return renderVisitDetailHelp(width)
-	case model.ScreenRestaurantDetail:
-		return renderRestaurantDetailHelp(width)
-	case model.ScreenWantToVisitDetail:
-		return renderWantToVisitDetailHelp(width)
-	default:
-		return renderDefaultHelp(width)
-	}
-}
-
-func renderVisitsHelp(width int) string {
-	keys := []string{
-		helpKey("j/k", "navigate"),
-		helpKey("b/f", "prev/next tab"),
-		helpKey("tab", "next col"),
-		helpKey("s", "cycle sort"),
-		helpKey("n", "cycle filter"),
-		helpKey("a", "add visit"),
-		helpKey("r", "restaurants"),
-		helpKey("w", "want to visit"),
-		helpKey("enter", "details"),
-		helpKey("/", "jump col"),
-	}
-	return renderHelpLine(keys, width)
-}
-
-func renderRestaurantsHelp(width int) string {
-	keys := []string{
-		helpKey("j/k", "navigate"),
-		helpKey("b/f", "prev/next tab"),
-		helpKey("tab", "next col"),
-		helpKey("s", "sort"),
-		helpKey("n", "filter"),
-		helpKey("c/C", "hide/show"),
-		helpKey("a", "add"),
-		helpKey("v", "log visit"),
-		helpKey("w", "want-to-visit"),
-		helpKey("enter", "details"),
-		helpKey("u/ctrl+r", "undo/redo"),
-	}
-	return renderHelpLine(keys, width)
-}
-
-func renderWantToVisitHelp(width int) string {
-	keys := []string{
-		helpKey("j/k", "navigate"),
-		helpKey("b/f", "prev/next tab"),
-		helpKey("tab", "next col"),
-		helpKey("s", "cycle sort"),
-		helpKey("n", "cycle filter"),
-		helpKey("a", "add place"),
-		helpKey("v", "visits"),
-		helpKey("r", "restaurants"),
-		helpKey("enter", "details"),
-		helpKey("/", "jump col"),
-	}
-	return renderHelpLine(keys, width)
+// footerItem is one entry in the help footer. Its key is the first key of
+// each action, joined with "/".
+type footerItem struct {
+	actions []Action
+	desc    string
 }
 
-func renderWantToVisitDetailHelp(width int) string {
-	keys := []string{
-		helpKey("h/esc", "back"),
-		helpKey("c", "mark visited"),
-		helpKey("e", "edit"),
-		helpKey("d", "delete"),
-	}
-	return renderHelpLine(keys, width)
+var footerItems = map[model.Screen][]footerItem{
+	model.ScreenVisits: {
+		{[]Action{ActionDown, ActionUp}, "navigate"},
+		{[]Action{ActionPrevTab, ActionNextTab}, "prev/next tab"},
+		{[]Action{ActionNextColumn}, "next col"},
+		{[]Action{ActionCycleSort}, "cycle sort"},
+		{[]Action{ActionCycleFilter}, "cycle filter"},
+		{[]Action{ActionAdd}, "add visit"},
+		{[]Action{ActionRestaurants}, "restaurants"},
+		{[]Action{ActionWantToVisit}, "want to visit"},
+		{[]Action{ActionVisitCalendar}, "calendar"},
+		{[]Action{ActionPlan}, "plan"},
+		{[]Action{ActionUpcoming}, "upcoming"},
+		{[]Action{ActionOpen}, "details"},
+		{[]Action{ActionColumnJump}, "jump col"},
+		{[]Action{ActionViews}, "views"},
+		{[]Action{ActionToggleMark, ActionVisual}, "select"},
+	},
+	model.ScreenRestaurants: {
+		{[]Action{ActionDown, ActionUp}, "navigate"},
+		{[]Action{ActionPrevTab, ActionNextTab}, "prev/next tab"},
+		{[]Action{ActionNextColumn}, "next col"},
+		{[]Action{ActionCycleSort}, "sort"},
+		{[]Action{ActionCycleFilter}, "filter"},
+		{[]Action{ActionHideColumn, ActionShowColumns}, "hide/show"},
+		{[]Action{ActionAdd}, "add"},
+		{[]Action{ActionLogVisit}, "log visit"},
+		{[]Action{ActionWantToVisit}, "want-to-visit"},
+		{[]Action{ActionOpen}, "details"},
+		{[]Action{ActionUndo, ActionRedo}, "undo/redo"},
+		{[]Action{ActionViews}, "views"},
+		{[]Action{ActionToggleMark, ActionVisual}, "select"},
+	},
+	model.ScreenWantToVisit: {
+		{[]Action{ActionDown, ActionUp}, "navigate"},
+		{[]Action{ActionPrevTab, ActionNextTab}, "prev/next tab"},
+		{[]Action{ActionNextColumn}, "next col"},
+		{[]Action{ActionCycleSort}, "cycle sort"},
+		{[]Action{ActionCycleFilter}, "cycle filter"},
+		{[]Action{ActionAdd}, "add place"},
+		{[]Action{ActionVisits}, "visits"},
+		{[]Action{ActionRestaurants}, "restaurants"},
+		{[]Action{ActionOpen}, "details"},
+		{[]Action{ActionColumnJump}, "jump col"},
+		{[]Action{ActionViews}, "views"},
+		{[]Action{ActionToggleMark, ActionVisual}, "select"},
+	},
+	model.ScreenUpcoming: {
+		{[]Action{ActionDown, ActionUp}, "move"},
+		{[]Action{ActionLogVisit}, "log visit"},
+		{[]Action{ActionOpen}, "restaurant"},
+		{[]Action{ActionAdd}, "add"},
+		{[]Action{ActionEdit}, "edit"},
+		{[]Action{ActionDelete}, "delete"},
+		{[]Action{ActionUndo, ActionRedo}, "undo/redo"},
+		{[]Action{ActionBack}, "back"},
+	},
+	model.ScreenWantToVisitDetail: {
+		{[]Action{ActionBack}, "back"},
+		{[]Action{ActionMarkVisited}, "mark visited"},
+		{[]Action{ActionPlan}, "plan"},
+		{[]Action{ActionEdit}, "edit"},
+		{[]Action{ActionEditNotes}, "notes"},
+		{[]Action{ActionDelete}, "delete"},
+	},
+	model.ScreenVisitDetail: {
+		{[]Action{ActionBack}, "back"},
+		{[]Action{ActionEdit}, "edit"},
+		{[]Action{ActionEditNotes}, "notes"},
+		{[]Action{ActionDelete}, "delete"},
+	},
+	model.ScreenPicker: {
+		{[]Action{ActionReroll}, "roll again"},
+		{[]Action{ActionLogVisit}, "log a visit here"},
+		{[]Action{ActionOpen}, "details"},
+		{[]Action{ActionEdit}, "change"},
+		{[]Action{ActionBack}, "back"},
+	},
+	model.ScreenCompare: {
+		{[]Action{ActionDown, ActionUp}, "move"},
+		{[]Action{ActionOpen}, "open"},
+		{[]Action{ActionBack}, "back"},
+	},
+	model.ScreenVisitCalendar: {
+		{[]Action{ActionPrevDay, ActionNextDay, ActionUp, ActionDown}, "move"},
+		{[]Action{ActionPrevMonth, ActionNextMonth}, "month"},
+		{[]Action{ActionPrevYear, ActionNextYear}, "year"},
+		{[]Action{ActionToggleLayout}, "heatmap/month"},
+		{[]Action{ActionColorBy}, "colour"},
+		{[]Action{ActionOpen}, "show visits"},
+		{[]Action{ActionBack}, "back"},
+	},
+	model.ScreenRestaurantDetail: {
+		{[]Action{ActionBack}, "back"},
+		{[]Action{ActionLogVisit}, "add visit"},
+		{[]Action{ActionPlan}, "plan"},
+		{[]Action{ActionEdit}, "edit"},
+		{[]Action{ActionDelete}, "delete"},
+	},
 }
 
-func renderVisitDetailHelp(width int) string {
-	keys := []string{
-		helpKey("h/esc", "back"),
-		helpKey("e", "edit"),
-		helpKey("d", "delete"),
-	}
-	return renderHelpLine(keys, width)
+var formFooterItems = []footerItem{
+	{[]Action{ActionNextField}, "next field"},
+	{[]Action{ActionPrevField}, "prev field"},
+	{[]Action{ActionSave}, "save"},
+	{[]Action{ActionEditNotes}, "notes in editor"},
+	{[]Action{ActionCancel}, "cancel"},
 }
 
-func renderRestaurantDetailHelp(width int) string {
-	keys := []string{
-		helpKey("h/esc", "back"),
-		helpKey("v", "add visit"),
-		helpKey("e", "edit"),
-		helpKey("d", "delete"),
+// RenderHelp renders context-sensitive help footer from the active keymap.
+func RenderHelp(keys KeyMap, screen model.Screen, mode model.Mode, width int) string {
+	contexts, items := navContexts(screen), footerItems[screen]
+	if mode == model.ModeInsert {
+		contexts, items = formChain, formFooterItems
 	}
-	return renderHelpLine(keys, width)
-}
-
-func renderFormHelp(width int) string {
-	keys := []string{
-		helpKey("tab", "next field"),
-		helpKey("shift+tab", "prev field"),
-		helpKey("ctrl+s", "save"),
-		helpKey("esc", "cancel"),
+	if items == nil {
+		items = []footerItem{{[]Action{ActionHelp}, "help"}}
 	}
-	return renderHelpLine(keys, width)
-}
 
-func renderDefaultHelp(width int) string {
-	keys := []string{
-		helpKey("j/k", "navigate"),
-		helpKey("h/l", "back/select"),
-		helpKey("q", "quit"),
+	var rendered []string
+	for _, item := range items {
+		var labels []string
+		for _, action := range item.actions {
+			if bound := keys.keysFor(contexts, action); len(bound) > 0 {
+				labels = append(labels, keyLabel(bound[0]))
+			}
+		}
+		if len(labels) > 0 {
+			rendered = append(rendered, helpKey(strings.Join(labels, "/"), item.desc))
+		}
 	}
-	return renderHelpLine(keys, width)
+	return renderHelpLine(rendered, width)
 }
 
 func helpKey(key, desc string) string {
@@ -137,81 +157,84 @@ func renderHelpLine(keys []string, width int) string {
 	return FooterStyle.Width(width).Render(line)
 }
 
-// RenderFullHelp renders the full help screen.
-func RenderFullHelp(width, height int) string {
+// fullHelpSections groups bindings on the help screen by context.
+var fullHelpSections = []struct {
+	title    string
+	contexts []Context
+}{
+	{"Navigation (Nav Mode)", []Context{ContextTable, ContextGlobal}},
+	{"Visits Screen", []Context{ContextVisits}},
+	{"Upcoming Plans", []Context{ContextUpcoming}},
+	{"Restaurants Screen", []Context{ContextRestaurants}},
+	{"Want to Visit Screen", []Context{ContextWantToVisit}},
+	{"Detail Screens", []Context{ContextDetail, ContextVisitDetail, ContextRestaurantDetail, ContextWantToVisitDetail}},
+	{"Forms (Insert/Edit Mode)", []Context{ContextForm}},
+	{"Autocomplete", []Context{ContextDropdown}},
+	{"Command Line", []Context{ContextCommandLine}},
+	{"Saved Views", []Context{ContextViewPicker}},
+	{"Finder", []Context{ContextFinder}},
+	{"Calendar", []Context{ContextCalendar}},
+	{"Visit Calendar", []Context{ContextVisitCalendar}},
+	{"Picker", []Context{ContextPicker}},
+	{"Compare", []Context{ContextCompare}},
+}
+
+// RenderFullHelp renders the full help screen from the active keymap.
+func RenderFullHelp(keys KeyMap, width, height int) string {
 	content := lipgloss.NewStyle().
 		Width(width-4).
 		Height(height-6).
 		Padding(1, 2)
 
-	sections := []string{
-		titleSection("Navigation (Nav Mode)"),
-		helpSection([]helpItem{
-			{"j / ↓", "Move down"},
-			{"k / ↑", "Move up"},
-			{"b / f", "Previous / next tab"},
-			{"B / F", "First / last tab"},
-			{"h / ←", "Go back / parent"},
-			{"l / → / enter", "Open / select"},
-			{"tab / shift+tab", "Cycle active column"},
-			{"/ then 1-9", "Jump to column"},
-			{"s", "Cycle sort: none -> asc -> desc -> none"},
-			{"c / C", "Hide active column / show all"},
-			{"n", "Cycle filter: apply selected value / clear"},
-			{"gg", "Jump to top"},
-			{"G", "Jump to bottom"},
-			{"ctrl+d", "Half page down"},
-			{"ctrl+u", "Half page up"},
-			{"pgdown / pgup", "Half page down / up"},
-			{"u / ctrl+r", "Undo / redo"},
-			{"esc", "Cancel / close"},
-			{"q", "Quit (from top-level)"},
-			{"?", "Toggle help"},
-		}),
-		titleSection("Visits Screen"),
-		helpSection([]helpItem{
-			{"a", "Quick-add visit"},
-			{"r", "Go to restaurants"},
-			{"w", "Go to want to visit"},
-			{"enter / l", "Open visit detail"},
-		}),
-		titleSection("Restaurants Screen"),
-		helpSection([]helpItem{
-			{"a", "Add restaurant"},
-			{"v", "Log visit for selected"},
-			{"b / f", "Previous / next tab"},
-			{"w", "Go to want to visit"},
-			{"enter / l", "Open restaurant detail"},
-			{"h", "Back to visits"},
-		}),
-		titleSection("Want to Visit Screen"),
-		helpSection([]helpItem{
-			{"a", "Add place to list"},
-			{"c", "Mark as visited (from detail)"},
-			{"b / f", "Previous / next tab"},
-			{"v", "Go to visits"},
-			{"r", "Go to restaurants"},
-			{"enter / l", "Open detail"},
-		}),
-		titleSection("Forms (Insert/Edit Mode)"),
-		helpSection([]helpItem{
-			{"tab", "Next field"},
-			{"shift+tab", "Previous field"},
-			{"ctrl+s", "Save"},
-			{"esc", "Cancel"},
-		}),
+	var sections []string
+	for _, section := range fullHelpSections {
+		var items []helpItem
+		for _, b := range keys.Bindings() {
+			if !containsContext(section.contexts, b.Context) || len(b.Keys) == 0 {
+				continue
+			}
+			labels := make([]string, len(b.Keys))
+			for i, seq := range b.Keys {
+				labels[i] = keyLabel(seq)
+			}
+			items = append(items, helpItem{strings.Join(labels, " / "), b.Help})
+		}
+		if section.contexts[0] == ContextTable {
+			items = append(items, helpItem{"ctrl+c", "Quit from anywhere"})
+		}
+		if section.contexts[0] == ContextCommandLine {
+			for _, c := range exCommands {
+				items = append(items, helpItem{":" + c.usage, c.help})
+			}
+		}
+		if len(items) > 0 {
+			sections = append(sections, titleSection(section.title), helpSection(items))
+		}
 	}
 
 	helpText := content.Render(strings.Join(sections, "\n\n"))
 
+	closeKey := "esc"
+	if bound := keys.keysFor(helpChain, ActionHelp); len(bound) > 0 {
+		closeKey = keyLabel(bound[0])
+	}
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
 		TitleStyle.Width(width).Render("Help"),
 		helpText,
-		FooterStyle.Width(width).Render(HelpKeyStyle.Render("esc")+" "+HelpDescStyle.Render("close help")),
+		FooterStyle.Width(width).Render(HelpKeyStyle.Render(closeKey)+" "+HelpDescStyle.Render("close help")),
 	)
 }
 
+func containsContext(contexts []Context, c Context) bool {
+	for _, x := range contexts {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
+
 type helpItem struct {
 	key  string
 	desc string
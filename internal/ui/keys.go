@@ -1,201 +1,621 @@
 package ui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"toni/internal/model"
 
-// GState represents the state for "gg" navigation.
-type GState int
+	"github.com/BurntSushi/toml"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Action names something a key can do. Keymap files bind keys to actions by
+// these names.
+type Action string
 
 const (
-	GStateIdle GState = iota
-	GStateFirstG
+	ActionHelp Action = "help"
+	ActionUndo Action = "undo"
+	ActionRedo Action = "redo"
+	ActionQuit Action = "quit"
+
+	ActionUp           Action = "up"
+	ActionDown         Action = "down"
+	ActionTop          Action = "top"
+	ActionBottom       Action = "bottom"
+	ActionHalfPageDown Action = "half_page_down"
+	ActionHalfPageUp   Action = "half_page_up"
+	ActionOpen         Action = "open"
+	ActionBack         Action = "back"
+
+	ActionPrevTab     Action = "prev_tab"
+	ActionNextTab     Action = "next_tab"
+	ActionFirstTab    Action = "first_tab"
+	ActionLastTab     Action = "last_tab"
+	ActionVisits      Action = "visits"
+	ActionRestaurants Action = "restaurants"
+	ActionWantToVisit Action = "want_to_visit"
+
+	ActionNextColumn  Action = "next_column"
+	ActionPrevColumn  Action = "prev_column"
+	ActionColumnJump  Action = "column_jump"
+	ActionCycleSort   Action = "cycle_sort"
+	ActionHideColumn  Action = "hide_column"
+	ActionShowColumns Action = "show_columns"
+	ActionCycleFilter Action = "cycle_filter"
+	ActionSearch      Action = "search"
+	ActionViews       Action = "views"
+
+	ActionToggleMark     Action = "toggle_mark"
+	ActionVisual         Action = "visual"
+	ActionClearSelection Action = "clear_selection"
+	ActionTag            Action = "tag"
+
+	ActionAdd         Action = "add"
+	ActionEdit        Action = "edit"
+	ActionDelete      Action = "delete"
+	ActionLogVisit    Action = "log_visit"
+	ActionMarkVisited Action = "mark_visited"
+	ActionEditNotes   Action = "edit_notes"
+
+	ActionPlan     Action = "plan"
+	ActionUpcoming Action = "upcoming"
+	ActionLogPlan  Action = "log_plan"
+
+	ActionNextField Action = "next_field"
+	ActionPrevField Action = "prev_field"
+	ActionSave      Action = "save"
+	ActionCancel    Action = "cancel"
+
+	ActionSelect  Action = "select"
+	ActionDismiss Action = "dismiss"
+
+	ActionCalendar  Action = "calendar"
+	ActionPrevDay   Action = "prev_day"
+	ActionNextDay   Action = "next_day"
+	ActionPrevMonth Action = "prev_month"
+	ActionNextMonth Action = "next_month"
+	ActionToday     Action = "today"
+
+	ActionVisitCalendar Action = "visit_calendar"
+	ActionPrevYear      Action = "prev_year"
+	ActionNextYear      Action = "next_year"
+	ActionToggleLayout  Action = "toggle_layout"
+	ActionColorBy       Action = "color_by"
+
+	ActionPick   Action = "pick"
+	ActionReroll Action = "reroll"
+
+	ActionFinder Action = "finder"
+
+	ActionCommandLine  Action = "command_line"
+	ActionExecute      Action = "execute"
+	ActionComplete     Action = "complete"
+	ActionCompletePrev Action = "complete_prev"
+	ActionHistoryPrev  Action = "history_prev"
+	ActionHistoryNext  Action = "history_next"
 )
 
-// KeyMap defines all keybindings for nav mode.
+// Context is the part of the UI a binding applies in. Each screen looks keys
+// up in a chain of contexts, most specific first (see keyChains), so a key
+// bound in "table" works on every list screen.
+type Context string
+
+const (
+	ContextGlobal            Context = "global"
+	ContextTable             Context = "table"
+	ContextVisits            Context = "visits"
+	ContextUpcoming          Context = "upcoming"
+	ContextRestaurants       Context = "restaurants"
+	ContextWantToVisit       Context = "want_to_visit"
+	ContextDetail            Context = "detail"
+	ContextVisitDetail       Context = "visit_detail"
+	ContextRestaurantDetail  Context = "restaurant_detail"
+	ContextWantToVisitDetail Context = "want_to_visit_detail"
+	ContextForm              Context = "form"
+	ContextDropdown          Context = "dropdown"
+	ContextHelp              Context = "help"
+	ContextCommandLine       Context = "command_line"
+	ContextViewPicker        Context = "view_picker"
+	ContextFinder            Context = "finder"
+	ContextCalendar          Context = "calendar"
+	ContextVisitCalendar     Context = "visit_calendar"
+	ContextPicker            Context = "picker"
+	ContextCompare           Context = "compare"
+)
+
+// KeyBinding binds key sequences to an action within a context. A sequence
+// is one or more key names separated by spaces, e.g. "g g" or "ctrl+x ctrl+s".
+type KeyBinding struct {
+	Context Context
+	Action  Action
+	Keys    []string
+	Help    string
+}
+
+// Name identifies the binding in keymap files and error messages, e.g.
+// "table.top".
+func (b KeyBinding) Name() string {
+	return string(b.Context) + "." + string(b.Action)
+}
+
+// defaultBindings lists every action that can be bound, by context, in the
+// order the help screen shows them. The first key of each binding is the one
+// shown in the footer.
+var defaultBindings = []KeyBinding{
+	{ContextGlobal, ActionHelp, []string{"?"}, "Toggle help"},
+	{ContextGlobal, ActionUndo, []string{"u"}, "Undo"},
+	{ContextGlobal, ActionRedo, []string{"ctrl+r"}, "Redo"},
+	{ContextGlobal, ActionCommandLine, []string{":"}, "Open command line"},
+	{ContextGlobal, ActionFinder, []string{"ctrl+p"}, "Find a restaurant, visit or wishlist entry"},
+
+	{ContextTable, ActionDown, []string{"j", "down"}, "Move down"},
+	{ContextTable, ActionUp, []string{"k", "up"}, "Move up"},
+	{ContextTable, ActionPrevTab, []string{"b", "left"}, "Previous tab"},
+	{ContextTable, ActionNextTab, []string{"f", "right"}, "Next tab"},
+	{ContextTable, ActionFirstTab, []string{"B"}, "First tab"},
+	{ContextTable, ActionLastTab, []string{"F"}, "Last tab"},
+	{ContextTable, ActionOpen, []string{"enter", "l"}, "Open / select"},
+	{ContextTable, ActionNextColumn, []string{"tab"}, "Next column"},
+	{ContextTable, ActionPrevColumn, []string{"shift+tab"}, "Previous column"},
+	{ContextTable, ActionColumnJump, []string{"/"}, "Jump to column (then 1-9)"},
+	{ContextTable, ActionCycleSort, []string{"s"}, "Cycle sort: none -> asc -> desc -> none"},
+	{ContextTable, ActionHideColumn, []string{"c"}, "Hide active column"},
+	{ContextTable, ActionShowColumns, []string{"C"}, "Show all columns"},
+	{ContextTable, ActionCycleFilter, []string{"n"}, "Cycle filter: apply selected value / clear"},
+	{ContextTable, ActionSearch, []string{"ctrl+f"}, "Filter with a query (:filter)"},
+	{ContextTable, ActionViews, []string{"'"}, "Pick a saved view (or press 1-9; 0 for the default)"},
+	{ContextTable, ActionToggleMark, []string{"space"}, "Select / unselect row"},
+	{ContextTable, ActionVisual, []string{"V"}, "Visual mode: select a range of rows"},
+	{ContextTable, ActionClearSelection, []string{"esc"}, "Clear selection"},
+	{ContextTable, ActionDelete, []string{"d"}, "Delete selected rows (or the current one)"},
+	{ContextTable, ActionTag, []string{"t"}, "Tag selected restaurants (:tag)"},
+	{ContextTable, ActionTop, []string{"g g"}, "Jump to top"},
+	{ContextTable, ActionBottom, []string{"G"}, "Jump to bottom"},
+	{ContextTable, ActionHalfPageDown, []string{"ctrl+d", "pgdown"}, "Half page down"},
+	{ContextTable, ActionHalfPageUp, []string{"ctrl+u", "pgup"}, "Half page up"},
+	{ContextTable, ActionPick, []string{"p"}, "Where should we eat? Pick at random (:pick)"},
+	{ContextTable, ActionQuit, []string{"q"}, "Quit"},
+
+	{ContextVisits, ActionAdd, []string{"a"}, "Quick-add visit"},
+	{ContextVisits, ActionRestaurants, []string{"r"}, "Go to restaurants"},
+	{ContextVisits, ActionWantToVisit, []string{"w"}, "Go to want to visit"},
+	{ContextVisits, ActionVisitCalendar, []string{"m"}, "Calendar of visits (:calendar)"},
+	{ContextVisits, ActionPlan, []string{"P"}, "Plan a visit or note a reservation"},
+	{ContextVisits, ActionUpcoming, []string{"U"}, "Browse upcoming planned visits"},
+	{ContextVisits, ActionLogPlan, []string{"L"}, "Log the earliest plan whose day has come"},
+
+	{ContextUpcoming, ActionDown, []string{"j", "down"}, "Next plan"},
+	{ContextUpcoming, ActionUp, []string{"k", "up"}, "Previous plan"},
+	{ContextUpcoming, ActionOpen, []string{"enter", "l"}, "Open the restaurant"},
+	{ContextUpcoming, ActionLogVisit, []string{"v"}, "Log the plan as a visit, once its day has come"},
+	{ContextUpcoming, ActionAdd, []string{"a"}, "Plan a visit"},
+	{ContextUpcoming, ActionEdit, []string{"e"}, "Edit plan"},
+	{ContextUpcoming, ActionDelete, []string{"d"}, "Delete plan"},
+	{ContextUpcoming, ActionBack, []string{"esc", "U"}, "Back to visits"},
+
+	{ContextRestaurants, ActionAdd, []string{"a"}, "Add restaurant"},
+	{ContextRestaurants, ActionLogVisit, []string{"v"}, "Log visit for selected"},
+	{ContextRestaurants, ActionWantToVisit, []string{"w"}, "Go to want to visit"},
+	{ContextRestaurants, ActionVisits, []string{"h"}, "Back to visits"},
+
+	{ContextWantToVisit, ActionAdd, []string{"a"}, "Add place to list"},
+	{ContextWantToVisit, ActionVisits, []string{"v"}, "Go to visits"},
+	{ContextWantToVisit, ActionRestaurants, []string{"r"}, "Go to restaurants"},
+
+	{ContextDetail, ActionBack, []string{"h", "esc", "b"}, "Back"},
+	{ContextDetail, ActionEdit, []string{"e"}, "Edit"},
+	{ContextDetail, ActionDelete, []string{"d"}, "Delete"},
+	{ContextRestaurantDetail, ActionLogVisit, []string{"v"}, "Log visit (restaurant detail)"},
+	{ContextRestaurantDetail, ActionPlan, []string{"P"}, "Plan a visit (restaurant detail)"},
+	{ContextWantToVisitDetail, ActionPlan, []string{"P"}, "Plan a visit (want to visit detail)"},
+	{ContextWantToVisitDetail, ActionMarkVisited, []string{"c"}, "Mark as visited (want to visit detail)"},
+	{ContextVisitDetail, ActionEditNotes, []string{"n"}, "Edit notes in $EDITOR (visit detail)"},
+	{ContextWantToVisitDetail, ActionEditNotes, []string{"n"}, "Edit notes in $EDITOR (want to visit detail)"},
+
+	{ContextForm, ActionNextField, []string{"tab"}, "Next field"},
+	{ContextForm, ActionPrevField, []string{"shift+tab"}, "Previous field"},
+	{ContextForm, ActionSave, []string{"ctrl+s"}, "Save"},
+	{ContextForm, ActionEditNotes, []string{"ctrl+x ctrl+e"}, "Edit notes in $EDITOR"},
+	{ContextForm, ActionCalendar, []string{"ctrl+t"}, "Pick the date from a calendar"},
+	{ContextForm, ActionCancel, []string{"esc"}, "Cancel"},
+	{ContextForm, ActionCommandLine, []string{"ctrl+o"}, "Open command line (:w, :q, :wq)"},
+
+	{ContextDropdown, ActionDown, []string{"j", "down"}, "Next suggestion"},
+	{ContextDropdown, ActionUp, []string{"k", "up"}, "Previous suggestion"},
+	{ContextDropdown, ActionSelect, []string{"enter", "tab"}, "Select suggestion"},
+	{ContextDropdown, ActionDismiss, []string{"esc"}, "Dismiss suggestions"},
+
+	{ContextCommandLine, ActionExecute, []string{"enter"}, "Run command"},
+	{ContextCommandLine, ActionCancel, []string{"esc"}, "Close command line"},
+	{ContextCommandLine, ActionComplete, []string{"tab"}, "Complete command, column or value"},
+	{ContextCommandLine, ActionCompletePrev, []string{"shift+tab"}, "Previous completion"},
+	{ContextCommandLine, ActionHistoryPrev, []string{"up", "ctrl+p"}, "Previous command in history"},
+	{ContextCommandLine, ActionHistoryNext, []string{"down", "ctrl+n"}, "Next command in history"},
+
+	{ContextViewPicker, ActionDown, []string{"j", "down"}, "Next view"},
+	{ContextViewPicker, ActionUp, []string{"k", "up"}, "Previous view"},
+	{ContextViewPicker, ActionSelect, []string{"enter"}, "Apply view (or press its number)"},
+	{ContextViewPicker, ActionDelete, []string{"d"}, "Delete view"},
+	{ContextViewPicker, ActionDismiss, []string{"esc", "'"}, "Close view picker"},
+
+	{ContextFinder, ActionDown, []string{"down", "ctrl+n"}, "Next match"},
+	{ContextFinder, ActionUp, []string{"up", "ctrl+p"}, "Previous match"},
+	{ContextFinder, ActionSelect, []string{"enter"}, "Open match"},
+	{ContextFinder, ActionDismiss, []string{"esc"}, "Close finder"},
+
+	{ContextCalendar, ActionPrevDay, []string{"h", "left"}, "Previous day"},
+	{ContextCalendar, ActionNextDay, []string{"l", "right"}, "Next day"},
+	{ContextCalendar, ActionUp, []string{"k", "up"}, "Previous week"},
+	{ContextCalendar, ActionDown, []string{"j", "down"}, "Next week"},
+	{ContextCalendar, ActionPrevMonth, []string{"H", "pgup"}, "Previous month"},
+	{ContextCalendar, ActionNextMonth, []string{"L", "pgdown"}, "Next month"},
+	{ContextCalendar, ActionToday, []string{"t"}, "Today"},
+	{ContextCalendar, ActionSelect, []string{"enter", "space"}, "Pick date"},
+	{ContextCalendar, ActionDismiss, []string{"esc", "q"}, "Close calendar"},
+
+	{ContextVisitCalendar, ActionPrevDay, []string{"h", "left"}, "Previous day"},
+	{ContextVisitCalendar, ActionNextDay, []string{"l", "right"}, "Next day"},
+	{ContextVisitCalendar, ActionUp, []string{"k", "up"}, "Previous week"},
+	{ContextVisitCalendar, ActionDown, []string{"j", "down"}, "Next week"},
+	{ContextVisitCalendar, ActionPrevMonth, []string{"H", "pgup"}, "Previous month"},
+	{ContextVisitCalendar, ActionNextMonth, []string{"L", "pgdown"}, "Next month"},
+	{ContextVisitCalendar, ActionPrevYear, []string{"["}, "Previous year"},
+	{ContextVisitCalendar, ActionNextYear, []string{"]"}, "Next year"},
+	{ContextVisitCalendar, ActionToday, []string{"t"}, "Today"},
+	{ContextVisitCalendar, ActionToggleLayout, []string{"tab"}, "Switch between year heatmap and month grid"},
+	{ContextVisitCalendar, ActionColorBy, []string{"c"}, "Colour by visit count / average rating"},
+	{ContextVisitCalendar, ActionOpen, []string{"enter"}, "Show the day's visits in the Visits list"},
+	{ContextVisitCalendar, ActionBack, []string{"esc", "q"}, "Back to visits"},
+
+	{ContextPicker, ActionReroll, []string{"space", "r"}, "Roll again"},
+	{ContextPicker, ActionLogVisit, []string{"v"}, "Log a visit here"},
+	{ContextPicker, ActionOpen, []string{"enter"}, "Stop the reel, then open the restaurant"},
+	{ContextPicker, ActionEdit, []string{"e"}, "Change what to pick from"},
+	{ContextPicker, ActionBack, []string{"esc", "q"}, "Back"},
+
+	{ContextCompare, ActionDown, []string{"j", "down"}, "Next place"},
+	{ContextCompare, ActionUp, []string{"k", "up"}, "Previous place"},
+	{ContextCompare, ActionTop, []string{"g", "home"}, "First place"},
+	{ContextCompare, ActionBottom, []string{"G", "end"}, "Last place"},
+	{ContextCompare, ActionOpen, []string{"enter"}, "Open the restaurant in your list"},
+	{ContextCompare, ActionBack, []string{"esc", "q"}, "Back"},
+
+	{ContextHelp, ActionHelp, []string{"esc", "?"}, "Close help"},
+}
+
+// Context chains keys are resolved in on each screen.
+var (
+	visitsChain            = []Context{ContextVisits, ContextTable, ContextGlobal}
+	upcomingChain          = []Context{ContextUpcoming, ContextGlobal}
+	restaurantsChain       = []Context{ContextRestaurants, ContextTable, ContextGlobal}
+	wantToVisitChain       = []Context{ContextWantToVisit, ContextTable, ContextGlobal}
+	visitDetailChain       = []Context{ContextVisitDetail, ContextDetail, ContextGlobal}
+	restaurantDetailChain  = []Context{ContextRestaurantDetail, ContextDetail, ContextGlobal}
+	wantToVisitDetailChain = []Context{ContextWantToVisitDetail, ContextDetail, ContextGlobal}
+	formChain              = []Context{ContextForm}
+	// While the autocomplete dropdown is open its keys take precedence over
+	// form keys, so the two are checked for conflicts separately.
+	dropdownChain = []Context{ContextDropdown, ContextForm}
+	helpChain     = []Context{ContextHelp}
+	// The command line takes every key while it is open.
+	commandLineChain = []Context{ContextCommandLine}
+	viewPickerChain  = []Context{ContextViewPicker}
+	// The finder, like the command line, takes every key while open.
+	finderChain = []Context{ContextFinder}
+	// The visit form's calendar takes every key while open.
+	calendarChain      = []Context{ContextCalendar}
+	visitCalendarChain = []Context{ContextVisitCalendar, ContextGlobal}
+	pickerChain        = []Context{ContextPicker, ContextGlobal}
+	compareChain       = []Context{ContextCompare, ContextGlobal}
+)
+
+// keyChains lists the chains checked for conflicts. Bindings within one
+// chain must not collide; the same key may mean different things in
+// different chains.
+var keyChains = [][]Context{
+	visitsChain, upcomingChain, restaurantsChain, wantToVisitChain,
+	visitDetailChain, restaurantDetailChain, wantToVisitDetailChain,
+	formChain, {ContextDropdown}, helpChain, commandLineChain, viewPickerChain,
+	finderChain, calendarChain, visitCalendarChain, pickerChain, compareChain,
+}
+
+// KeyMap holds the active key bindings.
 type KeyMap struct {
-	Up           key.Binding
-	Down         key.Binding
-	Left         key.Binding
-	Right        key.Binding
-	Select       key.Binding
-	Back         key.Binding
-	Top          key.Binding
-	Bottom       key.Binding
-	HalfPageDown key.Binding
-	HalfPageUp   key.Binding
-	Search       key.Binding
-	Quit         key.Binding
-	Help         key.Binding
-	Add          key.Binding
-	Edit         key.Binding
-	Delete       key.Binding
-	Restaurants  key.Binding
-	Visits       key.Binding
-	LogVisit     key.Binding
-	NextColumn   key.Binding
-	PrevColumn   key.Binding
-	SortAsc      key.Binding
-	SortDesc     key.Binding
-	HideColumn   key.Binding
-	ShowColumns  key.Binding
-	FilterValue  key.Binding
-	ClearFilter  key.Binding
-	ColumnJump   key.Binding
-	Undo         key.Binding
-	Redo         key.Binding
+	bindings []KeyBinding
+	index    map[Context]map[Action]int
 }
 
 // DefaultKeyMap returns the default keybindings.
 func DefaultKeyMap() KeyMap {
-	return KeyMap{
-		Up: key.NewBinding(
-			key.WithKeys("k", "up"),
-			key.WithHelp("k/↑", "up"),
-		),
-		Down: key.NewBinding(
-			key.WithKeys("j", "down"),
-			key.WithHelp("j/↓", "down"),
-		),
-		Left: key.NewBinding(
-			key.WithKeys("h", "left"),
-			key.WithHelp("h/←", "back"),
-		),
-		Right: key.NewBinding(
-			key.WithKeys("l", "right"),
-			key.WithHelp("l/→", "open"),
-		),
-		Select: key.NewBinding(
-			key.WithKeys("enter"),
-			key.WithHelp("enter", "select"),
-		),
-		Back: key.NewBinding(
-			key.WithKeys("b", "esc"),
-			key.WithHelp("b/esc", "back"),
-		),
-		Top: key.NewBinding(
-			key.WithKeys("g"),
-			key.WithHelp("gg", "top"),
-		),
-		Bottom: key.NewBinding(
-			key.WithKeys("G"),
-			key.WithHelp("G", "bottom"),
-		),
-		HalfPageDown: key.NewBinding(
-			key.WithKeys("ctrl+d"),
-			key.WithHelp("ctrl+d", "½ page down"),
-		),
-		HalfPageUp: key.NewBinding(
-			key.WithKeys("ctrl+u"),
-			key.WithHelp("ctrl+u", "½ page up"),
-		),
-		Search: key.NewBinding(
-			key.WithKeys("/"),
-			key.WithHelp("/", "search"),
-		),
-		Quit: key.NewBinding(
-			key.WithKeys("q", "ctrl+c"),
-			key.WithHelp("q", "quit"),
-		),
-		Help: key.NewBinding(
-			key.WithKeys("?"),
-			key.WithHelp("?", "help"),
-		),
-		Add: key.NewBinding(
-			key.WithKeys("a"),
-			key.WithHelp("a", "add"),
-		),
-		Edit: key.NewBinding(
-			key.WithKeys("e"),
-			key.WithHelp("e", "edit"),
-		),
-		Delete: key.NewBinding(
-			key.WithKeys("d"),
-			key.WithHelp("d", "delete"),
-		),
-		Restaurants: key.NewBinding(
-			key.WithKeys("r"),
-			key.WithHelp("r", "restaurants"),
-		),
-		Visits: key.NewBinding(
-			key.WithKeys("v"),
-			key.WithHelp("v", "visits"),
-		),
-		LogVisit: key.NewBinding(
-			key.WithKeys("v"),
-			key.WithHelp("v", "log visit"),
-		),
-		NextColumn: key.NewBinding(
-			key.WithKeys("tab"),
-			key.WithHelp("tab", "next col"),
-		),
-		PrevColumn: key.NewBinding(
-			key.WithKeys("shift+tab"),
-			key.WithHelp("shift+tab", "prev col"),
-		),
-		SortAsc: key.NewBinding(
-			key.WithKeys("s"),
-			key.WithHelp("s", "sort asc"),
-		),
-		SortDesc: key.NewBinding(
-			key.WithKeys("S"),
-			key.WithHelp("S", "sort desc"),
-		),
-		HideColumn: key.NewBinding(
-			key.WithKeys("c"),
-			key.WithHelp("c", "hide col"),
-		),
-		ShowColumns: key.NewBinding(
-			key.WithKeys("C"),
-			key.WithHelp("C", "show cols"),
-		),
-		FilterValue: key.NewBinding(
-			key.WithKeys("n"),
-			key.WithHelp("n", "filter value"),
-		),
-		ClearFilter: key.NewBinding(
-			key.WithKeys("N"),
-			key.WithHelp("N", "clear filter"),
-		),
-		ColumnJump: key.NewBinding(
-			key.WithKeys("/"),
-			key.WithHelp("/", "jump col"),
-		),
-		Undo: key.NewBinding(
-			key.WithKeys("u"),
-			key.WithHelp("u", "undo"),
-		),
-		Redo: key.NewBinding(
-			key.WithKeys("ctrl+r"),
-			key.WithHelp("ctrl+r", "redo"),
-		),
-	}
-}
-
-// FormKeyMap defines keybindings for insert/edit mode.
-type FormKeyMap struct {
-	NextField key.Binding
-	PrevField key.Binding
-	Save      key.Binding
-	Cancel    key.Binding
-}
-
-// DefaultFormKeyMap returns the default form keybindings.
-func DefaultFormKeyMap() FormKeyMap {
-	return FormKeyMap{
-		NextField: key.NewBinding(
-			key.WithKeys("tab"),
-			key.WithHelp("tab", "next field"),
-		),
-		PrevField: key.NewBinding(
-			key.WithKeys("shift+tab"),
-			key.WithHelp("shift+tab", "prev field"),
-		),
-		Save: key.NewBinding(
-			key.WithKeys("ctrl+s"),
-			key.WithHelp("ctrl+s", "save"),
-		),
-		Cancel: key.NewBinding(
-			key.WithKeys("esc"),
-			key.WithHelp("esc", "cancel"),
-		),
+	k := KeyMap{index: make(map[Context]map[Action]int)}
+	for _, b := range defaultBindings {
+		b.Keys = append([]string(nil), b.Keys...)
+		if k.index[b.Context] == nil {
+			k.index[b.Context] = make(map[Action]int)
+		}
+		k.index[b.Context][b.Action] = len(k.bindings)
+		k.bindings = append(k.bindings, b)
+	}
+	return k
+}
+
+// LoadKeyMap returns the default keybindings overridden by the keymap file at
+// path. A missing file yields the defaults. The file has one table per
+// context, mapping action names to a key sequence or a list of them; an empty
+// list unbinds the action:
+//
+//	[table]
+//	top = ["g g", "home"]
+//	quit = []
+//
+//	[form]
+//	save = ["ctrl+s", "ctrl+x ctrl+s"]
+//
+// Conflicting bindings are reported as an error.
+func LoadKeyMap(path string) (KeyMap, error) {
+	k := DefaultKeyMap()
+	if path == "" {
+		return k, nil
+	}
+	var raw map[string]map[string]any
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return k, nil
+		}
+		return KeyMap{}, fmt.Errorf("failed to read keymap %s: %w", path, err)
+	}
+
+	contexts := make([]string, 0, len(raw))
+	for context := range raw {
+		contexts = append(contexts, context)
+	}
+	sort.Strings(contexts)
+	for _, context := range contexts {
+		actions, ok := k.index[Context(context)]
+		if !ok {
+			return KeyMap{}, fmt.Errorf("%s: unknown context [%s]", path, context)
+		}
+		for action, value := range raw[context] {
+			i, ok := actions[Action(action)]
+			if !ok {
+				return KeyMap{}, fmt.Errorf("%s: unknown action %q in [%s]", path, action, context)
+			}
+			keys, err := parseKeySequences(value)
+			if err != nil {
+				return KeyMap{}, fmt.Errorf("%s: %s.%s: %w", path, context, action, err)
+			}
+			k.bindings[i].Keys = keys
+		}
+	}
+
+	if err := k.Validate(); err != nil {
+		return KeyMap{}, fmt.Errorf("%s: conflicting key bindings:\n%w", path, err)
+	}
+	return k, nil
+}
+
+// parseKeySequences normalizes a keymap value: a sequence string or a list of
+// them.
+func parseKeySequences(value any) ([]string, error) {
+	var raw []string
+	switch v := value.(type) {
+	case string:
+		raw = []string{v}
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("keys must be strings")
+			}
+			raw = append(raw, s)
+		}
+	default:
+		return nil, fmt.Errorf("keys must be a string or a list of strings")
+	}
+
+	seen := make(map[string]bool)
+	keys := make([]string, 0, len(raw))
+	for _, s := range raw {
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			if s == " " {
+				fields = []string{"space"}
+			} else {
+				return nil, fmt.Errorf("empty key sequence")
+			}
+		}
+		seq := strings.Join(fields, " ")
+		if !seen[seq] {
+			seen[seq] = true
+			keys = append(keys, seq)
+		}
+	}
+	return keys, nil
+}
+
+// Validate reports keys bound to more than one action on the same screen,
+// and sequences that can never be typed because a shorter binding fires
+// first.
+func (k KeyMap) Validate() error {
+	type bound struct {
+		seq     string
+		binding int
+	}
+	var errs []error
+	reported := make(map[string]bool)
+	report := func(msg string) {
+		if !reported[msg] {
+			reported[msg] = true
+			errs = append(errs, errors.New(msg))
+		}
+	}
+
+	for _, chain := range keyChains {
+		var all []bound
+		for _, c := range chain {
+			for _, i := range k.index[c] {
+				for _, seq := range k.bindings[i].Keys {
+					all = append(all, bound{seq, i})
+				}
+			}
+		}
+		sort.Slice(all, func(i, j int) bool {
+			if all[i].seq != all[j].seq {
+				return all[i].seq < all[j].seq
+			}
+			return all[i].binding < all[j].binding
+		})
+		for i, a := range all {
+			for _, b := range all[i+1:] {
+				if a.binding == b.binding {
+					continue
+				}
+				an, bn := k.bindings[a.binding].Name(), k.bindings[b.binding].Name()
+				switch {
+				case a.seq == b.seq:
+					report(fmt.Sprintf("  %q is bound to both %s and %s", a.seq, an, bn))
+				case strings.HasPrefix(b.seq, a.seq+" "):
+					report(fmt.Sprintf("  %q (%s) can never be typed because %q (%s) fires first", b.seq, bn, a.seq, an))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Bindings returns every binding in help order.
+func (k KeyMap) Bindings() []KeyBinding {
+	out := make([]KeyBinding, len(k.bindings))
+	copy(out, k.bindings)
+	return out
+}
+
+// keysFor returns the sequences bound to action in the first of contexts
+// that defines it.
+func (k KeyMap) keysFor(contexts []Context, action Action) []string {
+	for _, c := range contexts {
+		if i, ok := k.index[c][action]; ok {
+			return k.bindings[i].Keys
+		}
+	}
+	return nil
+}
+
+// lookup returns the action seq is bound to in contexts, most specific
+// first. prefix reports that seq starts a longer sequence.
+func (k KeyMap) lookup(contexts []Context, seq string) (action Action, prefix bool) {
+	for _, c := range contexts {
+		for _, b := range k.bindings {
+			if b.Context != c {
+				continue
+			}
+			for _, s := range b.Keys {
+				if s == seq {
+					return b.Action, false
+				}
+				if strings.HasPrefix(s, seq+" ") {
+					prefix = true
+				}
+			}
+		}
+	}
+	return "", prefix
+}
+
+// navContexts returns the context chain for keys pressed on a screen in nav
+// mode.
+func navContexts(screen model.Screen) []Context {
+	switch screen {
+	case model.ScreenVisits:
+		return visitsChain
+	case model.ScreenUpcoming:
+		return upcomingChain
+	case model.ScreenRestaurants:
+		return restaurantsChain
+	case model.ScreenWantToVisit:
+		return wantToVisitChain
+	case model.ScreenVisitDetail:
+		return visitDetailChain
+	case model.ScreenRestaurantDetail:
+		return restaurantDetailChain
+	case model.ScreenWantToVisitDetail:
+		return wantToVisitDetailChain
+	case model.ScreenVisitCalendar:
+		return visitCalendarChain
+	case model.ScreenPicker:
+		return pickerChain
+	case model.ScreenCompare:
+		return compareChain
+	default:
+		return []Context{ContextGlobal}
+	}
+}
+
+// keyName returns the name a key press has in keymaps.
+func keyName(msg tea.KeyMsg) string {
+	if msg.Type == tea.KeySpace {
+		return "space"
+	}
+	return msg.String()
+}
+
+func sequenceString(keys []tea.KeyMsg) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = keyName(k)
+	}
+	return strings.Join(names, " ")
+}
+
+// keyLabel formats a key sequence for help text: "g g" becomes "gg" and
+// arrow keys become arrows.
+func keyLabel(seq string) string {
+	fields := strings.Fields(seq)
+	single := true
+	for i, f := range fields {
+		switch f {
+		case "up":
+			fields[i] = "↑"
+		case "down":
+			fields[i] = "↓"
+		case "left":
+			fields[i] = "←"
+		case "right":
+			fields[i] = "→"
+		}
+		if len([]rune(fields[i])) != 1 {
+			single = false
+		}
+	}
+	if single {
+		return strings.Join(fields, "")
+	}
+	return strings.Join(fields, " ")
+}
+
+// resolveKey adds a key press to the pending sequence and returns the action
+// it completes. pending reports that the key started or continued a longer
+// sequence. When a sequence goes nowhere its earlier keys are returned in
+// replay and the last key is tried on its own, as vim does.
+func (m *Model) resolveKey(msg tea.KeyMsg, contexts []Context) (action Action, pending bool, replay []tea.KeyMsg) {
+	seq := append(append([]tea.KeyMsg(nil), m.pendingKeys...), msg)
+	action, prefix := m.keys.lookup(contexts, sequenceString(seq))
+	switch {
+	case action != "":
+		m.pendingKeys = nil
+		return action, false, nil
+	case prefix:
+		m.pendingKeys = seq
+		return "", true, nil
+	}
+	m.pendingKeys = nil
+	if len(seq) == 1 {
+		return "", false, nil
 	}
+	action, pending, _ = m.resolveKey(msg, contexts)
+	return action, pending, seq[:len(seq)-1]
 }
@@ -0,0 +1,255 @@
+package ui
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Notes are written in Markdown. renderMarkdown supports the parts that make
+// sense in a terminal: headings, bullet and numbered lists, block quotes,
+// fenced code, rules, and inline emphasis, code and links. Anything else is
+// shown as written.
+
+var (
+	mdHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	mdRule     = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	mdListItem = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	mdQuote    = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	mdFence    = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// renderMarkdown renders Markdown text wrapped to width, starting from
+// NormalRowStyle.
+func renderMarkdown(src string, width int) string {
+	width = max(width, 10)
+	base := NormalRowStyle
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(src), "\r\n", "\n"), "\n")
+
+	var blocks []string
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			text := renderInline(strings.Join(paragraph, " "), base)
+			blocks = append(blocks, ansi.Wrap(text, width, ""))
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+
+		case mdFence.MatchString(line):
+			flush()
+			fence := mdFence.FindStringSubmatch(line)[1]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, mdCodeStyle(base).Render(ansi.Truncate(lines[i], width, "…")))
+			}
+			blocks = append(blocks, strings.Join(code, "\n"))
+
+		case mdHeading.MatchString(line):
+			flush()
+			m := mdHeading.FindStringSubmatch(line)
+			style := base.Foreground(ColorAccent).Bold(true)
+			if len(m[1]) == 1 {
+				style = style.Underline(true)
+			}
+			blocks = append(blocks, ansi.Wrap(renderInline(m[2], style), width, ""))
+
+		case mdRule.MatchString(line):
+			flush()
+			blocks = append(blocks, TableDividerStyle.Render(strings.Repeat("─", width)))
+
+		case mdQuote.MatchString(line):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quoted = append(quoted, mdQuote.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			text := ansi.Wrap(renderInline(strings.Join(quoted, " "), base.Italic(true)), width-2, "")
+			bar := HelpDescStyle.Render("│ ")
+			blocks = append(blocks, bar+strings.ReplaceAll(text, "\n", "\n"+bar))
+
+		case mdListItem.MatchString(line):
+			flush()
+			var items []string
+			for i < len(lines) && mdListItem.MatchString(lines[i]) {
+				m := mdListItem.FindStringSubmatch(lines[i])
+				indent := len(strings.ReplaceAll(m[1], "\t", "    ")) / 2 * 2
+				text := []string{m[3]}
+				// Indented lines continue the item.
+				for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "" &&
+					!mdListItem.MatchString(lines[i]) && startsWithSpace(lines[i]); i++ {
+					text = append(text, strings.TrimSpace(lines[i]))
+				}
+				items = append(items, renderListItem(m[2], strings.Join(text, " "), indent, width, base))
+			}
+			i--
+			blocks = append(blocks, strings.Join(items, "\n"))
+
+		default:
+			paragraph = append(paragraph, strings.TrimSpace(line))
+		}
+	}
+	flush()
+	return strings.Join(blocks, "\n\n")
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && (s[0] == ' ' || s[0] == '\t')
+}
+
+// renderListItem renders one list item with a hanging indent.
+func renderListItem(marker, text string, indent, width int, base lipgloss.Style) string {
+	bullet := "• "
+	if marker[0] >= '0' && marker[0] <= '9' {
+		bullet = marker[:len(marker)-1] + ". "
+	}
+	hang := indent + lipgloss.Width(bullet)
+	body := ansi.Wrap(renderInline(text, base), max(width-hang, 1), "")
+	body = strings.ReplaceAll(body, "\n", "\n"+strings.Repeat(" ", hang))
+	return strings.Repeat(" ", indent) + HelpKeyStyle.Render(bullet) + body
+}
+
+func mdCodeStyle(base lipgloss.Style) lipgloss.Style {
+	return base.Foreground(ColorYellow)
+}
+
+// renderInline renders emphasis, code spans and links in text, with style
+// for plain runs. Nested emphasis builds on the enclosing style, so styles
+// combine instead of resetting each other.
+func renderInline(text string, style lipgloss.Style) string {
+	var out strings.Builder
+	var run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			out.WriteString(style.Render(run.String()))
+			run.Reset()
+		}
+	}
+
+	rs := []rune(text)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		rest := string(rs[i:])
+		switch {
+		case r == '\\' && i+1 < len(rs) && (unicode.IsPunct(rs[i+1]) || unicode.IsSymbol(rs[i+1])):
+			run.WriteRune(rs[i+1])
+			i++
+			continue
+
+		case r == '`':
+			if end := indexRune(rs, i+1, '`'); end > 0 {
+				flush()
+				out.WriteString(mdCodeStyle(style).Render(string(rs[i+1 : end])))
+				i = end
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			delim := rest[:2]
+			if inner, n, ok := delimited(rs, i, delim); ok && (delim == "**" || !wordRune(rs, i-1)) {
+				flush()
+				out.WriteString(renderInline(inner, style.Bold(true)))
+				i += n - 1
+				continue
+			}
+
+		case r == '*' || r == '_':
+			if inner, n, ok := delimited(rs, i, string(r)); ok && (r == '*' || !wordRune(rs, i-1)) {
+				flush()
+				out.WriteString(renderInline(inner, style.Italic(true)))
+				i += n - 1
+				continue
+			}
+
+		case r == '[':
+			if label, url, n, ok := link(rs, i); ok {
+				flush()
+				out.WriteString(renderInline(label, style.Foreground(ColorAccent).Underline(true)))
+				if url != label {
+					out.WriteString(style.Render(" ") + HelpDescStyle.Render("("+url+")"))
+				}
+				i += n - 1
+				continue
+			}
+
+		case r == '<':
+			if end := indexRune(rs, i+1, '>'); end > 0 {
+				url := string(rs[i+1 : end])
+				if strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "mailto:") {
+					flush()
+					out.WriteString(style.Foreground(ColorAccent).Underline(true).Render(url))
+					i = end
+					continue
+				}
+			}
+		}
+		run.WriteRune(r)
+	}
+	flush()
+	return out.String()
+}
+
+// delimited finds the text between delim at rs[i] and the next closing delim.
+// It returns the text and the number of runes consumed, delimiters included.
+// Emphasis may not start or end with a space, as in CommonMark.
+func delimited(rs []rune, i int, delim string) (string, int, bool) {
+	d := len([]rune(delim))
+	start := i + d
+	if start >= len(rs) || unicode.IsSpace(rs[start]) {
+		return "", 0, false
+	}
+	for j := start + 1; j+d <= len(rs); j++ {
+		if string(rs[j:j+d]) != delim || unicode.IsSpace(rs[j-1]) {
+			continue
+		}
+		// A closing "_" must not be inside a word.
+		if delim[0] == '_' && wordRune(rs, j+d) {
+			continue
+		}
+		return string(rs[start:j]), j + d - i, true
+	}
+	return "", 0, false
+}
+
+// link parses "[label](url)" at rs[i].
+func link(rs []rune, i int) (label, url string, n int, ok bool) {
+	s := string(rs[i:])
+	closeLabel := strings.Index(s, "](")
+	if closeLabel < 0 {
+		return "", "", 0, false
+	}
+	closeURL := strings.IndexRune(s[closeLabel+2:], ')')
+	if closeURL < 0 {
+		return "", "", 0, false
+	}
+	label = s[1:closeLabel]
+	url = strings.TrimSpace(s[closeLabel+2 : closeLabel+2+closeURL])
+	if label == "" || url == "" || strings.ContainsAny(label, "[]") {
+		return "", "", 0, false
+	}
+	return label, url, len([]rune(s[:closeLabel+2+closeURL+1])), true
+}
+
+// indexRune returns the index of the first r in rs at or after from, or -1.
+func indexRune(rs []rune, from int, r rune) int {
+	for j := from; j < len(rs); j++ {
+		if rs[j] == r {
+			return j
+		}
+	}
+	return -1
+}
+
+func wordRune(rs []rune, i int) bool {
+	return i >= 0 && i < len(rs) && (unicode.IsLetter(rs[i]) || unicode.IsDigit(rs[i]))
+}
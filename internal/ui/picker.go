@@ -0,0 +1,249 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+	"toni/internal/model"
+	"toni/internal/pick"
+	"toni/internal/util"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The picker draws a restaurant from the wishlist and favourites with a
+// slot-machine reveal: names roll past, slowing down, until the pick stops
+// under the marker.
+
+const (
+	// pickerSpins is how many names roll past before the pick.
+	pickerSpins = 14
+	// pickerFirstDelay is the delay between the first names; each later one
+	// is longer by pickerSlowdown.
+	pickerFirstDelay = 40 * time.Millisecond
+	pickerSlowdown   = 1.2
+)
+
+// PickerModel is the "where should we eat?" screen.
+type PickerModel struct {
+	query string
+	pool  []pick.Entry
+	// loaded is set once pool has been loaded for query.
+	loaded bool
+	rng    *rand.Rand
+
+	// reel holds the names rolled past, ending with the pick, and frame is
+	// the one showing.
+	reel  []pick.Entry
+	frame int
+	// roll counts draws so ticks of an earlier draw are ignored.
+	roll int
+	// from is the screen the picker was opened from.
+	from model.Screen
+}
+
+// pickPoolLoadedMsg carries the restaurants a picker query draws from.
+type pickPoolLoadedMsg struct {
+	query string
+	pool  []pick.Entry
+}
+
+// pickerTickMsg rolls the reel of a draw on by one name.
+type pickerTickMsg struct {
+	roll int
+}
+
+// NewPickerModel creates a picker for query.
+func NewPickerModel(query string, from model.Screen) *PickerModel {
+	seed := uint64(time.Now().UnixNano())
+	return &PickerModel{
+		query: query,
+		rng:   rand.New(rand.NewPCG(seed, seed>>32)),
+		from:  from,
+	}
+}
+
+func loadPickPoolCmd(database *sql.DB, query string) tea.Cmd {
+	return func() tea.Msg {
+		criteria, err := pick.Parse(query)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		pool, err := pick.Pool(database, criteria, time.Now())
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		return pickPoolLoadedMsg{query: query, pool: pool}
+	}
+}
+
+// setPool stores the pool and starts a draw.
+func (m *PickerModel) setPool(msg pickPoolLoadedMsg) tea.Cmd {
+	if msg.query != m.query {
+		return nil
+	}
+	m.pool = msg.pool
+	m.loaded = true
+	return m.draw()
+}
+
+// draw picks a restaurant and starts rolling the reel towards it.
+func (m *PickerModel) draw() tea.Cmd {
+	m.roll++
+	m.reel = nil
+	m.frame = 0
+	if len(m.pool) == 0 {
+		return nil
+	}
+	picked := pick.Draw(m.pool, m.rng)
+	if len(m.pool) == 1 {
+		m.reel = []pick.Entry{picked}
+		return nil
+	}
+	for len(m.reel) < pickerSpins {
+		e := m.pool[m.rng.IntN(len(m.pool))]
+		if n := len(m.reel); n > 0 && m.reel[n-1].Candidate.RestaurantID == e.Candidate.RestaurantID {
+			continue
+		}
+		m.reel = append(m.reel, e)
+	}
+	if m.reel[len(m.reel)-1].Candidate.RestaurantID == picked.Candidate.RestaurantID {
+		m.reel = m.reel[:len(m.reel)-1]
+	}
+	m.reel = append(m.reel, picked)
+	return m.tick()
+}
+
+func (m *PickerModel) tick() tea.Cmd {
+	delay := time.Duration(float64(pickerFirstDelay) * math.Pow(pickerSlowdown, float64(m.frame)))
+	roll := m.roll
+	return tea.Tick(delay, func(time.Time) tea.Msg { return pickerTickMsg{roll: roll} })
+}
+
+// advance rolls the reel on by one name.
+func (m *PickerModel) advance(msg pickerTickMsg) tea.Cmd {
+	if msg.roll != m.roll || !m.spinning() {
+		return nil
+	}
+	m.frame++
+	if !m.spinning() {
+		return nil
+	}
+	return m.tick()
+}
+
+// stop skips to the end of the reel.
+func (m *PickerModel) stop() {
+	if len(m.reel) > 0 {
+		m.frame = len(m.reel) - 1
+	}
+}
+
+func (m *PickerModel) spinning() bool {
+	return m.frame < len(m.reel)-1
+}
+
+// picked returns the restaurant drawn once the reel has stopped.
+func (m *PickerModel) picked() (pick.Entry, bool) {
+	if len(m.reel) == 0 || m.spinning() {
+		return pick.Entry{}, false
+	}
+	return m.reel[len(m.reel)-1], true
+}
+
+// View renders the picker.
+func (m *PickerModel) View(width, height int) string {
+	shortcuts := HelpDescStyle.Render("space roll again  v log a visit here  enter details  e change  esc back")
+	header := lipgloss.NewStyle().
+		Width(width - 4).
+		Align(lipgloss.Right).
+		Render(shortcuts)
+
+	query := m.query
+	if query == "" {
+		query = "anywhere on the wishlist or among favourites"
+	}
+	sections := []string{
+		LabelStyle.Render("Where should we eat?") + "  " + HelpDescStyle.Render(query),
+	}
+
+	switch {
+	case !m.loaded:
+		sections = append(sections, HelpDescStyle.Render("Loading…"))
+	case len(m.pool) == 0:
+		sections = append(sections, HelpDescStyle.Render("Nothing matches. Press e to change what to pick from."))
+	default:
+		sections = append(sections,
+			HelpDescStyle.Render(fmt.Sprintf("Drawing from %s", countNoun(len(m.pool), "place", "places"))),
+			m.renderReel(width-8),
+		)
+		if e, ok := m.picked(); ok {
+			sections = append(sections, renderPickDetails(e))
+		}
+	}
+
+	info := PanelStyle.
+		Width(width - 4).
+		Render(strings.Join(sections, "\n\n"))
+	return lipgloss.JoinVertical(lipgloss.Left, header, info)
+}
+
+// renderReel shows the name under the marker between the names before and
+// after it on the reel.
+func (m *PickerModel) renderReel(width int) string {
+	name := func(i int) string {
+		if i < 0 || i >= len(m.reel) {
+			return ""
+		}
+		return m.reel[i].Candidate.Name
+	}
+	line := lipgloss.NewStyle().Width(width).Align(lipgloss.Center)
+	current := SelectedRowStyle.Bold(true).Padding(0, 2).Render(name(m.frame))
+	if !m.spinning() {
+		current = SuccessStyle.Bold(true).Padding(0, 2).Render("▶ " + name(m.frame) + " ◀")
+	}
+	return strings.Join([]string{
+		line.Render(HelpDescStyle.Render(name(m.frame - 1))),
+		line.Render(current),
+		line.Render(HelpDescStyle.Render(name(m.frame + 1))),
+	}, "\n")
+}
+
+// renderPickDetails describes the pick.
+func renderPickDetails(e pick.Entry) string {
+	c := e.Candidate
+	var fields []string
+	place := c.Neighborhood
+	if c.City != "" {
+		if place != "" {
+			place += ", "
+		}
+		place += c.City
+	}
+	if place != "" {
+		fields = append(fields, renderField("Where", place))
+	}
+	if c.Address != "" {
+		fields = append(fields, renderField("Address", c.Address))
+	}
+	if c.Cuisine != "" {
+		fields = append(fields, renderField("Cuisine", c.Cuisine))
+	}
+	if c.PriceRange != "" {
+		fields = append(fields, renderField("Price Range", c.PriceRange))
+	}
+	if e.DistanceKm != nil {
+		fields = append(fields, renderField("Distance", fmt.Sprintf("%.1f km", *e.DistanceKm)))
+	}
+	last := "Never"
+	if c.LastVisit != "" {
+		last = util.FormatDateHuman(c.LastVisit)
+	}
+	fields = append(fields, renderField("Last Visit", last))
+	fields = append(fields, renderField("Why", e.Reason()))
+	return strings.Join(fields, "\n")
+}
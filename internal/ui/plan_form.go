@@ -0,0 +1,329 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/util"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PlanFormModel represents the form for planning a visit or noting a
+// reservation.
+type PlanFormModel struct {
+	db             *sql.DB
+	planID         int64
+	restaurantID   int64
+	focusedField   int
+	inputs         []textinput.Model
+	restaurantName string
+	error          string
+	// longNotes holds notes from the editor that the notes input can't.
+	longNotes string
+	dateOrder util.DateOrder
+	// plannedOn is the day of the plan being edited, which may stay in the
+	// past; any other day must be today or later.
+	plannedOn string
+	// calendar is the open date picker, nil when closed.
+	calendar *calendar
+}
+
+// NewPlanFormModel creates a new plan form.
+func NewPlanFormModel(database *sql.DB, dateOrder util.DateOrder, restaurantID int64) *PlanFormModel {
+	inputs := make([]textinput.Model, 5)
+
+	// Restaurant name
+	inputs[0] = textinput.New()
+	inputs[0].Placeholder = "Restaurant name"
+	inputs[0].Focus()
+	inputs[0].CharLimit = 100
+
+	// Date
+	inputs[1] = textinput.New()
+	inputs[1].Placeholder = "friday, tomorrow, next sat, oct 24"
+	if dateOrder == util.DayFirst {
+		inputs[1].Placeholder = "friday, tomorrow, next sat, 24/10"
+	}
+	inputs[1].CharLimit = 32
+
+	// Time
+	inputs[2] = textinput.New()
+	inputs[2].Placeholder = "19:30, 7:30pm (optional)"
+	inputs[2].CharLimit = 10
+
+	// Party size
+	inputs[3] = textinput.New()
+	inputs[3].Placeholder = "2 (optional)"
+	inputs[3].CharLimit = 3
+
+	// Notes
+	inputs[4] = textinput.New()
+	inputs[4].Placeholder = "Confirmation number, who's coming..."
+	inputs[4].CharLimit = 500
+
+	m := &PlanFormModel{
+		db:           database,
+		restaurantID: restaurantID,
+		focusedField: 0,
+		inputs:       inputs,
+		dateOrder:    dateOrder,
+	}
+
+	// If restaurant ID is provided, load the name
+	if restaurantID > 0 {
+		restaurant, err := db.GetRestaurant(database, restaurantID)
+		if err == nil {
+			m.restaurantName = restaurant.Name
+			m.inputs[0].SetValue(restaurant.Name)
+			m.focusedField = 1
+			m.inputs[0].Blur()
+			m.inputs[1].Focus()
+		}
+	}
+
+	return m
+}
+
+// LoadPlan loads an existing planned visit for editing.
+func (m *PlanFormModel) LoadPlan(plan model.PlannedVisit) {
+	m.planID = plan.ID
+	m.restaurantID = plan.RestaurantID
+	m.plannedOn = plan.PlannedOn
+
+	restaurant, err := db.GetRestaurant(m.db, plan.RestaurantID)
+	if err == nil {
+		m.restaurantName = restaurant.Name
+		m.inputs[0].SetValue(restaurant.Name)
+	}
+
+	m.inputs[1].SetValue(plan.PlannedOn)
+	if t, err := time.Parse("2006-01-02", plan.PlannedOn); err == nil {
+		m.inputs[1].SetValue(t.Format("January 2, 2006"))
+	}
+	m.inputs[2].SetValue(plan.PlannedAt)
+	if plan.PartySize != nil {
+		m.inputs[3].SetValue(strconv.Itoa(*plan.PartySize))
+	}
+	setNotes(&m.inputs[4], &m.longNotes, plan.Notes)
+}
+
+// Update handles input.
+func (m PlanFormModel) Update(msg formKeyMsg) (PlanFormModel, tea.Cmd) {
+	if m.calendar != nil {
+		return m.updateCalendar(msg.action), nil
+	}
+
+	switch msg.action {
+	case ActionCancel:
+		return m, func() tea.Msg {
+			return model.FormCancelledMsg{}
+		}
+	case ActionSave:
+		cmd := m.save()
+		return m, cmd
+	case ActionEditNotes:
+		return m, editNotesCmd(notesValue(m.inputs[4], m.longNotes), notesForm, 0)
+	case ActionCalendar:
+		m.openCalendar()
+		return m, nil
+	case ActionNextField:
+		m.nextField()
+		return m, nil
+	case ActionPrevField:
+		m.prevField()
+		return m, nil
+	}
+
+	// Notes from the editor can only be changed in the editor.
+	if m.focusedField == 4 && m.longNotes != "" {
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focusedField], cmd = m.inputs[m.focusedField].Update(msg.KeyMsg)
+
+	// A changed name no longer refers to the restaurant picked before.
+	if m.focusedField == 0 && strings.TrimSpace(m.inputs[0].Value()) != m.restaurantName {
+		m.restaurantID = 0
+	}
+	return m, cmd
+}
+
+// View renders the form.
+func (m *PlanFormModel) View(width, height int) string {
+	var fields []string
+
+	fields = append(fields, renderFormField("Restaurant *", m.inputs[0], m.focusedField == 0))
+	dateField := renderFormField("Date * (ctrl+t for calendar)", m.inputs[1], m.focusedField == 1)
+	if m.calendar != nil {
+		dateField = lipgloss.JoinVertical(lipgloss.Left, dateField, m.calendar.View())
+	}
+	fields = append(fields, dateField)
+	fields = append(fields, renderFormField("Time", m.inputs[2], m.focusedField == 2))
+	fields = append(fields, renderFormField("Party Size", m.inputs[3], m.focusedField == 3))
+	fields = append(fields, renderNotesField("Notes", m.inputs[4], m.longNotes, m.focusedField == 4))
+
+	if m.error != "" {
+		fields = append(fields, "")
+		fields = append(fields, ErrorStyle.Render(m.error))
+	}
+
+	return PanelStyle.
+		Width(width - 4).
+		Height(height - 4).
+		Render(strings.Join(fields, "\n\n"))
+}
+
+func (m *PlanFormModel) nextField() {
+	m.inputs[m.focusedField].Blur()
+	m.focusedField = (m.focusedField + 1) % len(m.inputs)
+	m.inputs[m.focusedField].Focus()
+}
+
+func (m *PlanFormModel) prevField() {
+	m.inputs[m.focusedField].Blur()
+	m.focusedField--
+	if m.focusedField < 0 {
+		m.focusedField = len(m.inputs) - 1
+	}
+	m.inputs[m.focusedField].Focus()
+}
+
+// openCalendar opens the date picker on the date typed so far, or today.
+func (m *PlanFormModel) openCalendar() {
+	m.inputs[m.focusedField].Blur()
+	m.focusedField = 1
+	m.inputs[1].Focus()
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	selected := today
+	if t, err := util.ParseUpcomingDate(m.inputs[1].Value(), now, m.dateOrder); err == nil {
+		selected = t
+	}
+	m.calendar = newCalendar(selected, today, m.dateOrder)
+	m.calendar.upcoming = true
+}
+
+// updateCalendar handles a key while the date picker is open.
+func (m PlanFormModel) updateCalendar(action Action) PlanFormModel {
+	switch action {
+	case ActionSelect:
+		m.inputs[1].SetValue(m.calendar.cursor.Format("January 2, 2006"))
+		m.calendar = nil
+		m.error = ""
+	case ActionDismiss:
+		m.calendar = nil
+	default:
+		m.calendar.handle(action)
+	}
+	return m
+}
+
+// parse reads the date, time and party size fields.
+func (m *PlanFormModel) parse() (date, at string, party *int, err error) {
+	input := strings.TrimSpace(m.inputs[1].Value())
+	if input == "" {
+		return "", "", nil, fmt.Errorf("date is required")
+	}
+	day, err := util.ParseUpcomingDate(input, time.Now(), m.dateOrder)
+	if err != nil {
+		return "", "", nil, err
+	}
+	date = day.Format("2006-01-02")
+	if date < util.TodayISO() && date != m.plannedOn {
+		return "", "", nil, fmt.Errorf("%s has passed; log it as a visit instead", util.FormatDate(date))
+	}
+
+	if input := strings.TrimSpace(m.inputs[2].Value()); input != "" {
+		if at, err = util.ParseTimeOfDay(input); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	if input := strings.TrimSpace(m.inputs[3].Value()); input != "" {
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 1 {
+			return "", "", nil, fmt.Errorf("party size must be a positive number")
+		}
+		party = &n
+	}
+	return date, at, party, nil
+}
+
+func (m *PlanFormModel) save() tea.Cmd {
+	date, at, party, err := m.parse()
+	if err != nil {
+		m.error = err.Error()
+		return nil
+	}
+	m.error = ""
+
+	return func() tea.Msg {
+		restaurantName := strings.TrimSpace(m.inputs[0].Value())
+		if restaurantName == "" {
+			return model.ErrorMsg{Err: fmt.Errorf("restaurant name is required")}
+		}
+
+		// Find or create restaurant
+		restaurantID := m.restaurantID
+		if restaurantID == 0 {
+			restaurants, err := db.SearchRestaurants(m.db, restaurantName)
+			if err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			if existingID, ok := findExactRestaurantID(restaurants, restaurantName); ok {
+				restaurantID = existingID
+			} else {
+				id, err := db.InsertRestaurant(m.db, model.NewRestaurant{Name: restaurantName})
+				if err != nil {
+					return model.ErrorMsg{Err: err}
+				}
+				restaurantID = id
+			}
+		}
+
+		notes := notesValue(m.inputs[4], m.longNotes)
+		after := model.PlannedVisit{
+			ID:           m.planID,
+			RestaurantID: restaurantID,
+			PlannedOn:    date,
+			PlannedAt:    at,
+			PartySize:    party,
+			Notes:        notes,
+		}
+
+		if m.planID > 0 {
+			before, err := db.GetPlannedVisit(m.db, m.planID)
+			if err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			err = db.UpdatePlannedVisit(m.db, planToUpdate(after))
+			if err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			after.CreatedAt = before.CreatedAt
+			return model.PlannedVisitSavedMsg{ID: m.planID, Operation: "update", Before: &before, After: after}
+		}
+
+		id, err := db.InsertPlannedVisit(m.db, model.NewPlannedVisit{
+			RestaurantID: restaurantID,
+			PlannedOn:    date,
+			PlannedAt:    at,
+			PartySize:    party,
+			Notes:        notes,
+		})
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		after.ID = id
+		return model.PlannedVisitSavedMsg{ID: id, Operation: "insert", After: after}
+	}
+}
@@ -15,18 +15,30 @@ type TablePrefs struct {
 	ActiveColumn  string   `json:"active_column"`
 }
 
+// SavedView is a named filter query and table layout for one list screen.
+type SavedView struct {
+	Name  string `json:"name"`
+	Query string `json:"query,omitempty"`
+	TablePrefs
+}
+
 // UIPreferences stores persisted app preferences.
 type UIPreferences struct {
 	Visits      TablePrefs `json:"visits"`
 	Restaurants TablePrefs `json:"restaurants"`
 	WantToVisit TablePrefs `json:"want_to_visit"`
+	// Views holds the saved views of each list screen, keyed by screen name
+	// ("visits", "restaurants", "want_to_visit"), in the order they are
+	// numbered.
+	Views map[string][]SavedView `json:"views,omitempty"`
 }
 
 func defaultUIPreferences() UIPreferences {
 	return UIPreferences{}
 }
 
-func prefsPath() (string, error) {
+// DefaultPrefsPath returns the preferences file used when none is configured.
+func DefaultPrefsPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", fmt.Errorf("failed to get home dir: %w", err)
@@ -34,9 +46,8 @@ func prefsPath() (string, error) {
 	return filepath.Join(home, ".toni", "ui_prefs.json"), nil
 }
 
-func loadUIPreferences() UIPreferences {
-	path, err := prefsPath()
-	if err != nil {
+func loadUIPreferences(path string) UIPreferences {
+	if path == "" {
 		return defaultUIPreferences()
 	}
 
@@ -52,10 +63,9 @@ func loadUIPreferences() UIPreferences {
 	return prefs
 }
 
-func saveUIPreferences(prefs UIPreferences) error {
-	path, err := prefsPath()
-	if err != nil {
-		return err
+func saveUIPreferences(path string, prefs UIPreferences) error {
+	if path == "" {
+		return nil
 	}
 
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
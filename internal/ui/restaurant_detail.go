@@ -26,7 +26,7 @@ func (m *RestaurantDetailModel) View(width, height int) string {
 	r := m.detail.Restaurant
 
 	// Keyboard shortcuts
-	shortcuts := HelpDescStyle.Render("v add visit  e edit  d delete  h back")
+	shortcuts := HelpDescStyle.Render("v add visit  P plan  e edit  d delete  h back")
 	header := lipgloss.NewStyle().
 		Width(width - 4).
 		Align(lipgloss.Right).
@@ -44,6 +44,9 @@ func (m *RestaurantDetailModel) View(width, height int) string {
 	fields = append(fields, renderField("Neighborhood", r.Neighborhood))
 	fields = append(fields, renderField("Cuisine", r.Cuisine))
 	fields = append(fields, renderField("Price Range", r.PriceRange))
+	if len(m.detail.Tags) > 0 {
+		fields = append(fields, renderField("Tags", strings.Join(m.detail.Tags, ", ")))
+	}
 
 	// Visit count summary
 	visitCountText := fmt.Sprintf("Visited %d times", len(m.detail.Visits))
@@ -77,15 +80,21 @@ func (m *RestaurantDetailModel) View(width, height int) string {
 	return lipgloss.JoinVertical(lipgloss.Left, header, info)
 }
 
-// renderVisitsTimeline renders visits as a compact inline timeline
+// renderVisitsTimeline renders the latest visits one per line, each with
+// its notes beneath it
 func (m *RestaurantDetailModel) renderVisitsTimeline(width int) string {
+	const notesIndent = 4
 	var entries []string
 
 	for _, v := range m.detail.Visits {
 		date := util.FormatDateHuman(v.VisitedOn)
 		rating := util.FormatRating(v.Rating)
 
-		entry := fmt.Sprintf("%s → %s", date, rating)
+		entry := NormalRowStyle.Render(fmt.Sprintf("%s → %s", date, rating))
+		if v.Notes != "" {
+			notes := renderMarkdown(v.Notes, width-8-notesIndent)
+			entry += "\n" + lipgloss.NewStyle().PaddingLeft(notesIndent).Render(notes)
+		}
 		entries = append(entries, entry)
 
 		// Show max 5 in timeline, rest in table
@@ -94,14 +103,14 @@ func (m *RestaurantDetailModel) renderVisitsTimeline(width int) string {
 		}
 	}
 
-	timeline := strings.Join(entries, "  ·  ")
+	timeline := strings.Join(entries, "\n")
 
 	// If more than 5 visits, show full table
 	if len(m.detail.Visits) > 5 {
 		timeline += "\n\n" + m.renderVisitsTable(width)
 	}
 
-	return NormalRowStyle.Render(timeline)
+	return timeline
 }
 
 func (m *RestaurantDetailModel) renderVisitsTable(width int) string {
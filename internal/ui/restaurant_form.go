@@ -75,25 +75,25 @@ func (m *RestaurantFormModel) LoadRestaurant(restaurant model.Restaurant) {
 }
 
 // Update handles input.
-func (m RestaurantFormModel) Update(msg tea.KeyMsg) (RestaurantFormModel, tea.Cmd) {
-	switch msg.String() {
-	case "esc":
+func (m RestaurantFormModel) Update(msg formKeyMsg) (RestaurantFormModel, tea.Cmd) {
+	switch msg.action {
+	case ActionCancel:
 		return m, func() tea.Msg {
 			return model.FormCancelledMsg{}
 		}
-	case "ctrl+s":
+	case ActionSave:
 		return m, m.save()
-	case "tab":
+	case ActionNextField:
 		m.nextField()
 		return m, nil
-	case "shift+tab":
+	case ActionPrevField:
 		m.prevField()
 		return m, nil
 	}
 
 	// Update current input
 	var cmd tea.Cmd
-	m.inputs[m.focusedField], cmd = m.inputs[m.focusedField].Update(msg)
+	m.inputs[m.focusedField], cmd = m.inputs[m.focusedField].Update(msg.KeyMsg)
 	return m, cmd
 }
 
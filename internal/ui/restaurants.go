@@ -2,7 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"sort"
+	"strconv"
 	"strings"
 	"toni/internal/model"
 	"toni/internal/util"
@@ -32,6 +34,12 @@ type RestaurantsModel struct {
 	sortDesc     bool
 	filterKey    string
 	filterValue  string
+	// query is the filter query the rows were loaded with.
+	query string
+	// view is the saved view last applied, if any.
+	view string
+	// sel holds the rows marked for bulk actions.
+	sel rowSelection
 }
 
 // NewRestaurantsModel creates a new restaurants model.
@@ -93,6 +101,7 @@ func (m *RestaurantsModel) Prefs() TablePrefs {
 }
 
 func (m *RestaurantsModel) rebuild() {
+	m.sel.commitVisual(m.rowIDs(), m.cursor)
 	rows := append([]model.RestaurantRow(nil), m.allRows...)
 
 	if m.filterKey != "" && m.filterValue != "" {
@@ -169,6 +178,95 @@ func (m *RestaurantsModel) getValue(row model.RestaurantRow, key string) string
 	}
 }
 
+// ColumnKeys returns the keys of every column, hidden or not.
+func (m *RestaurantsModel) ColumnKeys() []string {
+	keys := make([]string, len(m.columns))
+	for i, c := range m.columns {
+		keys[i] = c.key
+	}
+	return keys
+}
+
+func (m *RestaurantsModel) columnIndex(key string) int {
+	for i, c := range m.columns {
+		if c.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// SortByColumn sorts by the named column. An empty key clears sorting.
+func (m *RestaurantsModel) SortByColumn(key string, desc bool) bool {
+	if key != "" && m.columnIndex(key) < 0 {
+		return false
+	}
+	m.sortKey = key
+	m.sortDesc = desc && key != ""
+	m.rebuild()
+	return true
+}
+
+// SetColumnHidden hides or shows the named column. The last visible column
+// cannot be hidden.
+func (m *RestaurantsModel) SetColumnHidden(key string, hidden bool) bool {
+	i := m.columnIndex(key)
+	if i < 0 {
+		return false
+	}
+	if hidden && !m.columns[i].hidden && len(m.visibleColumnIndexes()) <= 1 {
+		return false
+	}
+	m.columns[i].hidden = hidden
+	m.ensureVisibleActiveColumn()
+	return true
+}
+
+// ColumnValues returns the distinct non-empty values of a column.
+func (m *RestaurantsModel) ColumnValues(key string) []string {
+	values := make([]string, 0, len(m.allRows))
+	for _, r := range m.allRows {
+		values = append(values, m.cellText(r, key))
+	}
+	return distinctValues(values)
+}
+
+// WriteCSV writes the rows and columns currently shown as CSV, or only the
+// selected rows if any are.
+func (m *RestaurantsModel) WriteCSV(w io.Writer) error {
+	visible := m.visibleColumnIndexes()
+	header := make([]string, len(visible))
+	for i, idx := range visible {
+		header[i] = m.columns[idx].key
+	}
+	records := [][]string{header}
+	rows := m.markedRows()
+	if len(rows) == 0 {
+		rows = m.rows
+	}
+	for _, r := range rows {
+		record := make([]string, len(visible))
+		for i, idx := range visible {
+			record[i] = m.cellText(r, m.columns[idx].key)
+		}
+		records = append(records, record)
+	}
+	return writeCSV(w, records)
+}
+
+// cellText returns a cell as plain text for export and completion.
+func (m *RestaurantsModel) cellText(row model.RestaurantRow, key string) string {
+	switch key {
+	case "rating":
+		if row.AvgRating != nil {
+			return strconv.FormatFloat(*row.AvgRating, 'f', 1, 64)
+		}
+	case "visits":
+		return strconv.Itoa(row.VisitCount)
+	}
+	return m.getValue(row, key)
+}
+
 func (m *RestaurantsModel) visibleColumnIndexes() []int {
 	var idxs []int
 	for i, c := range m.columns {
@@ -321,6 +419,9 @@ func (m *RestaurantsModel) CycleFilterBySelectedValue() string {
 func (m *RestaurantsModel) TableMeta() string {
 	col := strings.ToUpper(m.columns[m.activeColumn].label)
 	parts := []string{fmt.Sprintf("col %s", col)}
+	if m.view != "" {
+		parts = append([]string{"view " + m.view}, parts...)
+	}
 	if m.sortKey != "" {
 		order := "asc"
 		if m.sortDesc {
@@ -328,6 +429,9 @@ func (m *RestaurantsModel) TableMeta() string {
 		}
 		parts = append(parts, fmt.Sprintf("sort %s %s", strings.ToUpper(m.sortKey), order))
 	}
+	if m.query != "" {
+		parts = append(parts, "query "+m.query)
+	}
 	if m.filterKey != "" {
 		parts = append(parts, fmt.Sprintf("filter %s=%q", strings.ToUpper(m.filterKey), m.filterValue))
 	}
@@ -399,6 +503,9 @@ func (m *RestaurantsModel) View(width, height int) string {
 	for i := m.offset; i < len(m.rows) && i < m.offset+visibleHeight; i++ {
 		row := m.rows[i]
 		style := NormalRowStyle
+		if m.sel.isSelected(i, row.ID, m.cursor) {
+			style = MarkedRowStyle
+		}
 		if i == m.cursor {
 			style = SelectedRowStyle
 		}
@@ -458,7 +565,7 @@ func (m *RestaurantsModel) View(width, height int) string {
 	if len(m.rows) > 0 {
 		rowPos = fmt.Sprintf("  ·  row %d/%d", m.cursor+1, len(m.rows))
 	}
-	status := StatusBarStyle.Render(fmt.Sprintf("%d restaurants%s%s%s%s", len(m.rows), rowPos, overallAvg, filterInfo, meta))
+	status := StatusBarStyle.Render(fmt.Sprintf("%d restaurants%s%s%s%s%s", len(m.rows), rowPos, m.sel.status(m.rowIDs(), m.cursor), overallAvg, filterInfo, meta))
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -550,3 +657,57 @@ func (m *RestaurantsModel) HalfPageUp(pageSize int) {
 		m.offset = m.cursor
 	}
 }
+
+func (m *RestaurantsModel) rowIDs() []int64 {
+	ids := make([]int64, len(m.rows))
+	for i, r := range m.rows {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+// ToggleMark marks or unmarks the row under the cursor and moves down.
+func (m *RestaurantsModel) ToggleMark() {
+	if len(m.rows) == 0 {
+		return
+	}
+	m.sel.toggle(m.rows[m.cursor].ID)
+	m.MoveDown()
+}
+
+// ToggleVisual starts or ends visual mode. It reports whether visual mode is
+// now on.
+func (m *RestaurantsModel) ToggleVisual() bool {
+	return m.sel.toggleVisual(m.rowIDs(), m.cursor)
+}
+
+// ClearSelection unmarks every row. It reports whether any were selected.
+func (m *RestaurantsModel) ClearSelection() bool {
+	return m.sel.clear()
+}
+
+// SelectionCount returns the number of rows selected.
+func (m *RestaurantsModel) SelectionCount() int {
+	return len(m.sel.indexes(m.rowIDs(), m.cursor))
+}
+
+// markedRows returns the selected rows in list order.
+func (m *RestaurantsModel) markedRows() []model.RestaurantRow {
+	var out []model.RestaurantRow
+	for _, i := range m.sel.indexes(m.rowIDs(), m.cursor) {
+		out = append(out, m.rows[i])
+	}
+	return out
+}
+
+// selectedRows returns the rows a bulk action applies to: the selected rows,
+// or the row under the cursor when none are.
+func (m *RestaurantsModel) selectedRows() []model.RestaurantRow {
+	if rows := m.markedRows(); len(rows) > 0 {
+		return rows
+	}
+	if m.cursor < len(m.rows) {
+		return []model.RestaurantRow{m.rows[m.cursor]}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package ui
+
+import "fmt"
+
+// rowSelection tracks the rows of a list marked for a bulk action. Rows are
+// marked by ID so marks survive sorting and filtering. In visual mode the
+// rows between anchor and the cursor are selected too, as in vim's linewise
+// visual mode.
+type rowSelection struct {
+	marked map[int64]bool
+	visual bool
+	anchor int
+}
+
+// toggle marks or unmarks a row.
+func (s *rowSelection) toggle(id int64) {
+	if s.marked == nil {
+		s.marked = make(map[int64]bool)
+	}
+	if s.marked[id] {
+		delete(s.marked, id)
+	} else {
+		s.marked[id] = true
+	}
+}
+
+// toggleVisual starts visual mode at the cursor, or marks the visual range
+// and leaves it. It reports whether visual mode is now on.
+func (s *rowSelection) toggleVisual(ids []int64, cursor int) bool {
+	if s.visual {
+		s.commitVisual(ids, cursor)
+		return false
+	}
+	if len(ids) == 0 {
+		return false
+	}
+	s.visual = true
+	s.anchor = cursor
+	return true
+}
+
+// commitVisual marks the rows of the visual range and leaves visual mode.
+// Lists call it before reordering their rows, since the range is kept by
+// position.
+func (s *rowSelection) commitVisual(ids []int64, cursor int) {
+	if !s.visual {
+		return
+	}
+	if s.marked == nil {
+		s.marked = make(map[int64]bool)
+	}
+	for i, id := range ids {
+		if s.inRange(i, cursor) {
+			s.marked[id] = true
+		}
+	}
+	s.visual = false
+}
+
+func (s *rowSelection) inRange(i, cursor int) bool {
+	return s.visual && i >= min(s.anchor, cursor) && i <= max(s.anchor, cursor)
+}
+
+// isSelected reports whether the row at index i is marked or in the visual
+// range.
+func (s *rowSelection) isSelected(i int, id int64, cursor int) bool {
+	return s.marked[id] || s.inRange(i, cursor)
+}
+
+// indexes returns the positions of the selected rows among ids.
+func (s *rowSelection) indexes(ids []int64, cursor int) []int {
+	var out []int
+	for i, id := range ids {
+		if s.isSelected(i, id, cursor) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// clear unmarks every row and leaves visual mode. It reports whether
+// anything was selected.
+func (s *rowSelection) clear() bool {
+	had := s.visual || len(s.marked) > 0
+	s.marked = nil
+	s.visual = false
+	return had
+}
+
+// status describes the selection for the status line, or returns "" when
+// nothing is selected.
+func (s *rowSelection) status(ids []int64, cursor int) string {
+	n := len(s.indexes(ids, cursor))
+	switch {
+	case s.visual:
+		return fmt.Sprintf("  ·  VISUAL %d selected", n)
+	case n > 0:
+		return fmt.Sprintf("  ·  %d selected", n)
+	}
+	return ""
+}
@@ -2,118 +2,158 @@ package ui
 
 import "github.com/charmbracelet/lipgloss"
 
-// Color palette
+// Color palette, set by ApplyTheme. Until a theme is applied the "auto"
+// palette is used.
 var (
-	ColorBase       = lipgloss.AdaptiveColor{Light: "#F4F6F2", Dark: "#151A14"}
-	ColorSurface    = lipgloss.AdaptiveColor{Light: "#E8EDE5", Dark: "#1E251D"}
-	ColorSurfaceAlt = lipgloss.AdaptiveColor{Light: "#DCE4D7", Dark: "#273026"}
-	ColorMuted      = lipgloss.AdaptiveColor{Light: "#6E7B65", Dark: "#A8B3A2"}
-	ColorText       = lipgloss.AdaptiveColor{Light: "#243024", Dark: "#E8ECE5"}
-	ColorAccent     = lipgloss.AdaptiveColor{Light: "#8FA082", Dark: "#A5B69A"}
-	ColorOnAccent   = lipgloss.AdaptiveColor{Light: "#1B2818", Dark: "#102015"}
-	ColorGreen      = lipgloss.AdaptiveColor{Light: "#7B9372", Dark: "#97B089"}
-	ColorRed        = lipgloss.AdaptiveColor{Light: "#B8695D", Dark: "#D28A7D"}
-	ColorYellow     = lipgloss.AdaptiveColor{Light: "#A4935D", Dark: "#CFC08A"}
+	ColorBase       lipgloss.TerminalColor
+	ColorSurface    lipgloss.TerminalColor
+	ColorSurfaceAlt lipgloss.TerminalColor
+	ColorMuted      lipgloss.TerminalColor
+	ColorText       lipgloss.TerminalColor
+	ColorAccent     lipgloss.TerminalColor
+	ColorOnAccent   lipgloss.TerminalColor
+	ColorGreen      lipgloss.TerminalColor
+	ColorRed        lipgloss.TerminalColor
+	ColorYellow     lipgloss.TerminalColor
 )
 
-// Styles
+// Styles, rebuilt from the palette by ApplyTheme.
 var (
+	BaseStyle             lipgloss.Style
+	HeaderStyle           lipgloss.Style
+	TitleStyle            lipgloss.Style
+	HeaderBoxStyle        lipgloss.Style
+	TableHeaderStyle      lipgloss.Style
+	TableSeparatorStyle   lipgloss.Style
+	TableDividerStyle     lipgloss.Style
+	SelectedRowStyle      lipgloss.Style
+	MarkedRowStyle        lipgloss.Style
+	NormalRowStyle        lipgloss.Style
+	FooterStyle           lipgloss.Style
+	HelpKeyStyle          lipgloss.Style
+	HelpDescStyle         lipgloss.Style
+	ErrorStyle            lipgloss.Style
+	SuccessStyle          lipgloss.Style
+	LabelStyle            lipgloss.Style
+	InputStyle            lipgloss.Style
+	BorderStyle           lipgloss.Style
+	ActiveBorderStyle     lipgloss.Style
+	PanelStyle            lipgloss.Style
+	BreadcrumbStyle       lipgloss.Style
+	BreadcrumbActiveStyle lipgloss.Style
+	EmptyStateStyle       lipgloss.Style
+	StatusBarStyle        lipgloss.Style
+)
+
+func init() {
+	applyPalette(autoPalette)
+	buildStyles()
+}
+
+// buildStyles derives every style from the current palette.
+func buildStyles() {
 	BaseStyle = lipgloss.NewStyle().
-			Foreground(ColorText).
-			Background(ColorBase)
+		Foreground(ColorText).
+		Background(ColorBase)
 
 	HeaderStyle = lipgloss.NewStyle().
-			Foreground(ColorAccent).
-			Bold(true).
-			Padding(0, 1)
+		Foreground(ColorAccent).
+		Bold(true).
+		Padding(0, 1)
 
 	TitleStyle = lipgloss.NewStyle().
-			Foreground(ColorAccent).
-			Bold(true).
-			Padding(0, 1)
+		Foreground(ColorAccent).
+		Bold(true).
+		Padding(0, 1)
 
 	HeaderBoxStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(ColorMuted)
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(ColorMuted)
 
 	TableHeaderStyle = lipgloss.NewStyle().
-				Foreground(ColorText).
-				Bold(true).
-				Padding(0, 1)
+		Foreground(ColorText).
+		Bold(true).
+		Padding(0, 1)
 
 	TableSeparatorStyle = lipgloss.NewStyle().
-				Foreground(ColorMuted).
-				Faint(true)
+		Foreground(ColorMuted).
+		Faint(true)
 
 	TableDividerStyle = lipgloss.NewStyle().
-				Foreground(ColorMuted).
-				Faint(true)
+		Foreground(ColorMuted).
+		Faint(true)
 
 	SelectedRowStyle = lipgloss.NewStyle().
-				Foreground(ColorOnAccent).
-				Background(ColorAccent).
-				Bold(true)
+		Foreground(ColorOnAccent).
+		Background(ColorAccent).
+		Bold(true)
+
+	// MarkedRowStyle shows rows selected for a bulk action.
+	MarkedRowStyle = lipgloss.NewStyle().
+		Foreground(ColorText).
+		Background(ColorSurfaceAlt).
+		Bold(true)
 
 	NormalRowStyle = lipgloss.NewStyle().
-			Foreground(ColorText)
+		Foreground(ColorText)
 
 	FooterStyle = lipgloss.NewStyle().
-			Foreground(ColorMuted).
-			Padding(0, 1).
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderTop(true).
-			BorderForeground(ColorMuted)
+		Foreground(ColorMuted).
+		Padding(0, 1).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderTop(true).
+		BorderForeground(ColorMuted)
 
 	HelpKeyStyle = lipgloss.NewStyle().
-			Foreground(ColorAccent)
+		Foreground(ColorAccent)
 
 	HelpDescStyle = lipgloss.NewStyle().
-			Foreground(ColorMuted)
+		Foreground(ColorMuted)
 
 	ErrorStyle = lipgloss.NewStyle().
-			Foreground(ColorRed).
-			Padding(0, 1)
+		Foreground(ColorRed).
+		Padding(0, 1)
 
 	SuccessStyle = lipgloss.NewStyle().
-			Foreground(ColorGreen).
-			Padding(0, 1)
+		Foreground(ColorGreen).
+		Padding(0, 1)
 
 	LabelStyle = lipgloss.NewStyle().
-			Foreground(ColorAccent).
-			Bold(true)
+		Foreground(ColorAccent).
+		Bold(true)
 
 	InputStyle = lipgloss.NewStyle().
-			Foreground(ColorText).
-			Background(ColorSurface).
-			Padding(0, 1)
+		Foreground(ColorText).
+		Background(ColorSurface).
+		Padding(0, 1)
 
 	BorderStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(ColorMuted).
-			Padding(1, 2)
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(ColorMuted).
+		Padding(1, 2)
 
 	ActiveBorderStyle = lipgloss.NewStyle().
-				BorderStyle(lipgloss.NormalBorder()).
-				BorderForeground(ColorAccent).
-				Padding(1, 2)
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(ColorAccent).
+		Padding(1, 2)
 
 	PanelStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(ColorMuted).
-			Padding(1, 2)
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(ColorMuted).
+		Padding(1, 2)
 
 	BreadcrumbStyle = lipgloss.NewStyle().
-			Foreground(ColorMuted)
+		Foreground(ColorMuted)
 
 	BreadcrumbActiveStyle = lipgloss.NewStyle().
-				Foreground(ColorAccent)
+		Foreground(ColorAccent)
 
 	EmptyStateStyle = lipgloss.NewStyle().
-			Foreground(ColorMuted).
-			Italic(true).
-			Padding(2, 4)
+		Foreground(ColorMuted).
+		Italic(true).
+		Padding(2, 4)
 
 	StatusBarStyle = lipgloss.NewStyle().
-			Foreground(ColorMuted).
-			Padding(0, 1)
-)
+		Foreground(ColorMuted).
+		Padding(0, 1)
+}
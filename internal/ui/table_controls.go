@@ -1,5 +1,12 @@
 package ui
 
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+)
+
 type tableController interface {
 	NextColumn()
 	PrevColumn()
@@ -12,4 +19,42 @@ type tableController interface {
 	FilterBySelectedValue() bool
 	ClearFilter() bool
 	TableMeta() string
+	Prefs() TablePrefs
+	ApplyPrefs(prefs TablePrefs)
+
+	// Column operations by key, used by the command line.
+	ColumnKeys() []string
+	SortByColumn(key string, desc bool) bool
+	SetColumnHidden(key string, hidden bool) bool
+	ColumnValues(key string) []string
+	WriteCSV(w io.Writer) error
+
+	// Row selection for bulk actions.
+	ToggleMark()
+	ToggleVisual() bool
+	ClearSelection() bool
+	SelectionCount() int
+}
+
+// distinctValues returns the non-empty values sorted, without duplicates
+// (ignoring case).
+func distinctValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return strings.ToLower(out[i]) < strings.ToLower(out[j])
+	})
+	return out
+}
+
+func writeCSV(w io.Writer, records [][]string) error {
+	return csv.NewWriter(w).WriteAll(records)
 }
@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"toni/internal/model"
+	"toni/internal/recommend"
+	"toni/internal/search"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tasteLoadedMsg carries a taste model trained on the latest visits.
+type tasteLoadedMsg struct {
+	taste *recommend.Model
+}
+
+func loadTasteCmd(database *sql.DB) tea.Cmd {
+	return func() tea.Msg {
+		taste, err := recommend.Load(database)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		return tasteLoadedMsg{taste: taste}
+	}
+}
+
+// applyTaste hands a new taste model to the screens that predict ratings.
+func (m *Model) applyTaste(taste *recommend.Model) {
+	m.taste = taste
+	if m.wantToVisit != nil {
+		m.wantToVisit.setTaste(taste)
+	}
+	if m.visitForm != nil {
+		m.visitForm.taste = taste
+	}
+	if m.wantToVisitForm != nil {
+		m.wantToVisitForm.taste = taste
+	}
+}
+
+func suggestionTraits(s search.Suggestion) recommend.Traits {
+	return recommend.Traits{Cuisine: s.Cuisine, PriceRange: s.PriceRange, Neighborhood: s.Neighborhood}
+}
+
+// rankSuggestions orders search results by predicted rating, best first.
+// Results the model can't predict keep their order after the others.
+func rankSuggestions(results []search.Suggestion, taste *recommend.Model) {
+	if !taste.Ready() {
+		return
+	}
+	score := func(s search.Suggestion) float64 {
+		if p, ok := taste.Predict(suggestionTraits(s)); ok {
+			return p.Rating
+		}
+		return 0
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return score(results[i]) > score(results[j])
+	})
+}
+
+// suggestionDetail is the right-hand side of a search result line: its
+// cuisine and, when the taste model can tell, the predicted rating and the
+// factors behind it.
+func suggestionDetail(s search.Suggestion, taste *recommend.Model) string {
+	detail := HelpDescStyle.Render(s.Cuisine)
+	p, ok := taste.Predict(suggestionTraits(s))
+	if !ok {
+		return detail
+	}
+	predicted := lipgloss.NewStyle().Foreground(ColorYellow).Render(fmt.Sprintf("%.1f ★", p.Rating))
+	if why := p.Summary(2); why != "" {
+		predicted += HelpDescStyle.Render(" (" + why + ")")
+	}
+	if s.Cuisine == "" {
+		return predicted
+	}
+	return detail + "  " + predicted
+}
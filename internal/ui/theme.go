@@ -0,0 +1,431 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Palette is the set of colours every style is built from.
+type Palette struct {
+	Base       lipgloss.TerminalColor
+	Surface    lipgloss.TerminalColor
+	SurfaceAlt lipgloss.TerminalColor
+	Muted      lipgloss.TerminalColor
+	Text       lipgloss.TerminalColor
+	Accent     lipgloss.TerminalColor
+	OnAccent   lipgloss.TerminalColor
+	// Green marks good things: high ratings, "would return".
+	Green lipgloss.TerminalColor
+	// Red marks bad things: low ratings, errors.
+	Red lipgloss.TerminalColor
+	// Yellow highlights ratings and priorities.
+	Yellow lipgloss.TerminalColor
+}
+
+// StyleOverride changes parts of a named style. Colours are hex ("#A5B69A"),
+// ANSI numbers ("11") or palette names ("accent").
+type StyleOverride struct {
+	Foreground string `toml:"foreground"`
+	Background string `toml:"background"`
+	Border     string `toml:"border"`
+	Bold       *bool  `toml:"bold"`
+	Italic     *bool  `toml:"italic"`
+	Faint      *bool  `toml:"faint"`
+	Underline  *bool  `toml:"underline"`
+	Reverse    *bool  `toml:"reverse"`
+}
+
+// Theme is a palette plus overrides for individual styles.
+type Theme struct {
+	Name    string
+	Palette Palette
+	Styles  map[string]StyleOverride
+	// NoColor turns colour output off entirely.
+	NoColor bool
+}
+
+// Built-in theme names.
+const (
+	ThemeAuto         = "auto"
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeNone         = "none"
+)
+
+// autoPalette adapts to the terminal background.
+var autoPalette = Palette{
+	Base:       lipgloss.AdaptiveColor{Light: "#F4F6F2", Dark: "#151A14"},
+	Surface:    lipgloss.AdaptiveColor{Light: "#E8EDE5", Dark: "#1E251D"},
+	SurfaceAlt: lipgloss.AdaptiveColor{Light: "#DCE4D7", Dark: "#273026"},
+	Muted:      lipgloss.AdaptiveColor{Light: "#6E7B65", Dark: "#A8B3A2"},
+	Text:       lipgloss.AdaptiveColor{Light: "#243024", Dark: "#E8ECE5"},
+	Accent:     lipgloss.AdaptiveColor{Light: "#8FA082", Dark: "#A5B69A"},
+	OnAccent:   lipgloss.AdaptiveColor{Light: "#1B2818", Dark: "#102015"},
+	Green:      lipgloss.AdaptiveColor{Light: "#7B9372", Dark: "#97B089"},
+	Red:        lipgloss.AdaptiveColor{Light: "#B8695D", Dark: "#D28A7D"},
+	Yellow:     lipgloss.AdaptiveColor{Light: "#A4935D", Dark: "#CFC08A"},
+}
+
+// highContrastPalette uses black, white and the Okabe-Ito colours, which
+// stay distinguishable with the common kinds of colour blindness: good and
+// bad are blue and orange rather than green and red.
+var highContrastPalette = Palette{
+	Base:       lipgloss.AdaptiveColor{Light: "#FFFFFF", Dark: "#000000"},
+	Surface:    lipgloss.AdaptiveColor{Light: "#FFFFFF", Dark: "#000000"},
+	SurfaceAlt: lipgloss.AdaptiveColor{Light: "#E0E0E0", Dark: "#202020"},
+	Muted:      lipgloss.AdaptiveColor{Light: "#303030", Dark: "#D0D0D0"},
+	Text:       lipgloss.AdaptiveColor{Light: "#000000", Dark: "#FFFFFF"},
+	Accent:     lipgloss.AdaptiveColor{Light: "#0072B2", Dark: "#F0E442"},
+	OnAccent:   lipgloss.AdaptiveColor{Light: "#FFFFFF", Dark: "#000000"},
+	Green:      lipgloss.AdaptiveColor{Light: "#0072B2", Dark: "#56B4E9"},
+	Red:        lipgloss.AdaptiveColor{Light: "#D55E00", Dark: "#E69F00"},
+	Yellow:     lipgloss.AdaptiveColor{Light: "#000000", Dark: "#F0E442"},
+}
+
+// noColorPalette leaves every colour to the terminal.
+var noColorPalette = Palette{
+	Base: lipgloss.NoColor{}, Surface: lipgloss.NoColor{}, SurfaceAlt: lipgloss.NoColor{},
+	Muted: lipgloss.NoColor{}, Text: lipgloss.NoColor{}, Accent: lipgloss.NoColor{},
+	OnAccent: lipgloss.NoColor{}, Green: lipgloss.NoColor{}, Red: lipgloss.NoColor{},
+	Yellow: lipgloss.NoColor{},
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// BuiltinTheme returns a built-in theme by name.
+func BuiltinTheme(name string) (Theme, bool) {
+	switch name {
+	case ThemeAuto, "":
+		return Theme{Name: ThemeAuto, Palette: autoPalette}, true
+	case ThemeDark:
+		return Theme{Name: ThemeDark, Palette: fixedPalette(autoPalette, true)}, true
+	case ThemeLight:
+		return Theme{Name: ThemeLight, Palette: fixedPalette(autoPalette, false)}, true
+	case ThemeHighContrast:
+		return Theme{Name: ThemeHighContrast, Palette: highContrastPalette, Styles: map[string]StyleOverride{
+			"selected_row": {Underline: boolPtr(true)},
+			"help_key":     {Bold: boolPtr(true)},
+		}}, true
+	case ThemeNone:
+		// Without colour the selected row is shown in reverse video and
+		// marked rows are underlined.
+		return Theme{Name: ThemeNone, Palette: noColorPalette, NoColor: true, Styles: map[string]StyleOverride{
+			"selected_row":    {Reverse: boolPtr(true)},
+			"marked_row":      {Underline: boolPtr(true)},
+			"table_separator": {Faint: boolPtr(false)},
+			"table_divider":   {Faint: boolPtr(false)},
+		}}, true
+	}
+	return Theme{}, false
+}
+
+// fixedPalette picks one side of every adaptive colour.
+func fixedPalette(p Palette, dark bool) Palette {
+	pick := func(c lipgloss.TerminalColor) lipgloss.TerminalColor {
+		if a, ok := c.(lipgloss.AdaptiveColor); ok {
+			if dark {
+				return lipgloss.Color(a.Dark)
+			}
+			return lipgloss.Color(a.Light)
+		}
+		return c
+	}
+	return Palette{
+		Base: pick(p.Base), Surface: pick(p.Surface), SurfaceAlt: pick(p.SurfaceAlt),
+		Muted: pick(p.Muted), Text: pick(p.Text), Accent: pick(p.Accent),
+		OnAccent: pick(p.OnAccent), Green: pick(p.Green), Red: pick(p.Red),
+		Yellow: pick(p.Yellow),
+	}
+}
+
+// themeFile is the on-disk form of a theme:
+//
+//	extends = "dark"
+//
+//	[colors]
+//	accent = "#E69F00"
+//
+//	[styles.selected_row]
+//	background = "accent"
+//	bold = true
+type themeFile struct {
+	Extends string                   `toml:"extends"`
+	Colors  map[string]string        `toml:"colors"`
+	Styles  map[string]StyleOverride `toml:"styles"`
+}
+
+// LoadTheme returns the named theme: a built-in, a file <name>.toml in dir,
+// or a path to a .toml file. An empty name means "auto".
+func LoadTheme(name, dir string) (Theme, error) {
+	if t, ok := BuiltinTheme(name); ok {
+		return t, nil
+	}
+	path := name
+	if !strings.ContainsRune(name, filepath.Separator) && !strings.HasSuffix(name, ".toml") {
+		path = filepath.Join(dir, name+".toml")
+	}
+	return loadThemeFile(path, nil)
+}
+
+func loadThemeFile(path string, seen map[string]bool) (Theme, error) {
+	var file themeFile
+	md, err := toml.DecodeFile(path, &file)
+	if errors.Is(err, os.ErrNotExist) {
+		return Theme{}, fmt.Errorf("unknown theme %q (built-in themes: %s; no file %s)",
+			strings.TrimSuffix(filepath.Base(path), ".toml"), strings.Join(BuiltinThemeNames(), ", "), path)
+	}
+	if err != nil {
+		return Theme{}, fmt.Errorf("failed to read theme %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return Theme{}, fmt.Errorf("%s: unknown key %s", path, undecoded[0])
+	}
+
+	base, ok := BuiltinTheme(file.Extends)
+	if !ok {
+		// Themes may extend other theme files in the same directory.
+		if seen == nil {
+			seen = make(map[string]bool)
+		}
+		seen[path] = true
+		parent := filepath.Join(filepath.Dir(path), file.Extends+".toml")
+		if seen[parent] {
+			return Theme{}, fmt.Errorf("%s: theme %q extends itself", path, file.Extends)
+		}
+		if base, err = loadThemeFile(parent, seen); err != nil {
+			return Theme{}, err
+		}
+	}
+
+	t := Theme{
+		Name:    strings.TrimSuffix(filepath.Base(path), ".toml"),
+		Palette: base.Palette,
+		Styles:  make(map[string]StyleOverride),
+		NoColor: base.NoColor,
+	}
+	for name, o := range base.Styles {
+		t.Styles[name] = o
+	}
+
+	// Literal colours are set first so palette references such as
+	// green = "accent" see the theme's own values.
+	slots := t.Palette.slots()
+	names := sortedKeys(file.Colors)
+	for _, name := range names {
+		if _, ok := slots[name]; !ok {
+			return Theme{}, fmt.Errorf("%s: unknown colour %q (colours: %s)", path, name, strings.Join(paletteNames(), ", "))
+		}
+	}
+	for _, references := range []bool{false, true} {
+		for _, name := range names {
+			value := strings.TrimSpace(file.Colors[name])
+			if _, isRef := slots[value]; isRef != references {
+				continue
+			}
+			c, err := t.Palette.parseColor(value)
+			if err != nil {
+				return Theme{}, fmt.Errorf("%s: colors.%s: %w", path, name, err)
+			}
+			if c != nil {
+				*slots[name] = c
+			}
+		}
+	}
+
+	styles := styleRegistry()
+	for name, o := range file.Styles {
+		if _, ok := styles[name]; !ok {
+			return Theme{}, fmt.Errorf("%s: unknown style %q (styles: %s)", path, name, strings.Join(StyleNames(), ", "))
+		}
+		for _, c := range []string{o.Foreground, o.Background, o.Border} {
+			if _, err := t.Palette.parseColor(c); err != nil {
+				return Theme{}, fmt.Errorf("%s: styles.%s: %w", path, name, err)
+			}
+		}
+		t.Styles[name] = mergeOverride(t.Styles[name], o)
+	}
+	return t, nil
+}
+
+// ApplyTheme makes t the active theme.
+func ApplyTheme(t Theme) {
+	if t.NoColor {
+		disableColor()
+	}
+	applyPalette(t.Palette)
+	buildStyles()
+	styles := styleRegistry()
+	for name, o := range t.Styles {
+		if style, ok := styles[name]; ok {
+			*style = t.Palette.override(*style, o)
+		}
+	}
+}
+
+// disableColor turns off colour in output that does not come from the
+// palette, such as rendered images.
+func disableColor() {
+	colorDisabled = true
+}
+
+// colorDisabled is set when colour output is off. The terminal's colour
+// profile is left alone so bold, underline and reverse video still work.
+var colorDisabled bool
+
+func applyPalette(p Palette) {
+	ColorBase = p.Base
+	ColorSurface = p.Surface
+	ColorSurfaceAlt = p.SurfaceAlt
+	ColorMuted = p.Muted
+	ColorText = p.Text
+	ColorAccent = p.Accent
+	ColorOnAccent = p.OnAccent
+	ColorGreen = p.Green
+	ColorRed = p.Red
+	ColorYellow = p.Yellow
+}
+
+// slots maps colour names used in theme files to the palette's fields.
+func (p *Palette) slots() map[string]*lipgloss.TerminalColor {
+	return map[string]*lipgloss.TerminalColor{
+		"base": &p.Base, "surface": &p.Surface, "surface_alt": &p.SurfaceAlt,
+		"muted": &p.Muted, "text": &p.Text, "accent": &p.Accent,
+		"on_accent": &p.OnAccent, "green": &p.Green, "red": &p.Red,
+		"yellow": &p.Yellow,
+	}
+}
+
+func paletteNames() []string {
+	return sortedKeys((&Palette{}).slots())
+}
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+// parseColor accepts a hex colour, an ANSI colour number or a palette name.
+// The empty string means "unchanged" and yields nil.
+func (p Palette) parseColor(s string) (lipgloss.TerminalColor, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if slot, ok := p.slots()[s]; ok {
+		return *slot, nil
+	}
+	if hexColorPattern.MatchString(s) {
+		return lipgloss.Color(s), nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(s), nil
+	}
+	return nil, fmt.Errorf("invalid colour %q (want #RRGGBB, 0-255 or a palette name)", s)
+}
+
+func (p Palette) override(style lipgloss.Style, o StyleOverride) lipgloss.Style {
+	if c, _ := p.parseColor(o.Foreground); c != nil {
+		style = style.Foreground(c)
+	}
+	if c, _ := p.parseColor(o.Background); c != nil {
+		style = style.Background(c)
+	}
+	if c, _ := p.parseColor(o.Border); c != nil {
+		style = style.BorderForeground(c)
+	}
+	if o.Bold != nil {
+		style = style.Bold(*o.Bold)
+	}
+	if o.Italic != nil {
+		style = style.Italic(*o.Italic)
+	}
+	if o.Faint != nil {
+		style = style.Faint(*o.Faint)
+	}
+	if o.Underline != nil {
+		style = style.Underline(*o.Underline)
+	}
+	if o.Reverse != nil {
+		style = style.Reverse(*o.Reverse)
+	}
+	return style
+}
+
+// mergeOverride applies o on top of base, keeping base's settings for
+// anything o leaves unset.
+func mergeOverride(base, o StyleOverride) StyleOverride {
+	if o.Foreground != "" {
+		base.Foreground = o.Foreground
+	}
+	if o.Background != "" {
+		base.Background = o.Background
+	}
+	if o.Border != "" {
+		base.Border = o.Border
+	}
+	for _, f := range []struct{ dst, src **bool }{
+		{&base.Bold, &o.Bold}, {&base.Italic, &o.Italic}, {&base.Faint, &o.Faint},
+		{&base.Underline, &o.Underline}, {&base.Reverse, &o.Reverse},
+	} {
+		if *f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+	return base
+}
+
+// styleRegistry maps style names used in theme files to the style variables.
+func styleRegistry() map[string]*lipgloss.Style {
+	return map[string]*lipgloss.Style{
+		"base":              &BaseStyle,
+		"header":            &HeaderStyle,
+		"title":             &TitleStyle,
+		"header_box":        &HeaderBoxStyle,
+		"table_header":      &TableHeaderStyle,
+		"table_separator":   &TableSeparatorStyle,
+		"table_divider":     &TableDividerStyle,
+		"selected_row":      &SelectedRowStyle,
+		"marked_row":        &MarkedRowStyle,
+		"normal_row":        &NormalRowStyle,
+		"footer":            &FooterStyle,
+		"help_key":          &HelpKeyStyle,
+		"help_desc":         &HelpDescStyle,
+		"error":             &ErrorStyle,
+		"success":           &SuccessStyle,
+		"label":             &LabelStyle,
+		"input":             &InputStyle,
+		"border":            &BorderStyle,
+		"active_border":     &ActiveBorderStyle,
+		"panel":             &PanelStyle,
+		"breadcrumb":        &BreadcrumbStyle,
+		"breadcrumb_active": &BreadcrumbActiveStyle,
+		"empty_state":       &EmptyStateStyle,
+		"status_bar":        &StatusBarStyle,
+	}
+}
+
+// StyleNames lists the styles a theme file can override.
+func StyleNames() []string {
+	return sortedKeys(styleRegistry())
+}
+
+// BuiltinThemeNames lists the built-in themes.
+func BuiltinThemeNames() []string {
+	return []string{ThemeAuto, ThemeDark, ThemeLight, ThemeHighContrast, ThemeNone}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -53,6 +53,22 @@ func (m *Model) buildVisitSaveAction(msg model.VisitSavedMsg) *undoAction {
 	switch msg.Operation {
 	case "insert":
 		after := msg.After
+		if msg.Plan != nil || len(msg.WantToVisit) > 0 {
+			logged := model.LoggedVisit{Visit: after, Plan: msg.Plan, WantToVisit: msg.WantToVisit}
+			label := "visit logged from plan"
+			if msg.Plan == nil {
+				label = "want_to_visit marked visited"
+			}
+			return &undoAction{
+				label: label,
+				undo: func() error {
+					return db.UnlogVisit(m.db, logged)
+				},
+				redo: func() error {
+					return db.RelogVisit(m.db, logged)
+				},
+			}
+		}
 		return &undoAction{
 			label: "visit saved",
 			undo: func() error {
@@ -148,6 +164,52 @@ func (m *Model) buildWantToVisitSaveAction(msg model.WantToVisitSavedMsg) *undoA
 	}
 }
 
+func (m *Model) buildPlanSaveAction(msg model.PlannedVisitSavedMsg) *undoAction {
+	switch msg.Operation {
+	case "insert":
+		after := msg.After
+		return &undoAction{
+			label: "plan saved",
+			undo: func() error {
+				return db.DeletePlannedVisit(m.db, after.ID)
+			},
+			redo: func() error {
+				return db.InsertPlannedVisitWithID(m.db, after)
+			},
+		}
+	case "update":
+		if msg.Before == nil {
+			return nil
+		}
+		before := *msg.Before
+		after := msg.After
+		return &undoAction{
+			label: "plan updated",
+			undo: func() error {
+				return db.UpdatePlannedVisit(m.db, planToUpdate(before))
+			},
+			redo: func() error {
+				return db.UpdatePlannedVisit(m.db, planToUpdate(after))
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+func (m *Model) buildDeletePlanAction(msg model.DeletePlannedVisitMsg) undoAction {
+	deleted := msg.Deleted
+	return undoAction{
+		label: "plan deleted",
+		undo: func() error {
+			return db.InsertPlannedVisitWithID(m.db, deleted)
+		},
+		redo: func() error {
+			return db.DeletePlannedVisit(m.db, deleted.ID)
+		},
+	}
+}
+
 func (m *Model) buildDeleteVisitAction(msg model.DeleteVisitMsg) undoAction {
 	deleted := msg.Deleted
 	return undoAction{
@@ -175,56 +237,34 @@ func (m *Model) buildDeleteWantToVisitAction(msg model.DeleteWantToVisitMsg) und
 }
 
 func (m *Model) buildDeleteRestaurantAction(msg model.DeleteRestaurantMsg) undoAction {
-	deleted := msg.Deleted
-	visits := append([]model.Visit(nil), msg.DeletedVisits...)
-	entries := append([]model.WantToVisit(nil), msg.DeletedWantToVisit...)
-	return undoAction{
-		label: "restaurant deleted",
-		undo: func() error {
-			if err := db.InsertRestaurantWithID(m.db, deleted); err != nil {
-				return err
-			}
-			for _, e := range entries {
-				if err := db.InsertWantToVisitWithID(m.db, e); err != nil {
-					return err
-				}
-			}
-			for _, v := range visits {
-				if err := db.InsertVisitWithID(m.db, v); err != nil {
-					return err
-				}
-			}
-			return nil
-		},
-		redo: func() error {
-			return db.DeleteRestaurant(m.db, deleted.ID)
-		},
+	snapshot := model.RestaurantSnapshot{
+		Restaurant:    msg.Deleted,
+		Visits:        append([]model.Visit(nil), msg.DeletedVisits...),
+		WantToVisit:   append([]model.WantToVisit(nil), msg.DeletedWantToVisit...),
+		PlannedVisits: append([]model.PlannedVisit(nil), msg.DeletedPlannedVisits...),
+		Tags:          append([]string(nil), msg.DeletedTags...),
 	}
-}
-
-func (m *Model) buildConvertAction(msg model.ConvertToVisitMsg) undoAction {
-	deleted := msg.Deleted
 	return undoAction{
-		label: "converted want_to_visit",
+		label: "restaurant deleted",
 		undo: func() error {
-			return db.InsertWantToVisitWithID(m.db, deleted)
+			return db.RestoreRestaurants(m.db, []model.RestaurantSnapshot{snapshot})
 		},
 		redo: func() error {
-			return db.DeleteWantToVisit(m.db, deleted.ID)
+			return db.DeleteRestaurant(m.db, snapshot.Restaurant.ID)
 		},
 	}
 }
 
 func (m *Model) reloadCurrentTopLevelCmd() tea.Cmd {
 	switch m.screen {
-	case model.ScreenVisits, model.ScreenVisitDetail, model.ScreenVisitForm:
-		return loadVisitsCmd(m.db, "")
+	case model.ScreenVisits, model.ScreenVisitDetail, model.ScreenVisitForm, model.ScreenUpcoming, model.ScreenPlanForm:
+		return loadVisitsCmd(m.db, m.queries[model.ScreenVisits])
 	case model.ScreenRestaurants, model.ScreenRestaurantDetail, model.ScreenRestaurantForm:
-		return loadRestaurantsCmd(m.db, "")
+		return loadRestaurantsCmd(m.db, m.queries[model.ScreenRestaurants])
 	case model.ScreenWantToVisit, model.ScreenWantToVisitDetail, model.ScreenWantToVisitForm:
-		return loadWantToVisitCmd(m.db)
+		return loadWantToVisitCmd(m.db, m.queries[model.ScreenWantToVisit])
 	default:
-		return loadVisitsCmd(m.db, "")
+		return loadVisitsCmd(m.db, m.queries[model.ScreenVisits])
 	}
 }
 
@@ -239,6 +279,17 @@ func visitToUpdate(v model.Visit) model.UpdateVisit {
 	}
 }
 
+func planToUpdate(p model.PlannedVisit) model.UpdatePlannedVisit {
+	return model.UpdatePlannedVisit{
+		ID:           p.ID,
+		RestaurantID: p.RestaurantID,
+		PlannedOn:    p.PlannedOn,
+		PlannedAt:    p.PlannedAt,
+		PartySize:    p.PartySize,
+		Notes:        p.Notes,
+	}
+}
+
 func restaurantToUpdate(r model.Restaurant) model.UpdateRestaurant {
 	return model.UpdateRestaurant{
 		ID:           r.ID,
@@ -277,5 +328,5 @@ func (m *Model) applyUndoResult(msg undoAppliedMsg) tea.Cmd {
 		m.info = "Redid: " + msg.action.label
 	}
 	m.error = ""
-	return m.reloadCurrentTopLevelCmd()
+	return tea.Batch(m.reloadCurrentTopLevelCmd(), m.reloadDetailCmd(), loadPlannedVisitsCmd(m.db), loadTasteCmd(m.db))
 }
@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"toni/internal/model"
+	"toni/internal/util"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxUpcomingRows is how many planned visits the Upcoming section shows at
+// once; the rest scroll into view as the cursor reaches them.
+const maxUpcomingRows = 5
+
+// UpcomingModel is the Upcoming section of the Visits screen: planned visits
+// and reservations, soonest first. Plans whose day has come stay listed,
+// highlighted, until they are logged as visits or deleted.
+type UpcomingModel struct {
+	plans  []model.PlannedVisitRow
+	cursor int
+	offset int
+}
+
+// NewUpcomingModel creates the Upcoming section.
+func NewUpcomingModel(plans []model.PlannedVisitRow) *UpcomingModel {
+	return &UpcomingModel{plans: plans}
+}
+
+// setPlans replaces the plans, keeping the cursor where it was when it can.
+func (m *UpcomingModel) setPlans(plans []model.PlannedVisitRow) {
+	m.plans = plans
+	m.move(0)
+}
+
+func (m *UpcomingModel) move(delta int) {
+	m.cursor = max(0, min(m.cursor+delta, len(m.plans)-1))
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+maxUpcomingRows {
+		m.offset = m.cursor - maxUpcomingRows + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.plans)-maxUpcomingRows))
+}
+
+// selected returns the plan under the cursor.
+func (m *UpcomingModel) selected() (model.PlannedVisitRow, bool) {
+	if m.cursor < len(m.plans) {
+		return m.plans[m.cursor], true
+	}
+	return model.PlannedVisitRow{}, false
+}
+
+// firstDue returns the earliest plan whose day has come.
+func (m *UpcomingModel) firstDue(today string) (model.PlannedVisitRow, bool) {
+	if len(m.plans) > 0 && planDue(m.plans[0], today) {
+		return m.plans[0], true
+	}
+	return model.PlannedVisitRow{}, false
+}
+
+// planDue reports whether p's day has come, so it can be logged as a visit.
+func planDue(p model.PlannedVisitRow, today string) bool {
+	return p.PlannedOn <= today
+}
+
+// View renders the section, or nothing when there are no plans. The cursor
+// is shown only while the section has focus.
+func (m *UpcomingModel) View(width int, focused bool) string {
+	if len(m.plans) == 0 {
+		return ""
+	}
+	today := util.TodayISO()
+	due := 0
+	for _, p := range m.plans {
+		if planDue(p, today) {
+			due++
+		}
+	}
+
+	title := LabelStyle.Render(fmt.Sprintf("Upcoming (%d)", len(m.plans)))
+	hint := "U browse  L log due plan  P plan a visit"
+	if focused {
+		hint = "v log visit  a add  e edit  d delete  esc back"
+	}
+	if due > 0 {
+		title += "  " + lipgloss.NewStyle().Foreground(ColorYellow).Render(fmt.Sprintf("%d due to log", due))
+	}
+	header := title + "  " + HelpDescStyle.Render(hint)
+
+	lineWidth := max(20, width-4)
+	nameWidth := max(12, min(32, lineWidth-60))
+	lines := []string{header}
+	for i := m.offset; i < len(m.plans) && i < m.offset+maxUpcomingRows; i++ {
+		p := m.plans[i]
+		party := ""
+		if p.PartySize != nil {
+			party = fmt.Sprintf("party of %d", *p.PartySize)
+		}
+		line := fmt.Sprintf("%-16s  %-*s  %-14s  %-11s  %s",
+			formatPlanWhen(p, today),
+			nameWidth, util.TruncateString(p.RestaurantName, nameWidth),
+			util.TruncateString(p.City, 14),
+			party,
+			util.SingleLine(p.Notes))
+		line = util.TruncateString(line, lineWidth)
+
+		style := NormalRowStyle
+		if planDue(p, today) {
+			style = style.Foreground(ColorYellow)
+		}
+		if focused && i == m.cursor {
+			style = SelectedRowStyle
+		}
+		lines = append(lines, style.Width(lineWidth).Render(line))
+	}
+	if more := len(m.plans) - m.offset - maxUpcomingRows; more > 0 {
+		lines = append(lines, HelpDescStyle.Render(fmt.Sprintf("  … %d more", more)))
+	}
+
+	return lipgloss.NewStyle().
+		Padding(0, 1).
+		BorderBottom(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(ColorMuted).
+		Width(width).
+		Render(strings.Join(lines, "\n"))
+}
+
+// formatPlanWhen formats when a plan is, relative to today: "Today 19:30",
+// "Tomorrow", "Fri Oct 24 19:30", or "Oct 17 (past)" for plans left to log.
+func formatPlanWhen(p model.PlannedVisitRow, today string) string {
+	day, err := time.Parse("2006-01-02", p.PlannedOn)
+	if err != nil {
+		return p.PlannedOn
+	}
+	now, _ := time.Parse("2006-01-02", today)
+	var when string
+	switch days := int(day.Sub(now).Hours() / 24); {
+	case days == 0:
+		when = "Today"
+	case days == 1:
+		when = "Tomorrow"
+	case days < 0:
+		return day.Format("Jan 02") + " (past)"
+	case day.Year() == now.Year():
+		when = day.Format("Mon Jan 02")
+	default:
+		when = day.Format("Jan 02 '06")
+	}
+	if p.PlannedAt != "" {
+		when += " " + p.PlannedAt
+	}
+	return when
+}
@@ -0,0 +1,488 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"toni/internal/config"
+	"toni/internal/model"
+	"toni/internal/util"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// viewScreenKeys names the list screens in the prefs file and in exported
+// view files.
+var viewScreenKeys = map[model.Screen]string{
+	model.ScreenVisits:      "visits",
+	model.ScreenRestaurants: "restaurants",
+	model.ScreenWantToVisit: "want_to_visit",
+}
+
+// maxNumberedViews is how many views the number keys reach; 0 is the
+// default view.
+const maxNumberedViews = 9
+
+// viewPicker lists the saved views of the current screen. Row 0 is the
+// default view and row i is view i.
+type viewPicker struct {
+	cursor int
+}
+
+// screenViews returns the saved views of the current screen.
+func (m *Model) screenViews() []SavedView {
+	return m.prefs.Views[viewScreenKeys[m.screen]]
+}
+
+// setScreenViews replaces the saved views of the current screen and saves
+// the prefs file.
+func (m *Model) setScreenViews(views []SavedView) error {
+	key := viewScreenKeys[m.screen]
+	if len(views) == 0 {
+		delete(m.prefs.Views, key)
+	} else {
+		if m.prefs.Views == nil {
+			m.prefs.Views = make(map[string][]SavedView)
+		}
+		m.prefs.Views[key] = views
+	}
+	return saveUIPreferences(m.prefsPath, m.prefs)
+}
+
+// findView returns the index of the view called name, ignoring case, or -1.
+func findView(views []SavedView, name string) int {
+	for i, v := range views {
+		if strings.EqualFold(v.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyView reloads the current list with a saved view's query and layout.
+func (m *Model) applyView(v SavedView) tea.Cmd {
+	switch m.screen {
+	case model.ScreenVisits:
+		m.prefs.Visits = v.TablePrefs
+	case model.ScreenRestaurants:
+		m.prefs.Restaurants = v.TablePrefs
+	case model.ScreenWantToVisit:
+		m.prefs.WantToVisit = v.TablePrefs
+	}
+	if err := saveUIPreferences(m.prefsPath, m.prefs); err != nil {
+		m.error = err.Error()
+	}
+	m.queries[m.screen] = v.Query
+	m.views[m.screen] = v.Name
+	if v.Name == "" {
+		m.info = "Default view"
+	} else {
+		m.info = "View: " + v.Name
+	}
+	return m.reloadCurrentTable()
+}
+
+// applyViewNumber applies view n of the current screen; 0 is the default
+// view, with no query, sorting or hidden columns.
+func (m *Model) applyViewNumber(n int) tea.Cmd {
+	if n == 0 {
+		return m.applyView(SavedView{})
+	}
+	views := m.screenViews()
+	if n > len(views) {
+		m.info = fmt.Sprintf("No view %d (:view save name to add one)", n)
+		return nil
+	}
+	return m.applyView(views[n-1])
+}
+
+// setActiveView updates the view name the current table shows.
+func (m *Model) setActiveView(name string) {
+	m.views[m.screen] = name
+	switch m.screen {
+	case model.ScreenVisits:
+		if m.visits != nil {
+			m.visits.view = name
+		}
+	case model.ScreenRestaurants:
+		if m.restaurants != nil {
+			m.restaurants.view = name
+		}
+	case model.ScreenWantToVisit:
+		if m.wantToVisit != nil {
+			m.wantToVisit.view = name
+		}
+	}
+}
+
+// viewNumber returns the digit a key press types.
+func viewNumber(msg tea.KeyMsg) (int, bool) {
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(string(msg.Runes))
+	if err != nil || n > maxNumberedViews {
+		return 0, false
+	}
+	return n, true
+}
+
+// handleViewNumber applies the numbered view for an unbound digit key on a
+// list screen.
+func (m Model) handleViewNumber(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	n, ok := viewNumber(msg)
+	if !ok || m.currentTable() == nil {
+		return m, nil
+	}
+	return m, m.applyViewNumber(n)
+}
+
+// openViewPicker shows the saved views of the current screen.
+func (m Model) openViewPicker() (tea.Model, tea.Cmd) {
+	p := &viewPicker{}
+	if i := findView(m.screenViews(), m.views[m.screen]); i >= 0 {
+		p.cursor = i + 1
+	}
+	m.viewPicker = p
+	m.info = ""
+	return m, nil
+}
+
+// handleViewPicker handles key presses while the view picker is open.
+func (m Model) handleViewPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	action, pending, _ := m.resolveKey(msg, viewPickerChain)
+	if pending {
+		return m, nil
+	}
+	p := m.viewPicker
+	views := m.screenViews()
+
+	switch action {
+	case ActionDown:
+		if p.cursor < len(views) {
+			p.cursor++
+		}
+	case ActionUp:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case ActionSelect:
+		m.viewPicker = nil
+		return m, m.applyViewNumber(p.cursor)
+	case ActionDelete:
+		if p.cursor == 0 {
+			return m, nil
+		}
+		name := views[p.cursor-1].Name
+		if err := m.deleteView(name); err != nil {
+			m.error = err.Error()
+			return m, nil
+		}
+		if p.cursor > len(m.screenViews()) {
+			p.cursor--
+		}
+	case ActionDismiss:
+		m.viewPicker = nil
+	default:
+		if n, ok := viewNumber(msg); ok {
+			m.viewPicker = nil
+			return m, m.applyViewNumber(n)
+		}
+	}
+	return m, nil
+}
+
+// saveView saves the current query and layout as a view, replacing any view
+// with the same name.
+func (m *Model) saveView(name string) error {
+	t, err := m.requireTable()
+	if err != nil {
+		return err
+	}
+	v := SavedView{Name: name, Query: m.queries[m.screen], TablePrefs: t.Prefs()}
+	views := append([]SavedView(nil), m.screenViews()...)
+	i := findView(views, name)
+	if i >= 0 {
+		views[i] = v
+	} else {
+		views = append(views, v)
+		i = len(views) - 1
+	}
+	if err := m.setScreenViews(views); err != nil {
+		return err
+	}
+	m.setActiveView(name)
+	if i < maxNumberedViews {
+		m.info = fmt.Sprintf("Saved view %d: %s", i+1, name)
+	} else {
+		m.info = "Saved view: " + name
+	}
+	return nil
+}
+
+// deleteView removes the view called name from the current screen.
+func (m *Model) deleteView(name string) error {
+	views := m.screenViews()
+	i := findView(views, name)
+	if i < 0 {
+		return fmt.Errorf("no view %q on this screen", name)
+	}
+	name = views[i].Name
+	rest := append(append([]SavedView(nil), views[:i]...), views[i+1:]...)
+	if err := m.setScreenViews(rest); err != nil {
+		return err
+	}
+	if strings.EqualFold(m.views[m.screen], name) {
+		m.setActiveView("")
+	}
+	m.info = "Deleted view: " + name
+	return nil
+}
+
+// exportViews writes the saved views of every screen to path as JSON, in the
+// same shape as the "views" entry of the prefs file.
+func (m *Model) exportViews(path string) error {
+	if len(m.prefs.Views) == 0 {
+		return fmt.Errorf("no saved views to export")
+	}
+	data, err := json.MarshalIndent(m.prefs.Views, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal views: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write views: %w", err)
+	}
+	m.info = "Exported views to " + path
+	return nil
+}
+
+// importViews adds the views in a file written by exportViews, replacing
+// views with the same name. Nothing is imported if any query is invalid.
+func (m *Model) importViews(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read views: %w", err)
+	}
+	var imported map[string][]SavedView
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return fmt.Errorf("failed to parse views: %w", err)
+	}
+
+	keys := make([]string, 0, len(imported))
+	for key := range imported {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	screens := make(map[string]model.Screen, len(viewScreenKeys))
+	for screen, key := range viewScreenKeys {
+		screens[key] = screen
+	}
+	for _, key := range keys {
+		screen, ok := screens[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown screen %q", path, key)
+		}
+		schema, _ := querySchemaFor(screen)
+		for _, v := range imported[key] {
+			if strings.TrimSpace(v.Name) == "" {
+				return fmt.Errorf("%s: %s view without a name", path, key)
+			}
+			if err := schema.Check(v.Query); err != nil {
+				return fmt.Errorf("%s: view %q: %w", path, v.Name, err)
+			}
+		}
+	}
+
+	if m.prefs.Views == nil {
+		m.prefs.Views = make(map[string][]SavedView)
+	}
+	count := 0
+	for _, key := range keys {
+		views := append([]SavedView(nil), m.prefs.Views[key]...)
+		for _, v := range imported[key] {
+			if i := findView(views, v.Name); i >= 0 {
+				views[i] = v
+			} else {
+				views = append(views, v)
+			}
+			count++
+		}
+		if len(views) > 0 {
+			m.prefs.Views[key] = views
+		}
+	}
+	if err := saveUIPreferences(m.prefsPath, m.prefs); err != nil {
+		return err
+	}
+	noun := "views"
+	if count == 1 {
+		noun = "view"
+	}
+	m.info = fmt.Sprintf("Imported %d %s from %s", count, noun, path)
+	return nil
+}
+
+// viewSummary describes what a view shows.
+func viewSummary(v SavedView) string {
+	var parts []string
+	if v.Query != "" {
+		parts = append(parts, v.Query)
+	} else {
+		parts = append(parts, "all rows")
+	}
+	if v.SortKey != "" {
+		order := "asc"
+		if v.SortDesc {
+			order = "desc"
+		}
+		parts = append(parts, fmt.Sprintf("sort %s %s", strings.ToUpper(v.SortKey), order))
+	}
+	if n := len(v.HiddenColumns); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d hidden", n))
+	}
+	return strings.Join(parts, "  ·  ")
+}
+
+// renderViewPicker renders the saved views of the current screen in a panel
+// centred in width x height.
+func (m Model) renderViewPicker(width, height int) string {
+	views := m.screenViews()
+	rows := make([]SavedView, 0, len(views)+1)
+	rows = append(rows, SavedView{Name: "Default"})
+	rows = append(rows, views...)
+
+	nameWidth := 0
+	for _, v := range rows {
+		nameWidth = max(nameWidth, len([]rune(v.Name)))
+	}
+	innerWidth := max(20, min(width-8, 72))
+	nameWidth = min(nameWidth, innerWidth/2)
+
+	lines := []string{LabelStyle.Render("Saved views"), ""}
+	for i, v := range rows {
+		number := " "
+		if i <= maxNumberedViews {
+			number = strconv.Itoa(i)
+		}
+		marker := " "
+		if i > 0 && v.Name == m.views[m.screen] {
+			marker = "•"
+		}
+		name := util.TruncateString(v.Name, nameWidth)
+		line := fmt.Sprintf("%s %s %-*s  %s", number, marker, nameWidth, name, viewSummary(v))
+		line = util.TruncateString(line, innerWidth)
+		if i == m.viewPicker.cursor {
+			lines = append(lines, SelectedRowStyle.Width(innerWidth).Render(line))
+		} else {
+			lines = append(lines, NormalRowStyle.Render(line))
+		}
+	}
+	if len(views) == 0 {
+		lines = append(lines, "", HelpDescStyle.Render("No saved views yet: :view save name"))
+	}
+
+	var hints []string
+	for _, h := range []struct {
+		action Action
+		desc   string
+	}{
+		{ActionSelect, "apply"},
+		{ActionDelete, "delete"},
+		{ActionDismiss, "close"},
+	} {
+		if bound := m.keys.keysFor(viewPickerChain, h.action); len(bound) > 0 {
+			hints = append(hints, helpKey(keyLabel(bound[0]), h.desc))
+		}
+	}
+	lines = append(lines, "", strings.Join(hints, "  "))
+
+	panel := PanelStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, panel)
+}
+
+// Commands
+
+// viewSubcommands are the words after :view that are not view names.
+var viewSubcommands = []string{"save", "rm", "export", "import"}
+
+// viewNameCandidates completes a view name typed as words, returning each
+// match from the start of the last word.
+func viewNameCandidates(views []SavedView, words []string) []string {
+	typed := strings.ToLower(strings.Join(words, " "))
+	last := words[len(words)-1]
+	var out []string
+	for _, v := range views {
+		if strings.HasPrefix(strings.ToLower(v.Name), typed) {
+			out = append(out, v.Name[len(typed)-len(last):])
+		}
+	}
+	return out
+}
+
+func completeView(m *Model, args []string) []string {
+	if len(args) == 1 {
+		var out []string
+		for _, v := range m.screenViews() {
+			out = append(out, v.Name)
+		}
+		return append(out, viewSubcommands...)
+	}
+	switch args[0] {
+	case "export", "import":
+		if len(args) == 2 {
+			return completePath(args[1])
+		}
+		return nil
+	case "save", "rm":
+		return viewNameCandidates(m.screenViews(), args[1:])
+	}
+	return viewNameCandidates(m.screenViews(), args)
+}
+
+func runView(m *Model, args []string) (tea.Cmd, error) {
+	if len(args) > 0 && (args[0] == "export" || args[0] == "import") {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("usage: :view %s path", args[0])
+		}
+		path, err := config.ExpandHome(args[1])
+		if err != nil {
+			return nil, err
+		}
+		if args[0] == "export" {
+			return nil, m.exportViews(path)
+		}
+		return nil, m.importViews(path)
+	}
+
+	if _, err := m.requireTable(); err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		next, cmd := m.openViewPicker()
+		*m = next.(Model)
+		return cmd, nil
+	}
+	switch args[0] {
+	case "save":
+		if len(args) == 1 {
+			return nil, fmt.Errorf("usage: :view save name")
+		}
+		return nil, m.saveView(strings.Join(args[1:], " "))
+	case "rm":
+		if len(args) == 1 {
+			return nil, fmt.Errorf("usage: :view rm name")
+		}
+		return nil, m.deleteView(strings.Join(args[1:], " "))
+	}
+	name := strings.Join(args, " ")
+	views := m.screenViews()
+	i := findView(views, name)
+	if i < 0 {
+		return nil, fmt.Errorf("no view %q on this screen", name)
+	}
+	return m.applyView(views[i]), nil
+}
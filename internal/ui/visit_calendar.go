@@ -0,0 +1,335 @@
+package ui
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"toni/internal/db"
+	"toni/internal/model"
+	"toni/internal/util"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// calendarLayout is how the visit calendar shows its year.
+type calendarLayout int
+
+const (
+	// calendarHeatmap shows the whole year as a heatmap of weeks, as on a
+	// GitHub profile.
+	calendarHeatmap calendarLayout = iota
+	// calendarMonth shows one month as a grid listing each day's visits.
+	calendarMonth
+)
+
+// VisitCalendarModel is the calendar screen: visits by day, as a yearly
+// heatmap or a month grid. It holds the visits of the year under the cursor
+// and reloads when the cursor moves to another year.
+type VisitCalendarModel struct {
+	cal    *calendar
+	layout calendarLayout
+	// byRating colours days by average rating instead of visit count.
+	byRating bool
+	// year is the year days was loaded for, 0 before the first load.
+	year int
+	days map[string]model.VisitDay
+}
+
+// visitDaysLoadedMsg carries the visits of a year for the calendar screen.
+type visitDaysLoadedMsg struct {
+	year int
+	days []model.VisitDay
+}
+
+// NewVisitCalendarModel creates a calendar screen with the cursor on today.
+func NewVisitCalendarModel(order util.DateOrder) *VisitCalendarModel {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return &VisitCalendarModel{cal: newCalendar(today, today, order)}
+}
+
+func loadVisitDaysCmd(database *sql.DB, year int) tea.Cmd {
+	return func() tea.Msg {
+		from := fmt.Sprintf("%04d-01-01", year)
+		to := fmt.Sprintf("%04d-01-01", year+1)
+		days, err := db.ListVisitDays(database, from, to)
+		if err != nil {
+			return model.ErrorMsg{Err: err}
+		}
+		return visitDaysLoadedMsg{year: year, days: days}
+	}
+}
+
+// setDays stores the visits of a year. Results for a year the cursor has
+// already left are dropped.
+func (m *VisitCalendarModel) setDays(msg visitDaysLoadedMsg) {
+	if msg.year != m.cal.cursor.Year() {
+		return
+	}
+	m.year = msg.year
+	m.days = make(map[string]model.VisitDay, len(msg.days))
+	for _, d := range msg.days {
+		m.days[d.Date] = d
+	}
+}
+
+// needsLoad reports whether the year under the cursor hasn't been loaded.
+func (m *VisitCalendarModel) needsLoad() bool {
+	return m.year != m.cal.cursor.Year()
+}
+
+// handle moves the cursor or changes the layout for action and reports
+// whether action is one the calendar knows.
+func (m *VisitCalendarModel) handle(action Action) bool {
+	switch action {
+	case ActionPrevYear:
+		m.cal.cursor = addMonths(m.cal.cursor, -12)
+	case ActionNextYear:
+		m.cal.cursor = addMonths(m.cal.cursor, 12)
+	case ActionToggleLayout:
+		m.layout = 1 - m.layout
+	case ActionColorBy:
+		m.byRating = !m.byRating
+	default:
+		return m.cal.handle(action)
+	}
+	return true
+}
+
+// selectedDate returns the date under the cursor as YYYY-MM-DD.
+func (m *VisitCalendarModel) selectedDate() string {
+	return m.cal.cursor.Format("2006-01-02")
+}
+
+// View renders the calendar.
+func (m *VisitCalendarModel) View(width, height int) string {
+	inner := width - 8
+	var body string
+	if m.layout == calendarHeatmap {
+		body = m.renderHeatmap(inner)
+	} else {
+		body = m.renderMonth(inner, height-10)
+	}
+
+	mode := "visits"
+	if m.byRating {
+		mode = "average rating"
+	}
+	title := LabelStyle.Render(fmt.Sprintf("%d", m.cal.cursor.Year()))
+	if m.layout == calendarMonth {
+		title = LabelStyle.Render(m.cal.cursor.Format("January 2006"))
+	}
+	title += "  " + HelpDescStyle.Render("coloured by "+mode)
+
+	sections := []string{title, body, m.renderDaySummary()}
+	return PanelStyle.Width(width - 4).Render(strings.Join(sections, "\n\n"))
+}
+
+// level grades a day from 0 (no visits) to 4 for the heatmap.
+func (m *VisitCalendarModel) level(day model.VisitDay) int {
+	n := len(day.Restaurants)
+	if n == 0 {
+		return 0
+	}
+	if !m.byRating {
+		return min(n, 4)
+	}
+	if day.AvgRating == nil {
+		return 1
+	}
+	switch r := *day.AvgRating; {
+	case r >= 8.5:
+		return 4
+	case r >= 7:
+		return 3
+	case r >= 5:
+		return 2
+	default:
+		return 1
+	}
+}
+
+var heatmapGlyphs = []string{"·", "░", "▒", "▓", "█"}
+
+// heatmapStyle colours a heatmap cell: green by count, or from red to green
+// by rating.
+func (m *VisitCalendarModel) heatmapStyle(day model.VisitDay, level int) lipgloss.Style {
+	style := lipgloss.NewStyle().Foreground(ColorGreen)
+	switch {
+	case level == 0:
+		style = HelpDescStyle
+	case m.byRating && day.AvgRating == nil:
+		style = HelpDescStyle
+	case m.byRating && level == 1:
+		style = style.Foreground(ColorRed)
+	case m.byRating && level == 2:
+		style = style.Foreground(ColorYellow)
+	}
+	return style
+}
+
+// renderHeatmap renders the year as columns of weeks, with the weekdays
+// down the side and months along the top.
+func (m *VisitCalendarModel) renderHeatmap(width int) string {
+	const labelWidth = 4
+	year := m.cal.cursor.Year()
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	start := jan1.AddDate(0, 0, -((int(jan1.Weekday()) - int(m.cal.weekStart) + 7) % 7))
+	dec31 := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+	weeks := int(dec31.Sub(start).Hours()/24)/7 + 1
+
+	cell := 2
+	if labelWidth+weeks*cell > width {
+		cell = 1
+	}
+
+	// Month names go above the first week holding the 1st of the month.
+	months := []rune(strings.Repeat(" ", weeks*cell+3))
+	next := 0
+	for mo := time.January; mo <= time.December; mo++ {
+		first := time.Date(year, mo, 1, 0, 0, 0, 0, time.UTC)
+		col := int(first.Sub(start).Hours()/24) / 7 * cell
+		if col < next {
+			continue
+		}
+		copy(months[col:], []rune(first.Format("Jan")))
+		next = col + 4
+	}
+	lines := []string{strings.Repeat(" ", labelWidth) + HelpDescStyle.Render(strings.TrimRight(string(months), " "))}
+
+	for row := 0; row < 7; row++ {
+		var b strings.Builder
+		label := ""
+		if row%2 == 1 {
+			label = time.Weekday((int(m.cal.weekStart) + row) % 7).String()[:3]
+		}
+		b.WriteString(HelpDescStyle.Render(fmt.Sprintf("%-*s", labelWidth, label)))
+		for week := 0; week < weeks; week++ {
+			date := start.AddDate(0, 0, week*7+row)
+			if date.Year() != year {
+				b.WriteString(strings.Repeat(" ", cell))
+				continue
+			}
+			day := m.days[date.Format("2006-01-02")]
+			level := m.level(day)
+			style := m.heatmapStyle(day, level)
+			if date.Equal(m.cal.cursor) {
+				style = SelectedRowStyle
+			}
+			b.WriteString(style.Render(heatmapGlyphs[level]))
+			if cell > 1 {
+				b.WriteString(" ")
+			}
+		}
+		lines = append(lines, b.String())
+	}
+
+	legend := HelpDescStyle.Render("less ")
+	for level, glyph := range heatmapGlyphs {
+		legend += m.heatmapStyle(model.VisitDay{Restaurants: make([]string, level)}, level).Render(glyph) + " "
+	}
+	legend += HelpDescStyle.Render("more")
+	if m.byRating {
+		legend = HelpDescStyle.Render("rating ")
+		for _, bucket := range []struct {
+			rating float64
+			label  string
+		}{{4, "<5"}, {6, "5-7"}, {7.5, "7-8.5"}, {9, "8.5+"}} {
+			r := bucket.rating
+			day := model.VisitDay{Restaurants: []string{""}, AvgRating: &r}
+			level := m.level(day)
+			legend += " " + m.heatmapStyle(day, level).Render(heatmapGlyphs[level]) + HelpDescStyle.Render(" "+bucket.label)
+		}
+	}
+	lines = append(lines, "", strings.Repeat(" ", labelWidth)+legend, "", m.renderYearSummary())
+	return strings.Join(lines, "\n")
+}
+
+// renderYearSummary counts the visits and days out in the year.
+func (m *VisitCalendarModel) renderYearSummary() string {
+	visits := 0
+	for _, d := range m.days {
+		visits += len(d.Restaurants)
+	}
+	return HelpDescStyle.Render(fmt.Sprintf("%s on %s in %d",
+		countNoun(visits, "visit", "visits"), countNoun(len(m.days), "day", "days"), m.cal.cursor.Year()))
+}
+
+// renderMonth renders the month under the cursor as a grid of days, each
+// listing the restaurants visited as far as height allows.
+func (m *VisitCalendarModel) renderMonth(width, height int) string {
+	cellWidth := max(width/7, 4)
+	first := time.Date(m.cal.cursor.Year(), m.cal.cursor.Month(), 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(first.Weekday()) - int(m.cal.weekStart) + 7) % 7
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+	weeks := (offset + daysInMonth + 6) / 7
+	// Each day shows its number and then as many names as fit.
+	names := max(1, min(4, (height-2)/weeks-1))
+
+	cellStyle := lipgloss.NewStyle().Width(cellWidth)
+	var header []string
+	for i := 0; i < 7; i++ {
+		day := time.Weekday((int(m.cal.weekStart) + i) % 7)
+		header = append(header, cellStyle.Render(HelpDescStyle.Render(day.String()[:3])))
+	}
+	rows := []string{lipgloss.JoinHorizontal(lipgloss.Top, header...)}
+
+	for week := 0; week < weeks; week++ {
+		var cells []string
+		for col := 0; col < 7; col++ {
+			n := week*7 + col - offset + 1
+			if n < 1 || n > daysInMonth {
+				cells = append(cells, cellStyle.Render(""))
+				continue
+			}
+			date := first.AddDate(0, 0, n-1)
+			cells = append(cells, cellStyle.Render(m.renderMonthDay(date, cellWidth-1, names)))
+		}
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, cells...))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// renderMonthDay renders one day of the month grid.
+func (m *VisitCalendarModel) renderMonthDay(date time.Time, width, names int) string {
+	day := m.days[date.Format("2006-01-02")]
+	numberStyle := NormalRowStyle
+	switch {
+	case date.Equal(m.cal.cursor):
+		numberStyle = SelectedRowStyle
+	case date.Equal(m.cal.today):
+		numberStyle = HelpKeyStyle.Bold(true).Underline(true)
+	}
+	number := numberStyle.Render(fmt.Sprintf("%2d", date.Day()))
+	if len(day.Restaurants) > 0 {
+		level := m.level(day)
+		number += " " + m.heatmapStyle(day, level).Render(heatmapGlyphs[level])
+	}
+
+	lines := []string{number}
+	for i, name := range day.Restaurants {
+		if i == names-1 && len(day.Restaurants) > names {
+			lines = append(lines, HelpDescStyle.Render(fmt.Sprintf("+%d more", len(day.Restaurants)-i)))
+			break
+		}
+		lines = append(lines, NormalRowStyle.Render(util.TruncateString(name, width)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderDaySummary describes the visits on the day under the cursor.
+func (m *VisitCalendarModel) renderDaySummary() string {
+	date := m.cal.cursor.Format("Mon Jan 2, 2006")
+	day := m.days[m.selectedDate()]
+	if len(day.Restaurants) == 0 {
+		return LabelStyle.Render(date+":") + " " + HelpDescStyle.Render("no visits")
+	}
+	summary := countNoun(len(day.Restaurants), "visit", "visits")
+	if day.AvgRating != nil {
+		summary += ", average " + util.FormatRatingWithStar(day.AvgRating)
+	}
+	return LabelStyle.Render(date+":") + " " + NormalRowStyle.Render(summary+" — "+strings.Join(day.Restaurants, ", ")) +
+		"\n" + HelpDescStyle.Render("enter shows these visits in the Visits list")
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 	"toni/internal/model"
 	"toni/internal/util"
@@ -27,7 +28,7 @@ func (m *VisitDetailModel) View(width, height int) string {
 	var sections []string
 
 	// Keyboard shortcuts in top right corner
-	shortcuts := HelpDescStyle.Render("e edit  d delete  h back")
+	shortcuts := HelpDescStyle.Render("e edit  n notes  d delete  h back")
 
 	// Main info section
 	var fields []string
@@ -53,6 +54,9 @@ func (m *VisitDetailModel) View(width, height int) string {
 		returnValue = lipgloss.NewStyle().Foreground(color).Render(symbol) + "  " + returnValue
 	}
 	fields = append(fields, LabelStyle.Render("Would Return?")+" "+returnValue)
+	if m.visit.WishlistPriority != nil {
+		fields = append(fields, renderField("Want To Visit Priority", fmt.Sprintf("%d/5", *m.visit.WishlistPriority)))
+	}
 
 	sections = append(sections, strings.Join(fields, "\n"))
 
@@ -65,11 +69,17 @@ func (m *VisitDetailModel) View(width, height int) string {
 	// Notes section
 	if m.visit.Notes != "" {
 		sections = append(sections, LabelStyle.Render("Notes:"))
-		sections = append(sections, NormalRowStyle.Render(m.visit.Notes))
+		sections = append(sections, renderMarkdown(m.visit.Notes, width-8))
 	} else {
 		sections = append(sections, HelpDescStyle.Render("No notes for this visit"))
 	}
 
+	// Notes kept from the want to visit entry, for reference
+	if m.visit.WishlistNotes != "" {
+		sections = append(sections, LabelStyle.Render("Want To Visit Notes:"))
+		sections = append(sections, renderMarkdown(m.visit.WishlistNotes, width-8))
+	}
+
 	content := PanelStyle.
 		Width(width - 4).
 		Render(strings.Join(sections, "\n\n"))
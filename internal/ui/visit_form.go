@@ -3,12 +3,14 @@ package ui
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 	"toni/internal/db"
 	"toni/internal/model"
+	"toni/internal/recommend"
 	"toni/internal/search"
 	"toni/internal/util"
 
@@ -39,6 +41,23 @@ type VisitFormModel struct {
 	inputs         []textinput.Model
 	restaurantName string
 	error          string
+	// longNotes holds notes from the editor that the notes input can't.
+	longNotes string
+	dateOrder util.DateOrder
+	// futureDate is a date input in the future that a first save warned
+	// about; saving it again goes ahead.
+	futureDate string
+	// calendar is the open date picker, nil when closed.
+	calendar *calendar
+	// plan is the planned visit being logged, nil for other visits. Saving
+	// removes it, and the restaurant's want to visit entries, with the
+	// visit.
+	plan *model.PlannedVisitRow
+	// wantToVisit is the want to visit entry being marked visited, nil for
+	// other visits. Saving removes it with the visit, keeping its notes and
+	// priority on the visit; cancelling, or changing the restaurant, leaves
+	// it on the list.
+	wantToVisit *model.WantToVisit
 
 	// Autocomplete state
 	searchSeq     int
@@ -47,10 +66,12 @@ type VisitFormModel struct {
 	showDropdown  bool
 	searching     bool
 	searchSpinner spinner.Model
+	// taste ranks search results by predicted rating.
+	taste *recommend.Model
 }
 
 // NewVisitFormModel creates a new visit form.
-func NewVisitFormModel(database *sql.DB, yelpClient *search.YelpClient, restaurantID int64) *VisitFormModel {
+func NewVisitFormModel(database *sql.DB, yelpClient *search.YelpClient, taste *recommend.Model, dateOrder util.DateOrder, restaurantID int64) *VisitFormModel {
 	inputs := make([]textinput.Model, 5)
 
 	// Restaurant name
@@ -61,7 +82,10 @@ func NewVisitFormModel(database *sql.DB, yelpClient *search.YelpClient, restaura
 
 	// Date
 	inputs[1] = textinput.New()
-	inputs[1].Placeholder = "June 20, 2025 (optional)"
+	inputs[1].Placeholder = "today, last friday, 3 days ago, mar 14 (optional)"
+	if dateOrder == util.DayFirst {
+		inputs[1].Placeholder = "today, last friday, 3 days ago, 14/3 (optional)"
+	}
 	inputs[1].CharLimit = 32
 
 	// Rating
@@ -89,6 +113,8 @@ func NewVisitFormModel(database *sql.DB, yelpClient *search.YelpClient, restaura
 		focusedField:  0,
 		inputs:        inputs,
 		searchSpinner: sp,
+		taste:         taste,
+		dateOrder:     dateOrder,
 	}
 
 	// If restaurant ID is provided, load the name
@@ -134,7 +160,33 @@ func (m *VisitFormModel) LoadVisit(visit model.Visit) {
 			m.inputs[3].SetValue("n")
 		}
 	}
-	m.inputs[4].SetValue(visit.Notes)
+	setNotes(&m.inputs[4], &m.longNotes, visit.Notes)
+}
+
+// LoadPlan fills in the visit from a planned visit whose day has come, and
+// asks for the rating.
+func (m *VisitFormModel) LoadPlan(plan model.PlannedVisitRow) {
+	m.plan = &plan
+	m.restaurantID = plan.RestaurantID
+	m.restaurantName = plan.RestaurantName
+	m.inputs[0].SetValue(plan.RestaurantName)
+	if t, err := time.Parse("2006-01-02", plan.PlannedOn); err == nil {
+		m.inputs[1].SetValue(t.Format("January 2, 2006"))
+	}
+	m.inputs[m.focusedField].Blur()
+	m.focusedField = 2
+	m.inputs[2].Focus()
+}
+
+// LoadWantToVisit marks the visit as the one for a want to visit entry.
+func (m *VisitFormModel) LoadWantToVisit(entry model.WantToVisit) {
+	m.wantToVisit = &entry
+}
+
+// dropdownOpen reports whether autocomplete suggestions are showing, in
+// which case dropdown keys take precedence over form keys.
+func (m *VisitFormModel) dropdownOpen() bool {
+	return m.showDropdown && m.focusedField == 0
 }
 
 // Update handles all messages.
@@ -158,6 +210,7 @@ func (m VisitFormModel) Update(msg tea.Msg) (VisitFormModel, tea.Cmd) {
 			} else {
 				m.error = "" // Clear any previous errors
 				m.searchResults = msg.results
+				rankSuggestions(m.searchResults, m.taste)
 				m.searchCursor = 0
 				m.showDropdown = len(msg.results) > 0
 			}
@@ -170,28 +223,28 @@ func (m VisitFormModel) Update(msg tea.Msg) (VisitFormModel, tea.Cmd) {
 	}
 
 	// Handle keyboard input
-	keyMsg, ok := msg.(tea.KeyMsg)
+	keyMsg, ok := msg.(formKeyMsg)
 	if !ok {
 		return m, nil
 	}
 
 	// Handle dropdown navigation when visible
-	if m.showDropdown && m.focusedField == 0 {
-		switch keyMsg.String() {
-		case "esc":
+	if m.dropdownOpen() {
+		switch keyMsg.action {
+		case ActionDismiss:
 			m.showDropdown = false
 			return m, nil
-		case "j", "down":
+		case ActionDown:
 			if m.searchCursor < len(m.searchResults)-1 {
 				m.searchCursor++
 			}
 			return m, nil
-		case "k", "up":
+		case ActionUp:
 			if m.searchCursor > 0 {
 				m.searchCursor--
 			}
 			return m, nil
-		case "enter", "tab":
+		case ActionSelect:
 			if m.searchCursor < len(m.searchResults) {
 				m.selectSuggestion(m.searchResults[m.searchCursor])
 				m.showDropdown = false
@@ -201,9 +254,13 @@ func (m VisitFormModel) Update(msg tea.Msg) (VisitFormModel, tea.Cmd) {
 		}
 	}
 
+	if m.calendar != nil {
+		return m.updateCalendar(keyMsg.action), nil
+	}
+
 	// Handle form navigation
-	switch keyMsg.String() {
-	case "esc":
+	switch keyMsg.action {
+	case ActionCancel:
 		if m.showDropdown {
 			m.showDropdown = false
 			return m, nil
@@ -211,22 +268,34 @@ func (m VisitFormModel) Update(msg tea.Msg) (VisitFormModel, tea.Cmd) {
 		return m, func() tea.Msg {
 			return model.FormCancelledMsg{}
 		}
-	case "ctrl+s":
-		return m, m.save()
-	case "tab":
+	case ActionSave:
+		cmd := m.save()
+		return m, cmd
+	case ActionEditNotes:
+		m.showDropdown = false
+		return m, editNotesCmd(notesValue(m.inputs[4], m.longNotes), notesForm, 0)
+	case ActionCalendar:
+		m.openCalendar()
+		return m, nil
+	case ActionNextField:
 		if !m.showDropdown {
 			m.nextField()
 			return m, nil
 		}
-	case "shift+tab":
+	case ActionPrevField:
 		m.showDropdown = false
 		m.prevField()
 		return m, nil
 	}
 
+	// Notes from the editor can only be changed in the editor.
+	if m.focusedField == 4 && m.longNotes != "" {
+		return m, nil
+	}
+
 	// Update current input
 	var cmd tea.Cmd
-	m.inputs[m.focusedField], cmd = m.inputs[m.focusedField].Update(keyMsg)
+	m.inputs[m.focusedField], cmd = m.inputs[m.focusedField].Update(keyMsg.KeyMsg)
 	cmds = append(cmds, cmd)
 
 	// If restaurant text changed from the selected/prefilled name, clear stale ID.
@@ -311,6 +380,15 @@ func (m *VisitFormModel) View(width, height int) string {
 
 	useSearchSidebar := m.shouldUseSearchSidebar(width)
 
+	if m.plan != nil {
+		fields = append(fields, SuccessStyle.Width(width-8).Render(fmt.Sprintf(
+			"Logging your plan for %s. How was it? Saving removes the plan, keeps its notes if you leave yours empty and takes it off want to visit; a visit to another restaurant leaves the plan alone.",
+			util.FormatDate(m.plan.PlannedOn))))
+	} else if m.wantToVisit != nil {
+		fields = append(fields, SuccessStyle.Width(width-8).Render(
+			"Marking this want to visit as visited. How was it? Saving takes it off the list and keeps its notes and priority on the visit; a visit to another restaurant leaves it on the list."))
+	}
+
 	// Restaurant field
 	restaurantField := renderFormField("Restaurant *", m.inputs[0], m.focusedField == 0)
 	if !useSearchSidebar && m.showDropdown && len(m.searchResults) > 0 {
@@ -322,10 +400,14 @@ func (m *VisitFormModel) View(width, height int) string {
 	}
 	fields = append(fields, restaurantField)
 
-	fields = append(fields, renderFormField("Visit Date (optional)", m.inputs[1], m.focusedField == 1))
+	dateField := renderFormField("Visit Date (optional, ctrl+t for calendar)", m.inputs[1], m.focusedField == 1)
+	if m.calendar != nil {
+		dateField = lipgloss.JoinVertical(lipgloss.Left, dateField, m.calendar.View())
+	}
+	fields = append(fields, dateField)
 	fields = append(fields, renderFormField("Rating (1-10, optional)", m.inputs[2], m.focusedField == 2))
 	fields = append(fields, renderFormField("Would Return? (y/n)", m.inputs[3], m.focusedField == 3))
-	fields = append(fields, renderFormField("Notes", m.inputs[4], m.focusedField == 4))
+	fields = append(fields, renderNotesField("Notes", m.inputs[4], m.longNotes, m.focusedField == 4))
 
 	if m.error != "" {
 		fields = append(fields, "")
@@ -363,10 +445,7 @@ func (m *VisitFormModel) renderDropdown(width int) string {
 			left += "  ·  " + result.City
 		}
 
-		right := ""
-		if result.Cuisine != "" {
-			right = HelpDescStyle.Render(result.Cuisine)
-		}
+		right := suggestionDetail(result, m.taste)
 
 		availableWidth := width - 4
 		padding := max(0, availableWidth-lipgloss.Width(left)-lipgloss.Width(right))
@@ -417,10 +496,7 @@ func (m *VisitFormModel) renderSearchSidebar(width, availableHeight int) string
 			left += "  ·  " + result.City
 		}
 
-		right := ""
-		if result.Cuisine != "" {
-			right = HelpDescStyle.Render(result.Cuisine)
-		}
+		right := suggestionDetail(result, m.taste)
 
 		lineWidth := max(10, width-8)
 		padding := max(0, lineWidth-lipgloss.Width(left)-lipgloss.Width(right))
@@ -449,7 +525,60 @@ func (m *VisitFormModel) prevField() {
 	m.inputs[m.focusedField].Focus()
 }
 
+// openCalendar opens the date picker on the date typed so far, or today.
+func (m *VisitFormModel) openCalendar() {
+	m.showDropdown = false
+	m.inputs[m.focusedField].Blur()
+	m.focusedField = 1
+	m.inputs[1].Focus()
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	selected := today
+	if t, err := util.ParseDate(m.inputs[1].Value(), now, m.dateOrder); err == nil {
+		selected = t
+	}
+	m.calendar = newCalendar(selected, today, m.dateOrder)
+}
+
+// updateCalendar handles a key while the date picker is open.
+func (m VisitFormModel) updateCalendar(action Action) VisitFormModel {
+	switch action {
+	case ActionSelect:
+		m.inputs[1].SetValue(m.calendar.cursor.Format("January 2, 2006"))
+		m.calendar = nil
+		m.error = ""
+	case ActionDismiss:
+		m.calendar = nil
+	default:
+		m.calendar.handle(action)
+	}
+	return m
+}
+
+// parseDate parses the date field. A date after today is refused the first
+// time it is saved, with a warning, and accepted the second.
+func (m *VisitFormModel) parseDate() (string, error) {
+	input := strings.TrimSpace(m.inputs[1].Value())
+	date, err := util.ParseVisitDateInput(input, m.dateOrder, input == m.futureDate)
+	if errors.Is(err, util.ErrFutureDate) {
+		m.futureDate = input
+		return "", fmt.Errorf("%w; save again to keep it", err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return date, nil
+}
+
 func (m *VisitFormModel) save() tea.Cmd {
+	date, err := m.parseDate()
+	if err != nil {
+		m.error = err.Error()
+		return nil
+	}
+	m.error = ""
+
 	return func() tea.Msg {
 		// Validate and parse inputs
 		restaurantName := strings.TrimSpace(m.inputs[0].Value())
@@ -481,12 +610,6 @@ func (m *VisitFormModel) save() tea.Cmd {
 			}
 		}
 
-		dateInput := strings.TrimSpace(m.inputs[1].Value())
-		date, err := util.ParseVisitDateInput(dateInput)
-		if err != nil {
-			return model.ErrorMsg{Err: fmt.Errorf("invalid date format (e.g. June 20, 2025)")}
-		}
-
 		var rating *float64
 		ratingStr := strings.TrimSpace(m.inputs[2].Value())
 		if ratingStr != "" {
@@ -512,7 +635,7 @@ func (m *VisitFormModel) save() tea.Cmd {
 			wouldReturn = &wr
 		}
 
-		notes := strings.TrimSpace(m.inputs[4].Value())
+		notes := notesValue(m.inputs[4], m.longNotes)
 
 		// Save
 		if m.visitID > 0 {
@@ -536,14 +659,41 @@ func (m *VisitFormModel) save() tea.Cmd {
 				Operation: "update",
 				Before:    &before,
 				After: model.Visit{
-					ID:           m.visitID,
-					RestaurantID: restaurantID,
-					VisitedOn:    date,
-					Rating:       rating,
-					Notes:        notes,
-					WouldReturn:  wouldReturn,
+					ID:               m.visitID,
+					RestaurantID:     restaurantID,
+					VisitedOn:        date,
+					Rating:           rating,
+					Notes:            notes,
+					WouldReturn:      wouldReturn,
+					WishlistNotes:    before.WishlistNotes,
+					WishlistPriority: before.WishlistPriority,
 				},
 			}
+		} else if m.plan != nil || m.wantToVisit != nil {
+			visit := model.NewVisit{
+				RestaurantID: restaurantID,
+				VisitedOn:    date,
+				Rating:       rating,
+				Notes:        notes,
+				WouldReturn:  wouldReturn,
+			}
+			var logged model.LoggedVisit
+			var err error
+			if m.plan != nil {
+				logged, err = db.LogPlannedVisit(m.db, m.plan.ID, visit)
+			} else {
+				logged, err = db.LogWantToVisit(m.db, m.wantToVisit.ID, visit)
+			}
+			if err != nil {
+				return model.ErrorMsg{Err: err}
+			}
+			return model.VisitSavedMsg{
+				ID:          logged.Visit.ID,
+				Operation:   "insert",
+				After:       logged.Visit,
+				Plan:        logged.Plan,
+				WantToVisit: logged.WantToVisit,
+			}
 		} else {
 			id, err := db.InsertVisit(m.db, model.NewVisit{
 				RestaurantID: restaurantID,
@@ -2,9 +2,12 @@ package ui
 
 import (
 	"fmt"
+	"io"
 	"sort"
+	"strconv"
 	"strings"
 	"toni/internal/model"
+	"toni/internal/recommend"
 	"toni/internal/util"
 
 	"github.com/charmbracelet/lipgloss"
@@ -32,6 +35,14 @@ type WantToVisitModel struct {
 	sortDesc     bool
 	filterKey    string
 	filterValue  string
+	// query is the filter query the rows were loaded with.
+	query string
+	// view is the saved view last applied, if any.
+	view string
+	// sel holds the rows marked for bulk actions.
+	sel rowSelection
+	// taste predicts the rating of each entry.
+	taste *recommend.Model
 }
 
 // NewWantToVisitModel creates a new want to visit list model.
@@ -47,6 +58,8 @@ func NewWantToVisitModel(entries []model.WantToVisitRow) *WantToVisitModel {
 			{key: "cuisine", label: "cuisine", width: 14},
 			{key: "price", label: "price", width: 10},
 			{key: "priority", label: "priority", width: 12},
+			{key: "predicted", label: "predicted", width: 11},
+			{key: "why", label: "why", width: 26},
 			{key: "notes", label: "notes", width: 24},
 		},
 	}
@@ -92,6 +105,7 @@ func (m *WantToVisitModel) Prefs() TablePrefs {
 }
 
 func (m *WantToVisitModel) rebuild() {
+	m.sel.commitVisual(m.rowIDs(), m.cursor)
 	entries := append([]model.WantToVisitRow(nil), m.allEntries...)
 
 	if m.filterKey != "" && m.filterValue != "" {
@@ -123,6 +137,16 @@ func (m *WantToVisitModel) rebuild() {
 	m.clampCursor()
 }
 
+// setTaste predicts ratings with taste from now on.
+func (m *WantToVisitModel) setTaste(taste *recommend.Model) {
+	m.taste = taste
+	m.rebuild()
+}
+
+func (m *WantToVisitModel) predict(row model.WantToVisitRow) (recommend.Prediction, bool) {
+	return m.taste.Predict(recommend.Traits{Cuisine: row.Cuisine, PriceRange: row.PriceRange, Neighborhood: row.Neighborhood})
+}
+
 func (m *WantToVisitModel) clampCursor() {
 	if len(m.entries) == 0 {
 		m.cursor = 0
@@ -159,6 +183,15 @@ func (m *WantToVisitModel) getValue(row model.WantToVisitRow, key string) string
 			return ""
 		}
 		return fmt.Sprintf("%02d", *row.Priority)
+	case "predicted":
+		p, ok := m.predict(row)
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf("%05.2f", p.Rating)
+	case "why":
+		p, _ := m.predict(row)
+		return p.Summary(2)
 	case "notes":
 		return row.Notes
 	default:
@@ -166,6 +199,96 @@ func (m *WantToVisitModel) getValue(row model.WantToVisitRow, key string) string
 	}
 }
 
+// ColumnKeys returns the keys of every column, hidden or not.
+func (m *WantToVisitModel) ColumnKeys() []string {
+	keys := make([]string, len(m.columns))
+	for i, c := range m.columns {
+		keys[i] = c.key
+	}
+	return keys
+}
+
+func (m *WantToVisitModel) columnIndex(key string) int {
+	for i, c := range m.columns {
+		if c.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// SortByColumn sorts by the named column. An empty key clears sorting.
+func (m *WantToVisitModel) SortByColumn(key string, desc bool) bool {
+	if key != "" && m.columnIndex(key) < 0 {
+		return false
+	}
+	m.sortKey = key
+	m.sortDesc = desc && key != ""
+	m.rebuild()
+	return true
+}
+
+// SetColumnHidden hides or shows the named column. The last visible column
+// cannot be hidden.
+func (m *WantToVisitModel) SetColumnHidden(key string, hidden bool) bool {
+	i := m.columnIndex(key)
+	if i < 0 {
+		return false
+	}
+	if hidden && !m.columns[i].hidden && len(m.visibleColumnIndexes()) <= 1 {
+		return false
+	}
+	m.columns[i].hidden = hidden
+	m.ensureVisibleActiveColumn()
+	return true
+}
+
+// ColumnValues returns the distinct non-empty values of a column.
+func (m *WantToVisitModel) ColumnValues(key string) []string {
+	values := make([]string, 0, len(m.allEntries))
+	for _, r := range m.allEntries {
+		values = append(values, m.cellText(r, key))
+	}
+	return distinctValues(values)
+}
+
+// WriteCSV writes the rows and columns currently shown as CSV, or only the
+// selected rows if any are.
+func (m *WantToVisitModel) WriteCSV(w io.Writer) error {
+	visible := m.visibleColumnIndexes()
+	header := make([]string, len(visible))
+	for i, idx := range visible {
+		header[i] = m.columns[idx].key
+	}
+	records := [][]string{header}
+	rows := m.markedRows()
+	if len(rows) == 0 {
+		rows = m.entries
+	}
+	for _, r := range rows {
+		record := make([]string, len(visible))
+		for i, idx := range visible {
+			record[i] = m.cellText(r, m.columns[idx].key)
+		}
+		records = append(records, record)
+	}
+	return writeCSV(w, records)
+}
+
+// cellText returns a cell as plain text for export and completion.
+func (m *WantToVisitModel) cellText(row model.WantToVisitRow, key string) string {
+	if key == "priority" && row.Priority != nil {
+		return strconv.Itoa(*row.Priority)
+	}
+	if key == "predicted" {
+		if p, ok := m.predict(row); ok {
+			return fmt.Sprintf("%.1f", p.Rating)
+		}
+		return ""
+	}
+	return m.getValue(row, key)
+}
+
 func (m *WantToVisitModel) visibleColumnIndexes() []int {
 	var idxs []int
 	for i, c := range m.columns {
@@ -318,6 +441,9 @@ func (m *WantToVisitModel) CycleFilterBySelectedValue() string {
 func (m *WantToVisitModel) TableMeta() string {
 	col := strings.ToUpper(m.columns[m.activeColumn].label)
 	parts := []string{fmt.Sprintf("col %s", col)}
+	if m.view != "" {
+		parts = append([]string{"view " + m.view}, parts...)
+	}
 	if m.sortKey != "" {
 		order := "asc"
 		if m.sortDesc {
@@ -325,6 +451,9 @@ func (m *WantToVisitModel) TableMeta() string {
 		}
 		parts = append(parts, fmt.Sprintf("sort %s %s", strings.ToUpper(m.sortKey), order))
 	}
+	if m.query != "" {
+		parts = append(parts, "query "+m.query)
+	}
 	if m.filterKey != "" {
 		parts = append(parts, fmt.Sprintf("filter %s=%q", strings.ToUpper(m.filterKey), m.filterValue))
 	}
@@ -438,6 +567,9 @@ func (m *WantToVisitModel) View(width, height int) string {
 	for i := m.offset; i < len(m.entries) && i < m.offset+visibleHeight; i++ {
 		entry := m.entries[i]
 		style := NormalRowStyle
+		if m.sel.isSelected(i, entry.ID, m.cursor) {
+			style = MarkedRowStyle
+		}
 		if i == m.cursor {
 			style = SelectedRowStyle
 		}
@@ -483,8 +615,19 @@ func (m *WantToVisitModel) View(width, height int) string {
 				}
 				cells = append(cells, priorityStr)
 				aligns = append(aligns, lipgloss.Center)
+			case "predicted":
+				predicted := "—"
+				if p, ok := m.predict(entry); ok {
+					predicted = lipgloss.NewStyle().Foreground(ColorYellow).Render(fmt.Sprintf("%.1f ★", p.Rating))
+				}
+				cells = append(cells, predicted)
+				aligns = append(aligns, lipgloss.Center)
+			case "why":
+				p, _ := m.predict(entry)
+				cells = append(cells, util.TruncateString(p.Summary(2), col.width))
+				aligns = append(aligns, lipgloss.Left)
 			case "notes":
-				cells = append(cells, util.TruncateString(entry.Notes, col.width))
+				cells = append(cells, util.TruncateString(util.SingleLine(entry.Notes), col.width))
 				aligns = append(aligns, lipgloss.Left)
 			}
 		}
@@ -504,7 +647,7 @@ func (m *WantToVisitModel) View(width, height int) string {
 	if len(m.entries) > 0 {
 		rowPos = fmt.Sprintf("  ·  row %d/%d", m.cursor+1, len(m.entries))
 	}
-	status := StatusBarStyle.Render(fmt.Sprintf("Total places: %d%s%s%s", len(m.entries), rowPos, filterInfo, meta))
+	status := StatusBarStyle.Render(fmt.Sprintf("Total places: %d%s%s%s%s", len(m.entries), rowPos, m.sel.status(m.rowIDs(), m.cursor), filterInfo, meta))
 
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -524,3 +667,57 @@ func (m *WantToVisitModel) View(width, height int) string {
 		status,
 	)
 }
+
+func (m *WantToVisitModel) rowIDs() []int64 {
+	ids := make([]int64, len(m.entries))
+	for i, r := range m.entries {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+// ToggleMark marks or unmarks the row under the cursor and moves down.
+func (m *WantToVisitModel) ToggleMark() {
+	if len(m.entries) == 0 {
+		return
+	}
+	m.sel.toggle(m.entries[m.cursor].ID)
+	m.CursorDown()
+}
+
+// ToggleVisual starts or ends visual mode. It reports whether visual mode is
+// now on.
+func (m *WantToVisitModel) ToggleVisual() bool {
+	return m.sel.toggleVisual(m.rowIDs(), m.cursor)
+}
+
+// ClearSelection unmarks every row. It reports whether any were selected.
+func (m *WantToVisitModel) ClearSelection() bool {
+	return m.sel.clear()
+}
+
+// SelectionCount returns the number of rows selected.
+func (m *WantToVisitModel) SelectionCount() int {
+	return len(m.sel.indexes(m.rowIDs(), m.cursor))
+}
+
+// markedRows returns the selected rows in list order.
+func (m *WantToVisitModel) markedRows() []model.WantToVisitRow {
+	var out []model.WantToVisitRow
+	for _, i := range m.sel.indexes(m.rowIDs(), m.cursor) {
+		out = append(out, m.entries[i])
+	}
+	return out
+}
+
+// selectedRows returns the rows a bulk action applies to: the selected rows,
+// or the row under the cursor when none are.
+func (m *WantToVisitModel) selectedRows() []model.WantToVisitRow {
+	if rows := m.markedRows(); len(rows) > 0 {
+		return rows
+	}
+	if m.cursor < len(m.entries) {
+		return []model.WantToVisitRow{m.entries[m.cursor]}
+	}
+	return nil
+}
@@ -25,7 +25,7 @@ func NewWantToVisitDetailModel(entry model.WantToVisit, restaurant model.Restaur
 // View renders the want_to_visit detail.
 func (m *WantToVisitDetailModel) View(width, height int) string {
 	// Keyboard shortcuts
-	shortcuts := HelpDescStyle.Render("c mark visited  e edit  d delete  h back")
+	shortcuts := HelpDescStyle.Render("c mark visited  P plan  e edit  n notes  d delete  h back")
 	header := lipgloss.NewStyle().
 		Width(width - 4).
 		Align(lipgloss.Right).
@@ -57,6 +57,9 @@ func (m *WantToVisitDetailModel) View(width, height int) string {
 		priorityText = lipgloss.NewStyle().Foreground(color).Render(priorityText)
 	}
 	fields = append(fields, LabelStyle.Render("Priority:")+" "+priorityText)
+	if m.entry.Source != "" {
+		fields = append(fields, renderField("Source", m.entry.Source))
+	}
 
 	sections = append(sections, strings.Join(fields, "\n"))
 
@@ -67,7 +70,7 @@ func (m *WantToVisitDetailModel) View(width, height int) string {
 			Render(strings.Repeat("─", width-8))
 		sections = append(sections, divider)
 		sections = append(sections, LabelStyle.Render("Notes:"))
-		sections = append(sections, NormalRowStyle.Render(m.entry.Notes))
+		sections = append(sections, renderMarkdown(m.entry.Notes, width-8))
 	}
 
 	info := PanelStyle.
@@ -9,6 +9,7 @@ import (
 	"time"
 	"toni/internal/db"
 	"toni/internal/model"
+	"toni/internal/recommend"
 	"toni/internal/search"
 	"toni/internal/util"
 
@@ -38,6 +39,8 @@ type WantToVisitFormModel struct {
 	inputs         []textinput.Model
 	restaurantName string
 	error          string
+	// longNotes holds notes from the editor that the notes input can't.
+	longNotes string
 
 	// Autocomplete state
 	searchSeq     int
@@ -46,10 +49,12 @@ type WantToVisitFormModel struct {
 	showDropdown  bool
 	searching     bool
 	searchSpinner spinner.Model
+	// taste ranks search results by predicted rating.
+	taste *recommend.Model
 }
 
 // NewWantToVisitFormModel creates a new want_to_visit form.
-func NewWantToVisitFormModel(database *sql.DB, yelpClient *search.YelpClient, restaurantID int64) *WantToVisitFormModel {
+func NewWantToVisitFormModel(database *sql.DB, yelpClient *search.YelpClient, taste *recommend.Model, restaurantID int64) *WantToVisitFormModel {
 	inputs := make([]textinput.Model, 3)
 
 	// Restaurant name
@@ -78,6 +83,7 @@ func NewWantToVisitFormModel(database *sql.DB, yelpClient *search.YelpClient, re
 		focusedField:  0,
 		inputs:        inputs,
 		searchSpinner: sp,
+		taste:         taste,
 	}
 
 	// If restaurant ID is provided, load the name
@@ -110,7 +116,13 @@ func (m *WantToVisitFormModel) LoadWantToVisit(wtv model.WantToVisit) {
 	if wtv.Priority != nil {
 		m.inputs[1].SetValue(strconv.Itoa(*wtv.Priority))
 	}
-	m.inputs[2].SetValue(wtv.Notes)
+	setNotes(&m.inputs[2], &m.longNotes, wtv.Notes)
+}
+
+// dropdownOpen reports whether autocomplete suggestions are showing, in
+// which case dropdown keys take precedence over form keys.
+func (m *WantToVisitFormModel) dropdownOpen() bool {
+	return m.showDropdown && m.focusedField == 0
 }
 
 // Update handles input.
@@ -133,6 +145,7 @@ func (m WantToVisitFormModel) Update(msg tea.Msg) (WantToVisitFormModel, tea.Cmd
 			} else {
 				m.error = ""
 				m.searchResults = msg.results
+				rankSuggestions(m.searchResults, m.taste)
 				m.searchCursor = 0
 				m.showDropdown = len(msg.results) > 0
 			}
@@ -144,27 +157,27 @@ func (m WantToVisitFormModel) Update(msg tea.Msg) (WantToVisitFormModel, tea.Cmd
 		return m, cmd
 	}
 
-	keyMsg, ok := msg.(tea.KeyMsg)
+	keyMsg, ok := msg.(formKeyMsg)
 	if !ok {
 		return m, nil
 	}
 
-	if m.showDropdown && m.focusedField == 0 {
-		switch keyMsg.String() {
-		case "esc":
+	if m.dropdownOpen() {
+		switch keyMsg.action {
+		case ActionDismiss:
 			m.showDropdown = false
 			return m, nil
-		case "j", "down":
+		case ActionDown:
 			if m.searchCursor < len(m.searchResults)-1 {
 				m.searchCursor++
 			}
 			return m, nil
-		case "k", "up":
+		case ActionUp:
 			if m.searchCursor > 0 {
 				m.searchCursor--
 			}
 			return m, nil
-		case "enter", "tab":
+		case ActionSelect:
 			if m.searchCursor < len(m.searchResults) {
 				m.selectSuggestion(m.searchResults[m.searchCursor])
 				m.showDropdown = false
@@ -174,8 +187,8 @@ func (m WantToVisitFormModel) Update(msg tea.Msg) (WantToVisitFormModel, tea.Cmd
 		}
 	}
 
-	switch keyMsg.String() {
-	case "esc":
+	switch keyMsg.action {
+	case ActionCancel:
 		if m.showDropdown {
 			m.showDropdown = false
 			return m, nil
@@ -183,20 +196,28 @@ func (m WantToVisitFormModel) Update(msg tea.Msg) (WantToVisitFormModel, tea.Cmd
 		return m, func() tea.Msg {
 			return model.FormCancelledMsg{}
 		}
-	case "ctrl+s":
+	case ActionSave:
 		return m, m.save()
-	case "tab":
+	case ActionEditNotes:
+		m.showDropdown = false
+		return m, editNotesCmd(notesValue(m.inputs[2], m.longNotes), notesForm, 0)
+	case ActionNextField:
 		m.nextField()
 		return m, nil
-	case "shift+tab":
+	case ActionPrevField:
 		m.showDropdown = false
 		m.prevField()
 		return m, nil
 	}
 
+	// Notes from the editor can only be changed in the editor.
+	if m.focusedField == 2 && m.longNotes != "" {
+		return m, nil
+	}
+
 	// Update current input
 	var cmd tea.Cmd
-	m.inputs[m.focusedField], cmd = m.inputs[m.focusedField].Update(keyMsg)
+	m.inputs[m.focusedField], cmd = m.inputs[m.focusedField].Update(keyMsg.KeyMsg)
 	cmds = append(cmds, cmd)
 
 	// If restaurant text changed from selected/prefilled name, clear stale ID.
@@ -243,7 +264,7 @@ func (m *WantToVisitFormModel) View(width, height int) string {
 	}
 	fields = append(fields, restaurantField)
 	fields = append(fields, renderFormField("Priority (1-5)", m.inputs[1], m.focusedField == 1))
-	fields = append(fields, renderFormField("Notes", m.inputs[2], m.focusedField == 2))
+	fields = append(fields, renderNotesField("Notes", m.inputs[2], m.longNotes, m.focusedField == 2))
 
 	if m.error != "" {
 		fields = append(fields, "")
@@ -313,10 +334,7 @@ func (m *WantToVisitFormModel) renderDropdown(width int) string {
 			left += "  ·  " + result.City
 		}
 
-		right := ""
-		if result.Cuisine != "" {
-			right = HelpDescStyle.Render(result.Cuisine)
-		}
+		right := suggestionDetail(result, m.taste)
 
 		availableWidth := width - 4
 		padding := max(0, availableWidth-lipgloss.Width(left)-lipgloss.Width(right))
@@ -390,7 +408,7 @@ func (m *WantToVisitFormModel) save() tea.Cmd {
 			priority = &p
 		}
 
-		notes := strings.TrimSpace(m.inputs[2].Value())
+		notes := notesValue(m.inputs[2], m.longNotes)
 
 		// Save
 		if m.wantToVisitID > 0 {
@@ -0,0 +1,302 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateOrder says how numeric dates like 3/4 are read.
+type DateOrder int
+
+const (
+	// MonthFirst reads 3/4 as March 4, as in the US.
+	MonthFirst DateOrder = iota
+	// DayFirst reads 3/4 as April 3.
+	DayFirst
+)
+
+// ParseDateOrder parses "mdy" or "dmy". An empty string is MonthFirst.
+func ParseDateOrder(s string) (DateOrder, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "mdy", "md":
+		return MonthFirst, nil
+	case "dmy", "dm":
+		return DayFirst, nil
+	}
+	return MonthFirst, fmt.Errorf("unknown date order %q (want mdy or dmy)", s)
+}
+
+func (o DateOrder) String() string {
+	if o == DayFirst {
+		return "dmy"
+	}
+	return "mdy"
+}
+
+// ErrFutureDate is returned for dates after today when they aren't allowed.
+var ErrFutureDate = errors.New("date is in the future")
+
+var (
+	agoPattern     = regexp.MustCompile(`^(\d+|an?|one)\s*(d|days?|w|wks?|weeks?|m|mos?|months?|y|yrs?|years?)\s+ago$`)
+	isoWeekPattern = regexp.MustCompile(`^(\d{4})-?w(\d{2})(?:-?([1-7]))?$`)
+	numericPattern = regexp.MustCompile(`^(\d{1,2})[/.\-](\d{1,2})(?:[/.\-](\d{2}|\d{4}))?$`)
+	ordinalSuffix  = regexp.MustCompile(`(\d)(st|nd|rd|th)\b`)
+)
+
+var monthNames = map[string]time.Month{}
+
+var weekdayNames = map[string]time.Weekday{}
+
+func init() {
+	for m := time.January; m <= time.December; m++ {
+		name := strings.ToLower(m.String())
+		monthNames[name] = m
+		monthNames[name[:3]] = m
+	}
+	monthNames["sept"] = time.September
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		weekdayNames[name] = d
+		weekdayNames[name[:3]] = d
+	}
+	weekdayNames["tues"] = time.Tuesday
+	weekdayNames["thur"] = time.Thursday
+	weekdayNames["thurs"] = time.Thursday
+}
+
+// ParseDate parses a date typed by a person, relative to now. It accepts:
+//
+//   - today, yesterday and tomorrow
+//   - a weekday, meaning the latest one up to today, or "last friday" for
+//     the one before today
+//   - "3 days ago", "2 weeks ago", "a month ago"
+//   - a month name and day, with or without a year: "mar 14", "14 march",
+//     "March 14th, 2025"
+//   - numeric dates read in order: "3/14" or "14/3", "14.3.2025"
+//   - ISO dates (2025-03-14) and ISO week dates (2025-W11-5, 2025-W11)
+//
+// Dates without a year are the latest such day up to today.
+func ParseDate(input string, now time.Time, order DateOrder) (time.Time, error) {
+	return parseDate(input, now, order, false)
+}
+
+// ParseUpcomingDate parses a date as ParseDate does, but looking ahead, for
+// plans: a weekday is the next one from today, "next friday" the one after
+// today, and dates without a year the next such day from today.
+func ParseUpcomingDate(input string, now time.Time, order DateOrder) (time.Time, error) {
+	return parseDate(input, now, order, true)
+}
+
+func parseDate(input string, now time.Time, order DateOrder, upcoming bool) (time.Time, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(input), " "))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+
+	switch s {
+	case "today", "now":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if day, ok := weekdayNames[strings.TrimPrefix(s, "next ")]; ok && upcoming {
+		ahead := (int(day) - int(today.Weekday()) + 7) % 7
+		if ahead == 0 && strings.HasPrefix(s, "next ") {
+			ahead = 7
+		}
+		return today.AddDate(0, 0, ahead), nil
+	}
+
+	if day, ok := weekdayNames[strings.TrimPrefix(s, "last ")]; ok {
+		back := (int(today.Weekday()) - int(day) + 7) % 7
+		if back == 0 && strings.HasPrefix(s, "last ") {
+			back = 7
+		}
+		return today.AddDate(0, 0, -back), nil
+	}
+
+	if m := agoPattern.FindStringSubmatch(s); m != nil {
+		n := 1
+		if m[1] != "a" && m[1] != "an" && m[1] != "one" {
+			n, _ = strconv.Atoi(m[1])
+		}
+		switch m[2][0] {
+		case 'd':
+			return today.AddDate(0, 0, -n), nil
+		case 'w':
+			return today.AddDate(0, 0, -7*n), nil
+		case 'm':
+			return today.AddDate(0, -n, 0), nil
+		default:
+			return today.AddDate(-n, 0, 0), nil
+		}
+	}
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	if m := isoWeekPattern.FindStringSubmatch(s); m != nil {
+		return isoWeekDate(m[1], m[2], m[3])
+	}
+
+	if m := numericPattern.FindStringSubmatch(s); m != nil {
+		month, day := m[1], m[2]
+		if order == DayFirst {
+			month, day = day, month
+		}
+		mo, _ := strconv.Atoi(month)
+		if mo < 1 || mo > 12 {
+			return time.Time{}, fmt.Errorf("invalid date %q: no month %d (dates are read as %s)", input, mo, order.dateHint())
+		}
+		d, _ := strconv.Atoi(day)
+		return calendarDate(input, m[3], time.Month(mo), d, today, upcoming)
+	}
+
+	if t, ok, err := parseNamedMonth(input, s, today, upcoming); ok {
+		return t, err
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", input)
+}
+
+// dateHint shows how numeric dates are read in this order.
+func (o DateOrder) dateHint() string {
+	if o == DayFirst {
+		return "day/month"
+	}
+	return "month/day"
+}
+
+// parseNamedMonth parses "mar 14", "14 march", "march 14th, 2025" and the
+// like. It reports whether s named a month at all.
+func parseNamedMonth(input, s string, today time.Time, upcoming bool) (time.Time, bool, error) {
+	s = ordinalSuffix.ReplaceAllString(strings.NewReplacer(",", " ", ".", " ").Replace(s), "$1")
+	fields := strings.Fields(s)
+	if len(fields) < 2 || len(fields) > 3 {
+		return time.Time{}, false, nil
+	}
+	month, ok := monthNames[fields[0]]
+	dayField := fields[1]
+	if !ok {
+		month, ok = monthNames[fields[1]]
+		dayField = fields[0]
+	}
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	day, err := strconv.Atoi(dayField)
+	if err != nil {
+		return time.Time{}, true, fmt.Errorf("invalid date %q", input)
+	}
+	year := ""
+	if len(fields) == 3 {
+		year = fields[2]
+		if _, err := strconv.Atoi(year); err != nil || (len(year) != 2 && len(year) != 4) {
+			return time.Time{}, true, fmt.Errorf("invalid date %q", input)
+		}
+	}
+	t, err := calendarDate(input, year, month, day, today, upcoming)
+	return t, true, err
+}
+
+// calendarDate builds a date from its parts. Without a year it is the
+// latest such day up to today, so "feb 29" finds the last leap year, or the
+// next such day from today when upcoming is set. A two-digit year after this
+// year's is taken to be last century ("99" is 1999), except for upcoming
+// dates, which stay in this century.
+func calendarDate(input, year string, month time.Month, day int, today time.Time, upcoming bool) (time.Time, error) {
+	if day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("invalid date %q: %s has no day %d", input, month, day)
+	}
+	if year == "" {
+		step := -1
+		if upcoming {
+			step = 1
+		}
+		for i, y := 0, today.Year(); i < 8; i, y = i+1, y+step {
+			t := time.Date(y, month, day, 0, 0, 0, 0, time.UTC)
+			if t.Month() == month && (upcoming && !t.Before(today) || !upcoming && !t.After(today)) {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid date %q: %s has no day %d", input, month, day)
+	}
+
+	y, _ := strconv.Atoi(year)
+	if len(year) == 2 {
+		century := today.Year() / 100 * 100
+		if !upcoming && y > today.Year()%100 {
+			century -= 100
+		}
+		y += century
+	}
+	t := time.Date(y, month, day, 0, 0, 0, 0, time.UTC)
+	if t.Month() != month {
+		return time.Time{}, fmt.Errorf("invalid date %q: %s %d has no day %d", input, month, y, day)
+	}
+	return t, nil
+}
+
+// isoWeekDate returns the day of an ISO 8601 week. The day defaults to
+// Monday.
+func isoWeekDate(year, week, day string) (time.Time, error) {
+	y, _ := strconv.Atoi(year)
+	w, _ := strconv.Atoi(week)
+	d := 1
+	if day != "" {
+		d, _ = strconv.Atoi(day)
+	}
+	// January 4 is always in week 1.
+	jan4 := time.Date(y, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+	t := monday.AddDate(0, 0, (w-1)*7+d-1)
+	if _, got := t.ISOWeek(); w < 1 || got != w {
+		return time.Time{}, fmt.Errorf("invalid date: %d has no week %d", y, w)
+	}
+	return t, nil
+}
+
+var timeOfDayPattern = regexp.MustCompile(`^(\d{1,2})(?:[:.h]?(\d{2}))?\s*(am|pm|a|p)?$`)
+
+// ParseTimeOfDay parses a time typed by a person, such as 19:30, 7:30pm,
+// 7pm, 1930 or noon, and normalizes it to HH:MM.
+func ParseTimeOfDay(input string) (string, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(input), " "))
+	switch s {
+	case "noon", "midday":
+		return "12:00", nil
+	case "midnight":
+		return "00:00", nil
+	}
+	m := timeOfDayPattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", fmt.Errorf("invalid time %q", input)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if m[3] != "" {
+		if hour < 1 || hour > 12 {
+			return "", fmt.Errorf("invalid time %q", input)
+		}
+		hour %= 12
+		if m[3][0] == 'p' {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return "", fmt.Errorf("invalid time %q", input)
+	}
+	return fmt.Sprintf("%02d:%02d", hour, minute), nil
+}
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNow is a Wednesday.
+var testNow = time.Date(2026, time.October, 14, 15, 4, 0, 0, time.Local)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		input string
+		order DateOrder
+		want  string
+	}{
+		{"today", MonthFirst, "2026-10-14"},
+		{"  Yesterday ", MonthFirst, "2026-10-13"},
+		{"tomorrow", MonthFirst, "2026-10-15"},
+		{"wednesday", MonthFirst, "2026-10-14"},
+		{"last wednesday", MonthFirst, "2026-10-07"},
+		{"fri", MonthFirst, "2026-10-09"},
+		{"last friday", MonthFirst, "2026-10-09"},
+		{"3 days ago", MonthFirst, "2026-10-11"},
+		{"a week ago", MonthFirst, "2026-10-07"},
+		{"2 months ago", MonthFirst, "2026-08-14"},
+		{"1 yr ago", MonthFirst, "2025-10-14"},
+		{"mar 14", MonthFirst, "2026-03-14"},
+		{"dec 25", MonthFirst, "2025-12-25"},
+		{"14 march", MonthFirst, "2026-03-14"},
+		{"March 14th, 2025", MonthFirst, "2025-03-14"},
+		{"sept 3 24", MonthFirst, "2024-09-03"},
+		{"feb 29", MonthFirst, "2024-02-29"},
+		{"3/14", MonthFirst, "2026-03-14"},
+		{"14/3", DayFirst, "2026-03-14"},
+		{"14.3.2025", DayFirst, "2025-03-14"},
+		{"3/14/25", MonthFirst, "2025-03-14"},
+		{"3/14/26", MonthFirst, "2026-03-14"},
+		{"3/14/99", MonthFirst, "1999-03-14"},
+		{"3/14/27", MonthFirst, "1927-03-14"},
+		{"2025-03-14", MonthFirst, "2025-03-14"},
+		{"2025-W11-5", MonthFirst, "2025-03-14"},
+		{"2025w11", MonthFirst, "2025-03-10"},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.input, testNow, tt.order)
+		if err != nil {
+			t.Errorf("ParseDate(%q, %s): %v", tt.input, tt.order, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("ParseDate(%q, %s) = %s, want %s", tt.input, tt.order, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestParseUpcomingDate(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"today", "2026-10-14"},
+		{"wednesday", "2026-10-14"},
+		{"next wednesday", "2026-10-21"},
+		{"fri", "2026-10-16"},
+		{"next fri", "2026-10-16"},
+		{"mar 14", "2027-03-14"},
+		{"dec 25", "2026-12-25"},
+		{"3/14/27", "2027-03-14"},
+	}
+	for _, tt := range tests {
+		got, err := ParseUpcomingDate(tt.input, testNow, MonthFirst)
+		if err != nil {
+			t.Errorf("ParseUpcomingDate(%q): %v", tt.input, err)
+			continue
+		}
+		if got.Format("2006-01-02") != tt.want {
+			t.Errorf("ParseUpcomingDate(%q) = %s, want %s", tt.input, got.Format("2006-01-02"), tt.want)
+		}
+	}
+}
+
+func TestParseDateErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		order DateOrder
+		msg   string
+	}{
+		{"", MonthFirst, "empty date"},
+		{"someday", MonthFirst, "invalid date"},
+		{"14/3", MonthFirst, "no month 14 (dates are read as month/day)"},
+		{"2/30/2025", MonthFirst, "has no day 30"},
+		{"feb 30", MonthFirst, "has no day 30"},
+		{"mar 14 202", MonthFirst, "invalid date"},
+		{"2025-W54", MonthFirst, "has no week 54"},
+	}
+	for _, tt := range tests {
+		_, err := ParseDate(tt.input, testNow, tt.order)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("ParseDate(%q, %s) error = %v, want %q", tt.input, tt.order, err, tt.msg)
+		}
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"19:30", "19:30"},
+		{"7:30pm", "19:30"},
+		{"7pm", "19:00"},
+		{"12am", "00:00"},
+		{"12 pm", "12:00"},
+		{"1930", "19:30"},
+		{"noon", "12:00"},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeOfDay(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseTimeOfDay(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+	for _, input := range []string{"25:00", "13pm", "7:75", "soon"} {
+		if got, err := ParseTimeOfDay(input); err == nil {
+			t.Errorf("ParseTimeOfDay(%q) = %q, want an error", input, got)
+		}
+	}
+}
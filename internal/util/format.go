@@ -142,29 +142,25 @@ func formatRatingNumber(v float64) string {
 	return s
 }
 
-// ParseVisitDateInput parses flexible user input and normalizes to ISO (YYYY-MM-DD).
-// Empty input is allowed and returns "".
-func ParseVisitDateInput(input string) (string, error) {
+// ParseVisitDateInput parses a visit date as ParseDate does and normalizes
+// it to ISO (YYYY-MM-DD). Empty input is allowed and returns "". Dates after
+// today return ErrFutureDate unless allowFuture is set.
+func ParseVisitDateInput(input string, order DateOrder, allowFuture bool) (string, error) {
 	s := strings.TrimSpace(input)
 	if s == "" {
 		return "", nil
 	}
 
-	layouts := []string{
-		"2006-01-02",
-		"January 2, 2006",
-		"Jan 2, 2006",
-		"1/2/2006",
-		"01/02/2006",
+	now := time.Now()
+	t, err := ParseDate(s, now, order)
+	if err != nil {
+		return "", err
 	}
-
-	for _, layout := range layouts {
-		if t, err := time.Parse(layout, s); err == nil {
-			return t.Format("2006-01-02"), nil
-		}
+	date := t.Format("2006-01-02")
+	if !allowFuture && date > now.Format("2006-01-02") {
+		return "", fmt.Errorf("%s: %w", FormatDate(date), ErrFutureDate)
 	}
-
-	return "", fmt.Errorf("invalid date format")
+	return date, nil
 }
 
 // TruncateString truncates a string to maxLen and adds "..." if needed.
@@ -178,3 +174,9 @@ func TruncateString(s string, maxLen int) string {
 	}
 	return string(runes[:maxLen-3]) + "..."
 }
+
+// SingleLine collapses line breaks and runs of whitespace in s to single
+// spaces, for showing multi-line text in a table cell.
+func SingleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
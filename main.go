@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"toni/cmd"
-	"toni/internal/db"
 	"toni/internal/search"
 	"toni/internal/ui"
 
@@ -23,31 +22,70 @@ func main() {
 		os.Exit(1)
 	}
 
+	if config.Command != "" {
+		if err := cmd.RunCommand(config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Initialize Yelp client
 	var yelpClient *search.YelpClient
 	if config.YelpAPIKey != "" {
 		yelpClient = search.NewYelpClient(config.YelpAPIKey)
+		yelpClient.SetDefaultLocation(config.Location)
 	} else if !config.YelpEnabled {
 		fmt.Fprintln(os.Stderr, "ℹ  Yelp autocomplete disabled in onboarding settings")
 	} else {
 		fmt.Fprintln(os.Stderr, "ℹ  No YELP_API_KEY set — restaurant autocomplete disabled")
 	}
 
+	// Load key bindings before touching the database so a broken keymap
+	// fails fast.
+	keys, err := ui.LoadKeyMap(config.KeymapPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Detect terminal capabilities
 	termCaps := ui.DetectTerminalCapabilities()
 
 	// Open database
-	database, err := db.Open(config.DBPath)
+	store, err := cmd.OpenStore(config)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to open database: %v\n", err)
 		os.Exit(1)
 	}
-	defer database.Close()
+
+	// An encrypted store must be closed before exiting so the latest changes
+	// are sealed and the decrypted working copy is wiped.
+	exit := func(code int) {
+		if err := store.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to close database: %v\n", err)
+			code = 1
+		}
+		os.Exit(code)
+	}
+
+	if err := cmd.ReviewIntegrity(store.DB); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to check database integrity: %v\n", err)
+		exit(1)
+	}
+	cmd.StartupBackup(config, store)
+	store.StartAutoSync()
 
 	// Create and run Bubble Tea app
-	p := tea.NewProgram(ui.New(database, yelpClient, termCaps), tea.WithAltScreen())
+	p := tea.NewProgram(ui.New(store.DB, yelpClient, termCaps, ui.Options{
+		PrefsPath:   config.PrefsPath,
+		Keys:        keys,
+		HistoryPath: config.HistoryPath,
+		DateOrder:   config.DateOrder,
+	}), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running app: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
+	exit(0)
 }